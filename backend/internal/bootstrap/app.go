@@ -57,6 +57,104 @@ func New() (*App, error) {
 		log.Printf("⚠️  Profile customization migrations failed: %v", err)
 	}
 
+	if err := database.MigrateAuthSessions(gormDB); err != nil {
+		log.Printf("⚠️  Auth session migrations failed: %v", err)
+	}
+
+	if err := database.MigrateEmailVerification(gormDB); err != nil {
+		log.Printf("⚠️  Email verification migrations failed: %v", err)
+	}
+
+	if err := database.MigrateOIDCIdentities(gormDB); err != nil {
+		log.Printf("⚠️  OIDC identity migrations failed: %v", err)
+	}
+
+	if err := database.MigrateGuestProgress(gormDB); err != nil {
+		log.Printf("⚠️  Guest progress migrations failed: %v", err)
+	}
+
+	if err := database.MigrateTwoFactor(gormDB); err != nil {
+		log.Printf("⚠️  Two-factor migrations failed: %v", err)
+	}
+
+	if err := database.MigrateLoginEvents(gormDB); err != nil {
+		log.Printf("⚠️  Login event migrations failed: %v", err)
+	}
+
+	if err := database.MigrateAccountDeletion(gormDB); err != nil {
+		log.Printf("⚠️  Account deletion migrations failed: %v", err)
+	}
+
+	if err := database.MigrateAssetKeys(gormDB); err != nil {
+		log.Printf("⚠️  Asset key migrations failed: %v", err)
+	}
+
+	if err := database.MigrateStorageQuotas(gormDB); err != nil {
+		log.Printf("⚠️  Storage quota migrations failed: %v", err)
+	}
+
+	if err := database.MigratePlayerProfiles(gormDB); err != nil {
+		log.Printf("⚠️  Player profile migrations failed: %v", err)
+	}
+
+	if err := database.MigrateFriends(gormDB); err != nil {
+		log.Printf("⚠️  Friends migrations failed: %v", err)
+	}
+
+	if err := database.MigrateNotifications(gormDB); err != nil {
+		log.Printf("⚠️  Notification migrations failed: %v", err)
+	}
+
+	if err := database.MigrateTournaments(gormDB); err != nil {
+		log.Printf("⚠️  Tournament migrations failed: %v", err)
+	}
+
+	if err := database.MigrateDailyChallengeAttempts(gormDB); err != nil {
+		log.Printf("⚠️  Daily challenge migrations failed: %v", err)
+	}
+
+	if err := database.MigrateDailyStreaks(gormDB); err != nil {
+		log.Printf("⚠️  Daily streak migrations failed: %v", err)
+	}
+
+	if err := database.MigrateAchievementEngine(gormDB); err != nil {
+		log.Printf("⚠️  Achievement migrations failed: %v", err)
+	}
+
+	if err := database.MigrateMasterySchedule(gormDB); err != nil {
+		log.Printf("⚠️  Mastery schedule migrations failed: %v", err)
+	}
+
+	if err := database.MigrateSeasonRollover(gormDB); err != nil {
+		log.Printf("⚠️  Season rollover migrations failed: %v", err)
+	}
+
+	if err := database.MigrateUnifiedRanks(gormDB); err != nil {
+		log.Printf("⚠️  Unified rank migrations failed: %v", err)
+	}
+
+	if err := database.MigrateLeaderboards(gormDB); err != nil {
+		log.Printf("⚠️  Leaderboard migrations failed: %v", err)
+	}
+
+	if err := database.MigrateRankDecay(gormDB); err != nil {
+		log.Printf("⚠️  Rank decay migrations failed: %v", err)
+	}
+
+	if err := database.MigrateAntiCheat(gormDB); err != nil {
+		log.Printf("⚠️  Anti-cheat migrations failed: %v", err)
+	}
+
+	if err := database.MigrateAdmin(gormDB); err != nil {
+		log.Printf("⚠️  Admin migrations failed: %v", err)
+	}
+	if err := database.MigrateGuestClaims(gormDB); err != nil {
+		log.Printf("⚠️  Guest claim migrations failed: %v", err)
+	}
+	if err := database.MigrateAnswerMisses(gormDB); err != nil {
+		log.Printf("⚠️  Answer miss migrations failed: %v", err)
+	}
+
 	// Redis
 	if err := redis.InitRedis(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.TLS); err != nil {
 		log.Printf("⚠️  Redis unavailable: %v", err)
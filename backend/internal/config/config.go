@@ -8,13 +8,19 @@ import (
 )
 
 type Config struct {
-	DB    DBConfig
-	JWT   JWTConfig
-	Game  GameConfig
-	SMTP  SMTPConfig
-	Redis RedisConfig
-	Port  string
-	Env   string
+	DB      DBConfig
+	JWT     JWTConfig
+	Auth    AuthConfig
+	OIDC    OIDCConfig
+	Game    GameConfig
+	SMTP    SMTPConfig
+	Redis   RedisConfig
+	Storage StorageConfig
+	Port    string
+	Env     string
+	// FrontendURL is the public address of the web app. Links in emails
+	// point at it, never at the Host a request came in with.
+	FrontendURL string
 }
 
 type DBConfig struct {
@@ -32,10 +38,59 @@ type JWTConfig struct {
 	RefreshTokenExpiry  int
 }
 
+type AuthConfig struct {
+	// RequireVerifiedEmail keeps unverified accounts out of rated games and
+	// the leaderboard.
+	RequireVerifiedEmail bool
+	// LockoutThreshold is how many failed logins an account absorbs before
+	// it is locked. Each further failure doubles the lock, from
+	// LockoutBaseSeconds up to LockoutMaxSeconds.
+	LockoutThreshold   int
+	LockoutBaseSeconds int
+	LockoutMaxSeconds  int
+	// DeletionGraceDays is how long a deleted account can still be restored
+	// before it is anonymized.
+	DeletionGraceDays int
+}
+
+type OIDCConfig struct {
+	// RedirectBaseURL is the public URL of this API; callbacks go to
+	// {RedirectBaseURL}/api/v2/auth/oidc/{provider}/callback.
+	RedirectBaseURL string
+	// FrontendURL receives the browser after login. Tokens are passed in the
+	// URL fragment. When empty the callback answers with JSON.
+	FrontendURL string
+	Providers   []OIDCProviderConfig
+}
+
+type OIDCProviderConfig struct {
+	Name         string
+	DiscoveryURL string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
 type GameConfig struct {
 	MaxPlayersPerRoom   int
 	RoundDurationSeconds int
 	RoundsPerGame       int
+	// StreakTimezone is the IANA timezone whose calendar days daily
+	// challenge streaks are counted in
+	StreakTimezone string
+	// RankDecayFromRank is the lowest rank whose players lose rating after
+	// RankDecayInactiveDays without a ranked game, RankDecayPointsPerDay a
+	// day, down to that rank's threshold. They are emailed
+	// RankDecayWarningDays before it starts. 0 inactive days turns decay off.
+	RankDecayFromRank     string
+	RankDecayInactiveDays int
+	RankDecayWarningDays  int
+	RankDecayPointsPerDay int
+	// AntiCheatMinResponseMs is the fastest a person can answer, counted
+	// from the start of the round. AntiCheatShadowExclude hides flagged
+	// players from the leaderboards until their flags are reviewed.
+	AntiCheatMinResponseMs int
+	AntiCheatShadowExclude bool
 }
 
 type SMTPConfig struct {
@@ -53,6 +108,50 @@ type RedisConfig struct {
 	TLS      bool
 }
 
+type StorageConfig struct {
+	// Backend is "cloudinary", "local", "s3" or "memory". When empty,
+	// Cloudinary is used if it is configured and the local disk otherwise.
+	Backend string
+	// CloudinaryURL takes precedence over the individual Cloudinary fields.
+	CloudinaryURL       string
+	CloudinaryCloudName string
+	CloudinaryAPIKey    string
+	CloudinaryAPISecret string
+	// LocalDir holds uploads of the local backend, served under LocalBaseURL.
+	LocalDir     string
+	LocalBaseURL string
+	S3           S3Config
+	// Quotas limits the profile assets of each storage tier. Users on a tier
+	// without an entry get the DefaultTier quota.
+	Quotas      map[string]StorageQuota
+	DefaultTier string
+	// UnusedAssetDays is how long an asset may stay unreferenced before it is
+	// deleted. Zero keeps unused assets forever.
+	UnusedAssetDays int
+}
+
+// StorageQuota caps the total size and number of a user's profile assets.
+// Zero means unlimited.
+type StorageQuota struct {
+	MaxBytes  int64
+	MaxAssets int
+}
+
+// S3Config points at AWS S3 or any S3-compatible service such as MinIO or R2.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PublicURL is the base of object URLs handed to clients, e.g. a CDN.
+	// Defaults to the bucket URL.
+	PublicURL string
+	// PathStyle addresses the bucket as {Endpoint}/{Bucket} instead of a
+	// virtual host, which most self-hosted services require.
+	PathStyle bool
+}
+
 func Load() *Config {
 	env := getEnv("ENV", "production")
 	sslMode := "disable"
@@ -74,10 +173,29 @@ func Load() *Config {
 			Expiry:             getEnvInt("JWT_EXPIRY", 86400),
 			RefreshTokenExpiry: getEnvInt("REFRESH_TOKEN_EXPIRY", 2592000),
 		},
+		Auth: AuthConfig{
+			RequireVerifiedEmail: getEnv("REQUIRE_VERIFIED_EMAIL", "false") == "true",
+			LockoutThreshold:     getEnvInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+			LockoutBaseSeconds:   getEnvInt("LOGIN_LOCKOUT_BASE_SECONDS", 60),
+			LockoutMaxSeconds:    getEnvInt("LOGIN_LOCKOUT_MAX_SECONDS", 3600),
+			DeletionGraceDays:    getEnvInt("ACCOUNT_DELETION_GRACE_DAYS", 14),
+		},
+		OIDC: OIDCConfig{
+			RedirectBaseURL: strings.TrimRight(getEnv("OIDC_REDIRECT_BASE_URL", ""), "/"),
+			FrontendURL:     strings.TrimRight(getEnv("OIDC_FRONTEND_URL", ""), "/"),
+			Providers:       loadOIDCProviders(),
+		},
 		Game: GameConfig{
 			MaxPlayersPerRoom:   getEnvInt("MAX_PLAYERS_PER_ROOM", 6),
 			RoundDurationSeconds: getEnvInt("ROUND_DURATION_SECONDS", 15),
 			RoundsPerGame:       getEnvInt("ROUNDS_PER_GAME", 10),
+			StreakTimezone:      getEnv("DAILY_STREAK_TIMEZONE", "UTC"),
+			RankDecayFromRank:     getEnv("RANK_DECAY_FROM_RANK", "DIAMOND"),
+			RankDecayInactiveDays: getEnvInt("RANK_DECAY_INACTIVE_DAYS", 14),
+			RankDecayWarningDays:  getEnvInt("RANK_DECAY_WARNING_DAYS", 3),
+			RankDecayPointsPerDay: getEnvInt("RANK_DECAY_POINTS_PER_DAY", 25),
+			AntiCheatMinResponseMs: getEnvInt("ANTI_CHEAT_MIN_RESPONSE_MS", 400),
+			AntiCheatShadowExclude: getEnv("ANTI_CHEAT_SHADOW_EXCLUDE", "false") == "true",
 		},
 		SMTP: SMTPConfig{
 			Host:     getEnv("SMTP_HOST", "smtp.gmail.com"),
@@ -92,8 +210,30 @@ func Load() *Config {
 			DB:       getEnvInt("REDIS_DB", 0),
 			TLS:      getEnv("REDIS_TLS", "false") == "true",
 		},
-		Port: getEnv("PORT", "8085"),
-		Env:  env,
+		Storage: StorageConfig{
+			Backend:             strings.ToLower(getEnv("STORAGE_BACKEND", "")),
+			CloudinaryURL:       getEnv("CLOUDINARY_API_ENVIRONMENT_VARIABLE", ""),
+			CloudinaryCloudName: getEnv("CLOUDINARY_CLOUD_NAME", getEnv("CLOUDINARY_ClOUD_NAME", "")),
+			CloudinaryAPIKey:    getEnv("CLOUDINARY_API_KEY", ""),
+			CloudinaryAPISecret: getEnv("CLOUDINARY_API_SECRET", ""),
+			LocalDir:            getEnv("STORAGE_LOCAL_DIR", "./uploads"),
+			LocalBaseURL:        strings.TrimRight(getEnv("STORAGE_LOCAL_BASE_URL", "/uploads"), "/"),
+			S3: S3Config{
+				Endpoint:        strings.TrimRight(getEnv("S3_ENDPOINT", "https://s3.amazonaws.com"), "/"),
+				Region:          getEnv("S3_REGION", "us-east-1"),
+				Bucket:          getEnv("S3_BUCKET", ""),
+				AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+				SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+				PublicURL:       strings.TrimRight(getEnv("S3_PUBLIC_URL", ""), "/"),
+				PathStyle:       getEnv("S3_PATH_STYLE", "false") == "true",
+			},
+			Quotas:          loadStorageQuotas(),
+			DefaultTier:     "free",
+			UnusedAssetDays: getEnvInt("STORAGE_UNUSED_ASSET_DAYS", 30),
+		},
+		Port:        getEnv("PORT", "8085"),
+		Env:         env,
+		FrontendURL: strings.TrimRight(getEnv("FRONTEND_URL", "https://briworld.onrender.com"), "/"),
 	}
 }
 
@@ -118,6 +258,60 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// loadOIDCProviders reads OIDC_PROVIDERS (e.g. "google,microsoft") and the
+// OIDC_<NAME>_* variables of each listed provider.
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		discoveryURL := getEnv(prefix+"DISCOVERY_URL", "")
+		if discoveryURL == "" {
+			if issuer := getEnv(prefix+"ISSUER", ""); issuer != "" {
+				discoveryURL = strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration"
+			}
+		}
+		clientID := getEnv(prefix+"CLIENT_ID", "")
+		if discoveryURL == "" || clientID == "" {
+			continue
+		}
+		providers = append(providers, OIDCProviderConfig{
+			Name:         name,
+			DiscoveryURL: discoveryURL,
+			ClientID:     clientID,
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		})
+	}
+	return providers
+}
+
+// loadStorageQuotas reads STORAGE_TIERS (e.g. "free,supporter") and the
+// STORAGE_TIER_<NAME>_MAX_BYTES and STORAGE_TIER_<NAME>_MAX_ASSETS variables of
+// each tier. The free tier always exists.
+func loadStorageQuotas() map[string]StorageQuota {
+	quotas := make(map[string]StorageQuota)
+	for _, name := range strings.Split("free,"+getEnv("STORAGE_TIERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "STORAGE_TIER_" + strings.ToUpper(name) + "_"
+		defaultBytes, defaultAssets := 0, 0
+		if name == "free" {
+			defaultBytes, defaultAssets = 100*1024*1024, 100
+		}
+		quotas[name] = StorageQuota{
+			MaxBytes:  int64(getEnvInt(prefix+"MAX_BYTES", defaultBytes)),
+			MaxAssets: getEnvInt(prefix+"MAX_ASSETS", defaultAssets),
+		}
+	}
+	return quotas
+}
+
 func getRedisAddr() string {
 	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
 		return strings.TrimSpace(addr)
@@ -0,0 +1,38 @@
+package database
+
+import "gorm.io/gorm"
+
+const (
+	assetKeysMigrationVersion     = "2026_10_18_asset_store_keys"
+	storageQuotasMigrationVersion = "2026_10_18_storage_quotas"
+)
+
+// MigrateAssetKeys points public_id of locally stored assets at the file on
+// disk. Local uploads used to record a Cloudinary-style public ID that did not
+// match the file name, while the asset store deletes by key.
+func MigrateAssetKeys(db *GormDB) error {
+	return runVersionedMigration(db, assetKeysMigrationVersion, func(tx *gorm.DB) error {
+		return tx.Exec(`
+			UPDATE profile_assets
+			SET public_id = SUBSTRING(url FROM 10)
+			WHERE provider = 'local' AND url LIKE '/uploads/%'
+		`).Error
+	})
+}
+
+// MigrateStorageQuotas adds the storage tier of each user and the index the
+// quota and unused-asset queries filter on.
+func MigrateStorageQuotas(db *GormDB) error {
+	return runVersionedMigration(db, storageQuotasMigrationVersion, func(tx *gorm.DB) error {
+		statements := []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS storage_tier VARCHAR(20) NOT NULL DEFAULT 'free'`,
+			`CREATE INDEX IF NOT EXISTS idx_profile_assets_user_created ON profile_assets (user_id, created_at)`,
+		}
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"briworld/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	authSessionsMigrationVersion      = "2026_10_18_auth_refresh_sessions"
+	emailVerificationMigrationVersion = "2026_10_18_email_verification"
+	oidcIdentitiesMigrationVersion    = "2026_10_18_oidc_identities"
+	twoFactorMigrationVersion         = "2026_10_18_two_factor"
+	loginEventsMigrationVersion       = "2026_10_18_login_events"
+	accountDeletionMigrationVersion   = "2026_10_18_account_deletion"
+)
+
+// MigrateAuthSessions adds device metadata to sessions and the refresh token table.
+func MigrateAuthSessions(db *GormDB) error {
+	return runVersionedMigration(db, authSessionsMigrationVersion, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.Session{}, &models.RefreshToken{})
+	})
+}
+
+// MigrateEmailVerification adds the verification token expiry column. Accounts
+// that existed before verification was introduced are treated as verified.
+func MigrateEmailVerification(db *GormDB) error {
+	return runVersionedMigration(db, emailVerificationMigrationVersion, func(tx *gorm.DB) error {
+		statements := []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN DEFAULT FALSE`,
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_token VARCHAR(64)`,
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_token_expiry TIMESTAMPTZ`,
+			`UPDATE users SET email_verified = TRUE WHERE email_verified IS NOT TRUE`,
+		}
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateOIDCIdentities creates the table linking users to OIDC providers.
+func MigrateOIDCIdentities(db *GormDB) error {
+	return runVersionedMigration(db, oidcIdentitiesMigrationVersion, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.UserIdentity{})
+	})
+}
+
+// MigrateTwoFactor adds the TOTP columns to users and the recovery code table.
+func MigrateTwoFactor(db *GormDB) error {
+	return runVersionedMigration(db, twoFactorMigrationVersion, func(tx *gorm.DB) error {
+		statements := []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS two_factor_enabled BOOLEAN DEFAULT FALSE`,
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS two_factor_secret VARCHAR(64)`,
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS two_factor_last_step BIGINT DEFAULT 0`,
+		}
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return tx.AutoMigrate(&models.TwoFactorRecoveryCode{})
+	})
+}
+
+// MigrateLoginEvents creates the login audit table.
+func MigrateLoginEvents(db *GormDB) error {
+	return runVersionedMigration(db, loginEventsMigrationVersion, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.LoginEvent{})
+	})
+}
+
+// MigrateAccountDeletion adds the columns tracking scheduled and completed
+// account deletions.
+func MigrateAccountDeletion(db *GormDB) error {
+	return runVersionedMigration(db, accountDeletionMigrationVersion, func(tx *gorm.DB) error {
+		statements := []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMPTZ`,
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMPTZ`,
+			`CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL`,
+		}
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
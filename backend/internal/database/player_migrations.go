@@ -0,0 +1,39 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+const playerProfilesMigrationVersion = "2026_10_18_player_profiles"
+
+// MigratePlayerProfiles adds the profile privacy setting and the indexes
+// behind player search. Fuzzy search needs the pg_trgm extension; when the
+// database user may not create it, search falls back to substring matching,
+// which the player service logs at startup and reports in search results.
+func MigratePlayerProfiles(db *GormDB) error {
+	err := runVersionedMigration(db, playerProfilesMigrationVersion, func(tx *gorm.DB) error {
+		statements := []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS profile_visibility VARCHAR(20) NOT NULL DEFAULT 'public'`,
+			`CREATE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username) text_pattern_ops)`,
+		}
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Outside the versioned transaction: a failed CREATE EXTENSION would
+	// abort it.
+	if err := db.DB.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`).Error; err != nil {
+		log.Printf("⚠️  pg_trgm unavailable, player search will not be fuzzy: %v", err)
+		return nil
+	}
+	return db.DB.Exec(`CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING gin (username gin_trgm_ops)`).Error
+}
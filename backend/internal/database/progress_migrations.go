@@ -0,0 +1,218 @@
+package database
+
+import (
+	"briworld/internal/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	guestProgressMigrationVersion   = "2026_10_18_guest_progress"
+	dailyChallengeMigrationVersion  = "2026_10_18_daily_challenge_attempts"
+	dailyStreakMigrationVersion     = "2026_10_18_daily_streaks"
+	achievementMigrationVersion     = "2026_10_18_achievement_engine"
+	masteryScheduleMigrationVersion = "2026_10_18_mastery_schedule"
+	seasonRolloverMigrationVersion  = "2026_10_18_season_rollover"
+	unifiedRanksMigrationVersion    = "2026_10_18_unified_ranks"
+	leaderboardMigrationVersion     = "2026_10_18_leaderboards"
+	rankDecayMigrationVersion       = "2026_10_18_rank_decay"
+	antiCheatMigrationVersion       = "2026_10_18_anti_cheat"
+	adminMigrationVersion           = "2026_10_18_admin"
+	guestClaimMigrationVersion      = "2026_10_18_guest_claims"
+	answerMissesMigrationVersion    = "2026_10_18_answer_misses"
+)
+
+// MigrateGuestProgress adds match history and lets mastery and challenge
+// completions be recorded against a guest session.
+func MigrateGuestProgress(db *GormDB) error {
+	return runVersionedMigration(db, guestProgressMigrationVersion, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.MatchResult{}, &models.CountryMastery{}, &models.ChallengeCompletion{})
+	})
+}
+
+// MigrateDailyChallengeAttempts stores each daily challenge's countries and
+// limits users to one completion per challenge. Users who completed one more
+// than once keep their best score.
+func MigrateDailyChallengeAttempts(db *GormDB) error {
+	return runVersionedMigration(db, dailyChallengeMigrationVersion, func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.DailyChallenge{}, &models.ChallengeCompletion{}); err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM challenge_completions c USING challenge_completions d
+			WHERE c.user_id <> '00000000-0000-0000-0000-000000000000'
+			AND c.user_id = d.user_id AND c.challenge_id = d.challenge_id
+			AND (c.score < d.score OR (c.score = d.score AND c.id > d.id))`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_challenge_completions_attempt ON challenge_completions (challenge_id, user_id) WHERE user_id <> '00000000-0000-0000-0000-000000000000'`).Error
+	})
+}
+
+// MigrateDailyStreaks creates the daily challenge streak table.
+func MigrateDailyStreaks(db *GormDB) error {
+	return runVersionedMigration(db, dailyStreakMigrationVersion, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.DailyStreak{})
+	})
+}
+
+// MigrateAchievementEngine adds achievement progress counters and lets each
+// achievement be unlocked only once per user.
+func MigrateAchievementEngine(db *GormDB) error {
+	return runVersionedMigration(db, achievementMigrationVersion, func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.AchievementProgress{}); err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM user_achievements a USING user_achievements b
+			WHERE a.user_id = b.user_id AND a.achievement_id = b.achievement_id
+			AND (a.unlocked_at > b.unlocked_at OR (a.unlocked_at = b.unlocked_at AND a.id > b.id))`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_achievements_once ON user_achievements (user_id, achievement_id)`).Error
+	})
+}
+
+// MigrateMasterySchedule adds the review schedule to country mastery and
+// counts every user's mastered countries.
+func MigrateMasterySchedule(db *GormDB) error {
+	return runVersionedMigration(db, masteryScheduleMigrationVersion, func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.CountryMastery{}); err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE users SET countries_mastered = (
+			SELECT COUNT(*) FROM country_masteries m WHERE m.user_id = users.id AND m.level >= ?
+		)`, models.MasteredLevel).Error
+	})
+}
+
+// MigrateSeasonRollover adds what season rollover needs: its progress on the
+// season and a table of final standings.
+func MigrateSeasonRollover(db *GormDB) error {
+	return runVersionedMigration(db, seasonRolloverMigrationVersion, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.Season{}, &models.SeasonResult{})
+	})
+}
+
+// MigrateUnifiedRanks moves season ranks onto the rating ladder. Duplicate
+// season ranks are merged into one per player and season. Each season rank
+// takes the player's archived final rating for ended seasons and their
+// current rating otherwise. Every rank and tier is then derived from rating
+// again, the same way ranked games do.
+func MigrateUnifiedRanks(db *GormDB) error {
+	return runVersionedMigration(db, unifiedRanksMigrationVersion, func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.SeasonRank{}); err != nil {
+			return err
+		}
+		if err := tx.Exec(`UPDATE season_ranks s SET points = m.points, wins = m.wins, losses = m.losses
+			FROM (
+				SELECT MIN(id::text)::uuid AS id, SUM(points) AS points, SUM(wins) AS wins, SUM(losses) AS losses
+				FROM season_ranks GROUP BY user_id, season_id HAVING COUNT(*) > 1
+			) m
+			WHERE s.id = m.id`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM season_ranks a USING season_ranks b
+			WHERE a.user_id = b.user_id AND a.season_id = b.season_id AND a.id::text > b.id::text`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_season_ranks_player ON season_ranks (user_id, season_id)`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`UPDATE season_ranks s SET rating = COALESCE(
+			(SELECT r.rating FROM season_results r WHERE r.season_id = s.season_id AND r.user_id = s.user_id),
+			(SELECT u.rating FROM users u WHERE u.id = s.user_id),
+			s.rating)`).Error; err != nil {
+			return err
+		}
+
+		var ratings []int
+		if err := tx.Raw(`SELECT rating FROM users WHERE rating IS NOT NULL UNION SELECT rating FROM season_ranks WHERE rating IS NOT NULL`).Scan(&ratings).Error; err != nil {
+			return err
+		}
+		for start := 0; start < len(ratings); start += 1000 {
+			end := min(start+1000, len(ratings))
+			rows := make([]string, 0, end-start)
+			args := make([]interface{}, 0, 3*(end-start))
+			for _, rating := range ratings[start:end] {
+				rank, tier := models.GetRankFromRating(rating)
+				rows = append(rows, "(?::int, ?, ?::int)")
+				args = append(args, rating, rank, tier)
+			}
+			values := `(VALUES ` + strings.Join(rows, ", ") + `) AS v(rating, rank, tier)`
+			if err := tx.Exec(`UPDATE users SET rank = v.rank, rank_tier = v.tier FROM `+values+`
+				WHERE users.rating = v.rating`, args...).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(`UPDATE season_ranks SET rank = v.rank, rank_tier = v.tier FROM `+values+`
+				WHERE season_ranks.rating = v.rating`, args...).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateLeaderboards creates the daily leaderboard totals and fills the
+// game mode boards from past match results. Answers were never recorded by
+// region, so region boards start empty.
+func MigrateLeaderboards(db *GormDB) error {
+	return runVersionedMigration(db, leaderboardMigrationVersion, func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.LeaderboardScore{}); err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO leaderboard_scores (user_id, board, day, points, correct, answered)
+			SELECT user_id, 'mode:' || game_mode, (played_at AT TIME ZONE 'UTC')::date, SUM(score), 0, 0
+			FROM match_results
+			WHERE user_id <> '00000000-0000-0000-0000-000000000000' AND game_mode NOT IN ('', 'PRACTICE')
+			GROUP BY user_id, game_mode, (played_at AT TIME ZONE 'UTC')::date
+			ON CONFLICT DO NOTHING`).Error
+	})
+}
+
+// MigrateRankDecay tracks when players last played a ranked game, starting
+// from their match history, and why each rank history entry was recorded.
+func MigrateRankDecay(db *GormDB) error {
+	return runVersionedMigration(db, rankDecayMigrationVersion, func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.User{}, &models.RankHistory{}); err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE users SET last_ranked_at = m.played_at
+			FROM (
+				SELECT user_id, MAX(played_at) AS played_at
+				FROM match_results
+				WHERE game_mode NOT IN ('', 'PRACTICE')
+				GROUP BY user_id
+			) AS m
+			WHERE users.id = m.user_id AND users.last_ranked_at IS NULL`).Error
+	})
+}
+
+// MigrateAntiCheat adds the cheat review queue, shadow exclusion from the
+// leaderboards and the per-game accuracy the pipeline compares players by.
+func MigrateAntiCheat(db *GormDB) error {
+	return runVersionedMigration(db, antiCheatMigrationVersion, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.User{}, &models.MatchResult{}, &models.CheatFlag{})
+	})
+}
+
+// MigrateAdmin adds roles and bans to users and the admin audit log.
+func MigrateAdmin(db *GormDB) error {
+	return runVersionedMigration(db, adminMigrationVersion, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.User{}, &models.AdminAuditLog{})
+	})
+}
+
+// MigrateGuestClaims records which guest sessions were already claimed.
+func MigrateGuestClaims(db *GormDB) error {
+	return runVersionedMigration(db, guestClaimMigrationVersion, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.GuestClaim{})
+	})
+}
+
+// MigrateAnswerMisses counts wrong answers in match history, so accuracy is
+// measured against every attempt.
+func MigrateAnswerMisses(db *GormDB) error {
+	return runVersionedMigration(db, answerMissesMigrationVersion, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.MatchResult{})
+	})
+}
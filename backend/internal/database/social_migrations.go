@@ -0,0 +1,30 @@
+package database
+
+import (
+	"briworld/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	friendsMigrationVersion       = "2026_10_18_friends"
+	notificationsMigrationVersion = "2026_10_18_notifications"
+)
+
+// MigrateFriends creates the friendship and block tables. The unique index
+// on the unordered pair keeps two players from having requests both ways.
+func MigrateFriends(db *GormDB) error {
+	return runVersionedMigration(db, friendsMigrationVersion, func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.Friendship{}, &models.UserBlock{}); err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_friendships_pair ON friendships (LEAST(requester_id, addressee_id), GREATEST(requester_id, addressee_id))`).Error
+	})
+}
+
+// MigrateNotifications creates the notification inbox table.
+func MigrateNotifications(db *GormDB) error {
+	return runVersionedMigration(db, notificationsMigrationVersion, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.Notification{})
+	})
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"briworld/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const tournamentsMigrationVersion = "2026_10_18_tournaments"
+
+// MigrateTournaments creates the tournament, entrant and bracket tables.
+func MigrateTournaments(db *GormDB) error {
+	return runVersionedMigration(db, tournamentsMigrationVersion, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(
+			&models.Tournament{},
+			&models.TournamentEntrant{},
+			&models.TournamentEntrantMember{},
+			&models.TournamentMatch{},
+		)
+	})
+}
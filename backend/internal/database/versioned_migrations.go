@@ -0,0 +1,59 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// runVersionedMigration applies migrate exactly once per version inside a
+// transaction. Unlike the AutoMigrate calls in NewGorm, it also runs against
+// databases whose tables already exist.
+func runVersionedMigration(db *GormDB, version string, migrate func(tx *gorm.DB) error) error {
+	if err := db.DB.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migration_versions (
+			version VARCHAR(120) PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`).Error; err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.DB.Raw(
+		`SELECT COUNT(*) FROM schema_migration_versions WHERE version = ?`,
+		version,
+	).Scan(&count).Error; err != nil {
+		return err
+	}
+
+	if count > 0 {
+		log.Printf("✓ Migration %s already applied", version)
+		return nil
+	}
+
+	tx := db.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := migrate(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Exec(
+		`INSERT INTO schema_migration_versions (version) VALUES (?)`,
+		version,
+	).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	log.Printf("✓ Migration %s applied", version)
+	return nil
+}
@@ -22,6 +22,9 @@ const (
 	RegionOceania  = "Oceania"
 )
 
+// Regions lists every region countries are grouped into
+var Regions = []string{RegionEurope, RegionAsia, RegionAmericas, RegionAfrica, RegionOceania}
+
 var Data = &GameData{}
 
 var iso3to2 map[string]string
@@ -62,4 +62,12 @@ var modeConfigs = map[GameMode]ModeConfig{
 		MinPlayers:       1,
 		SupportsMultiple: true,
 	},
+
+	// Practice asks flags a single player is due to review
+	ModePractice: {
+		IsTimed:        true,
+		DefaultTimeout: 20,
+		QuestionType:   QuestionFlagGuess,
+		MinPlayers:     1,
+	},
 }
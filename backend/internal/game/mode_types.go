@@ -10,6 +10,7 @@ const (
 	ModeEmoji        GameMode = "EMOJI"
 	ModeLastStanding GameMode = "LAST_STANDING"
 	ModeBorderLogic  GameMode = "BORDER_LOGIC"
+	ModePractice     GameMode = "PRACTICE"
 )
 
 // QuestionType represents the type of question for a mode
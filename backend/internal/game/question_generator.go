@@ -2,6 +2,8 @@ package game
 
 import (
 	"errors"
+	"math/rand"
+	"sort"
 )
 
 func (g *GameData) GenerateQuestion(mode string, usedCountries map[string]bool) (*Question, error) {
@@ -16,56 +18,99 @@ func (g *GameData) GenerateQuestion(mode string, usedCountries map[string]bool)
 
 		code, name := g.GetRandomCountry()
 
-		if usedCountries[code] {
+		if usedCountries[code] || !g.fitsMode(mode, code) {
 			continue
 		}
 
-		q := &Question{
-			CountryCode: code,
-			CountryName: name,
-			TimeLimit:   GetDefaultTimeout(mode),
-		}
+		return g.newQuestion(mode, code, name), nil
+	}
 
-		switch mode {
+	return nil, errors.New("failed to generate question")
+}
 
-		case "FLAG_QUIZ", "FLAG", "LAST_STANDING":
-			q.Type = "flag"
-			q.FlagCode = code
+// QuestionForCountry builds the question of mode about the country code.
+func (g *GameData) QuestionForCountry(mode, code string) (*Question, error) {
+	name, ok := g.Countries[code]
+	if !ok {
+		return nil, errors.New("unknown country")
+	}
+	if !g.fitsMode(mode, code) {
+		return nil, errors.New("country has no question in this mode")
+	}
+	return g.newQuestion(mode, code, name), nil
+}
 
-		case "SILHOUETTE":
-			s := GetSilhouetteForCountry(code)
-			if s == "" {
-				continue
-			}
+// PickCountries returns count distinct countries that fit mode. The same
+// seed always picks the same countries in the same order.
+func (g *GameData) PickCountries(mode string, count int, seed int64) []string {
+	codes := make([]string, 0, len(g.Countries))
+	for code := range g.Countries {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
 
-			q.Type = "silhouette"
-			q.Silhouette = s
-			q.Options = g.GenerateAnswerOptions(code, 4)
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(codes), func(i, j int) {
+		codes[i], codes[j] = codes[j], codes[i]
+	})
 
-		case "EMOJI":
-			q.Type = "emoji"
-			q.Emoji = GetEmojiForCountry(code)
+	picked := make([]string, 0, count)
+	for _, code := range codes {
+		if len(picked) == count {
+			break
+		}
+		if g.fitsMode(mode, code) {
+			picked = append(picked, code)
+		}
+	}
+	return picked
+}
 
-		case "BORDER_LOGIC":
-			neighbors := g.GetNeighborsForCountry(code)
-			if len(neighbors) == 0 {
-				continue
-			}
+// fitsMode reports whether a question about code can be asked in mode.
+func (g *GameData) fitsMode(mode, code string) bool {
+	switch mode {
+	case "SILHOUETTE":
+		return GetSilhouetteForCountry(code) != ""
+	case "BORDER_LOGIC":
+		return len(g.GetNeighborsForCountry(code)) > 0
+	}
+	return true
+}
 
-			q.Type = "border"
-			q.Neighbors = neighbors
+func (g *GameData) newQuestion(mode, code, name string) *Question {
+	q := &Question{
+		CountryCode: code,
+		CountryName: name,
+		TimeLimit:   GetDefaultTimeout(mode),
+	}
 
-		case "WORLD_MAP":
-			q.Type = "map"
-			q.FlagCode = code
+	switch mode {
 
-		default:
-			q.Type = "flag"
-			q.FlagCode = code
-		}
+	case "FLAG_QUIZ", "FLAG", "LAST_STANDING", "PRACTICE":
+		q.Type = "flag"
+		q.FlagCode = code
 
-		return q, nil
+	case "SILHOUETTE":
+		q.Type = "silhouette"
+		q.Silhouette = GetSilhouetteForCountry(code)
+		q.Options = g.GenerateAnswerOptions(code, 4)
+
+	case "EMOJI":
+		q.Type = "emoji"
+		q.Emoji = GetEmojiForCountry(code)
+
+	case "BORDER_LOGIC":
+		q.Type = "border"
+		q.Neighbors = g.GetNeighborsForCountry(code)
+
+	case "WORLD_MAP":
+		q.Type = "map"
+		q.FlagCode = code
+
+	default:
+		q.Type = "flag"
+		q.FlagCode = code
 	}
 
-	return nil, errors.New("failed to generate question")
+	return q
 }
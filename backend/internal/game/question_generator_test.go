@@ -156,6 +156,56 @@ func TestGenerateQuestionBorderMode(t *testing.T) {
 	}
 }
 
+func TestPickCountriesIsSeeded(t *testing.T) {
+	setupGameData(t)
+
+	first := Data.PickCountries("SILHOUETTE", 10, 20261018)
+	second := Data.PickCountries("SILHOUETTE", 10, 20261018)
+
+	if len(first) != 10 {
+		t.Fatalf("Expected 10 countries, got %d", len(first))
+	}
+
+	seen := make(map[string]bool)
+	for i, code := range first {
+		if code != second[i] {
+			t.Fatalf("Same seed picked %v and %v", first, second)
+		}
+		if seen[code] {
+			t.Fatalf("Country %s was picked twice", code)
+		}
+		seen[code] = true
+
+		q, err := Data.QuestionForCountry("SILHOUETTE", code)
+		if err != nil {
+			t.Fatalf("QuestionForCountry(%s) failed: %v", code, err)
+		}
+		if q.CountryCode != code || q.Silhouette == "" {
+			t.Fatalf("Unexpected question for %s: %+v", code, q)
+		}
+	}
+
+	other := Data.PickCountries("SILHOUETTE", 10, 20261019)
+	same := true
+	for i := range first {
+		if first[i] != other[i] {
+			same = false
+			break
+		}
+	}
+	if same {
+		t.Fatal("Different seeds picked the same countries")
+	}
+}
+
+func TestQuestionForUnknownCountry(t *testing.T) {
+	setupGameData(t)
+
+	if _, err := Data.QuestionForCountry("FLAG", "XX"); err == nil {
+		t.Fatal("Expected error for an unknown country")
+	}
+}
+
 func BenchmarkGenerateQuestion(b *testing.B) {
 
 	if err := LoadStaticData(); err != nil {
@@ -7,7 +7,7 @@ type State struct {
 	CurrentRound      int                            `json:"current_round"`
 	TotalRounds       int                            `json:"total_rounds"`
 	RoundTimeLimit    int                            `json:"round_time_limit"`
-	Question          *Question                      `json:"question"`
+	Question          *Question                      `json:"-"` // holds the answer; clients get Question.Public()
 	Scores            map[string]int                 `json:"scores"`
 	TimeRemaining     int                            `json:"time_remaining"`
 	GameMode          string                         `json:"game_mode"`
@@ -26,9 +26,10 @@ type State struct {
 
 type Question struct {
 	Type                  string   `json:"type"`
-	FlagCode              string   `json:"flag_code"`
-	CountryName           string   `json:"country_name"`
-	CountryCode           string   `json:"country_code"`
+	FlagCode              string   `json:"flag_code,omitempty"`
+	FlagURL               string   `json:"flag_url,omitempty"`
+	CountryName           string   `json:"country_name,omitempty"`
+	CountryCode           string   `json:"country_code,omitempty"`
 	TimeLimit             int      `json:"time_limit"`
 	Emoji                 string   `json:"emoji,omitempty"`
 	Silhouette            string   `json:"silhouette,omitempty"`
@@ -38,6 +39,20 @@ type Question struct {
 	Options               []string `json:"options,omitempty"`
 }
 
+// Public returns a copy of q without the country it asks about, for
+// sending to players while the round is still being played. The flag is
+// only shown through FlagURL, which does not name the country.
+func (q *Question) Public() *Question {
+	if q == nil {
+		return nil
+	}
+	public := *q
+	public.CountryName = ""
+	public.CountryCode = ""
+	public.FlagCode = ""
+	return &public
+}
+
 func NewState() *State {
 	return &State{
 		Status:            domain.RoomWaiting,
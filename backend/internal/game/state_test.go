@@ -2,6 +2,8 @@ package game
 
 import (
 	"briworld/internal/domain"
+	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -333,6 +335,46 @@ func TestQuestionStructure(t *testing.T) {
 	}
 }
 
+// TestQuestionPublicHidesTheCountry tests the copy sent during a round
+func TestQuestionPublicHidesTheCountry(t *testing.T) {
+	question := &Question{
+		Type:        "silhouette",
+		CountryName: "France",
+		CountryCode: "FR",
+		FlagCode:    "FR",
+		FlagURL:     "/api/v2/flags/opaque",
+		Silhouette:  "<svg/>",
+		Options:     []string{"France", "Spain"},
+	}
+
+	public := question.Public()
+	if public.CountryName != "" || public.CountryCode != "" || public.FlagCode != "" {
+		t.Errorf("Public() kept the country: %s (%s, flag %s)", public.CountryName, public.CountryCode, public.FlagCode)
+	}
+	if public.Silhouette != question.Silhouette || len(public.Options) != 2 || public.FlagURL != question.FlagURL {
+		t.Error("Public() dropped what the players need to answer")
+	}
+	if question.CountryName != "France" {
+		t.Error("Public() changed the question")
+	}
+	if (*Question)(nil).Public() != nil {
+		t.Error("Public() of no question should be nil")
+	}
+}
+
+func TestStateJSONLeavesOutTheQuestion(t *testing.T) {
+	state := NewState()
+	state.Question = &Question{Type: "flag", CountryName: "France", CountryCode: "FR", FlagCode: "FR"}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "France") || strings.Contains(string(data), `"question"`) {
+		t.Errorf("state JSON carries the question: %s", data)
+	}
+}
+
 // TestQuestionWithCapital tests capital question structure
 func TestQuestionWithCapital(t *testing.T) {
 	question := &Question{
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"briworld/internal/services"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AccountHandler struct {
+	accounts      *services.AccountService
+	twoFactor     *services.TwoFactorService
+	users         *services.AuthServiceGorm
+	refreshTokens *services.RefreshTokenService
+}
+
+func NewAccountHandler(accounts *services.AccountService, twoFactor *services.TwoFactorService, users *services.AuthServiceGorm, refreshTokens *services.RefreshTokenService) *AccountHandler {
+	return &AccountHandler{
+		accounts:      accounts,
+		twoFactor:     twoFactor,
+		users:         users,
+		refreshTokens: refreshTokens,
+	}
+}
+
+// Export returns everything stored about the current user as a JSON
+// document, or as a ZIP archive with ?format=zip
+func (h *AccountHandler) Export(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "zip" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format must be json or zip"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 15*time.Second)
+	defer cancel()
+
+	export, err := h.accounts.Export(ctx, userID)
+	if err != nil {
+		log.Printf("Account export failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to export account data"})
+	}
+
+	filename := fmt.Sprintf("briworld-export-%s", export.ExportedAt.Format("20060102-150405"))
+	if format == "json" {
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		return c.JSON(export)
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteZip(&buf); err != nil {
+		log.Printf("Account export archive failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to export account data"})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+	return c.Send(buf.Bytes())
+}
+
+// RequestDeletion schedules the current user's account for deletion after
+// the grace period
+func (h *AccountHandler) RequestDeletion(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		TwoFactorCode   string `json:"two_factor_code"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if ok, err := checkStepUp(c, h.twoFactor, h.refreshTokens, user, req.CurrentPassword, req.TwoFactorCode); !ok {
+		return err
+	}
+
+	sessionID, _ := c.Locals("session_id").(string)
+	scheduledAt, err := h.accounts.ScheduleDeletion(ctx, userID, sessionID)
+	if errors.Is(err, services.ErrAccountDeleted) {
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "Account has already been deleted"})
+	}
+	if err != nil {
+		log.Printf("Scheduling deletion failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to schedule account deletion"})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message":               "Account scheduled for deletion",
+		"deletion_scheduled_at": scheduledAt,
+	})
+}
+
+// CancelDeletion keeps an account whose deletion is still pending
+func (h *AccountHandler) CancelDeletion(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.accounts.CancelDeletion(ctx, userID); err != nil {
+		if errors.Is(err, services.ErrDeletionNotScheduled) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No deletion is pending"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cancel account deletion"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Account deletion cancelled"})
+}
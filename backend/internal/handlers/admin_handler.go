@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"briworld/internal/services"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// AdminHandler serves the admin API. Routes are guarded by role, so every
+// handler can assume a moderator or admin is signed in.
+type AdminHandler struct {
+	admin *services.AdminService
+}
+
+func NewAdminHandler(admin *services.AdminService) *AdminHandler {
+	return &AdminHandler{admin: admin}
+}
+
+func adminErrorResponse(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidRole),
+		errors.Is(err, services.ErrInvalidBan),
+		errors.Is(err, services.ErrInvalidAchievement),
+		errors.Is(err, services.ErrInvalidDailyChallenge),
+		errors.Is(err, services.ErrInvalidVerdict):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotModerate):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrRoomNotFound),
+		errors.Is(err, services.ErrPlayerNotFound),
+		errors.Is(err, services.ErrAchievementNotFound),
+		errors.Is(err, services.ErrCheatFlagNotFound),
+		errors.Is(err, services.ErrNoActiveSeason):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrDailyChallengeAttempts),
+		errors.Is(err, services.ErrSeasonActive),
+		errors.Is(err, services.ErrSeasonEnding):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Printf("Admin request failed: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
+
+// ListRooms returns the rooms being played on this instance
+func (h *AdminHandler) ListRooms(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"rooms": h.admin.LiveRooms()})
+}
+
+// CloseRoom closes a live room and kicks everyone in it
+func (h *AdminHandler) CloseRoom(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.admin.CloseRoom(ctx, actorID, c.Params("code"), req.Reason); err != nil {
+		return adminErrorResponse(c, err, "Failed to close room")
+	}
+	return c.JSON(fiber.Map{"message": "Room closed"})
+}
+
+// GetUser returns a user's role and ban
+func (h *AdminHandler) GetUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	standing, err := h.admin.UserStanding(ctx, userID)
+	if err != nil {
+		return adminErrorResponse(c, err, "Failed to load user")
+	}
+	return c.JSON(standing)
+}
+
+// BanUser bans a user, for good unless the body says until when
+func (h *AdminHandler) BanUser(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	var req struct {
+		Reason string     `json:"reason"`
+		Until  *time.Time `json:"until"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A ban needs a reason"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	standing, err := h.admin.BanUser(ctx, actorID, userID, req.Reason, req.Until)
+	if err != nil {
+		return adminErrorResponse(c, err, "Failed to ban user")
+	}
+	return c.JSON(standing)
+}
+
+// UnbanUser lifts a user's ban
+func (h *AdminHandler) UnbanUser(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	standing, err := h.admin.UnbanUser(ctx, actorID, userID)
+	if err != nil {
+		return adminErrorResponse(c, err, "Failed to unban user")
+	}
+	return c.JSON(standing)
+}
+
+// SetRole makes a user a player, moderator or admin
+func (h *AdminHandler) SetRole(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	standing, err := h.admin.SetRole(ctx, actorID, userID, req.Role)
+	if err != nil {
+		return adminErrorResponse(c, err, "Failed to set role")
+	}
+	return c.JSON(standing)
+}
+
+// UpdateAchievement edits an achievement's name, description, icon, reward
+// or rarity
+func (h *AdminHandler) UpdateAchievement(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var edit services.AchievementEdit
+	if err := c.BodyParser(&edit); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	achievement, err := h.admin.UpdateAchievement(ctx, actorID, c.Params("code"), edit)
+	if err != nil {
+		return adminErrorResponse(c, err, "Failed to update achievement")
+	}
+	return c.JSON(achievement)
+}
+
+// SeedAchievements creates the built-in achievements the database is missing
+func (h *AdminHandler) SeedAchievements(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	created, err := h.admin.SeedAchievements(ctx, actorID)
+	if err != nil {
+		return adminErrorResponse(c, err, "Failed to seed achievements")
+	}
+	return c.JSON(fiber.Map{"created": created})
+}
+
+// SetDailyChallenge sets the daily challenge of the date in the path
+func (h *AdminHandler) SetDailyChallenge(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	date, err := time.Parse(time.DateOnly, c.Params("date"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Date must be YYYY-MM-DD"})
+	}
+
+	var edit services.DailyChallengeEdit
+	if err := c.BodyParser(&edit); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	challenge, err := h.admin.SetDailyChallenge(ctx, actorID, date, edit)
+	if err != nil {
+		return adminErrorResponse(c, err, "Failed to set daily challenge")
+	}
+	return c.JSON(challenge)
+}
+
+// StartSeason opens a season while none is active
+func (h *AdminHandler) StartSeason(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Name string `json:"name"`
+		Days int    `json:"days"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	season, err := h.admin.StartSeason(ctx, actorID, req.Name, req.Days)
+	if err != nil {
+		return adminErrorResponse(c, err, "Failed to start season")
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"season": season})
+}
+
+// EndSeason ends the active season now. Its rollover runs in the background.
+func (h *AdminHandler) EndSeason(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		NextName string `json:"next_name"`
+		NextDays int    `json:"next_days"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	ended, next, err := h.admin.EndSeason(ctx, actorID, req.NextName, req.NextDays)
+	if err != nil {
+		return adminErrorResponse(c, err, "Failed to end season")
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"ended": ended, "next": next})
+}
+
+// ListCheatFlags returns a page of the anti-cheat review queue
+func (h *AdminHandler) ListCheatFlags(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	flags, total, err := h.admin.CheatReviewQueue(ctx, c.Query("status"), c.QueryInt("limit", 50), c.QueryInt("offset", 0))
+	if err != nil {
+		return adminErrorResponse(c, err, "Failed to load cheat flags")
+	}
+	return c.JSON(fiber.Map{"flags": flags, "total": total})
+}
+
+// ReviewCheatFlag dismisses or confirms a cheat flag
+func (h *AdminHandler) ReviewCheatFlag(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	flagID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid flag ID"})
+	}
+
+	var req struct {
+		Verdict string `json:"verdict"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	flag, err := h.admin.ReviewCheatFlag(ctx, actorID, flagID, req.Verdict)
+	if err != nil {
+		return adminErrorResponse(c, err, "Failed to review cheat flag")
+	}
+	return c.JSON(flag)
+}
+
+// GetAuditLog returns a page of the admin audit log, newest first
+func (h *AdminHandler) GetAuditLog(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	entries, total, err := h.admin.AuditLog(ctx, c.Query("action"), c.Query("target_id"), c.QueryInt("limit", 50), c.QueryInt("offset", 0))
+	if err != nil {
+		return adminErrorResponse(c, err, "Failed to load audit log")
+	}
+	return c.JSON(fiber.Map{"entries": entries, "total": total})
+}
@@ -6,10 +6,17 @@ import (
 	"briworld/internal/services"
 	"briworld/internal/utils"
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type RegisterRequest struct {
@@ -24,27 +31,203 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	AccessToken string               `json:"access_token"`
-	ExpiresIn   int                  `json:"expires_in"`
-	User        *models.UserResponse `json:"user"`
+	AccessToken      string               `json:"access_token"`
+	ExpiresIn        int                  `json:"expires_in"`
+	RefreshToken     string               `json:"refresh_token,omitempty"`
+	RefreshExpiresIn int                  `json:"refresh_expires_in,omitempty"`
+	User             *models.UserResponse `json:"user"`
+	Warning          string               `json:"warning,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// twoFactorChallengeTTL is how long, in seconds, a client has to submit the
+// second factor after the password step.
+const twoFactorChallengeTTL = 300
+
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int    `json:"expires_in"`
+}
+
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
 }
 
 type AuthHandlerGorm struct {
-	authService *services.AuthServiceGorm
-	jwtSecret   string
-	jwtExpiry   int
-	mailer      *mailer.Mailer
+	authService   *services.AuthServiceGorm
+	refreshTokens *services.RefreshTokenService
+	twoFactor     *services.TwoFactorService
+	guard         *services.LoginGuard
+	security      *services.SecurityService
+	jwtSecret     string
+	jwtExpiry     int
+	mailer        *mailer.Mailer
+	frontendURL   string
 }
 
-func NewAuthHandlerGorm(authService *services.AuthServiceGorm, jwtSecret string, jwtExpiry int, m *mailer.Mailer) *AuthHandlerGorm {
+func NewAuthHandlerGorm(authService *services.AuthServiceGorm, refreshTokens *services.RefreshTokenService, twoFactor *services.TwoFactorService, guard *services.LoginGuard, security *services.SecurityService, jwtSecret string, jwtExpiry int, m *mailer.Mailer, frontendURL string) *AuthHandlerGorm {
 	return &AuthHandlerGorm{
-		authService: authService,
-		jwtSecret:   jwtSecret,
-		jwtExpiry:   jwtExpiry,
-		mailer:      m,
+		authService:   authService,
+		refreshTokens: refreshTokens,
+		twoFactor:     twoFactor,
+		guard:         guard,
+		security:      security,
+		jwtSecret:     jwtSecret,
+		jwtExpiry:     jwtExpiry,
+		mailer:        m,
+		frontendURL:   frontendURL,
+	}
+}
+
+func deviceInfo(c *fiber.Ctx) services.DeviceInfo {
+	return services.DeviceInfo{
+		UserAgent: c.Get("User-Agent"),
+		IPAddress: c.IP(),
 	}
 }
 
+func toUserResponse(user *models.User) *models.UserResponse {
+	return &models.UserResponse{
+		ID:               user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		Role:             user.Role,
+		EmailVerified:    user.EmailVerified,
+		TwoFactorEnabled: user.TwoFactorEnabled,
+		TotalPoints:      user.TotalPoints,
+		TotalGames:       user.TotalGames,
+		WinStreak:        user.WinStreak,
+	}
+}
+
+// sendVerification issues a fresh verification token for user and mails the link.
+func (h *AuthHandlerGorm) sendVerification(ctx context.Context, user *models.User) error {
+	return sendVerificationEmail(ctx, h.authService, h.mailer, h.frontendURL, user)
+}
+
+// sendVerificationEmail issues a fresh verification token for user and mails
+// user.Email a link to the frontend's verification page.
+func sendVerificationEmail(ctx context.Context, authService *services.AuthServiceGorm, m *mailer.Mailer, frontendURL string, user *models.User) error {
+	token, err := authService.CreateVerificationToken(ctx, user.ID)
+	if err != nil {
+		log.Printf("Failed to create verification token for %s: %v", user.Email, err)
+		return err
+	}
+
+	verifyURL := frontendURL + "/verify-email?token=" + url.QueryEscape(token)
+	go m.SendVerification(user.Email, user.Username, verifyURL)
+	return nil
+}
+
+// issueSession opens a device session for user and signs an access token bound to it.
+func (h *AuthHandlerGorm) issueSession(c *fiber.Ctx, ctx context.Context, user *models.User) (*AuthResponse, error) {
+	refresh, err := h.refreshTokens.StartSession(ctx, user, deviceInfo(c))
+	if err != nil {
+		return nil, err
+	}
+
+	h.recordLogin(c, ctx, user)
+
+	token, err := utils.GenerateSessionJWT(user.ID.String(), user.Username, user.Email, refresh.SessionID, h.jwtSecret, h.jwtExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		AccessToken:      token,
+		ExpiresIn:        h.jwtExpiry,
+		RefreshToken:     refresh.Token,
+		RefreshExpiresIn: int(time.Until(refresh.ExpiresAt).Seconds()),
+		User:             toUserResponse(user),
+	}, nil
+}
+
+// recordLogin clears the account's failed attempts, adds the sign-in to the
+// audit log and mails an alert when it came from a new IP or device.
+func (h *AuthHandlerGorm) recordLogin(c *fiber.Ctx, ctx context.Context, user *models.User) {
+	if err := h.guard.Succeed(ctx, user.Email); err != nil {
+		log.Printf("Failed to reset login lockout for %s: %v", user.Email, err)
+	}
+
+	// Fiber reuses request buffers once the handler returns, so copy the
+	// header values the alert goroutine keeps.
+	device := services.DeviceInfo{
+		UserAgent: strings.Clone(c.Get("User-Agent")),
+		IPAddress: strings.Clone(c.IP()),
+	}
+
+	newDevice, err := h.security.RecordSuccess(ctx, user.ID, device)
+	if err != nil {
+		log.Printf("Failed to record login for %s: %v", user.Email, err)
+		return
+	}
+	if newDevice {
+		go h.mailer.SendLoginAlert(user.Email, user.Username, device.IPAddress, device.UserAgent, time.Now())
+	}
+}
+
+// loginFailed counts a failed attempt against email and logs it. It returns
+// the lock the failure triggered, if any.
+func (h *AuthHandlerGorm) loginFailed(c *fiber.Ctx, ctx context.Context, email, reason string) time.Duration {
+	if err := h.security.RecordFailure(ctx, email, deviceInfo(c), reason); err != nil {
+		log.Printf("Failed to record failed login for %s: %v", email, err)
+	}
+
+	locked, err := h.guard.Fail(ctx, email)
+	if err != nil {
+		log.Printf("Failed to count failed login for %s: %v", email, err)
+		return 0
+	}
+	return locked
+}
+
+// checkLockout answers with 429 when email is locked. A broken lockout store
+// lets the attempt through rather than locking everyone out.
+func (h *AuthHandlerGorm) checkLockout(c *fiber.Ctx, ctx context.Context, email string) (bool, error) {
+	locked, err := h.guard.Check(ctx, email)
+	if err != nil {
+		log.Printf("Login lockout check failed for %s: %v", email, err)
+		return false, nil
+	}
+	if locked == 0 {
+		return false, nil
+	}
+
+	if err := h.security.RecordFailure(ctx, email, deviceInfo(c), models.LoginFailureLocked); err != nil {
+		log.Printf("Failed to record failed login for %s: %v", email, err)
+	}
+	return true, lockedResponse(c, locked)
+}
+
+func lockedResponse(c *fiber.Ctx, locked time.Duration) error {
+	seconds := int(math.Ceil(locked.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       fmt.Sprintf("Too many failed login attempts. Try again in %d minute(s).", int(math.Ceil(locked.Minutes()))),
+		"retry_after": seconds,
+	})
+}
+
+// twoFactorChallenge returns the token that LoginTwoFactor trades for a
+// session once the user proves the second factor.
+func (h *AuthHandlerGorm) twoFactorChallenge(user *models.User) (*TwoFactorChallengeResponse, error) {
+	token, err := utils.GenerateTwoFactorChallenge(user.ID.String(), h.jwtSecret, twoFactorChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         twoFactorChallengeTTL,
+	}, nil
+}
+
 func (h *AuthHandlerGorm) Register(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
@@ -75,26 +258,20 @@ func (h *AuthHandlerGorm) Register(c *fiber.Ctx) error {
 		return c.Status(409).JSON(fiber.Map{"error": "User already exists"})
 	}
 
-	go h.mailer.SendWelcome(user.Email, user.Username)
+	verificationErr := h.sendVerification(ctx, user)
+	if verificationErr != nil {
+		log.Printf("Failed to send verification email to new user %s: %v", user.ID, verificationErr)
+	}
 
-	// Generate JWT token - handle errors properly
-	token, err := utils.GenerateJWT(user.ID.String(), user.Username, user.Email, h.jwtSecret, h.jwtExpiry)
+	response, err := h.issueSession(c, ctx, user)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate authentication token"})
 	}
+	if verificationErr != nil {
+		response.Warning = "The verification email could not be sent. Request a new one to verify your email."
+	}
 
-	return c.Status(201).JSON(AuthResponse{
-		AccessToken: token,
-		ExpiresIn:   h.jwtExpiry,
-		User: &models.UserResponse{
-			ID:          user.ID,
-			Username:    user.Username,
-			Email:       user.Email,
-			TotalPoints: user.TotalPoints,
-			TotalGames:  user.TotalGames,
-			WinStreak:   user.WinStreak,
-		},
-	})
+	return c.Status(201).JSON(response)
 }
 
 func (h *AuthHandlerGorm) Login(c *fiber.Ctx) error {
@@ -108,31 +285,135 @@ func (h *AuthHandlerGorm) Login(c *fiber.Ctx) error {
 
 	req.Email = utils.SanitizeInput(req.Email)
 
+	if locked, err := h.checkLockout(c, ctx, req.Email); locked {
+		return err
+	}
+
 	user, err := h.authService.LoginUser(ctx, req.Email, req.Password)
+	if err != nil {
+		if locked := h.loginFailed(c, ctx, req.Email, models.LoginFailureInvalidCredentials); locked > 0 {
+			return lockedResponse(c, locked)
+		}
+		return c.Status(401).JSON(fiber.Map{"error": "Invalid credentials"})
+	}
+
+	if user.TwoFactorEnabled {
+		challenge, err := h.twoFactorChallenge(user)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to start two-factor login"})
+		}
+		return c.JSON(challenge)
+	}
+
+	response, err := h.issueSession(c, ctx, user)
+	if errors.Is(err, services.ErrAccountBanned) {
+		return c.Status(403).JSON(fiber.Map{"error": "This account is banned"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate authentication token"})
+	}
+
+	return c.JSON(response)
+}
+
+// LoginTwoFactor completes a login that Login answered with a challenge.
+// It accepts a TOTP code or one of the account's recovery codes.
+func (h *AuthHandlerGorm) LoginTwoFactor(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	var req LoginTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	subject, err := utils.ValidateTwoFactorChallenge(req.ChallengeToken, h.jwtSecret)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "Login challenge expired, please sign in again"})
+	}
+
+	userID, err := uuid.Parse(subject)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "Login challenge expired, please sign in again"})
+	}
+
+	user, err := h.authService.GetUserByID(ctx, userID)
 	if err != nil {
 		return c.Status(401).JSON(fiber.Map{"error": "Invalid credentials"})
 	}
 
-	// Generate JWT token - handle errors properly
-	token, err := utils.GenerateJWT(user.ID.String(), user.Username, user.Email, h.jwtSecret, h.jwtExpiry)
+	if locked, err := h.checkLockout(c, ctx, user.Email); locked {
+		return err
+	}
+
+	if err := h.twoFactor.Verify(ctx, userID, req.Code); err != nil {
+		if !errors.Is(err, services.ErrTwoFactorCodeInvalid) {
+			log.Printf("Two-factor login check failed for %s: %v", userID, err)
+		}
+		if locked := h.loginFailed(c, ctx, user.Email, models.LoginFailureInvalidTwoFactor); locked > 0 {
+			return lockedResponse(c, locked)
+		}
+		return c.Status(401).JSON(fiber.Map{"error": "Invalid two-factor code"})
+	}
+
+	response, err := h.issueSession(c, ctx, user)
+	if errors.Is(err, services.ErrAccountBanned) {
+		return c.Status(403).JSON(fiber.Map{"error": "This account is banned"})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate authentication token"})
 	}
 
-	return c.JSON(AuthResponse{
-		AccessToken: token,
-		ExpiresIn:   h.jwtExpiry,
-		User: &models.UserResponse{
-			ID:          user.ID,
-			Username:    user.Username,
-			Email:       user.Email,
-			TotalPoints: user.TotalPoints,
-			TotalGames:  user.TotalGames,
-			WinStreak:   user.WinStreak,
-		},
+	return c.JSON(response)
+}
+
+// VerifyEmail consumes the token from a verification email.
+func (h *AuthHandlerGorm) VerifyEmail(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.authService.VerifyEmail(ctx, c.Query("token"))
+	if errors.Is(err, services.ErrVerificationTokenInvalid) {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid or expired verification link"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to verify email"})
+	}
+
+	go h.mailer.SendWelcome(user.Email, user.Username)
+
+	return c.JSON(fiber.Map{
+		"message":        "Email verified",
+		"email_verified": true,
 	})
 }
 
+// ResendVerification mails a new verification link to the current user.
+func (h *AuthHandlerGorm) ResendVerification(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	user, err := h.authService.GetUserByID(ctx, userID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.EmailVerified {
+		return c.JSON(fiber.Map{"message": "Email already verified"})
+	}
+
+	if err := h.sendVerification(ctx, user); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to send verification email"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Verification email sent"})
+}
+
 func (h *AuthHandlerGorm) ForgotPassword(c *fiber.Ctx) error {
 	var req struct {
 		Email string `json:"email"`
@@ -156,7 +437,7 @@ func (h *AuthHandlerGorm) ForgotPassword(c *fiber.Ctx) error {
 		expiry := time.Now().Add(1 * time.Hour)
 		h.authService.UpdateResetToken(c.Context(), user.ID, token, expiry)
 		
-		resetURL := fmt.Sprintf("https://briworld.onrender.com/reset-password?token=%s", token)
+		resetURL := h.frontendURL + "/reset-password?token=" + url.QueryEscape(token)
 		go h.mailer.SendPasswordReset(user.Email, token, resetURL)
 	}
 	
@@ -165,27 +446,57 @@ func (h *AuthHandlerGorm) ForgotPassword(c *fiber.Ctx) error {
 	})
 }
 
+// RefreshToken rotates a refresh token and returns a new access/refresh pair.
 func (h *AuthHandlerGorm) RefreshToken(c *fiber.Ctx) error {
-	var req struct {
-		AccessToken string `json:"access_token"`
-	}
-	if err := c.BodyParser(&req); err != nil {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	claims, err := utils.ValidateJWT(req.AccessToken, h.jwtSecret)
+	user, refresh, err := h.refreshTokens.Rotate(ctx, req.RefreshToken, deviceInfo(c))
+	if errors.Is(err, services.ErrRefreshTokenReused) {
+		log.Printf("Refresh token reuse detected from %s, session revoked", c.IP())
+		return c.Status(401).JSON(fiber.Map{"error": "Refresh token reuse detected, please log in again"})
+	}
+	if errors.Is(err, services.ErrAccountBanned) {
+		return c.Status(403).JSON(fiber.Map{"error": "This account is banned"})
+	}
 	if err != nil {
-		return c.Status(401).JSON(fiber.Map{"error": "Invalid token"})
+		return c.Status(401).JSON(fiber.Map{"error": "Invalid refresh token"})
 	}
 
-	// Generate new JWT token - handle errors properly
-	newToken, err := utils.GenerateJWT(claims.UserID, claims.Username, claims.Email, h.jwtSecret, h.jwtExpiry)
+	token, err := utils.GenerateSessionJWT(user.ID.String(), user.Username, user.Email, refresh.SessionID, h.jwtSecret, h.jwtExpiry)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate new token"})
 	}
 
-	return c.Status(200).JSON(fiber.Map{
-		"access_token": newToken,
-		"expires_in":   h.jwtExpiry,
+	return c.Status(200).JSON(AuthResponse{
+		AccessToken:      token,
+		ExpiresIn:        h.jwtExpiry,
+		RefreshToken:     refresh.Token,
+		RefreshExpiresIn: int(time.Until(refresh.ExpiresAt).Seconds()),
+		User:             toUserResponse(user),
 	})
 }
+
+// Logout revokes the device session behind the given refresh token.
+func (h *AuthHandlerGorm) Logout(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if req.RefreshToken != "" {
+		if err := h.refreshTokens.RevokeToken(ctx, req.RefreshToken); err != nil && !errors.Is(err, services.ErrRefreshTokenInvalid) {
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to log out"})
+		}
+	}
+
+	return c.JSON(fiber.Map{"message": "Logged out"})
+}
@@ -2,68 +2,38 @@ package handlers
 
 import (
 	"briworld/internal/database"
+	"briworld/internal/imaging"
 	"briworld/internal/models"
+	"briworld/internal/services"
+	"briworld/internal/storage"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/cloudinary/cloudinary-go/v2"
-	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 type AvatarHandler struct {
-	db  *database.GormDB
-	cld *cloudinary.Cloudinary
+	db      *database.GormDB
+	store   storage.AssetStore
+	library *services.AssetLibraryService
 }
 
 func (h *AvatarHandler) ensureProfileSchema() error {
 	return database.MigrateProfileCustomization(h.db)
 }
 
-func NewAvatarHandler(db *database.GormDB) *AvatarHandler {
-	var cld *cloudinary.Cloudinary
-	var err error
-
-	// Try to initialize from environment variable first
-	cloudinaryURL := os.Getenv("CLOUDINARY_API_ENVIRONMENT_VARIABLE")
-	if cloudinaryURL != "" {
-		cld, err = cloudinary.NewFromURL(cloudinaryURL)
-		if err != nil {
-			log.Printf("Warning: Failed to initialize Cloudinary from URL: %v", err)
-		}
-	}
-
-	// Fallback: Initialize from individual credentials
-	if cld == nil {
-		cloudName := os.Getenv("CLOUDINARY_CLOUD_NAME")
-		if cloudName == "" {
-			cloudName = os.Getenv("CLOUDINARY_ClOUD_NAME") // Fallback for typo
-		}
-		apiKey := os.Getenv("CLOUDINARY_API_KEY")
-		apiSecret := os.Getenv("CLOUDINARY_API_SECRET")
-
-		if cloudName != "" && apiKey != "" && apiSecret != "" {
-			cld, err = cloudinary.NewFromParams(cloudName, apiKey, apiSecret)
-			if err != nil {
-				log.Printf("Warning: Failed to initialize Cloudinary from params: %v", err)
-			}
-		}
-	}
-
-	if cld == nil {
-		log.Printf("Warning: Cloudinary not configured - avatars will not be uploaded")
-	}
-
-	return &AvatarHandler{db: db, cld: cld}
+func NewAvatarHandler(db *database.GormDB, store storage.AssetStore, library *services.AssetLibraryService) *AvatarHandler {
+	return &AvatarHandler{db: db, store: store, library: library}
 }
 
 func (h *AvatarHandler) parseUserID(c *fiber.Ctx) (uuid.UUID, error) {
@@ -125,77 +95,214 @@ func validateAssetFile(file *multipart.FileHeader, allowed map[string]bool) (str
 	return assetType, resourceType, nil
 }
 
-func (h *AvatarHandler) uploadToCloudinary(file *multipart.FileHeader, publicID, folder, resourceType string) (string, error) {
-	if h.cld == nil {
-		return "", fmt.Errorf("upload service not configured")
+// validateProcessedImage checks that an avatar or banner image is in a format
+// the imaging package decodes. AVIF and SVG files are only taken as library
+// assets and decorations, which are stored as uploaded.
+func validateProcessedImage(file *multipart.FileHeader) error {
+	if imaging.ClaimedFormat(file.Filename, file.Header.Get("Content-Type")) == "" {
+		return imaging.ErrUnsupportedFormat
 	}
+	return nil
+}
+
+var uploadExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".webp": true,
+	".gif": true, ".avif": true, ".svg": true, ".json": true,
+}
+
+// uploadBase builds a fresh store key prefix below briworld/<folder>/<user id>.
+// Every upload gets its own keys so cached URLs never show a replaced file.
+func uploadBase(folder string, userID uuid.UUID) string {
+	return fmt.Sprintf("briworld/%s/%s/%d", folder, userID.String(), time.Now().UnixNano())
+}
+
+func uploadKey(folder string, userID uuid.UUID, file *multipart.FileHeader) string {
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !uploadExtensions[ext] {
+		ext = ""
+	}
+	return uploadBase(folder, userID) + ext
+}
+
+// assetVariant is one rendition of a processed image, listed under
+// "variants" in ProfileAsset.MetadataJSON.
+type assetVariant struct {
+	URL         string `json:"url"`
+	Key         string `json:"key"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ContentType string `json:"content_type"`
+}
 
+// storedImage is an avatar or banner whose renditions are in the asset store.
+// The first requested size is the primary one.
+type storedImage struct {
+	URL       string
+	Key       string
+	AssetType string
+	Result    *imaging.Result
+	Variants  map[string]assetVariant
+}
+
+func isImageRejection(err error) bool {
+	return errors.Is(err, imaging.ErrUnsupportedFormat) ||
+		errors.Is(err, imaging.ErrTypeMismatch) ||
+		errors.Is(err, imaging.ErrTooLarge) ||
+		errors.Is(err, imaging.ErrTooManyFrames)
+}
+
+// storeImageUpload decodes an avatar or banner, renders it at sizes and puts
+// every rendition into the asset store. The original file is never stored.
+func (h *AvatarHandler) storeImageUpload(ctx context.Context, file *multipart.FileHeader, folder string, userID uuid.UUID, sizes []imaging.Size) (*storedImage, error) {
 	src, err := file.Open()
 	if err != nil {
-		return "", fmt.Errorf("failed to read file")
+		return nil, fmt.Errorf("failed to read file")
+	}
+	data, err := io.ReadAll(src)
+	src.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file")
+	}
+
+	result, err := imaging.Process(data, imaging.ClaimedFormat(file.Filename, file.Header.Get("Content-Type")), sizes)
+	if err != nil {
+		return nil, err
 	}
-	defer src.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	uploadResult, err := h.cld.Upload.Upload(ctx, src, uploader.UploadParams{
-		PublicID:     publicID,
-		Folder:       folder,
-		ResourceType: resourceType,
-	})
-	if err != nil {
-		return "", err
+	stored := &storedImage{AssetType: "image", Result: result, Variants: make(map[string]assetVariant)}
+	if result.Animated {
+		stored.AssetType = "gif"
+	}
+	base := uploadBase(folder, userID)
+	for i, variant := range result.Variants {
+		key := base + "_" + variant.Name + variant.Ext
+		url, err := h.store.Put(ctx, storage.Object{
+			Key:          key,
+			Body:         bytes.NewReader(variant.Data),
+			Size:         int64(len(variant.Data)),
+			ContentType:  variant.ContentType,
+			ResourceType: "image",
+		})
+		if err != nil {
+			for _, done := range stored.Variants {
+				if cleanupErr := h.store.Delete(ctx, done.Key, "image"); cleanupErr != nil {
+					log.Printf("Warning: failed to clean up %s: %v", done.Key, cleanupErr)
+				}
+			}
+			return nil, err
+		}
+		stored.Variants[variant.Name] = assetVariant{
+			URL:         url,
+			Key:         key,
+			Width:       variant.Width,
+			Height:      variant.Height,
+			ContentType: variant.ContentType,
+		}
+		if i == 0 {
+			stored.URL, stored.Key = url, key
+		}
 	}
+	return stored, nil
+}
 
-	return uploadResult.SecureURL, nil
+// size is the number of bytes all renditions take in the asset store.
+func (stored *storedImage) size() int64 {
+	var total int64
+	for _, variant := range stored.Result.Variants {
+		total += int64(len(variant.Data))
+	}
+	return total
 }
 
-func saveUploadedFile(file *multipart.FileHeader, destination string) error {
+// contentType is the type of the primary rendition.
+func (stored *storedImage) contentType() string {
+	return stored.Result.Variants[0].ContentType
+}
+
+// metadata describes stored for ProfileAsset.MetadataJSON.
+func (stored *storedImage) metadata(usage string) map[string]any {
+	return map[string]any{
+		"usage":         usage,
+		"source_format": stored.Result.Format,
+		"frames":        stored.Result.Frames,
+		"variants":      stored.Variants,
+	}
+}
+
+// validateLottieFile checks that a Lottie upload is JSON.
+func validateLottieFile(file *multipart.FileHeader) error {
 	src, err := file.Open()
 	if err != nil {
 		return fmt.Errorf("failed to read file")
 	}
 	defer src.Close()
 
-	dst, err := os.Create(destination)
+	data, err := io.ReadAll(src)
 	if err != nil {
-		return fmt.Errorf("failed to create file")
+		return fmt.Errorf("failed to read file")
 	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, src); err != nil {
-		return fmt.Errorf("failed to write file")
+	if !json.Valid(data) {
+		return fmt.Errorf("lottie files must be valid JSON")
 	}
-
 	return nil
 }
 
-func (h *AvatarHandler) uploadAsset(file *multipart.FileHeader, publicID, folder, resourceType string) (string, string, error) {
-	if h.cld != nil {
-		url, err := h.uploadToCloudinary(file, publicID, folder, resourceType)
-		if err == nil {
-			return url, "cloudinary", nil
-		}
-		log.Printf("Warning: cloudinary upload failed, falling back to local storage: %v", err)
+// uploadAsset puts file into the asset store under key and returns its URL.
+func (h *AvatarHandler) uploadAsset(ctx context.Context, file *multipart.FileHeader, key, resourceType string) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to read file")
 	}
+	defer src.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return h.store.Put(ctx, storage.Object{
+		Key:          key,
+		Body:         src,
+		Size:         file.Size,
+		ContentType:  file.Header.Get("Content-Type"),
+		ResourceType: resourceType,
+	})
+}
 
-	cleanFolder := strings.TrimPrefix(filepath.Clean(folder), "/")
-	diskDir := filepath.Join("uploads", cleanFolder)
-	if err := os.MkdirAll(diskDir, 0o755); err != nil {
-		return "", "", fmt.Errorf("failed to create upload directory")
+// currentURL reads one of the user's avatar_url, banner_url or
+// avatar_decoration_url columns.
+func (h *AvatarHandler) currentURL(userID uuid.UUID, column string) string {
+	var urls []string
+	if err := h.db.DB.Model(&models.User{}).Where("id = ?", userID).Pluck(column, &urls).Error; err != nil || len(urls) == 0 {
+		return ""
 	}
+	return urls[0]
+}
 
-	filename := fmt.Sprintf("%d%s", time.Now().UnixNano(), strings.ToLower(filepath.Ext(file.Filename)))
-	diskPath := filepath.Join(diskDir, filename)
-	if err := saveUploadedFile(file, diskPath); err != nil {
-		return "", "", err
+// checkQuota tells early whether an upload of size bytes fits userID's
+// storage quota. It returns false after writing the error response.
+func (h *AvatarHandler) checkQuota(c *fiber.Ctx, userID uuid.UUID, size int64) (bool, error) {
+	usage, err := h.library.CheckQuota(c.Context(), userID, size)
+	if err != nil {
+		return false, quotaErrorResponse(c, usage, err, "Failed to check storage quota")
 	}
+	return true, nil
+}
 
-	return fmt.Sprintf("/uploads/%s/%s", filepath.ToSlash(cleanFolder), filename), "local", nil
+// quotaErrorResponse writes the response for an asset that could not be
+// recorded: 413 when it does not fit the quota, fallback otherwise.
+func quotaErrorResponse(c *fiber.Ctx, usage services.StorageUsage, err error, fallback string) error {
+	if errors.Is(err, services.ErrStorageQuotaExceeded) {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "Storage quota exceeded", "storage": usage})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
 }
 
-func (h *AvatarHandler) createProfileAsset(userID uuid.UUID, kind, assetType string, file *multipart.FileHeader, url, publicID, resourceType, provider string, metadata map[string]any) {
+// createProfileAsset records an uploaded avatar, banner or avatar decoration
+// against the user's storage quota. When it cannot be recorded, the uploaded
+// files are removed again.
+func (h *AvatarHandler) createProfileAsset(ctx context.Context, userID uuid.UUID, kind, assetType string, file *multipart.FileHeader, mimeType string, size int64, url, publicID, resourceType string, metadata map[string]any) (services.StorageUsage, error) {
 	metadataJSON, _ := json.Marshal(metadata)
 	row := models.ProfileAsset{
 		UserID:       userID,
@@ -205,17 +312,21 @@ func (h *AvatarHandler) createProfileAsset(userID uuid.UUID, kind, assetType str
 		URL:          url,
 		PublicID:     publicID,
 		ResourceType: resourceType,
-		MimeType:     file.Header.Get("Content-Type"),
-		FileSize:     file.Size,
-		Provider:     provider,
+		MimeType:     mimeType,
+		FileSize:     size,
+		Provider:     h.store.Name(),
 		MetadataJSON: string(metadataJSON),
 	}
-	if err := h.db.DB.Create(&row).Error; err != nil {
-		log.Printf("Warning: failed to persist profile asset metadata: %v", err)
+	usage, err := h.library.CreateAsset(ctx, &row)
+	if err != nil {
+		if removeErr := h.removeStoredAsset(ctx, row); removeErr != nil {
+			log.Printf("Warning: failed to remove rejected %s: %v", kind, removeErr)
+		}
 	}
+	return usage, err
 }
 
-// UploadAvatar handles avatar file upload to Cloudinary
+// UploadAvatar stores an uploaded avatar in the asset store
 func (h *AvatarHandler) UploadAvatar(c *fiber.Ctx) error {
 	if err := h.ensureProfileSchema(); err != nil {
 		log.Printf("Error ensuring profile schema before avatar upload: %v", err)
@@ -239,29 +350,43 @@ func (h *AvatarHandler) UploadAvatar(c *fiber.Ctx) error {
 		})
 	}
 
-	assetType, resourceType, err := validateAssetFile(file, map[string]bool{
+	if _, _, err := validateAssetFile(file, map[string]bool{
 		"image": true,
 		"gif":   true,
-	})
-	if err != nil {
+	}); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := validateProcessedImage(file); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	publicID := fmt.Sprintf("briworld/avatars/%s", userID.String())
-	avatarURL, provider, err := h.uploadAsset(
-		file,
-		publicID,
-		"briworld/avatars",
-		resourceType,
-	)
+	if ok, err := h.checkQuota(c, userID, file.Size); !ok {
+		return err
+	}
+
+	previousURL := h.currentURL(userID, "avatar_url")
+	stored, err := h.storeImageUpload(c.Context(), file, "avatars", userID, imaging.AvatarSizes)
+	if isImageRejection(err) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
-		log.Printf("Error uploading to Cloudinary: %v", err)
+		log.Printf("Error uploading avatar: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to upload avatar",
 		})
 	}
+	avatarURL, assetType := stored.URL, stored.AssetType
+
+	if usage, err := h.createProfileAsset(c.Context(), userID, "avatar", assetType, file, stored.contentType(), stored.size(), avatarURL, stored.Key, "image", stored.metadata("current_avatar")); err != nil {
+		log.Printf("Error saving avatar asset: %v", err)
+		return quotaErrorResponse(c, usage, err, "Failed to save avatar")
+	}
 
 	if err := h.db.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
 		"avatar_url":  avatarURL,
@@ -273,14 +398,13 @@ func (h *AvatarHandler) UploadAvatar(c *fiber.Ctx) error {
 		})
 	}
 
-	h.createProfileAsset(userID, "avatar", assetType, file, avatarURL, publicID, resourceType, provider, map[string]any{
-		"usage": "current_avatar",
-	})
+	h.removeReplacedAsset(c.Context(), userID, "avatars", previousURL)
 
 	return c.JSON(fiber.Map{
-		"message":     "Avatar uploaded successfully",
-		"avatar_url":  avatarURL,
-		"avatar_type": assetType,
+		"message":         "Avatar uploaded successfully",
+		"avatar_url":      avatarURL,
+		"avatar_type":     assetType,
+		"avatar_variants": stored.Variants,
 	})
 }
 
@@ -293,18 +417,7 @@ func (h *AvatarHandler) DeleteAvatar(c *fiber.Ctx) error {
 		})
 	}
 
-	// Delete from Cloudinary
-	if h.cld != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		_, err := h.cld.Upload.Destroy(ctx, uploader.DestroyParams{
-			PublicID: fmt.Sprintf("briworld/avatars/%s", userID.String()),
-		})
-		if err != nil {
-			log.Printf("Warning: Failed to delete from Cloudinary: %v", err)
-		}
-	}
+	previousURL := h.currentURL(userID, "avatar_url")
 
 	// Update user's avatar URL to empty
 	if err := h.db.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
@@ -316,6 +429,7 @@ func (h *AvatarHandler) DeleteAvatar(c *fiber.Ctx) error {
 			"error": "Failed to delete avatar",
 		})
 	}
+	h.removeReplacedAsset(c.Context(), userID, "avatars", previousURL)
 
 	return c.JSON(fiber.Map{
 		"message": "Avatar deleted successfully",
@@ -343,22 +457,51 @@ func (h *AvatarHandler) UploadBanner(c *fiber.Ctx) error {
 		"gif":    true,
 		"lottie": true,
 	})
+	if err == nil && assetType != "lottie" {
+		err = validateProcessedImage(file)
+	}
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	publicID := fmt.Sprintf("briworld/banners/%s", userID.String())
-	bannerURL, provider, err := h.uploadAsset(
-		file,
-		publicID,
-		"briworld/banners",
-		resourceType,
-	)
+	if ok, err := h.checkQuota(c, userID, file.Size); !ok {
+		return err
+	}
+
+	previousURL := h.currentURL(userID, "banner_url")
+	var bannerURL, publicID string
+	var variants map[string]assetVariant
+	metadata := map[string]any{"usage": "current_banner"}
+	mimeType, size := file.Header.Get("Content-Type"), file.Size
+	if assetType == "lottie" {
+		if err := validateLottieFile(file); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		publicID = uploadKey("banners", userID, file)
+		bannerURL, err = h.uploadAsset(c.Context(), file, publicID, resourceType)
+	} else {
+		var stored *storedImage
+		stored, err = h.storeImageUpload(c.Context(), file, "banners", userID, imaging.BannerSizes)
+		if isImageRejection(err) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err == nil {
+			bannerURL, publicID, assetType = stored.URL, stored.Key, stored.AssetType
+			variants = stored.Variants
+			metadata = stored.metadata("current_banner")
+			mimeType, size = stored.contentType(), stored.size()
+		}
+	}
 	if err != nil {
 		log.Printf("Error uploading banner: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Banner upload failed: %v", err)})
 	}
 
+	if usage, err := h.createProfileAsset(c.Context(), userID, "banner", assetType, file, mimeType, size, bannerURL, publicID, resourceType, metadata); err != nil {
+		log.Printf("Error saving banner asset: %v", err)
+		return quotaErrorResponse(c, usage, err, "Failed to save banner")
+	}
+
 	if err := h.db.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
 		"banner_url":  bannerURL,
 		"banner_type": assetType,
@@ -367,14 +510,13 @@ func (h *AvatarHandler) UploadBanner(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Failed to save banner metadata: %v", err)})
 	}
 
-	h.createProfileAsset(userID, "banner", assetType, file, bannerURL, publicID, resourceType, provider, map[string]any{
-		"usage": "current_banner",
-	})
+	h.removeReplacedAsset(c.Context(), userID, "banners", previousURL)
 
 	return c.JSON(fiber.Map{
-		"message":     "Banner uploaded successfully",
-		"banner_url":  bannerURL,
-		"banner_type": assetType,
+		"message":         "Banner uploaded successfully",
+		"banner_url":      bannerURL,
+		"banner_type":     assetType,
+		"banner_variants": variants,
 	})
 }
 
@@ -384,16 +526,7 @@ func (h *AvatarHandler) DeleteBanner(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
 
-	if h.cld != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		_, err := h.cld.Upload.Destroy(ctx, uploader.DestroyParams{
-			PublicID: fmt.Sprintf("briworld/banners/%s", userID.String()),
-		})
-		if err != nil {
-			log.Printf("Warning: Failed to delete banner from Cloudinary: %v", err)
-		}
-	}
+	previousURL := h.currentURL(userID, "banner_url")
 
 	if err := h.db.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
 		"banner_url":  "",
@@ -401,6 +534,7 @@ func (h *AvatarHandler) DeleteBanner(c *fiber.Ctx) error {
 	}).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete banner"})
 	}
+	h.removeReplacedAsset(c.Context(), userID, "banners", previousURL)
 
 	return c.JSON(fiber.Map{"message": "Banner deleted successfully"})
 }
@@ -430,18 +564,25 @@ func (h *AvatarHandler) UploadAvatarDecoration(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	publicID := fmt.Sprintf("briworld/avatar-decorations/%s", userID.String())
-	decorationURL, provider, err := h.uploadAsset(
-		file,
-		publicID,
-		"briworld/avatar-decorations",
-		resourceType,
-	)
+	if ok, err := h.checkQuota(c, userID, file.Size); !ok {
+		return err
+	}
+
+	previousURL := h.currentURL(userID, "avatar_decoration_url")
+	publicID := uploadKey("avatar-decorations", userID, file)
+	decorationURL, err := h.uploadAsset(c.Context(), file, publicID, resourceType)
 	if err != nil {
 		log.Printf("Error uploading avatar decoration: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to upload avatar decoration"})
 	}
 
+	if usage, err := h.createProfileAsset(c.Context(), userID, "decoration", assetType, file, file.Header.Get("Content-Type"), file.Size, decorationURL, publicID, resourceType, map[string]any{
+		"usage": "avatar_decoration",
+	}); err != nil {
+		log.Printf("Error saving avatar decoration asset: %v", err)
+		return quotaErrorResponse(c, usage, err, "Failed to save avatar decoration")
+	}
+
 	if err := h.db.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
 		"avatar_decoration_url":    decorationURL,
 		"avatar_decoration_preset": "",
@@ -449,9 +590,7 @@ func (h *AvatarHandler) UploadAvatarDecoration(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update avatar decoration"})
 	}
 
-	h.createProfileAsset(userID, "decoration", assetType, file, decorationURL, publicID, resourceType, provider, map[string]any{
-		"usage": "avatar_decoration",
-	})
+	h.removeReplacedAsset(c.Context(), userID, "avatar-decorations", previousURL)
 
 	return c.JSON(fiber.Map{
 		"message":               "Avatar decoration uploaded successfully",
@@ -466,16 +605,7 @@ func (h *AvatarHandler) DeleteAvatarDecoration(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
 
-	if h.cld != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		_, err := h.cld.Upload.Destroy(ctx, uploader.DestroyParams{
-			PublicID: fmt.Sprintf("briworld/avatar-decorations/%s", userID.String()),
-		})
-		if err != nil {
-			log.Printf("Warning: Failed to delete avatar decoration from Cloudinary: %v", err)
-		}
-	}
+	previousURL := h.currentURL(userID, "avatar_decoration_url")
 
 	if err := h.db.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
 		"avatar_decoration_url":    "",
@@ -483,6 +613,7 @@ func (h *AvatarHandler) DeleteAvatarDecoration(c *fiber.Ctx) error {
 	}).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete avatar decoration"})
 	}
+	h.removeReplacedAsset(c.Context(), userID, "avatar-decorations", previousURL)
 
 	return c.JSON(fiber.Map{"message": "Avatar decoration deleted successfully"})
 }
@@ -537,9 +668,13 @@ func (h *AvatarHandler) UploadProfileAsset(c *fiber.Ctx) error {
 	}
 	name := c.FormValue("name", file.Filename)
 	target := c.FormValue("target", "avatar")
-	publicID := fmt.Sprintf("briworld/profile-assets/%s/%d", userID.String(), time.Now().UnixNano())
+	publicID := uploadKey("profile-assets", userID, file)
+
+	if ok, err := h.checkQuota(c, userID, file.Size); !ok {
+		return err
+	}
 
-	url, provider, err := h.uploadAsset(file, publicID, "briworld/profile-assets", resourceType)
+	url, err := h.uploadAsset(c.Context(), file, publicID, resourceType)
 	if err != nil {
 		log.Printf("Error uploading profile asset: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to upload asset"})
@@ -560,17 +695,67 @@ func (h *AvatarHandler) UploadProfileAsset(c *fiber.Ctx) error {
 		ResourceType: resourceType,
 		MimeType:     file.Header.Get("Content-Type"),
 		FileSize:     file.Size,
-		Provider:     provider,
+		Provider:     h.store.Name(),
 		MetadataJSON: string(metadataJSON),
 	}
 
-	if err := h.db.DB.Create(&row).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save asset metadata"})
+	if usage, err := h.library.CreateAsset(c.Context(), &row); err != nil {
+		if removeErr := h.removeStoredAsset(c.Context(), row); removeErr != nil {
+			log.Printf("Warning: failed to remove rejected profile asset: %v", removeErr)
+		}
+		return quotaErrorResponse(c, usage, err, "Failed to save asset metadata")
 	}
 
 	return c.JSON(row)
 }
 
+// RenameProfileAsset changes the display name of a library asset.
+func (h *AvatarHandler) RenameProfileAsset(c *fiber.Ctx) error {
+	userID, err := h.parseUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	assetID, err := uuid.Parse(c.Params("assetId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid asset ID"})
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	asset, err := h.library.RenameAsset(c.Context(), userID, assetID, req.Name)
+	switch {
+	case errors.Is(err, services.ErrInvalidAssetName):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrAssetNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Asset not found"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rename asset"})
+	}
+
+	return c.JSON(asset)
+}
+
+// StorageUsage reports the user's asset storage use against their quota.
+func (h *AvatarHandler) StorageUsage(c *fiber.Ctx) error {
+	userID, err := h.parseUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	usage, err := h.library.Usage(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load storage usage"})
+	}
+
+	return c.JSON(usage)
+}
+
 func (h *AvatarHandler) DeleteProfileAsset(c *fiber.Ctx) error {
 	userID, err := h.parseUserID(c)
 	if err != nil {
@@ -587,21 +772,10 @@ func (h *AvatarHandler) DeleteProfileAsset(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Asset not found"})
 	}
 
-	if asset.Provider == "cloudinary" && h.cld != nil && asset.PublicID != "" {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		_, err := h.cld.Upload.Destroy(ctx, uploader.DestroyParams{
-			PublicID:     asset.PublicID,
-			ResourceType: asset.ResourceType,
-		})
-		if err != nil {
-			log.Printf("Warning: failed to delete profile asset from cloudinary: %v", err)
-		}
-	} else if asset.Provider == "local" && strings.HasPrefix(asset.URL, "/uploads/") {
-		localPath := filepath.Clean(strings.TrimPrefix(asset.URL, "/"))
-		if err := os.Remove(localPath); err != nil && !os.IsNotExist(err) {
-			log.Printf("Warning: failed to delete local profile asset: %v", err)
-		}
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+	if err := h.removeStoredAsset(ctx, asset); err != nil {
+		log.Printf("Warning: failed to delete profile asset: %v", err)
 	}
 
 	if err := h.db.DB.Delete(&asset).Error; err != nil {
@@ -610,3 +784,78 @@ func (h *AvatarHandler) DeleteProfileAsset(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{"message": "Asset deleted successfully"})
 }
+
+// removeStoredAsset deletes the file behind asset from the asset store.
+func (h *AvatarHandler) removeStoredAsset(ctx context.Context, asset models.ProfileAsset) error {
+	if asset.PublicID == "" {
+		return nil
+	}
+	if asset.Provider != h.store.Name() {
+		return fmt.Errorf("asset %s is stored in %q but the configured store is %q", asset.ID, asset.Provider, h.store.Name())
+	}
+	if err := h.store.Delete(ctx, asset.PublicID, asset.ResourceType); err != nil {
+		return err
+	}
+
+	var metadata struct {
+		Variants map[string]assetVariant `json:"variants"`
+	}
+	if asset.MetadataJSON != "" {
+		if err := json.Unmarshal([]byte(asset.MetadataJSON), &metadata); err != nil {
+			return nil
+		}
+	}
+	var errs []error
+	for _, variant := range metadata.Variants {
+		if variant.Key != "" && variant.Key != asset.PublicID {
+			errs = append(errs, h.store.Delete(ctx, variant.Key, "image"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// removeReplacedAsset deletes the avatar, banner or avatar decoration that
+// was served from url after the user replaced or removed it. Only assets
+// uploaded into folder are touched, so a library asset in use is kept.
+func (h *AvatarHandler) removeReplacedAsset(ctx context.Context, userID uuid.UUID, folder, url string) {
+	if url == "" {
+		return
+	}
+
+	var assets []models.ProfileAsset
+	if err := h.db.DB.Where("user_id = ? AND url = ? AND public_id LIKE ?", userID, url, "briworld/"+folder+"/%").
+		Find(&assets).Error; err != nil {
+		log.Printf("Warning: failed to load replaced %s: %v", folder, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	for _, asset := range assets {
+		if err := h.removeStoredAsset(ctx, asset); err != nil {
+			log.Printf("Warning: failed to delete replaced %s: %v", folder, err)
+			continue
+		}
+		if err := h.db.DB.Delete(&asset).Error; err != nil {
+			log.Printf("Warning: failed to delete replaced %s metadata: %v", folder, err)
+		}
+	}
+}
+
+// PurgeUserUploads removes the files behind assets, which hold every upload
+// of a user including the avatar, banner and avatar decoration.
+func (h *AvatarHandler) PurgeUserUploads(ctx context.Context, assets []models.ProfileAsset) error {
+	var errs []error
+	seen := make(map[string]bool)
+	for _, asset := range assets {
+		key := asset.Provider + ":" + asset.PublicID
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err := h.removeStoredAsset(ctx, asset); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
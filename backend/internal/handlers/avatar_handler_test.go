@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"briworld/internal/imaging"
+	"errors"
+	"mime/multipart"
+	"net/textproto"
+	"testing"
+)
+
+func fileHeader(name, contentType string) *multipart.FileHeader {
+	return &multipart.FileHeader{
+		Filename: name,
+		Header:   textproto.MIMEHeader{"Content-Type": {contentType}},
+		Size:     1024,
+	}
+}
+
+func TestValidateProcessedImageRefusesUndecodableFormats(t *testing.T) {
+	for _, file := range []*multipart.FileHeader{
+		fileHeader("avatar.avif", "image/avif"),
+		fileHeader("avatar.svg", "image/svg+xml"),
+	} {
+		if _, _, err := validateAssetFile(file, map[string]bool{"image": true, "gif": true}); err != nil {
+			t.Fatalf("%s: asset check: %v", file.Filename, err)
+		}
+		if err := validateProcessedImage(file); !errors.Is(err, imaging.ErrUnsupportedFormat) {
+			t.Fatalf("%s: err = %v", file.Filename, err)
+		}
+	}
+
+	for _, file := range []*multipart.FileHeader{
+		fileHeader("avatar.png", "image/png"),
+		fileHeader("avatar.jpg", "image/jpeg"),
+		fileHeader("avatar.gif", "image/gif"),
+		fileHeader("avatar.webp", "image/webp"),
+	} {
+		if err := validateProcessedImage(file); err != nil {
+			t.Fatalf("%s: err = %v", file.Filename, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"briworld/internal/services"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type DailyChallengeHandler struct {
+	daily *services.DailyChallengeService
+}
+
+func NewDailyChallengeHandler(daily *services.DailyChallengeService) *DailyChallengeHandler {
+	return &DailyChallengeHandler{daily: daily}
+}
+
+// GetDailyChallenge returns today's challenge, with the streak of a
+// signed-in caller and whether they have played it
+func (h *DailyChallengeHandler) GetDailyChallenge(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uuid.UUID)
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	view, err := h.daily.Today(ctx, userID)
+	if errors.Is(err, services.ErrDailyChallengeUnavailable) {
+		return c.JSON(fiber.Map{"challenge": nil})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load the daily challenge"})
+	}
+	return c.JSON(view)
+}
+
+// StartDailyChallenge opens the room of the current user's one attempt at
+// today's challenge. The score is recorded by the server when the game ends.
+func (h *DailyChallengeHandler) StartDailyChallenge(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	attempt, err := h.daily.Start(ctx, userID)
+	switch {
+	case errors.Is(err, services.ErrDailyChallengePlayed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrDailyChallengeUnavailable):
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start the daily challenge"})
+	}
+	return c.JSON(attempt)
+}
+
+// GetDailyLeaderboard ranks the finished attempts at the challenge of the
+// date in the query, today by default
+func (h *DailyChallengeHandler) GetDailyLeaderboard(c *fiber.Ctx) error {
+	date := time.Now().UTC()
+	if raw := c.Query("date"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "date must be YYYY-MM-DD"})
+		}
+		date = parsed
+	}
+	viewerID, _ := c.Locals("user_id").(uuid.UUID)
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	board, err := h.daily.Leaderboard(ctx, date, viewerID, c.QueryInt("limit", 50))
+	if errors.Is(err, services.ErrDailyChallengeNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load leaderboard"})
+	}
+	return c.JSON(board)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"briworld/internal/services"
+	"briworld/internal/utils"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FlagHandler struct {
+	flags     *services.FlagImageService
+	jwtSecret string
+}
+
+func NewFlagHandler(flags *services.FlagImageService, jwtSecret string) *FlagHandler {
+	return &FlagHandler{flags: flags, jwtSecret: jwtSecret}
+}
+
+// GetFlag serves the flag behind a round's opaque flag token
+func (h *FlagHandler) GetFlag(c *fiber.Ctx) error {
+	code, err := utils.OpenFlagToken(c.Params("token"), h.jwtSecret)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Flag not found"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	image, err := h.flags.Image(ctx, code)
+	if errors.Is(err, services.ErrFlagUnknown) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Flag not found"})
+	}
+	if err != nil {
+		log.Printf("Failed to load flag %s: %v", code, err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Flag unavailable"})
+	}
+
+	// Each round gets its own URL, so the browser may keep it
+	c.Set(fiber.HeaderCacheControl, "private, max-age=3600")
+	c.Set(fiber.HeaderContentType, "image/png")
+	return c.Send(image)
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"briworld/internal/services"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type FriendHandler struct {
+	friends  *services.FriendService
+	presence *services.PresenceService
+}
+
+func NewFriendHandler(friends *services.FriendService, presence *services.PresenceService) *FriendHandler {
+	return &FriendHandler{friends: friends, presence: presence}
+}
+
+func friendErrorResponse(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrPlayerNotFound),
+		errors.Is(err, services.ErrFriendNotFound),
+		errors.Is(err, services.ErrFriendRequestNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotFriendSelf):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyFriends), errors.Is(err, services.ErrPlayerBlocked):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
+
+// ListFriends returns the current user's friends with their presence and
+// pending requests. Polling it also keeps the user shown as online.
+func (h *FriendHandler) ListFriends(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.presence.Touch(ctx, userID); err != nil {
+		log.Printf("Failed to update presence: %v", err)
+	}
+
+	list, err := h.friends.List(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load friends"})
+	}
+	return c.JSON(list)
+}
+
+// SendRequest sends a friend request to a player by username
+func (h *FriendHandler) SendRequest(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Username == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Username is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	friendship, err := h.friends.Request(ctx, userID, req.Username)
+	if err != nil {
+		return friendErrorResponse(c, err, "Failed to send friend request")
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"friendship": friendship})
+}
+
+// AcceptRequest accepts a friend request sent to the current user
+func (h *FriendHandler) AcceptRequest(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	requestID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.friends.Accept(ctx, userID, requestID); err != nil {
+		return friendErrorResponse(c, err, "Failed to accept friend request")
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// DeclineRequest declines a received friend request or cancels a sent one
+func (h *FriendHandler) DeclineRequest(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	requestID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.friends.Decline(ctx, userID, requestID); err != nil {
+		return friendErrorResponse(c, err, "Failed to decline friend request")
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// RemoveFriend ends a friendship
+func (h *FriendHandler) RemoveFriend(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	friendID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.friends.Remove(ctx, userID, friendID); err != nil {
+		return friendErrorResponse(c, err, "Failed to remove friend")
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// ListBlocked returns the players the current user blocked
+func (h *FriendHandler) ListBlocked(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	blocked, err := h.friends.Blocked(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load blocked players"})
+	}
+	return c.JSON(fiber.Map{"blocked": blocked})
+}
+
+// Block blocks a player by username and ends any friendship with them
+func (h *FriendHandler) Block(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Username == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Username is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	blocked, err := h.friends.Block(ctx, userID, req.Username)
+	if err != nil {
+		return friendErrorResponse(c, err, "Failed to block player")
+	}
+	return c.JSON(fiber.Map{"success": true, "user_id": blocked.ID})
+}
+
+// Unblock lifts a block
+func (h *FriendHandler) Unblock(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	blockedID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.friends.Unblock(ctx, userID, blockedID); err != nil {
+		return friendErrorResponse(c, err, "Failed to unblock player")
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"briworld/internal/services"
+	"briworld/internal/utils"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type GuestHandler struct {
+	progress  *services.ProgressService
+	jwtSecret string
+}
+
+func NewGuestHandler(progress *services.ProgressService, jwtSecret string) *GuestHandler {
+	return &GuestHandler{progress: progress, jwtSecret: jwtSecret}
+}
+
+// ClaimGuest moves the progress a guest session earned onto the current user.
+// The guest token the game server handed out proves the session is theirs.
+func (h *GuestHandler) ClaimGuest(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		SessionID  string `json:"session_id"`
+		GuestToken string `json:"guest_token"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.SessionID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "session_id is required"})
+	}
+	if !utils.VerifyGuestSession(req.SessionID, req.GuestToken, h.jwtSecret) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid guest token"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	summary, err := h.progress.ClaimGuest(ctx, userID, req.SessionID)
+	if errors.Is(err, services.ErrGuestAlreadyClaimed) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Guest progress was already claimed"})
+	}
+	if err != nil {
+		log.Printf("Guest claim failed for user %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to claim guest progress"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Guest progress claimed",
+		"claimed": summary,
+	})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"briworld/internal/services"
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type NotificationHandler struct {
+	notifications *services.NotificationService
+}
+
+func NewNotificationHandler(notifications *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notifications: notifications}
+}
+
+// ListNotifications returns the current user's inbox, newest first. Pass the
+// created_at of the last notification as before to page back.
+func (h *NotificationHandler) ListNotifications(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var before time.Time
+	if raw := c.Query("before"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "before must be an RFC 3339 time"})
+		}
+		before = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	notifications, err := h.notifications.List(ctx, userID, c.QueryBool("unread"), before, c.QueryInt("limit", 50))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load notifications"})
+	}
+	unread, err := h.notifications.UnreadCount(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load notifications"})
+	}
+
+	return c.JSON(fiber.Map{"notifications": notifications, "unread": unread})
+}
+
+// MarkRead marks the listed notifications as read, or all of them when no
+// IDs are given
+func (h *NotificationHandler) MarkRead(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.IDs))
+	for _, raw := range req.IDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid notification ID"})
+		}
+		ids = append(ids, id)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	updated, err := h.notifications.MarkRead(ctx, userID, ids)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update notifications"})
+	}
+	return c.JSON(fiber.Map{"updated": updated})
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"briworld/internal/models"
+	"briworld/internal/services"
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// oidcBindingCookie ties a login to the browser that started it. It is only
+// sent back to the OIDC routes.
+const (
+	oidcBindingCookie = "oidc_binding"
+	oidcCookiePath    = "/api/v2/auth/oidc"
+)
+
+type OIDCHandler struct {
+	oidc        *services.OIDCService
+	auth        *AuthHandlerGorm
+	frontendURL string
+}
+
+func NewOIDCHandler(oidcService *services.OIDCService, auth *AuthHandlerGorm, frontendURL string) *OIDCHandler {
+	return &OIDCHandler{
+		oidc:        oidcService,
+		auth:        auth,
+		frontendURL: frontendURL,
+	}
+}
+
+// ListProviders returns the social login providers the server is configured for
+func (h *OIDCHandler) ListProviders(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"providers": h.oidc.Providers()})
+}
+
+// Begin redirects the browser to the provider's login page
+func (h *OIDCHandler) Begin(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	start, err := h.oidc.BeginLogin(ctx, c.Params("provider"))
+	if errors.Is(err, services.ErrOIDCProviderUnknown) {
+		return c.Status(404).JSON(fiber.Map{"error": "Unknown provider"})
+	}
+	if err != nil {
+		log.Printf("OIDC begin failed for %s: %v", c.Params("provider"), err)
+		return c.Status(502).JSON(fiber.Map{"error": "Identity provider unavailable"})
+	}
+
+	h.setBinding(c, start.Binding, start.TTL)
+	return c.Redirect(start.AuthURL, fiber.StatusFound)
+}
+
+// Callback completes a login or link started with Begin or Link
+func (h *OIDCHandler) Callback(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	binding := c.Cookies(oidcBindingCookie)
+	h.setBinding(c, "", -time.Hour)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		return h.fail(c, 400, "Login was cancelled or denied")
+	}
+
+	result, err := h.oidc.Complete(ctx, c.Params("provider"), c.Query("code"), c.Query("state"), binding)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOIDCProviderUnknown):
+			return h.fail(c, 404, "Unknown provider")
+		case errors.Is(err, services.ErrOIDCStateInvalid):
+			return h.fail(c, 400, "Login request expired, please try again")
+		case errors.Is(err, services.ErrOIDCEmailUnverified):
+			return h.fail(c, 403, "Your provider account has no verified email")
+		case errors.Is(err, services.ErrIdentityLinkedToOther):
+			return h.fail(c, 409, "This account is already linked to another user")
+		case errors.Is(err, services.ErrOIDCProviderAlreadyLinked):
+			return h.fail(c, 409, "This profile already has a different account from this provider linked")
+		}
+		log.Printf("OIDC callback failed for %s: %v", c.Params("provider"), err)
+		return h.fail(c, 401, "Login failed")
+	}
+
+	if result.Linked {
+		if h.frontendURL != "" {
+			return c.Redirect(h.frontendURL+"/profile?linked="+url.QueryEscape(c.Params("provider")), fiber.StatusFound)
+		}
+		return c.JSON(fiber.Map{"message": "Provider linked", "provider": c.Params("provider")})
+	}
+
+	if result.User.TwoFactorEnabled {
+		return h.challenge(c, result.User)
+	}
+
+	response, err := h.auth.issueSession(c, ctx, result.User)
+	if errors.Is(err, services.ErrAccountBanned) {
+		return h.fail(c, 403, "This account is banned")
+	}
+	if err != nil {
+		return h.fail(c, 500, "Failed to generate authentication token")
+	}
+
+	if h.frontendURL == "" {
+		return c.JSON(response)
+	}
+
+	// Tokens travel in the fragment so they never reach server logs.
+	fragment := url.Values{}
+	fragment.Set("access_token", response.AccessToken)
+	fragment.Set("expires_in", strconv.Itoa(response.ExpiresIn))
+	fragment.Set("refresh_token", response.RefreshToken)
+	fragment.Set("username", result.User.Username)
+	if result.Created {
+		fragment.Set("new_account", "true")
+	}
+	return c.Redirect(h.frontendURL+"/oauth/callback#"+fragment.Encode(), fiber.StatusFound)
+}
+
+// challenge hands the frontend a 2FA challenge instead of tokens. The login
+// finishes through /auth/login/2fa like a password login.
+func (h *OIDCHandler) challenge(c *fiber.Ctx, user *models.User) error {
+	challenge, err := h.auth.twoFactorChallenge(user)
+	if err != nil {
+		return h.fail(c, 500, "Failed to start two-factor login")
+	}
+
+	if h.frontendURL == "" {
+		return c.JSON(challenge)
+	}
+
+	fragment := url.Values{}
+	fragment.Set("challenge_token", challenge.ChallengeToken)
+	fragment.Set("expires_in", strconv.Itoa(challenge.ExpiresIn))
+	return c.Redirect(h.frontendURL+"/login#"+fragment.Encode(), fiber.StatusFound)
+}
+
+// setBinding stores the browser binding for a login in progress, or clears
+// it when ttl is negative. Lax lets the cookie ride along on the provider's
+// redirect back while keeping it off cross-site subrequests.
+func (h *OIDCHandler) setBinding(c *fiber.Ctx, value string, ttl time.Duration) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcBindingCookie,
+		Value:    value,
+		Path:     oidcCookiePath,
+		Expires:  time.Now().Add(ttl),
+		HTTPOnly: true,
+		Secure:   c.Secure(),
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+func (h *OIDCHandler) fail(c *fiber.Ctx, status int, message string) error {
+	if h.frontendURL != "" {
+		return c.Redirect(h.frontendURL+"/login?oauth_error="+url.QueryEscape(message), fiber.StatusFound)
+	}
+	return c.Status(status).JSON(fiber.Map{"error": message})
+}
+
+// ListIdentities returns the providers linked to the current user
+func (h *OIDCHandler) ListIdentities(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	identities, err := h.oidc.ListIdentities(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load linked accounts"})
+	}
+
+	return c.JSON(fiber.Map{
+		"identities": identities,
+		"available":  h.oidc.Providers(),
+	})
+}
+
+// Link starts linking a provider to the current user. The client navigates
+// to the returned URL.
+func (h *OIDCHandler) Link(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	start, err := h.oidc.BeginLink(ctx, c.Params("provider"), userID)
+	if errors.Is(err, services.ErrOIDCProviderUnknown) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown provider"})
+	}
+	if err != nil {
+		log.Printf("OIDC link failed for %s: %v", c.Params("provider"), err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Identity provider unavailable"})
+	}
+
+	h.setBinding(c, start.Binding, start.TTL)
+	return c.JSON(fiber.Map{"authorization_url": start.AuthURL})
+}
+
+// Unlink removes a provider from the current user
+func (h *OIDCHandler) Unlink(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.oidc.Unlink(ctx, userID, c.Params("provider")); err != nil {
+		switch {
+		case errors.Is(err, services.ErrIdentityNotLinked):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Provider is not linked"})
+		case errors.Is(err, services.ErrLastLoginMethod):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Set a password before unlinking your only sign-in method"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to unlink provider"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Provider unlinked"})
+}
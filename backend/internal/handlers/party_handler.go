@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"briworld/internal/services"
+	"briworld/internal/ws"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type PartyHandler struct {
+	players       *services.PlayerService
+	friends       *services.FriendService
+	notifications *services.NotificationService
+}
+
+func NewPartyHandler(players *services.PlayerService, friends *services.FriendService, notifications *services.NotificationService) *PartyHandler {
+	return &PartyHandler{players: players, friends: friends, notifications: notifications}
+}
+
+func partyErrorResponse(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, ws.ErrNotInParty),
+		errors.Is(err, ws.ErrPartyInviteNotFound),
+		errors.Is(err, ws.ErrPartyMemberNotFound),
+		errors.Is(err, services.ErrPlayerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ws.ErrNotPartyLeader):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ws.ErrAlreadyInParty), errors.Is(err, ws.ErrPartyFull):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
+
+// GetParty returns the current user's party, or null when they are not in one
+func (h *PartyHandler) GetParty(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	party, found := ws.Parties.Get(userID)
+	if !found {
+		return c.JSON(fiber.Map{"party": nil})
+	}
+	return c.JSON(fiber.Map{"party": party})
+}
+
+// CreateParty starts a party led by the current user
+func (h *PartyHandler) CreateParty(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	card, err := h.players.Card(ctx, userID)
+	if err != nil {
+		return partyErrorResponse(c, err, "Failed to create party")
+	}
+	party, err := ws.Parties.Create(*card)
+	if err != nil {
+		return partyErrorResponse(c, err, "Failed to create party")
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"party": party})
+}
+
+// LeaveParty takes the current user out of their party
+func (h *PartyHandler) LeaveParty(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := ws.Parties.Leave(userID); err != nil {
+		return partyErrorResponse(c, err, "Failed to leave party")
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// InviteToParty invites a friend to the current user's party through their
+// notification inbox. Only the leader can invite.
+func (h *PartyHandler) InviteToParty(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	friendID, err := uuid.Parse(req.UserID)
+	if err != nil || friendID == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	friends, err := h.friends.AreFriends(ctx, userID, friendID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send invite"})
+	}
+	if !friends {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only invite friends"})
+	}
+
+	party, err := ws.Parties.Invite(userID, friendID)
+	if err != nil {
+		return partyErrorResponse(c, err, "Failed to send invite")
+	}
+
+	var leader services.PlayerCard
+	for _, member := range party.Members {
+		if member.ID == userID {
+			leader = member.PlayerCard
+		}
+	}
+	if _, err := h.notifications.Notify(ctx, friendID, services.NotificationPartyInvite, map[string]interface{}{
+		"party_id": party.ID,
+		"from":     leader,
+		"members":  len(party.Members),
+	}); err != nil {
+		log.Printf("Failed to send party invite: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send invite"})
+	}
+	return c.JSON(fiber.Map{"party": party})
+}
+
+// JoinParty accepts an invite to the party in the URL
+func (h *PartyHandler) JoinParty(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	card, err := h.players.Card(ctx, userID)
+	if err != nil {
+		return partyErrorResponse(c, err, "Failed to join party")
+	}
+	party, err := ws.Parties.Join(c.Params("id"), *card)
+	if err != nil {
+		return partyErrorResponse(c, err, "Failed to join party")
+	}
+	return c.JSON(fiber.Map{"party": party})
+}
+
+// KickMember removes a member from the current user's party. Only the leader
+// can kick.
+func (h *PartyHandler) KickMember(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	memberID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	party, err := ws.Parties.Kick(userID, memberID)
+	if err != nil {
+		return partyErrorResponse(c, err, "Failed to remove party member")
+	}
+	return c.JSON(fiber.Map{"party": party})
+}
+
+// FindMatch places the current user in a public room of the requested game
+// mode. A party leader queues the whole party, which always lands in the
+// same room; the other members get a match_found notification.
+func (h *PartyHandler) FindMatch(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		GameMode string `json:"game_mode"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	if req.GameMode == "" {
+		req.GameMode = "FLAG"
+	}
+	if req.GameMode == "EMOJI" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Emoji mode is temporarily disabled"})
+	}
+	if req.GameMode == "PRACTICE" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Practice is a single player mode"})
+	}
+
+	userIDs := []uuid.UUID{userID}
+	party, inParty := ws.Parties.Get(userID)
+	if inParty {
+		if party.LeaderID != userID {
+			return partyErrorResponse(c, ws.ErrNotPartyLeader, "Failed to find a match")
+		}
+		userIDs = party.MemberIDs()
+	}
+
+	roomCode := ws.Matchmaking.Place(req.GameMode, userIDs)
+	match := fiber.Map{
+		"room_code": roomCode,
+		"game_mode": req.GameMode,
+		"room_type": "PUBLIC",
+	}
+
+	if inParty {
+		followers := make([]uuid.UUID, 0, len(userIDs)-1)
+		for _, id := range userIDs {
+			if id != userID {
+				followers = append(followers, id)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		if err := h.notifications.NotifyMany(ctx, followers, services.NotificationMatchFound, fiber.Map{
+			"room_code": roomCode,
+			"game_mode": req.GameMode,
+			"room_type": "PUBLIC",
+			"party_id":  party.ID,
+		}); err != nil {
+			log.Printf("Failed to notify party %s of match: %v", party.ID, err)
+		}
+	}
+
+	return c.JSON(match)
+}
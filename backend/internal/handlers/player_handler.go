@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"briworld/internal/services"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type PlayerHandler struct {
+	players *services.PlayerService
+}
+
+func NewPlayerHandler(players *services.PlayerService) *PlayerHandler {
+	return &PlayerHandler{players: players}
+}
+
+// GetPlayer returns the public profile of a player. Private profiles only
+// show their card to anyone but the owner.
+func (h *PlayerHandler) GetPlayer(c *fiber.Ctx) error {
+	viewerID, _ := c.Locals("user_id").(uuid.UUID)
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	profile, err := h.players.Profile(ctx, c.Params("username"), viewerID)
+	if errors.Is(err, services.ErrPlayerNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Player not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load player"})
+	}
+
+	return c.JSON(profile)
+}
+
+// SearchPlayers finds players by username
+func (h *PlayerHandler) SearchPlayers(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	page, err := h.players.Search(ctx, c.Query("q"), c.QueryInt("page", 1), c.QueryInt("page_size", 20))
+	if errors.Is(err, services.ErrInvalidSearchQuery) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to search players"})
+	}
+
+	return c.JSON(page)
+}
@@ -2,19 +2,119 @@ package handlers
 
 import (
 	"briworld/internal/database"
+	"briworld/internal/mailer"
 	"briworld/internal/models"
+	"briworld/internal/services"
+	"briworld/internal/utils"
+	"context"
+	"errors"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 type ProfileHandler struct {
-	db *database.GormDB
+	db            *database.GormDB
+	twoFactor     *services.TwoFactorService
+	refreshTokens *services.RefreshTokenService
+	authService   *services.AuthServiceGorm
+	mailer        *mailer.Mailer
+	frontendURL   string
 }
 
-func NewProfileHandler(db *database.GormDB) *ProfileHandler {
-	return &ProfileHandler{db: db}
+func NewProfileHandler(db *database.GormDB, twoFactor *services.TwoFactorService, refreshTokens *services.RefreshTokenService, authService *services.AuthServiceGorm, m *mailer.Mailer, frontendURL string) *ProfileHandler {
+	return &ProfileHandler{
+		db:            db,
+		twoFactor:     twoFactor,
+		refreshTokens: refreshTokens,
+		authService:   authService,
+		mailer:        m,
+		frontendURL:   frontendURL,
+	}
+}
+
+// recentSignInWindow is how long after signing in an account without a
+// password or 2FA may make sensitive changes.
+const recentSignInWindow = 10 * time.Minute
+
+// checkStepUp confirms the caller still controls the account before a
+// sensitive change: the current password and, with 2FA on, a code checked on
+// this request. Social login accounts with neither must have signed in on
+// this device within recentSignInWindow. It returns false after writing the
+// error response.
+func checkStepUp(c *fiber.Ctx, twoFactor *services.TwoFactorService, sessions *services.RefreshTokenService, user *models.User, currentPassword, code string) (bool, error) {
+	if user.PasswordHash != "" && !utils.VerifyPassword(user.PasswordHash, currentPassword) {
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Current password is incorrect",
+		})
+	}
+
+	if !user.TwoFactorEnabled {
+		if user.PasswordHash == "" {
+			return checkRecentSignIn(c, sessions, user)
+		}
+		return true, nil
+	}
+
+	if code == "" {
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":               "Two-factor code required",
+			"two_factor_required": true,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	err := twoFactor.Verify(ctx, user.ID, code)
+	if errors.Is(err, services.ErrTwoFactorCodeInvalid) {
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":               "Invalid two-factor code",
+			"two_factor_required": true,
+		})
+	}
+	if err != nil {
+		log.Printf("Error checking two-factor code: %v", err)
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to verify two-factor code",
+		})
+	}
+
+	return true, nil
+}
+
+// checkRecentSignIn confirms the current device session of user was opened
+// within recentSignInWindow. It returns false after writing the error response.
+func checkRecentSignIn(c *fiber.Ctx, sessions *services.RefreshTokenService, user *models.User) (bool, error) {
+	sessionID, _ := c.Locals("session_id").(string)
+	if sessionID == "" {
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":           "Sign in again to make this change",
+			"reauth_required": true,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	startedAt, err := sessions.SessionStartedAt(ctx, user.ID, sessionID)
+	if err != nil && !errors.Is(err, services.ErrSessionNotFound) {
+		log.Printf("Error checking session sign-in time: %v", err)
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to verify sign-in",
+		})
+	}
+	if err != nil || time.Since(startedAt) > recentSignInWindow {
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":           "Sign in again to make this change",
+			"reauth_required": true,
+		})
+	}
+
+	return true, nil
 }
 
 // GetProfile returns the current user's profile
@@ -48,6 +148,9 @@ func (h *ProfileHandler) GetProfile(c *fiber.Ctx) error {
 		"id":                         user.ID,
 		"username":                   user.Username,
 		"email":                      user.Email,
+		"email_verified":             user.EmailVerified,
+		"two_factor_enabled":         user.TwoFactorEnabled,
+		"deletion_scheduled_at":      user.DeletionScheduledAt,
 		"avatar_url":                 user.AvatarURL,
 		"avatar_type":                user.AvatarType,
 		"banner_url":                 user.BannerURL,
@@ -63,6 +166,7 @@ func (h *ProfileHandler) GetProfile(c *fiber.Ctx) error {
 		"longest_win_streak":         user.LongestWinStreak,
 		"countries_mastered":         user.CountriesMastered,
 		"favorite_color":             user.FavoriteColor,
+		"profile_visibility":         user.ProfileVisibility,
 		"rating":                     user.Rating,
 		"rank":                       user.Rank,
 		"rank_tier":                  user.RankTier,
@@ -91,6 +195,11 @@ func (h *ProfileHandler) UpdateProfile(c *fiber.Ctx) error {
 		Username                 *string `json:"username"`
 		AvatarDecorationPreset   *string `json:"avatar_decoration_preset"`
 		ProfileCustomizationJSON *string `json:"profile_customization_json"`
+		ProfileVisibility        *string `json:"profile_visibility"`
+		Email                    *string `json:"email"`
+		NewPassword              *string `json:"new_password"`
+		CurrentPassword          string  `json:"current_password"`
+		TwoFactorCode            string  `json:"two_factor_code"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -101,6 +210,61 @@ func (h *ProfileHandler) UpdateProfile(c *fiber.Ctx) error {
 
 	updates := map[string]interface{}{}
 
+	// Email and password changes take over the account, so they need the
+	// current password and, with 2FA on, a code checked on this request.
+	var user models.User
+	if req.Email != nil || req.NewPassword != nil {
+		if err := h.db.DB.First(&user, "id = ?", userID).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "User not found",
+			})
+		}
+
+		if ok, err := checkStepUp(c, h.twoFactor, h.refreshTokens, &user, req.CurrentPassword, req.TwoFactorCode); !ok {
+			return err
+		}
+
+		if req.Email != nil {
+			email := utils.SanitizeInput(strings.TrimSpace(*req.Email))
+			if !utils.ValidateEmail(email) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid email address",
+				})
+			}
+
+			var existingUser models.User
+			if err := h.db.DB.Where("LOWER(email) = LOWER(?) AND id != ?", email, userID).First(&existingUser).Error; err == nil {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error": "Email already in use",
+				})
+			}
+
+			// A link sent to the old address must not verify the new one
+			if !strings.EqualFold(email, user.Email) {
+				updates["email"] = email
+				updates["email_verified"] = false
+				updates["verification_token"] = ""
+				updates["verification_token_expiry"] = nil
+			}
+		}
+
+		if req.NewPassword != nil {
+			if !utils.ValidatePasswordStrength(*req.NewPassword) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Password must be 8+ chars with uppercase, digit, and special char",
+				})
+			}
+
+			passwordHash, err := utils.HashPassword(*req.NewPassword)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to process password",
+				})
+			}
+			updates["password_hash"] = passwordHash
+		}
+	}
+
 	if req.Username != nil {
 		if len(*req.Username) < 3 || len(*req.Username) > 32 {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -126,6 +290,15 @@ func (h *ProfileHandler) UpdateProfile(c *fiber.Ctx) error {
 		updates["profile_customization_json"] = *req.ProfileCustomizationJSON
 	}
 
+	if req.ProfileVisibility != nil {
+		if *req.ProfileVisibility != models.ProfileVisibilityPublic && *req.ProfileVisibility != models.ProfileVisibilityPrivate {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Profile visibility must be public or private",
+			})
+		}
+		updates["profile_visibility"] = *req.ProfileVisibility
+	}
+
 	if len(updates) == 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "No profile updates provided",
@@ -139,6 +312,13 @@ func (h *ProfileHandler) UpdateProfile(c *fiber.Ctx) error {
 		})
 	}
 
+	if _, changed := updates["password_hash"]; changed {
+		currentSessionID, _ := c.Locals("session_id").(string)
+		if err := h.refreshTokens.RevokeAllSessions(c.Context(), userID, currentSessionID); err != nil {
+			log.Printf("Error revoking sessions after password change: %v", err)
+		}
+	}
+
 	usernameResponse := ""
 	if req.Username != nil {
 		usernameResponse = *req.Username
@@ -152,12 +332,34 @@ func (h *ProfileHandler) UpdateProfile(c *fiber.Ctx) error {
 		customizationResponse = *req.ProfileCustomizationJSON
 	}
 
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"message":                    "Profile updated successfully",
 		"username":                   usernameResponse,
 		"avatar_decoration_preset":   decorationResponse,
 		"profile_customization_json": customizationResponse,
-	})
+	}
+	if email, changed := updates["email"]; changed {
+		response["email"] = email
+		response["email_verified"] = false
+
+		user.Email = email.(string)
+		if req.Username != nil {
+			user.Username = *req.Username
+		}
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		if err := sendVerificationEmail(ctx, h.authService, h.mailer, h.frontendURL, &user); err != nil {
+			response["warning"] = "The verification email could not be sent. Request a new one to verify your email."
+		}
+	}
+	if visibility, changed := updates["profile_visibility"]; changed {
+		response["profile_visibility"] = visibility
+	}
+	if _, changed := updates["password_hash"]; changed {
+		response["password_changed"] = true
+	}
+
+	return c.JSON(response)
 }
 
 func (h *ProfileHandler) SaveCustomization(c *fiber.Ctx) error {
@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"briworld/internal/config"
 	"briworld/internal/models"
 	"briworld/internal/services"
-	"briworld/internal/utils"
-	"strings"
+	"context"
+	"errors"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -13,21 +13,22 @@ import (
 )
 
 type RankingHandler struct {
-	db            *gorm.DB
-	ratingService *services.RatingService
-	seasonService *services.SeasonService
-	jwtSecret     string
+	db             *gorm.DB
+	ratingService  *services.RatingService
+	rankingService *services.RankingService
+	seasonService  *services.SeasonService
+	leaderboards   *services.LeaderboardService
+	decay          services.RankDecayPolicy
 }
 
-func NewRankingHandler(db *gorm.DB, jwtSecret string) *RankingHandler {
-	if jwtSecret == "" {
-		jwtSecret = config.Load().JWT.Secret
-	}
+func NewRankingHandler(db *gorm.DB, requireVerifiedEmail bool, leaderboards *services.LeaderboardService, decay services.RankDecayPolicy) *RankingHandler {
 	return &RankingHandler{
-		db:            db,
-		ratingService: services.NewRatingService(),
-		seasonService: services.NewSeasonService(db),
-		jwtSecret:     jwtSecret,
+		db:             db,
+		ratingService:  services.NewRatingService(),
+		rankingService: services.NewRankingService(db, requireVerifiedEmail),
+		seasonService:  services.NewSeasonService(db),
+		leaderboards:   leaderboards,
+		decay:          decay,
 	}
 }
 
@@ -36,30 +37,8 @@ type leaderboardEntry struct {
 	Position int64 `json:"position"`
 }
 
-func leaderboardScope(db *gorm.DB) *gorm.DB {
-	return db.Where("(total_games > 0 OR total_points > 0 OR rating > 1000)")
-}
-
-func (h *RankingHandler) resolveViewerID(c *fiber.Ctx) uuid.UUID {
-	authHeader := strings.TrimSpace(c.Get("Authorization"))
-	if authHeader == "" || !strings.HasPrefix(strings.ToLower(authHeader), "bearer ") {
-		return uuid.Nil
-	}
-
-	claims, err := utils.ValidateJWT(strings.TrimSpace(authHeader[7:]), h.jwtSecret)
-	if err != nil {
-		return uuid.Nil
-	}
-
-	viewerID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return uuid.Nil
-	}
-
-	return viewerID
-}
-
-// GetLeaderboard returns top players by rating
+// GetLeaderboard returns top players by rating. With ?active=true players
+// inactive long enough to decay are left out.
 func (h *RankingHandler) GetLeaderboard(c *fiber.Ctx) error {
 	limit := c.QueryInt("limit", 100)
 	if limit <= 0 {
@@ -69,8 +48,16 @@ func (h *RankingHandler) GetLeaderboard(c *fiber.Ctx) error {
 		limit = 200
 	}
 
+	scope := h.rankingService.LeaderboardScope
+	if c.QueryBool("active") {
+		now := time.Now()
+		scope = func(db *gorm.DB) *gorm.DB {
+			return h.decay.ActiveScope(h.rankingService.LeaderboardScope(db), now)
+		}
+	}
+
 	var users []models.User
-	err := leaderboardScope(h.db).
+	err := scope(h.db).
 		Select("id, username, avatar_url, rating, rank, rank_tier, total_games, total_wins, total_points, updated_at").
 		Order("rating DESC, total_points DESC, total_wins DESC, updated_at ASC, username ASC").
 		Limit(limit).
@@ -90,12 +77,11 @@ func (h *RankingHandler) GetLeaderboard(c *fiber.Ctx) error {
 
 	response := fiber.Map{"leaderboard": leaderboard}
 
-	viewerID := h.resolveViewerID(c)
-	if viewerID != uuid.Nil {
+	if viewerID, ok := c.Locals("user_id").(uuid.UUID); ok {
 		var viewer models.User
 		if err := h.db.First(&viewer, "id = ?", viewerID).Error; err == nil {
 			var position int64
-			leaderboardScope(h.db.Model(&models.User{})).
+			scope(h.db.Model(&models.User{})).
 				Where(
 					"(rating > ?) OR (rating = ? AND total_points > ?) OR (rating = ? AND total_points = ? AND total_wins > ?) OR (rating = ? AND total_points = ? AND total_wins = ? AND updated_at < ?) OR (rating = ? AND total_points = ? AND total_wins = ? AND updated_at = ? AND username < ?)",
 					viewer.Rating,
@@ -116,31 +102,52 @@ func (h *RankingHandler) GetLeaderboard(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
-// GetUserRank returns user's rank and position
+// GetModeLeaderboard returns the players who scored most in a game mode
+func (h *RankingHandler) GetModeLeaderboard(c *fiber.Ctx) error {
+	return h.boardLeaderboard(c, services.BoardMode, c.Params("mode"))
+}
+
+// GetRegionLeaderboard returns the most accurate players on a region's countries
+func (h *RankingHandler) GetRegionLeaderboard(c *fiber.Ctx) error {
+	return h.boardLeaderboard(c, services.BoardRegion, c.Params("region"))
+}
+
+func (h *RankingHandler) boardLeaderboard(c *fiber.Ctx, kind, key string) error {
+	viewerID, _ := c.Locals("user_id").(uuid.UUID)
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	board, err := h.leaderboards.Leaderboard(ctx, kind, key, c.Query("window", services.WindowAllTime), viewerID, c.QueryInt("limit", 50))
+	switch {
+	case errors.Is(err, services.ErrUnknownLeaderboard), errors.Is(err, services.ErrNoActiveSeason):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrUnknownWindow):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch leaderboard"})
+	}
+	return c.JSON(board)
+}
+
+// GetUserRank returns user's rank, position, active season record and decay status
 func (h *RankingHandler) GetUserRank(c *fiber.Ctx) error {
 	userID, ok := c.Locals("user_id").(uuid.UUID)
 	if !ok {
 		return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	var user models.User
-	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	status, err := h.rankingService.UserRank(ctx, userID, h.decay)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
 	}
-
-	// Calculate position
-	var position int64
-	leaderboardScope(h.db.Model(&models.User{})).Where("rating > ?", user.Rating).Count(&position)
-	position++ // Add 1 for current user's position
-
-	return c.JSON(fiber.Map{
-		"rating":                user.Rating,
-		"rank":                  user.Rank,
-		"rank_tier":             user.RankTier,
-		"position":              position,
-		"placement_matches":     user.PlacementMatches,
-		"is_placement_complete": user.IsPlacementComplete,
-	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch rank"})
+	}
+	return c.JSON(status)
 }
 
 // GetActiveSeason returns current season info
@@ -152,3 +159,35 @@ func (h *RankingHandler) GetActiveSeason(c *fiber.Ctx) error {
 
 	return c.JSON(season)
 }
+
+// GetSeasons lists every season, newest first
+func (h *RankingHandler) GetSeasons(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	seasons, err := h.seasonService.ListSeasons(ctx)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch seasons"})
+	}
+	return c.JSON(fiber.Map{"seasons": seasons})
+}
+
+// GetSeasonStandings returns a page of a past season's final standings
+func (h *RankingHandler) GetSeasonStandings(c *fiber.Ctx) error {
+	seasonID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid season ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	season, standings, total, err := h.seasonService.Standings(ctx, seasonID, c.QueryInt("limit", 50), c.QueryInt("offset", 0))
+	if errors.Is(err, services.ErrSeasonNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch standings"})
+	}
+	return c.JSON(fiber.Map{"season": season, "standings": standings, "total": total})
+}
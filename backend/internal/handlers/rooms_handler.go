@@ -2,19 +2,12 @@ package handlers
 
 import (
 	"briworld/internal/ws"
-	"crypto/rand"
 	"github.com/gofiber/fiber/v2"
 )
 
 // GenerateRoomCode creates a 6-character room code
 func GenerateRoomCode() string {
-	const chars = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
-	b := make([]byte, 6)
-	rand.Read(b)
-	for i := range b {
-		b[i] = chars[int(b[i])%len(chars)]
-	}
-	return string(b)
+	return ws.NewRoomCode()
 }
 
 // CreateRoom generates a new room code
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"briworld/internal/services"
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type SecurityHandler struct {
+	security *services.SecurityService
+}
+
+func NewSecurityHandler(security *services.SecurityService) *SecurityHandler {
+	return &SecurityHandler{security: security}
+}
+
+// ListEvents returns the recent sign-in attempts against the current user
+func (h *SecurityHandler) ListEvents(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	events, err := h.security.ListEvents(ctx, userID, c.QueryInt("limit", 50))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load security events"})
+	}
+
+	return c.JSON(fiber.Map{"events": events})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"briworld/internal/services"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type SessionHandler struct {
+	refreshTokens *services.RefreshTokenService
+}
+
+func NewSessionHandler(refreshTokens *services.RefreshTokenService) *SessionHandler {
+	return &SessionHandler{refreshTokens: refreshTokens}
+}
+
+type deviceSessionResponse struct {
+	ID         string     `json:"id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Current    bool       `json:"current"`
+}
+
+// ListSessions returns the devices the current user is signed in on
+func (h *SessionHandler) ListSessions(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	currentSessionID, _ := c.Locals("session_id").(string)
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	sessions, err := h.refreshTokens.ListSessions(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load sessions"})
+	}
+
+	response := make([]deviceSessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, deviceSessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	return c.JSON(fiber.Map{"sessions": response})
+}
+
+// RevokeSession logs the current user out of one device
+func (h *SessionHandler) RevokeSession(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.refreshTokens.RevokeSession(ctx, userID, c.Params("id")); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Session not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke session"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Session revoked"})
+}
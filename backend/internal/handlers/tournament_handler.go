@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"briworld/internal/models"
+	"briworld/internal/services"
+	"briworld/internal/ws"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type TournamentHandler struct {
+	tournaments *services.TournamentService
+	players     *services.PlayerService
+}
+
+func NewTournamentHandler(tournaments *services.TournamentService, players *services.PlayerService) *TournamentHandler {
+	return &TournamentHandler{tournaments: tournaments, players: players}
+}
+
+func tournamentErrorResponse(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidTournament),
+		errors.Is(err, services.ErrWrongTeamSize):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrNotTournamentOrganizer),
+		errors.Is(err, ws.ErrNotPartyLeader):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrTournamentNotFound),
+		errors.Is(err, services.ErrEntrantNotFound),
+		errors.Is(err, services.ErrTournamentMatchNotFound),
+		errors.Is(err, services.ErrPlayerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrRegistrationClosed),
+		errors.Is(err, services.ErrTournamentFull),
+		errors.Is(err, services.ErrAlreadyRegistered),
+		errors.Is(err, services.ErrCheckInClosed),
+		errors.Is(err, services.ErrTournamentNotStartable),
+		errors.Is(err, services.ErrNotEnoughEntrants),
+		errors.Is(err, services.ErrTournamentMatchNotLive),
+		errors.Is(err, services.ErrTournamentFinished):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
+
+// ListTournaments returns upcoming and running tournaments, or those with the
+// status in the query
+func (h *TournamentHandler) ListTournaments(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	tournaments, err := h.tournaments.List(ctx, c.Query("status"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load tournaments"})
+	}
+	return c.JSON(fiber.Map{"tournaments": tournaments})
+}
+
+// GetTournament returns a tournament with its entrants and bracket
+func (h *TournamentHandler) GetTournament(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid tournament ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	bracket, err := h.tournaments.Get(ctx, id)
+	if err != nil {
+		return tournamentErrorResponse(c, err, "Failed to load tournament")
+	}
+	return c.JSON(bracket)
+}
+
+// CreateTournament schedules a tournament organized by the current user
+func (h *TournamentHandler) CreateTournament(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var input services.TournamentInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	tournament, err := h.tournaments.Create(ctx, userID, input)
+	if err != nil {
+		return tournamentErrorResponse(c, err, "Failed to create tournament")
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"tournament": tournament})
+}
+
+// Register enters the current user. In team tournaments the user must lead a
+// party of the team size, which registers as the team.
+func (h *TournamentHandler) Register(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid tournament ID"})
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	captain, err := h.players.Card(ctx, userID)
+	if err != nil {
+		return tournamentErrorResponse(c, err, "Failed to register")
+	}
+
+	var team []services.PlayerCard
+	if party, inParty := ws.Parties.Get(userID); inParty {
+		if party.LeaderID != userID {
+			return tournamentErrorResponse(c, ws.ErrNotPartyLeader, "Failed to register")
+		}
+		for _, member := range party.Members {
+			team = append(team, member.PlayerCard)
+		}
+	}
+
+	entrant, err := h.tournaments.Register(ctx, id, *captain, team, req.Name)
+	if err != nil {
+		return tournamentErrorResponse(c, err, "Failed to register")
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"entrant": entrant})
+}
+
+// Withdraw removes the current user's team before the tournament starts
+func (h *TournamentHandler) Withdraw(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid tournament ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.tournaments.Withdraw(ctx, id, userID); err != nil {
+		return tournamentErrorResponse(c, err, "Failed to withdraw")
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// CheckIn confirms the current user's team will play
+func (h *TournamentHandler) CheckIn(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid tournament ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	entrant, err := h.tournaments.CheckIn(ctx, id, userID)
+	if err != nil {
+		return tournamentErrorResponse(c, err, "Failed to check in")
+	}
+	return c.JSON(fiber.Map{"entrant": entrant})
+}
+
+// StartTournament lets the organizer start once check-in has opened
+func (h *TournamentHandler) StartTournament(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid tournament ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.tournaments.Start(ctx, id, userID); err != nil {
+		return tournamentErrorResponse(c, err, "Failed to start tournament")
+	}
+	bracket, err := h.tournaments.Get(ctx, id)
+	if err != nil {
+		return tournamentErrorResponse(c, err, "Failed to load tournament")
+	}
+	return c.JSON(bracket)
+}
+
+// CancelTournament lets the organizer call off a tournament
+func (h *TournamentHandler) CancelTournament(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid tournament ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.tournaments.Cancel(ctx, id, userID); err != nil {
+		return tournamentErrorResponse(c, err, "Failed to cancel tournament")
+	}
+	return c.JSON(fiber.Map{"status": models.TournamentCancelled})
+}
+
+// ResolveMatch lets the organizer pick the winner of a live match, such as
+// when a team does not show up
+func (h *TournamentHandler) ResolveMatch(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid tournament ID"})
+	}
+	matchID, err := uuid.Parse(c.Params("matchId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid match ID"})
+	}
+
+	var req struct {
+		WinnerID string `json:"winner_id"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	winnerID, err := uuid.Parse(req.WinnerID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid winner ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.tournaments.Resolve(ctx, id, matchID, userID, winnerID); err != nil {
+		return tournamentErrorResponse(c, err, "Failed to resolve match")
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
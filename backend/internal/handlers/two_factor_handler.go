@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"briworld/internal/services"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type TwoFactorHandler struct {
+	twoFactor *services.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactor *services.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactor: twoFactor}
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// Status reports whether 2FA is on and how many recovery codes are left
+func (h *TwoFactorHandler) Status(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	enabled, err := h.twoFactor.IsEnabled(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	remaining, err := h.twoFactor.RemainingRecoveryCodes(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load two-factor status"})
+	}
+
+	return c.JSON(fiber.Map{
+		"enabled":                  enabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// Enroll starts 2FA setup and returns the secret for the authenticator app
+func (h *TwoFactorHandler) Enroll(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	enrollment, err := h.twoFactor.Enroll(ctx, userID)
+	if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+	if err != nil {
+		log.Printf("Two-factor enroll failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start two-factor setup"})
+	}
+
+	return c.JSON(enrollment)
+}
+
+// Confirm enables 2FA with a code from the authenticator and returns the
+// recovery codes
+func (h *TwoFactorHandler) Confirm(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req twoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	codes, err := h.twoFactor.Confirm(ctx, userID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+		case errors.Is(err, services.ErrTwoFactorNotEnrolled):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Start two-factor setup first"})
+		case errors.Is(err, services.ErrTwoFactorCodeInvalid):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid two-factor code"})
+		}
+		log.Printf("Two-factor confirm failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to enable two-factor authentication"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// Disable turns 2FA off after checking a TOTP or recovery code
+func (h *TwoFactorHandler) Disable(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req twoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.twoFactor.Disable(ctx, userID, req.Code); err != nil {
+		switch {
+		case errors.Is(err, services.ErrTwoFactorNotEnabled):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+		case errors.Is(err, services.ErrTwoFactorCodeInvalid):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid two-factor code"})
+		}
+		log.Printf("Two-factor disable failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to disable two-factor authentication"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Two-factor authentication disabled"})
+}
@@ -6,8 +6,15 @@ import (
 	"briworld/internal/handlers"
 	"briworld/internal/mailer"
 	"briworld/internal/middleware"
+	"briworld/internal/models"
+	"briworld/internal/redis"
 	"briworld/internal/services"
+	"briworld/internal/storage"
 	"briworld/internal/ws"
+	"context"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -16,9 +23,18 @@ import (
 )
 
 func SetupRoutes(app *fiber.App, gormDB *database.GormDB, cfg *config.Config, m *mailer.Mailer) {
+	services.UseSessionGuard(services.NewSessionGuard(gormDB.DB, newSessionStatusCache()))
 	authService := services.NewAuthServiceGorm(gormDB)
-	authHandler := handlers.NewAuthHandlerGorm(authService, cfg.JWT.Secret, cfg.JWT.Expiry, m)
+	refreshTokenService := services.NewRefreshTokenService(gormDB, cfg.JWT.RefreshTokenExpiry)
+	twoFactorService := services.NewTwoFactorService(gormDB)
+	securityService := services.NewSecurityService(gormDB)
+	authHandler := handlers.NewAuthHandlerGorm(authService, refreshTokenService, twoFactorService, newLoginGuard(cfg), securityService, cfg.JWT.Secret, cfg.JWT.Expiry, m, cfg.FrontendURL)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	securityHandler := handlers.NewSecurityHandler(securityService)
+	sessionHandler := handlers.NewSessionHandler(refreshTokenService)
+	oidcHandler := handlers.NewOIDCHandler(services.NewOIDCService(gormDB, cfg.OIDC, newOIDCStateStore()), authHandler, cfg.OIDC.FrontendURL)
 	passwordResetHandler := handlers.NewPasswordResetHandler()
+	guestHandler := handlers.NewGuestHandler(services.NewProgressService(gormDB), cfg.JWT.Secret)
 
 	// Root endpoint
 	app.Get("/", func(c *fiber.Ctx) error {
@@ -40,7 +56,7 @@ func SetupRoutes(app *fiber.App, gormDB *database.GormDB, cfg *config.Config, m
 	// Static files (game data only)
 	app.Static("/static", "./static")
 	app.Static("/Music", "./Music")
-	app.Static("/uploads", "./uploads")
+	app.Static(cfg.Storage.LocalBaseURL, cfg.Storage.LocalDir)
 
 	app.Get("/api/v2/rooms", handlers.GetPublicRooms)
 	app.Post("/api/v2/rooms", handlers.CreateRoom)
@@ -56,6 +72,7 @@ func SetupRoutes(app *fiber.App, gormDB *database.GormDB, cfg *config.Config, m
 	})
 
 	api.Get("/emoji/clues", handlers.GetEmojiCluesHandler)
+	api.Get("/flags/:token", handlers.NewFlagHandler(services.NewFlagImageService(), cfg.JWT.Secret).GetFlag)
 
 	auth := api.Group("/auth")
 	auth.Use(limiter.New(limiter.Config{
@@ -64,17 +81,58 @@ func SetupRoutes(app *fiber.App, gormDB *database.GormDB, cfg *config.Config, m
 	}))
 	auth.Post("/register", authHandler.Register)
 	auth.Post("/login", authHandler.Login)
+	auth.Post("/login/2fa", authHandler.LoginTwoFactor)
 	auth.Post("/refresh", authHandler.RefreshToken)
+	auth.Post("/logout", authHandler.Logout)
+	auth.Get("/verify", authHandler.VerifyEmail)
+	auth.Post("/verify/resend",
+		middleware.AuthMiddleware(cfg.JWT.Secret),
+		limiter.New(limiter.Config{
+			Max:        3,
+			Expiration: 15 * time.Minute,
+			KeyGenerator: func(c *fiber.Ctx) string {
+				return fmt.Sprint(c.Locals("user_id"))
+			},
+		}),
+		authHandler.ResendVerification,
+	)
+	auth.Post("/claim-guest", middleware.AuthMiddleware(cfg.JWT.Secret), guestHandler.ClaimGuest)
+	auth.Get("/oidc/providers", oidcHandler.ListProviders)
+	auth.Get("/oidc/:provider", oidcHandler.Begin)
+	auth.Get("/oidc/:provider/callback", oidcHandler.Callback)
 	auth.Post("/forgot-password", passwordResetHandler.RequestPasswordReset)
 	auth.Post("/reset-password", passwordResetHandler.ResetPassword)
 
 	// Profile routes (protected)
-	profileHandler := handlers.NewProfileHandler(gormDB)
-	avatarHandler := handlers.NewAvatarHandler(gormDB)
-	rankingHandler := handlers.NewRankingHandler(gormDB.DB, cfg.JWT.Secret)
+	profileHandler := handlers.NewProfileHandler(gormDB, twoFactorService, refreshTokenService, authService, m, cfg.FrontendURL)
+	assetStore, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to configure asset storage: %v", err)
+	}
+	log.Printf("✓ Profile assets stored with %s", assetStore.Name())
+	assetLibrary := services.NewAssetLibraryService(gormDB, cfg.Storage)
+	avatarHandler := handlers.NewAvatarHandler(gormDB, assetStore, assetLibrary)
+	accountService := services.NewAccountService(gormDB, avatarHandler, refreshTokenService, cfg.Auth.DeletionGraceDays)
+	accountHandler := handlers.NewAccountHandler(accountService, twoFactorService, authService, refreshTokenService)
+	accountService.StartDeletionJob(time.Hour)
+	services.NewSessionService(gormDB).StartCleanupJob(time.Hour)
+	assetLibrary.StartUnusedAssetJob(avatarHandler, 6*time.Hour)
+	leaderboardService := newLeaderboardService(gormDB, cfg)
+	services.UseLeaderboards(leaderboardService)
+	antiCheatService := services.NewAntiCheatService(gormDB.DB, services.AntiCheatPolicy{
+		MinResponseMs: cfg.Game.AntiCheatMinResponseMs,
+		ShadowExclude: cfg.Game.AntiCheatShadowExclude,
+	})
+	services.UseAntiCheat(antiCheatService)
+	decayPolicy := rankDecayPolicy(cfg)
+	rankingHandler := handlers.NewRankingHandler(gormDB.DB, cfg.Auth.RequireVerifiedEmail, leaderboardService, decayPolicy)
+	seasonService := services.NewSeasonService(gormDB.DB)
+	seasonService.StartScheduler(time.Minute)
+	services.NewRankDecayService(gormDB.DB, decayPolicy, m).StartScheduler(time.Hour)
 
 	profile := api.Group("/user")
 	profile.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+	profile.Delete("/", accountHandler.RequestDeletion)
 	profile.Get("/profile", profileHandler.GetProfile)
 	profile.Put("/profile", profileHandler.UpdateProfile)
 	profile.Put("/profile/customization", profileHandler.SaveCustomization)
@@ -86,21 +144,238 @@ func SetupRoutes(app *fiber.App, gormDB *database.GormDB, cfg *config.Config, m
 	profile.Delete("/avatar-decoration", avatarHandler.DeleteAvatarDecoration)
 	profile.Get("/profile-assets", avatarHandler.ListProfileAssets)
 	profile.Post("/profile-assets", avatarHandler.UploadProfileAsset)
+	profile.Patch("/profile-assets/:assetId", avatarHandler.RenameProfileAsset)
 	profile.Delete("/profile-assets/:assetId", avatarHandler.DeleteProfileAsset)
+	profile.Get("/storage", avatarHandler.StorageUsage)
+	profile.Get("/sessions", sessionHandler.ListSessions)
+	profile.Delete("/sessions/:id", sessionHandler.RevokeSession)
+	profile.Get("/identities", oidcHandler.ListIdentities)
+	profile.Post("/identities/:provider", oidcHandler.Link)
+	profile.Delete("/identities/:provider", oidcHandler.Unlink)
+	profile.Get("/2fa", twoFactorHandler.Status)
+	profile.Post("/2fa/enroll", twoFactorHandler.Enroll)
+	profile.Post("/2fa/confirm", twoFactorHandler.Confirm)
+	profile.Post("/2fa/disable", twoFactorHandler.Disable)
+	profile.Get("/security/events", securityHandler.ListEvents)
+	profile.Post("/export",
+		limiter.New(limiter.Config{
+			Max:        3,
+			Expiration: 1 * time.Hour,
+			KeyGenerator: func(c *fiber.Ctx) string {
+				return fmt.Sprint(c.Locals("user_id"))
+			},
+		}),
+		accountHandler.Export,
+	)
+	profile.Delete("/deletion", accountHandler.CancelDeletion)
 
 	// Meta system routes
-	api.Get("/daily-challenge", handlers.GetDailyChallenge)
-	api.Post("/daily-challenge/complete", middleware.AuthMiddleware(cfg.JWT.Secret), handlers.CompleteChallenge)
-	api.Get("/rank", middleware.AuthMiddleware(cfg.JWT.Secret), handlers.GetUserRank)
+	streakLocation, err := time.LoadLocation(cfg.Game.StreakTimezone)
+	if err != nil {
+		log.Printf("⚠️  Unknown DAILY_STREAK_TIMEZONE %q, counting streaks in UTC: %v", cfg.Game.StreakTimezone, err)
+		streakLocation = time.UTC
+	}
+	dailyChallengeService := services.NewDailyChallengeService(gormDB, services.NewMetaService(), ws.GlobalHub, streakLocation)
+	dailyChallengeHandler := handlers.NewDailyChallengeHandler(dailyChallengeService)
+	api.Get("/daily-challenge", middleware.OptionalAuthMiddleware(cfg.JWT.Secret), dailyChallengeHandler.GetDailyChallenge)
+	api.Post("/daily-challenge/start", middleware.AuthMiddleware(cfg.JWT.Secret), dailyChallengeHandler.StartDailyChallenge)
+	api.Get("/daily-challenge/leaderboard", middleware.OptionalAuthMiddleware(cfg.JWT.Secret), dailyChallengeHandler.GetDailyLeaderboard)
+	api.Get("/rank", middleware.AuthMiddleware(cfg.JWT.Secret), rankingHandler.GetUserRank)
 	api.Get("/mastery", middleware.AuthMiddleware(cfg.JWT.Secret), handlers.GetUserMastery)
 	api.Get("/achievements", middleware.AuthMiddleware(cfg.JWT.Secret), handlers.GetUserAchievements)
 
+	// Player profiles
+	playerService := services.NewPlayerService(gormDB)
+	playerHandler := handlers.NewPlayerHandler(playerService)
+	api.Get("/players",
+		limiter.New(limiter.Config{
+			Max:        30,
+			Expiration: 1 * time.Minute,
+		}),
+		playerHandler.SearchPlayers,
+	)
+	api.Get("/players/:username", middleware.OptionalAuthMiddleware(cfg.JWT.Secret), playerHandler.GetPlayer)
+
+	// Friends, presence and notifications
+	if redis.Available() {
+		ws.Notifications.Listen(context.Background(), redis.Client)
+	}
+	notificationService := services.NewNotificationService(gormDB, ws.Notifications)
+	services.UseNotifications(notificationService)
+	notificationService.StartDailyResetJob(services.NewMetaService())
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+
+	achievementEngine := services.NewAchievementEngine(gormDB, services.AchievementRules, ws.GlobalHub)
+	achievementEngine.Start()
+	services.UseAchievements(achievementEngine)
+
+	presence := newPresenceService()
+	friendService := services.NewFriendService(gormDB, presence)
+	friendHandler := handlers.NewFriendHandler(friendService, presence)
+	ws.Configure(cfg)
+	ws.SetSocial(friendService, presence, notificationService)
+
+	friends := api.Group("/friends")
+	friends.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+	friends.Get("/", friendHandler.ListFriends)
+	friends.Post("/requests",
+		limiter.New(limiter.Config{
+			Max:        20,
+			Expiration: 1 * time.Minute,
+			KeyGenerator: func(c *fiber.Ctx) string {
+				return fmt.Sprint(c.Locals("user_id"))
+			},
+		}),
+		friendHandler.SendRequest,
+	)
+	friends.Post("/requests/:id/accept", friendHandler.AcceptRequest)
+	friends.Delete("/requests/:id", friendHandler.DeclineRequest)
+	friends.Get("/blocks", friendHandler.ListBlocked)
+	friends.Post("/blocks", friendHandler.Block)
+	friends.Delete("/blocks/:userId", friendHandler.Unblock)
+	friends.Delete("/:userId", friendHandler.RemoveFriend)
+
+	api.Get("/notifications", middleware.AuthMiddleware(cfg.JWT.Secret), notificationHandler.ListNotifications)
+	api.Post("/notifications/read", middleware.AuthMiddleware(cfg.JWT.Secret), notificationHandler.MarkRead)
+
+	// Parties and matchmaking
+	partyHandler := handlers.NewPartyHandler(playerService, friendService, notificationService)
+	party := api.Group("/party")
+	party.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+	party.Get("/", partyHandler.GetParty)
+	party.Post("/", partyHandler.CreateParty)
+	party.Delete("/", partyHandler.LeaveParty)
+	party.Post("/invites",
+		limiter.New(limiter.Config{
+			Max:        20,
+			Expiration: 1 * time.Minute,
+			KeyGenerator: func(c *fiber.Ctx) string {
+				return fmt.Sprint(c.Locals("user_id"))
+			},
+		}),
+		partyHandler.InviteToParty,
+	)
+	party.Post("/:id/join", partyHandler.JoinParty)
+	party.Delete("/members/:userId", partyHandler.KickMember)
+	api.Post("/matchmaking", middleware.AuthMiddleware(cfg.JWT.Secret), partyHandler.FindMatch)
+
+	// Tournaments
+	tournamentService := services.NewTournamentService(gormDB, ws.GlobalHub, ws.Notifications)
+	tournamentService.StartScheduler(time.Minute)
+	tournamentHandler := handlers.NewTournamentHandler(tournamentService, playerService)
+	tournaments := api.Group("/tournaments")
+	tournaments.Get("/", tournamentHandler.ListTournaments)
+	tournaments.Get("/:id", tournamentHandler.GetTournament)
+	tournaments.Post("/", middleware.AuthMiddleware(cfg.JWT.Secret), tournamentHandler.CreateTournament)
+	tournaments.Post("/:id/register", middleware.AuthMiddleware(cfg.JWT.Secret), tournamentHandler.Register)
+	tournaments.Delete("/:id/register", middleware.AuthMiddleware(cfg.JWT.Secret), tournamentHandler.Withdraw)
+	tournaments.Post("/:id/check-in", middleware.AuthMiddleware(cfg.JWT.Secret), tournamentHandler.CheckIn)
+	tournaments.Post("/:id/start", middleware.AuthMiddleware(cfg.JWT.Secret), tournamentHandler.StartTournament)
+	tournaments.Post("/:id/cancel", middleware.AuthMiddleware(cfg.JWT.Secret), tournamentHandler.CancelTournament)
+	tournaments.Post("/:id/matches/:matchId/resolve", middleware.AuthMiddleware(cfg.JWT.Secret), tournamentHandler.ResolveMatch)
+
 	// Ranking routes
-	api.Get("/leaderboard", rankingHandler.GetLeaderboard)
+	api.Get("/leaderboard", middleware.OptionalAuthMiddleware(cfg.JWT.Secret), rankingHandler.GetLeaderboard)
+	api.Get("/leaderboards/modes/:mode", middleware.OptionalAuthMiddleware(cfg.JWT.Secret), rankingHandler.GetModeLeaderboard)
+	api.Get("/leaderboards/regions/:region", middleware.OptionalAuthMiddleware(cfg.JWT.Secret), rankingHandler.GetRegionLeaderboard)
 	api.Get("/user/rank", middleware.AuthMiddleware(cfg.JWT.Secret), rankingHandler.GetUserRank)
 	api.Get("/season", rankingHandler.GetActiveSeason)
+	api.Get("/seasons", rankingHandler.GetSeasons)
+	api.Get("/seasons/:id/standings", rankingHandler.GetSeasonStandings)
+
+	// Admin routes. Moderators run rooms, bans and the cheat review queue;
+	// only admins change roles, game content and seasons.
+	adminHandler := handlers.NewAdminHandler(services.NewAdminService(gormDB.DB, ws.GlobalHub, refreshTokenService, seasonService, antiCheatService))
+	admin := api.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(cfg.JWT.Secret), middleware.RequireRole(gormDB.DB, models.RoleModerator))
+	admin.Get("/rooms", adminHandler.ListRooms)
+	admin.Delete("/rooms/:code", adminHandler.CloseRoom)
+	admin.Get("/users/:id", adminHandler.GetUser)
+	admin.Post("/users/:id/ban", adminHandler.BanUser)
+	admin.Delete("/users/:id/ban", adminHandler.UnbanUser)
+	admin.Get("/cheat-flags", adminHandler.ListCheatFlags)
+	admin.Post("/cheat-flags/:id/review", adminHandler.ReviewCheatFlag)
+
+	adminOnly := middleware.RequireRole(gormDB.DB, models.RoleAdmin)
+	admin.Put("/users/:id/role", adminOnly, adminHandler.SetRole)
+	admin.Patch("/achievements/:code", adminOnly, adminHandler.UpdateAchievement)
+	admin.Post("/achievements/seed", adminOnly, adminHandler.SeedAchievements)
+	admin.Put("/daily-challenges/:date", adminOnly, adminHandler.SetDailyChallenge)
+	admin.Post("/seasons", adminOnly, adminHandler.StartSeason)
+	admin.Post("/seasons/end", adminOnly, adminHandler.EndSeason)
+	admin.Get("/audit-log", adminOnly, adminHandler.GetAuditLog)
 
 	// WebSocket routes
 	app.Use("/ws", ws.UpgradeWebSocket)
 	app.Get("/ws", websocket.New(ws.HandleWebSocket))
+	app.Get("/ws/notify", ws.AuthenticateNotify, websocket.New(ws.HandleNotifySocket))
+}
+
+// newLoginGuard shares lockout state through Redis when it is connected and
+// keeps it in memory otherwise.
+func newLoginGuard(cfg *config.Config) *services.LoginGuard {
+	var store services.LockoutStore = services.NewMemoryLockoutStore()
+	if redis.Available() {
+		store = redis.NewLockoutStore(redis.Client)
+	}
+	return services.NewLoginGuard(store, services.LockoutPolicy{
+		Threshold: cfg.Auth.LockoutThreshold,
+		BaseDelay: time.Duration(cfg.Auth.LockoutBaseSeconds) * time.Second,
+		MaxDelay:  time.Duration(cfg.Auth.LockoutMaxSeconds) * time.Second,
+	})
+}
+
+// newOIDCStateStore keeps pending social logins in Redis when it is connected
+// so any instance can take the provider's callback, and in memory otherwise.
+func newOIDCStateStore() services.OIDCStateStore {
+	if redis.Available() {
+		return redis.NewOIDCStateStore(redis.Client)
+	}
+	return services.NewMemoryOIDCStateStore()
+}
+
+// newSessionStatusCache shares session checks through Redis when it is
+// connected and keeps them in memory otherwise.
+func newSessionStatusCache() services.SessionStatusCache {
+	if redis.Available() {
+		return redis.NewSessionStatusCache(redis.Client)
+	}
+	return services.NewMemorySessionStatusCache()
+}
+
+// newPresenceService shares presence through Redis when it is connected and
+// keeps it in memory otherwise.
+func newPresenceService() *services.PresenceService {
+	var store services.PresenceStore = services.NewMemoryPresenceStore()
+	if redis.Available() {
+		store = redis.NewPresenceStore(redis.Client)
+	}
+	return services.NewPresenceService(store)
+}
+
+// newLeaderboardService caches leaderboards in Redis when it is connected and
+// reads them from the database otherwise.
+func newLeaderboardService(db *database.GormDB, cfg *config.Config) *services.LeaderboardService {
+	var store services.LeaderboardStore
+	if redis.Available() {
+		store = redis.NewLeaderboardStore(redis.Client)
+	}
+	return services.NewLeaderboardService(db.DB, store, cfg.Auth.RequireVerifiedEmail)
+}
+
+// rankDecayPolicy decays players from cfg.Game.RankDecayFromRank up, falling
+// back to Diamond for an unknown rank.
+func rankDecayPolicy(cfg *config.Config) services.RankDecayPolicy {
+	rank := strings.ToUpper(cfg.Game.RankDecayFromRank)
+	floor, ok := models.RankThresholds[rank]
+	if !ok {
+		log.Printf("⚠️  Unknown RANK_DECAY_FROM_RANK %q, decaying from %s", cfg.Game.RankDecayFromRank, models.RankDiamond)
+		floor = models.RankThresholds[models.RankDiamond]
+	}
+	return services.RankDecayPolicy{
+		FloorRating:  floor,
+		InactiveDays: cfg.Game.RankDecayInactiveDays,
+		WarningDays:  cfg.Game.RankDecayWarningDays,
+		PointsPerDay: cfg.Game.RankDecayPointsPerDay,
+	}
 }
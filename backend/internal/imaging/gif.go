@@ -0,0 +1,159 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+)
+
+// scanGIF walks the blocks of a GIF without decoding it and returns how many
+// frames it has and the pixels of all of them together. It stops at the first
+// block it cannot read and counts what came before; decoding rejects the rest.
+func scanGIF(data []byte) (frames, area int) {
+	const (
+		headerLen     = 6
+		screenLen     = 7
+		descriptorLen = 9
+	)
+	colorTableLen := func(packed byte) int {
+		if packed&0x80 == 0 {
+			return 0
+		}
+		return 3 << ((packed & 0x07) + 1)
+	}
+	// skipSubBlocks returns the offset after the data sub-blocks at i.
+	skipSubBlocks := func(i int) int {
+		for i < len(data) {
+			size := int(data[i])
+			i++
+			if size == 0 {
+				return i
+			}
+			i += size
+		}
+		return len(data)
+	}
+
+	if len(data) < headerLen+screenLen {
+		return 0, 0
+	}
+	i := headerLen + screenLen + colorTableLen(data[headerLen+4])
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension: label, then sub-blocks
+			i = skipSubBlocks(i + 2)
+		case 0x2C: // image descriptor
+			if i+1+descriptorLen > len(data) {
+				return frames, area
+			}
+			descriptor := data[i+1 : i+1+descriptorLen]
+			width := int(binary.LittleEndian.Uint16(descriptor[4:6]))
+			height := int(binary.LittleEndian.Uint16(descriptor[6:8]))
+			frames++
+			area += width * height
+			// Local color table and LZW minimum code size, then the image data
+			i = skipSubBlocks(i + 1 + descriptorLen + colorTableLen(descriptor[8]) + 1)
+		default: // trailer or something unreadable
+			return frames, area
+		}
+	}
+	return frames, area
+}
+
+// processAnimation renders every size of an animated GIF. Frames are
+// composited onto a full canvas first so disposal and partial frames come out
+// right, and each output frame is a complete picture. Frames past maxGIFWork
+// of compositing are dropped.
+func processAnimation(animation *gif.GIF, sizes []Size) (*Result, error) {
+	bounds := image.Rect(0, 0, animation.Config.Width, animation.Config.Height)
+	if bounds.Empty() {
+		for _, frame := range animation.Image {
+			bounds = bounds.Union(frame.Bounds())
+		}
+	}
+
+	outputs := make([]*gif.GIF, len(sizes))
+	for i, size := range sizes {
+		outputs[i] = &gif.GIF{
+			LoopCount: animation.LoopCount,
+			Config:    image.Config{Width: size.Width, Height: size.Height},
+		}
+	}
+
+	canvas := image.NewRGBA(bounds)
+	work := 0
+	frames := 0
+	for i, frame := range animation.Image {
+		work += bounds.Dx() * bounds.Dy()
+		if frames > 0 && work > maxGIFWork {
+			break
+		}
+
+		disposal := byte(gif.DisposalNone)
+		if i < len(animation.Disposal) {
+			disposal = animation.Disposal[i]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		delay := 0
+		if i < len(animation.Delay) {
+			delay = animation.Delay[i]
+		}
+		for j, size := range sizes {
+			scaled := resize(canvas, size.Width, size.Height)
+			paletted := image.NewPaletted(scaled.Bounds(), framePalette(frame.Palette))
+			draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), scaled, image.Point{})
+			outputs[j].Image = append(outputs[j].Image, paletted)
+			outputs[j].Delay = append(outputs[j].Delay, delay)
+			outputs[j].Disposal = append(outputs[j].Disposal, gif.DisposalBackground)
+		}
+		frames++
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	result := &Result{Format: "gif", Animated: true, Frames: frames}
+	for i, size := range sizes {
+		var buf bytes.Buffer
+		if err := gif.EncodeAll(&buf, outputs[i]); err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, Variant{
+			Size:        size,
+			Data:        buf.Bytes(),
+			ContentType: "image/gif",
+			Ext:         ".gif",
+		})
+	}
+	return result, nil
+}
+
+// framePalette returns the palette of a frame with a transparent entry, so
+// transparent areas of the canvas stay transparent after quantizing.
+func framePalette(p color.Palette) color.Palette {
+	for _, c := range p {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			return p
+		}
+	}
+	out := make(color.Palette, 0, len(p)+1)
+	if len(p) >= 256 {
+		p = p[:255]
+	}
+	out = append(out, p...)
+	return append(out, color.RGBA{})
+}
@@ -0,0 +1,166 @@
+// Package imaging validates uploaded avatars and banners and renders them at
+// the sizes the frontend displays. Re-encoding drops EXIF, GPS and any other
+// metadata the original file carried.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("only JPG, PNG, WebP and GIF images can be processed")
+	ErrTypeMismatch      = errors.New("file content does not match its type")
+	ErrTooLarge          = errors.New("image dimensions are too large")
+	ErrTooManyFrames     = errors.New("animated GIFs can have at most 100 frames")
+)
+
+const (
+	// MaxSourceSide and MaxSourcePixels bound the images decoded into memory.
+	MaxSourceSide   = 8000
+	MaxSourcePixels = 40_000_000
+	// MaxGIFFrames is how many frames an animated upload may have.
+	MaxGIFFrames = 100
+	// maxGIFWork caps the pixels of all frames of an animation together,
+	// both as decoded and as composited; frames past it when compositing
+	// are dropped.
+	maxGIFWork = 200_000_000
+)
+
+// Size is a rendition of an upload. Images are center-cropped to its aspect
+// ratio and scaled to fit exactly.
+type Size struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+var (
+	AvatarSizes = []Size{{Name: "256", Width: 256, Height: 256}, {Name: "64", Width: 64, Height: 64}}
+	BannerSizes = []Size{{Name: "1500x500", Width: 1500, Height: 500}}
+)
+
+// Variant is an encoded rendition.
+type Variant struct {
+	Size
+	Data        []byte
+	ContentType string
+	Ext         string
+}
+
+// Result holds the renditions of one upload, in the order of the requested
+// sizes.
+type Result struct {
+	// Format is the detected source format: "jpeg", "png", "webp" or "gif".
+	Format   string
+	Animated bool
+	Frames   int
+	Variants []Variant
+}
+
+// ClaimedFormat maps a file name, or failing that a Content-Type, to the
+// format the uploader says the file is in. It returns "" for formats that
+// cannot be processed.
+func ClaimedFormat(filename, contentType string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jpg", ".jpeg":
+		return "jpeg"
+	case ".png":
+		return "png"
+	case ".webp":
+		return "webp"
+	case ".gif":
+		return "gif"
+	case "":
+	default:
+		return ""
+	}
+
+	switch strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0])) {
+	case "image/jpeg", "image/jpg":
+		return "jpeg"
+	case "image/png":
+		return "png"
+	case "image/webp":
+		return "webp"
+	case "image/gif":
+		return "gif"
+	}
+	return ""
+}
+
+// Process decodes data, checks it really is in the claimed format and renders
+// every size. Animated GIFs stay animated GIFs; everything else becomes PNG.
+func Process(data []byte, claimed string, sizes []Size) (*Result, error) {
+	if claimed == "" {
+		return nil, ErrUnsupportedFormat
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || format != claimed {
+		return nil, ErrTypeMismatch
+	}
+	if config.Width <= 0 || config.Height <= 0 ||
+		config.Width > MaxSourceSide || config.Height > MaxSourceSide ||
+		config.Width*config.Height > MaxSourcePixels {
+		return nil, ErrTooLarge
+	}
+
+	if format == "gif" {
+		// Every frame is held in memory once decoded, so check them first
+		frames, area := scanGIF(data)
+		if frames > MaxGIFFrames {
+			return nil, ErrTooManyFrames
+		}
+		if area > maxGIFWork {
+			return nil, ErrTooLarge
+		}
+		animation, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrTypeMismatch
+		}
+		if len(animation.Image) > 1 {
+			return processAnimation(animation, sizes)
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrTypeMismatch
+	}
+	source := toRGBA(img)
+	if format == "jpeg" {
+		source = applyOrientation(source, jpegOrientation(data))
+	}
+
+	result := &Result{Format: format, Frames: 1}
+	for _, size := range sizes {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, resize(source, size.Width, size.Height)); err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, Variant{
+			Size:        size,
+			Data:        buf.Bytes(),
+			ContentType: "image/png",
+			Ext:         ".png",
+		})
+	}
+	return result, nil
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(out, out.Bounds(), img, bounds.Min, draw.Src)
+	return out
+}
@@ -0,0 +1,214 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withExif inserts an APP1 segment carrying orientation and a GPS marker
+// right after the SOI marker of a JPEG.
+func withExif(jpegData []byte, orientation uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, []byte("GPS 51.5007N 0.1246W")...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpegData[:2]...)
+	out = append(out, segment...)
+	return append(out, jpegData[2:]...)
+}
+
+func TestProcessRejectsMismatchedContent(t *testing.T) {
+	data := encodePNG(t, image.NewRGBA(image.Rect(0, 0, 10, 10)))
+
+	if _, err := Process(data, ClaimedFormat("avatar.jpg", "image/jpeg"), AvatarSizes); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("PNG claimed as JPEG: err = %v", err)
+	}
+	if _, err := Process([]byte("<svg/>"), ClaimedFormat("avatar.png", ""), AvatarSizes); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("text claimed as PNG: err = %v", err)
+	}
+	if _, err := Process(data, ClaimedFormat("avatar.webp", "image/webp"), AvatarSizes); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("PNG claimed as WebP: err = %v", err)
+	}
+	if _, err := Process(data, ClaimedFormat("avatar.avif", "image/avif"), AvatarSizes); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("AVIF: err = %v", err)
+	}
+}
+
+// tinyWebP is a 1x1 lossless WebP; the standard library has no encoder.
+const tinyWebP = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func TestProcessReencodesWebPAsPNG(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(tinyWebP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Process(data, ClaimedFormat("avatar.webp", "image/webp"), AvatarSizes)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if result.Format != "webp" || len(result.Variants) != len(AvatarSizes) {
+		t.Fatalf("result = %+v", result)
+	}
+	for _, variant := range result.Variants {
+		if variant.ContentType != "image/png" {
+			t.Errorf("%s: content type = %s, want image/png", variant.Name, variant.ContentType)
+		}
+		if _, format, err := image.DecodeConfig(bytes.NewReader(variant.Data)); err != nil || format != "png" {
+			t.Errorf("%s: decoded as %q, %v", variant.Name, format, err)
+		}
+	}
+}
+
+func TestProcessCropsAndResizes(t *testing.T) {
+	// A 300x100 image whose middle third is red: a centered square crop
+	// keeps only red.
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.RGBA{0, 0, 255, 255}
+			if x >= 100 && x < 200 {
+				c = color.RGBA{255, 0, 0, 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	result, err := Process(encodePNG(t, src), "png", AvatarSizes)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if len(result.Variants) != 2 {
+		t.Fatalf("variants = %d", len(result.Variants))
+	}
+	for _, variant := range result.Variants {
+		img, err := png.Decode(bytes.NewReader(variant.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds().Dx() != variant.Width || img.Bounds().Dy() != variant.Height {
+			t.Fatalf("%s: size = %v", variant.Name, img.Bounds())
+		}
+		if r, g, b, _ := img.At(0, 0).RGBA(); r>>8 != 255 || g != 0 || b != 0 {
+			t.Fatalf("%s: corner = %v, want red", variant.Name, img.At(0, 0))
+		}
+	}
+}
+
+func TestProcessAppliesAndStripsExif(t *testing.T) {
+	// Left half red, right half blue. Rotating clockwise puts red on top.
+	src := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.RGBA{0, 0, 255, 255}
+			if x < 20 {
+				c = color.RGBA{255, 0, 0, 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	data := withExif(buf.Bytes(), 6)
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("jpegOrientation = %d", got)
+	}
+
+	result, err := Process(data, "jpeg", []Size{{Name: "tall", Width: 20, Height: 40}})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	out := result.Variants[0].Data
+	if bytes.Contains(out, []byte("Exif")) || bytes.Contains(out, []byte("GPS")) {
+		t.Fatal("metadata survived re-encoding")
+	}
+	if result.Variants[0].ContentType != "image/png" {
+		t.Fatalf("content type = %s", result.Variants[0].ContentType)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, b, _ := img.At(10, 5).RGBA(); r < b {
+		t.Fatalf("top = %v, want red", img.At(10, 5))
+	}
+	if r, _, b, _ := img.At(10, 35).RGBA(); b < r {
+		t.Fatalf("bottom = %v, want blue", img.At(10, 35))
+	}
+}
+
+func encodeAnimation(t *testing.T, frames int) []byte {
+	t.Helper()
+	palette := color.Palette{color.Black, color.White}
+	animation := &gif.GIF{Config: image.Config{Width: 512, Height: 512, ColorModel: palette}}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 512, 512), palette)
+		frame.SetColorIndex(i%512, 0, 1)
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 5)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessKeepsAnimation(t *testing.T) {
+	result, err := Process(encodeAnimation(t, MaxGIFFrames), ClaimedFormat("party.gif", ""), AvatarSizes)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if !result.Animated || result.Frames != MaxGIFFrames {
+		t.Fatalf("animated = %v, frames = %d", result.Animated, result.Frames)
+	}
+	for _, variant := range result.Variants {
+		decoded, err := gif.DecodeAll(bytes.NewReader(variant.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(decoded.Image) != MaxGIFFrames || decoded.Config.Width != variant.Width {
+			t.Fatalf("%s: %d frames, width %d", variant.Name, len(decoded.Image), decoded.Config.Width)
+		}
+	}
+}
+
+func TestProcessRejectsTooManyFrames(t *testing.T) {
+	data := encodeAnimation(t, MaxGIFFrames+1)
+
+	if frames, area := scanGIF(data); frames != MaxGIFFrames+1 || area != (MaxGIFFrames+1)*512*512 {
+		t.Fatalf("scanGIF = %d frames, %d pixels", frames, area)
+	}
+	if _, err := Process(data, ClaimedFormat("party.gif", ""), AvatarSizes); !errors.Is(err, ErrTooManyFrames) {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package imaging
+
+import "image"
+
+// cropRect returns the largest centered rectangle of bounds with the aspect
+// ratio width:height.
+func cropRect(bounds image.Rectangle, width, height int) image.Rectangle {
+	sw, sh := bounds.Dx(), bounds.Dy()
+	if sw*height > sh*width {
+		cw := max(sh*width/height, 1)
+		x0 := bounds.Min.X + (sw-cw)/2
+		return image.Rect(x0, bounds.Min.Y, x0+cw, bounds.Max.Y)
+	}
+	ch := max(sw*height/width, 1)
+	y0 := bounds.Min.Y + (sh-ch)/2
+	return image.Rect(bounds.Min.X, y0, bounds.Max.X, y0+ch)
+}
+
+// resize center-crops src to the aspect ratio of width x height and scales
+// it with a box filter, which averages every source pixel when shrinking.
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	crop := cropRect(src.Bounds(), width, height)
+	cw, ch := crop.Dx(), crop.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for dy := 0; dy < height; dy++ {
+		sy0 := crop.Min.Y + dy*ch/height
+		sy1 := max(crop.Min.Y+(dy+1)*ch/height, sy0+1)
+		for dx := 0; dx < width; dx++ {
+			sx0 := crop.Min.X + dx*cw/width
+			sx1 := max(crop.Min.X+(dx+1)*cw/width, sx0+1)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				row := src.Pix[src.PixOffset(sx0, sy):src.PixOffset(sx1, sy)]
+				for i := 0; i < len(row); i += 4 {
+					r += uint64(row[i])
+					g += uint64(row[i+1])
+					b += uint64(row[i+2])
+					a += uint64(row[i+3])
+					n++
+				}
+			}
+
+			offset := dst.PixOffset(dx, dy)
+			dst.Pix[offset] = uint8((r + n/2) / n)
+			dst.Pix[offset+1] = uint8((g + n/2) / n)
+			dst.Pix[offset+2] = uint8((b + n/2) / n)
+			dst.Pix[offset+3] = uint8((a + n/2) / n)
+		}
+	}
+	return dst
+}
+
+// applyOrientation turns src upright according to an EXIF orientation value,
+// since the tag itself does not survive re-encoding.
+func applyOrientation(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var tx, ty int
+			switch orientation {
+			case 2: // mirrored
+				tx, ty = w-1-x, y
+			case 3: // rotated 180°
+				tx, ty = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				tx, ty = x, h-1-y
+			case 5: // transposed
+				tx, ty = y, x
+			case 6: // rotated 90° clockwise
+				tx, ty = h-1-y, x
+			case 7: // transversed
+				tx, ty = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise
+				tx, ty = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(tx, ty):dst.PixOffset(tx, ty)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
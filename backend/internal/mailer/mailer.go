@@ -2,8 +2,10 @@ package mailer
 
 import (
 	"fmt"
+	"html"
 	"log"
 	"net/smtp"
+	"time"
 )
 
 type Mailer struct {
@@ -88,6 +90,39 @@ func (m *Mailer) SendWelcome(to, username string) error {
 	return m.sendHTML(to, subject, body)
 }
 
+func (m *Mailer) SendVerification(to, username, verifyURL string) error {
+	subject := "Verify your BriWorld email"
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; background-color: #f5f5f5; }
+        .container { max-width: 600px; margin: 0 auto; background-color: white; padding: 20px; border-radius: 8px; }
+        .header { color: #333; margin-bottom: 20px; }
+        .button { display: inline-block; background-color: #28a745; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; margin: 20px 0; }
+        .footer { color: #666; font-size: 12px; margin-top: 30px; border-top: 1px solid #eee; padding-top: 20px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h2 class="header">Confirm your email, %s</h2>
+        <p>Thanks for signing up! Please confirm this is your email address:</p>
+        <a href="%s" class="button">Verify Email</a>
+        <p>This link expires in 24 hours.</p>
+        <p>If you didn't create a BriWorld account, you can safely ignore this email.</p>
+        <div class="footer">
+            <p>BriWorld - Real-Time Multiplayer Geography Quiz Game</p>
+            <p>© 2026 BriWorld. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>
+	`, username, verifyURL)
+
+	return m.sendHTML(to, subject, body)
+}
+
 func (m *Mailer) SendGameInvite(to, inviterName, roomCode string) error {
 	subject := fmt.Sprintf("%s invited you to play BriWorld!", inviterName)
 	body := fmt.Sprintf(`
@@ -154,6 +189,79 @@ func (m *Mailer) SendLeaderboardNotification(to, username string, rank int, poin
 	return m.sendHTML(to, subject, body)
 }
 
+// SendLoginAlert tells a user their account was signed in to from an IP
+// address or device it has not been used from before.
+func (m *Mailer) SendLoginAlert(to, username, ipAddress, userAgent string, at time.Time) error {
+	subject := "New sign-in to your BriWorld account"
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; background-color: #f5f5f5; }
+        .container { max-width: 600px; margin: 0 auto; background-color: white; padding: 20px; border-radius: 8px; }
+        .header { color: #333; margin-bottom: 20px; }
+        .button { display: inline-block; background-color: #dc3545; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; margin: 20px 0; }
+        .footer { color: #666; font-size: 12px; margin-top: 30px; border-top: 1px solid #eee; padding-top: 20px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h2 class="header">New sign-in detected</h2>
+        <p>Hi %s,</p>
+        <p>Your account was just signed in to from a new location or device:</p>
+        <p><strong>When:</strong> %s</p>
+        <p><strong>IP address:</strong> %s</p>
+        <p><strong>Device:</strong> %s</p>
+        <p>If this was you, there is nothing to do. If not, reset your password and sign out your other devices.</p>
+        <a href="https://briworld.onrender.com/forgot-password" class="button">Secure My Account</a>
+        <div class="footer">
+            <p>BriWorld - Real-Time Multiplayer Geography Quiz Game</p>
+            <p>© 2026 BriWorld. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>
+	`, html.EscapeString(username), at.UTC().Format("2006-01-02 15:04 MST"), html.EscapeString(ipAddress), html.EscapeString(userAgent))
+
+	return m.sendHTML(to, subject, body)
+}
+
+// SendRankDecayWarning tells a user their rank starts to decay at startsAt
+// unless they play a ranked game before then.
+func (m *Mailer) SendRankDecayWarning(to, username, rank string, startsAt time.Time, pointsPerDay int) error {
+	subject := "Your BriWorld rank is about to decay"
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; background-color: #f5f5f5; }
+        .container { max-width: 600px; margin: 0 auto; background-color: white; padding: 20px; border-radius: 8px; }
+        .header { color: #333; margin-bottom: 20px; }
+        .button { display: inline-block; background-color: #4A90A4; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; margin: 20px 0; }
+        .footer { color: #666; font-size: 12px; margin-top: 30px; border-top: 1px solid #eee; padding-top: 20px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h2 class="header">Defend your %s rank</h2>
+        <p>Hi %s,</p>
+        <p>You have not played a ranked game in a while. From <strong>%s</strong>, your rating will drop by %d points a day until you play again.</p>
+        <p>One ranked game is all it takes to stop it.</p>
+        <a href="https://briworld.onrender.com/lobby" class="button">Play Now</a>
+        <div class="footer">
+            <p>BriWorld - Real-Time Multiplayer Geography Quiz Game</p>
+            <p>© 2026 BriWorld. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>
+	`, html.EscapeString(rank), html.EscapeString(username), startsAt.UTC().Format("2006-01-02 15:04 MST"), pointsPerDay)
+
+	return m.sendHTML(to, subject, body)
+}
+
 func (m *Mailer) sendHTML(to, subject, htmlBody string) error {
 	defer func() {
 		if r := recover(); r != nil {
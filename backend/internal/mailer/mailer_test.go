@@ -0,0 +1,113 @@
+package mailer
+
+import (
+	"briworld/internal/mailer/mailtest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendVerification(t *testing.T) {
+	server, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start SMTP stand-in: %v", err)
+	}
+	defer server.Close()
+
+	m := New(server.Host, server.Port, "noreply@briworld.test", "secret")
+	link := "https://briworld.test/api/v2/auth/verify?token=abc123"
+
+	if err := m.SendVerification("player@example.com", "player", link); err != nil {
+		t.Fatalf("SendVerification failed: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+
+	msg := messages[0]
+	if msg.From != "noreply@briworld.test" {
+		t.Errorf("Expected sender noreply@briworld.test, got %q", msg.From)
+	}
+	if len(msg.To) != 1 || msg.To[0] != "player@example.com" {
+		t.Errorf("Expected recipient player@example.com, got %v", msg.To)
+	}
+	if msg.Subject() != "Verify your BriWorld email" {
+		t.Errorf("Unexpected subject %q", msg.Subject())
+	}
+	if !strings.Contains(msg.Data, link) {
+		t.Error("Expected message body to contain the verification link")
+	}
+}
+
+func TestSendPlainTextCapturesEveryMessage(t *testing.T) {
+	server, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start SMTP stand-in: %v", err)
+	}
+	defer server.Close()
+
+	m := New(server.Host, server.Port, "noreply@briworld.test", "secret")
+	for _, to := range []string{"a@example.com", "b@example.com"} {
+		if err := m.SendPlainText(to, "Hello", "body"); err != nil {
+			t.Fatalf("SendPlainText(%s) failed: %v", to, err)
+		}
+	}
+
+	if got := len(server.Messages()); got != 2 {
+		t.Errorf("Expected 2 messages, got %d", got)
+	}
+}
+
+func TestSendLoginAlertEscapesClientDetails(t *testing.T) {
+	server, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start SMTP stand-in: %v", err)
+	}
+	defer server.Close()
+
+	m := New(server.Host, server.Port, "noreply@briworld.test", "secret")
+	if err := m.SendLoginAlert("player@example.com", "player", "203.0.113.7", "<script>x</script>", time.Now()); err != nil {
+		t.Fatalf("SendLoginAlert failed: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	if messages[0].Subject() != "New sign-in to your BriWorld account" {
+		t.Errorf("Unexpected subject %q", messages[0].Subject())
+	}
+	if !strings.Contains(messages[0].Data, "203.0.113.7") {
+		t.Error("Expected message body to contain the IP address")
+	}
+	if strings.Contains(messages[0].Data, "<script>") {
+		t.Error("Expected user agent to be HTML escaped")
+	}
+}
+
+func TestSendRankDecayWarning(t *testing.T) {
+	server, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start SMTP stand-in: %v", err)
+	}
+	defer server.Close()
+
+	m := New(server.Host, server.Port, "noreply@briworld.test", "secret")
+	startsAt := time.Date(2026, 10, 21, 9, 30, 0, 0, time.UTC)
+	if err := m.SendRankDecayWarning("player@example.com", "player", "GRANDMASTER", startsAt, 25); err != nil {
+		t.Fatalf("SendRankDecayWarning failed: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	if messages[0].Subject() != "Your BriWorld rank is about to decay" {
+		t.Errorf("Unexpected subject %q", messages[0].Subject())
+	}
+	if !strings.Contains(messages[0].Data, "2026-10-21 09:30 UTC") {
+		t.Error("Expected message body to contain when decay starts")
+	}
+}
@@ -0,0 +1,158 @@
+// Package mailtest provides an in-process SMTP server that records messages
+// instead of delivering them, so tests can exercise mailer.Mailer end to end.
+package mailtest
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// Message is one mail transaction accepted by the server.
+type Message struct {
+	From string
+	To   []string
+	Data string
+}
+
+// Subject returns the Subject header of the message, if any.
+func (m Message) Subject() string {
+	parsed, err := mail.ReadMessage(strings.NewReader(m.Data))
+	if err != nil {
+		return ""
+	}
+	return parsed.Header.Get("Subject")
+}
+
+// Server is a minimal SMTP server listening on 127.0.0.1. It accepts any
+// AUTH PLAIN credentials and never advertises STARTTLS.
+type Server struct {
+	Host string
+	Port int
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewServer starts a server on a random local port.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	addr := listener.Addr().(*net.TCPAddr)
+	s := &Server{
+		Host:     addr.IP.String(),
+		Port:     addr.Port,
+		listener: listener,
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Messages returns a copy of every message received so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Message, len(s.messages))
+	copy(out, s.messages)
+	return out
+}
+
+// Close stops accepting connections and waits for open sessions to finish.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+
+	tp.PrintfLine("220 mailtest ESMTP ready")
+
+	var current Message
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			tp.PrintfLine("250-mailtest")
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "HELO":
+			tp.PrintfLine("250 mailtest")
+		case "AUTH":
+			tp.PrintfLine("235 2.7.0 Authentication successful")
+		case "MAIL":
+			current = Message{From: trimPath(arg)}
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			current.To = append(current.To, trimPath(arg))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(bufio.NewReader(tp.DotReader()))
+			if err != nil {
+				return
+			}
+			current.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, current)
+			s.mu.Unlock()
+			current = Message{}
+			tp.PrintfLine("250 OK")
+		case "RSET":
+			current = Message{}
+			tp.PrintfLine("250 OK")
+		case "NOOP":
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// trimPath turns "FROM:<a@b.c>" or "TO:<a@b.c>" into "a@b.c".
+func trimPath(arg string) string {
+	if _, rest, ok := strings.Cut(arg, ":"); ok {
+		arg = rest
+	}
+	arg = strings.TrimSpace(arg)
+	if end := strings.Index(arg, ">"); end >= 0 {
+		arg = arg[:end]
+	}
+	return strings.TrimPrefix(arg, "<")
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"briworld/internal/services"
 	"briworld/internal/utils"
 	"log"
 	"strings"
@@ -9,6 +10,8 @@ import (
 	"github.com/google/uuid"
 )
 
+// AuthMiddleware requires a valid access token whose device session is still
+// signed in.
 func AuthMiddleware(secret string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
@@ -43,9 +46,56 @@ func AuthMiddleware(secret string) fiber.Handler {
 			})
 		}
 
+		active, err := services.SessionActive(c.Context(), userID, claims.SessionID)
+		if err != nil {
+			log.Printf("Auth failed: Session check for %s - %v", userID, err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Failed to verify session",
+			})
+		}
+		if !active {
+			log.Printf("Auth failed: Session of %s was signed out", userID)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Session has been signed out",
+			})
+		}
+
+		c.Locals("user_id", userID)
+		c.Locals("username", claims.Username)
+		c.Locals("email", claims.Email)
+		c.Locals("session_id", claims.SessionID)
+
+		return c.Next()
+	}
+}
+
+// OptionalAuthMiddleware sets the same locals as AuthMiddleware when a valid
+// bearer token is present and lets the request through as a guest otherwise.
+func OptionalAuthMiddleware(secret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		parts := strings.Split(c.Get("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			return c.Next()
+		}
+
+		claims, err := utils.ValidateJWT(parts[1], secret)
+		if err != nil {
+			return c.Next()
+		}
+
+		userID, err := uuid.Parse(claims.UserID)
+		if err != nil {
+			return c.Next()
+		}
+
+		if active, err := services.SessionActive(c.Context(), userID, claims.SessionID); err != nil || !active {
+			return c.Next()
+		}
+
 		c.Locals("user_id", userID)
 		c.Locals("username", claims.Username)
 		c.Locals("email", claims.Email)
+		c.Locals("session_id", claims.SessionID)
 
 		return c.Next()
 	}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"briworld/internal/models"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RequireRole lets a request through only when the user AuthMiddleware
+// signed in has role or a more trusted one and is not banned. Roles are read
+// from the database on every request, so a demotion or ban takes effect at
+// once. The user's role is kept in the "role" local.
+func RequireRole(db *gorm.DB, role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(uuid.UUID)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Unauthorized",
+			})
+		}
+
+		var user models.User
+		if err := db.WithContext(c.Context()).
+			Select("id", "role", "banned_at", "banned_until").
+			First(&user, "id = ?", userID).Error; err != nil {
+			log.Printf("Role check failed for %s: %v", userID, err)
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Forbidden",
+			})
+		}
+
+		if !user.HasRole(role) || user.Banned(time.Now()) {
+			log.Printf("Role check failed for %s: has %q, needs %q", userID, user.Role, role)
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Forbidden",
+			})
+		}
+
+		c.Locals("role", user.Role)
+		return c.Next()
+	}
+}
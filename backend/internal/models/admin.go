@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Actions recorded in the admin audit log
+const (
+	AdminActionCloseRoom         = "close_room"
+	AdminActionBanUser           = "ban_user"
+	AdminActionUnbanUser         = "unban_user"
+	AdminActionSetRole           = "set_role"
+	AdminActionUpdateAchievement = "update_achievement"
+	AdminActionSeedAchievements  = "seed_achievements"
+	AdminActionUpdateChallenge   = "update_daily_challenge"
+	AdminActionStartSeason       = "start_season"
+	AdminActionEndSeason         = "end_season"
+	AdminActionReviewCheatFlag   = "review_cheat_flag"
+)
+
+// AdminAuditLog is one action a moderator or admin took through the admin
+// API. Entries are never updated or deleted.
+type AdminAuditLog struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ActorID    uuid.UUID `gorm:"type:uuid;not null;index" json:"actor_id"`
+	Action     string    `gorm:"size:40;not null;index" json:"action"`
+	TargetType string    `gorm:"size:30" json:"target_type"`
+	TargetID   string    `gorm:"size:64;index" json:"target_id"`
+	Detail     string    `gorm:"type:text" json:"detail,omitempty"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Signals the cheat detection pipeline flags a game for
+const (
+	// CheatSignalFastAnswers is correct answers given faster than a person
+	// can read the question.
+	CheatSignalFastAnswers = "fast_answers"
+	// CheatSignalRegularTiming is answers spaced too evenly to be typed.
+	CheatSignalRegularTiming = "regular_timing"
+	// CheatSignalAccuracyOutlier is accuracy far above what players of the
+	// same rank manage in the game mode.
+	CheatSignalAccuracyOutlier = "accuracy_outlier"
+)
+
+// Where a flag is in the review queue
+const (
+	CheatFlagOpen      = "open"
+	CheatFlagDismissed = "dismissed"
+	CheatFlagConfirmed = "confirmed"
+)
+
+// CheatFlag is a game the cheat detection pipeline found implausible,
+// waiting for a person to review it.
+type CheatFlag struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Signal     string     `gorm:"size:30;not null" json:"signal"`
+	Detail     string     `gorm:"size:255" json:"detail"`
+	RoomCode   string     `gorm:"size:32" json:"room_code"`
+	GameMode   string     `gorm:"size:20" json:"game_mode"`
+	Status     string     `gorm:"size:20;not null;default:'open';index" json:"status"`
+	ReviewedBy *uuid.UUID `gorm:"type:uuid" json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Friendship states. A declined request is deleted rather than kept.
+const (
+	FriendshipPending  = "pending"
+	FriendshipAccepted = "accepted"
+)
+
+// Friendship links two players. There is at most one row per pair, whoever
+// sent the request.
+type Friendship struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	RequesterID uuid.UUID  `gorm:"type:uuid;not null;index" json:"requester_id"`
+	AddresseeID uuid.UUID  `gorm:"type:uuid;not null;index" json:"addressee_id"`
+	Status      string     `gorm:"size:20;not null;default:pending" json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+}
+
+// UserBlock stops BlockedID from sending friend requests and invites to
+// BlockerID.
+type UserBlock struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	BlockerID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_blocks_pair" json:"blocker_id"`
+	BlockedID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_blocks_pair;index" json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
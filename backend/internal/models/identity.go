@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links a user to their account at an external OIDC provider.
+// A user has at most one identity per provider.
+type UserIdentity struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_identities_user_provider" json:"user_id"`
+	Provider  string    `gorm:"size:32;not null;uniqueIndex:idx_user_identities_user_provider;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_user_identities_provider_subject" json:"-"`
+	Email     string    `gorm:"size:255" json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LeaderboardScore is what one player did on one leaderboard in one UTC day.
+// Board is "mode:<GAME_MODE>" for the points scored in a game mode, or
+// "region:<Region>" for the answers given about a region's countries.
+type LeaderboardScore struct {
+	UserID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	Board    string    `gorm:"size:40;primaryKey;index:idx_leaderboard_scores_board_day,priority:1" json:"board"`
+	Day      time.Time `gorm:"type:date;primaryKey;index:idx_leaderboard_scores_board_day,priority:2" json:"day"`
+	Points   int       `gorm:"not null;default:0" json:"points"`
+	Correct  int       `gorm:"not null;default:0" json:"correct"`
+	Answered int       `gorm:"not null;default:0" json:"answered"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Login failure reasons stored on LoginEvent.
+const (
+	LoginFailureInvalidCredentials = "invalid_credentials"
+	LoginFailureInvalidTwoFactor   = "invalid_two_factor_code"
+	LoginFailureLocked             = "locked"
+)
+
+// LoginEvent is one sign-in attempt against an existing account, kept as an
+// audit trail the user can review.
+type LoginEvent struct {
+	ID                uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID            uuid.UUID `gorm:"type:uuid;not null;index:idx_login_events_user_created" json:"-"`
+	IPAddress         string    `gorm:"size:64" json:"ip_address"`
+	UserAgent         string    `gorm:"size:255" json:"user_agent"`
+	DeviceFingerprint string    `gorm:"size:32;index" json:"device_fingerprint"`
+	Success           bool      `gorm:"not null" json:"success"`
+	FailureReason     string    `gorm:"size:32" json:"failure_reason,omitempty"`
+	CreatedAt         time.Time `gorm:"index:idx_login_events_user_created" json:"created_at"`
+}
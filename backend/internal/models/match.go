@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MatchResult is one player's outcome of a finished game.
+type MatchResult struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	GuestSessionID string    `gorm:"size:255;index" json:"-"`
+	Username       string    `gorm:"size:100;not null" json:"username"`
+	RoomCode       string    `gorm:"size:32;index" json:"room_code"`
+	GameMode       string    `gorm:"size:20" json:"game_mode"`
+	RoomType       string    `gorm:"size:20" json:"room_type"`
+	Score          int       `gorm:"default:0" json:"score"`
+	Placement      int       `gorm:"default:1" json:"placement"`
+	PlayerCount    int       `gorm:"default:1" json:"player_count"`
+	Won            bool      `gorm:"default:false" json:"won"`
+	Rounds         int       `gorm:"default:0" json:"rounds"`
+	Correct        int       `gorm:"default:0" json:"correct"`
+	Misses         int       `gorm:"default:0" json:"misses"`
+	PlayedAt       time.Time `gorm:"index" json:"played_at"`
+}
+
+// GuestClaim records that a guest session's progress was moved onto an
+// account, so it can never be claimed again.
+type GuestClaim struct {
+	SessionID string    `gorm:"primaryKey;size:255" json:"-"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	ClaimedAt time.Time `json:"claimed_at"`
+}
@@ -5,52 +5,118 @@ import (
 	"github.com/google/uuid"
 )
 
+// DailyChallenge is the game everyone plays on Date. Countries holds the
+// comma-separated country codes asked, in order, and is never sent to clients.
 type DailyChallenge struct {
-	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	Date        time.Time `gorm:"uniqueIndex;not null" json:"date"`
-	GameMode    string    `gorm:"size:20;not null" json:"game_mode"`
-	Difficulty  string    `gorm:"size:20;not null" json:"difficulty"`
-	CountryCode string    `gorm:"size:3" json:"country_code,omitempty"`
-	Reward      int       `gorm:"default:100" json:"reward"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Date           time.Time `gorm:"uniqueIndex;not null" json:"date"`
+	GameMode       string    `gorm:"size:20;not null" json:"game_mode"`
+	Difficulty     string    `gorm:"size:20;not null" json:"difficulty"`
+	CountryCode    string    `gorm:"size:3" json:"country_code,omitempty"`
+	Rounds         int       `gorm:"default:10" json:"rounds"`
+	RoundTimeLimit int       `gorm:"default:15" json:"round_time_limit"`
+	Countries      string    `gorm:"type:text" json:"-"`
+	Reward         int       `gorm:"default:100" json:"reward"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
+// ChallengeCompletion, CountryMastery and MatchResult rows recorded for a
+// guest have a nil UserID and carry the guest's session ID until claimed.
+//
+// A completion is created when a user starts the daily challenge and gets its
+// score and CompletedAt when the game in RoomCode ends.
 type ChallengeCompletion struct {
-	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID      uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
-	ChallengeID uuid.UUID `gorm:"type:uuid;not null;index" json:"challenge_id"`
-	Score       int       `gorm:"default:0" json:"score"`
-	CompletedAt time.Time `json:"completed_at"`
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	GuestSessionID string     `gorm:"size:255;index" json:"-"`
+	ChallengeID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"challenge_id"`
+	RoomCode       string     `gorm:"size:8" json:"room_code,omitempty"`
+	Score          int        `gorm:"default:0" json:"score"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at"`
 }
 
+// DailyStreak is a user's run of consecutive days with a daily challenge
+// completed. LastDay is the last such day in the streak timezone. Freezes
+// are earned along the way and each covers one missed day.
+type DailyStreak struct {
+	UserID      uuid.UUID  `gorm:"type:uuid;primary_key" json:"-"`
+	Current     int        `gorm:"default:0" json:"current"`
+	Longest     int        `gorm:"default:0" json:"longest"`
+	LastDay     *time.Time `gorm:"type:date" json:"last_day,omitempty"`
+	Freezes     int        `gorm:"default:0" json:"freezes"`
+	FreezesUsed int        `gorm:"default:0" json:"freezes_used"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Season is a ranked season. Once EndDate passes, one instance holds the
+// rollover lease while it archives standings, pays rewards, resets ratings
+// and opens NextSeasonID; EndedAt is set when all of that is done.
 type Season struct {
-	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	Name      string     `gorm:"size:100;not null" json:"name"`
-	StartDate time.Time  `gorm:"not null" json:"start_date"`
-	EndDate   time.Time  `gorm:"not null" json:"end_date"`
-	IsActive  bool       `gorm:"default:false" json:"is_active"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID                 uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name               string     `gorm:"size:100;not null" json:"name"`
+	StartDate          time.Time  `gorm:"not null" json:"start_date"`
+	EndDate            time.Time  `gorm:"not null" json:"end_date"`
+	IsActive           bool       `gorm:"default:false" json:"is_active"`
+	EndedAt            *time.Time `json:"ended_at,omitempty"`
+	NextSeasonID       *uuid.UUID `gorm:"type:uuid" json:"next_season_id,omitempty"`
+	RolloverLeaseUntil *time.Time `json:"-"`
+	CreatedAt          time.Time  `json:"created_at"`
 }
 
+// SeasonResult is a player's final standing in an ended season. Reward is
+// paid into their points once, when RewardedAt is set.
+type SeasonResult struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SeasonID   uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_season_results_player,priority:1" json:"season_id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_season_results_player,priority:2;index" json:"user_id"`
+	Position   int        `gorm:"not null" json:"position"`
+	Rating     int        `json:"rating"`
+	Rank       string     `gorm:"size:20" json:"rank"`
+	RankTier   int        `json:"rank_tier"`
+	Games      int        `json:"games"`
+	Wins       int        `json:"wins"`
+	Reward     int        `json:"reward"`
+	RewardedAt *time.Time `json:"rewarded_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// SeasonRank is a player's record in one season's ranked games. Rating,
+// Rank and RankTier are where the player stood after their latest ranked
+// game of the season. There is one per player and season.
 type SeasonRank struct {
-	ID       uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID   uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
-	SeasonID uuid.UUID `gorm:"type:uuid;not null;index" json:"season_id"`
-	Rank     string    `gorm:"size:20;default:BRONZE" json:"rank"`
-	Points   int       `gorm:"default:0" json:"points"`
-	Wins     int       `gorm:"default:0" json:"wins"`
-	Losses   int       `gorm:"default:0" json:"losses"`
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	SeasonID  uuid.UUID `gorm:"type:uuid;not null;index" json:"season_id"`
+	Rating    int       `gorm:"default:1000" json:"rating"`
+	Rank      string    `gorm:"size:20;default:BRONZE" json:"rank"`
+	RankTier  int       `gorm:"default:3" json:"rank_tier"`
+	Points    int       `gorm:"default:0" json:"points"`
+	Wins      int       `gorm:"default:0" json:"wins"`
+	Losses    int       `gorm:"default:0" json:"losses"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// MasteredLevel is the mastery level at which a country counts as mastered.
+const MasteredLevel = 2
+
+// CountryMastery is how well a player knows one country. Ease, IntervalDays,
+// Repetitions and DueAt schedule its next review in practice games; a
+// country with no DueAt is due now.
 type CountryMastery struct {
-	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID      uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
-	CountryCode string    `gorm:"size:3;not null;index" json:"country_code"`
-	Level       int       `gorm:"default:1" json:"level"`
-	XP          int       `gorm:"default:0" json:"xp"`
-	Correct     int       `gorm:"default:0" json:"correct"`
-	Incorrect   int       `gorm:"default:0" json:"incorrect"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	GuestSessionID string     `gorm:"size:255;index" json:"-"`
+	CountryCode    string     `gorm:"size:3;not null;index" json:"country_code"`
+	Level          int        `gorm:"default:1" json:"level"`
+	XP             int        `gorm:"default:0" json:"xp"`
+	Correct        int        `gorm:"default:0" json:"correct"`
+	Incorrect      int        `gorm:"default:0" json:"incorrect"`
+	Ease           float64    `gorm:"default:2.5" json:"ease"`
+	IntervalDays   int        `gorm:"default:0" json:"interval_days"`
+	Repetitions    int        `gorm:"default:0" json:"repetitions"`
+	DueAt          *time.Time `json:"due_at,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 type Achievement struct {
@@ -71,6 +137,15 @@ type UserAchievement struct {
 	UnlockedAt    time.Time `json:"unlocked_at"`
 }
 
+// AchievementProgress counts the events towards an achievement that takes
+// more than one.
+type AchievementProgress struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	Code      string    `gorm:"size:50;primaryKey" json:"code"`
+	Progress  int       `gorm:"default:0" json:"progress"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 type CustomRoomRule struct {
 	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	RoomID          string    `gorm:"size:8;not null;index" json:"room_id"`
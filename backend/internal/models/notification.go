@@ -0,0 +1,33 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification is a server-pushed event kept in a user's inbox so it can be
+// read later when it was missed live.
+type Notification struct {
+	ID     uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID uuid.UUID `gorm:"type:uuid;not null;index:idx_notifications_user_created" json:"-"`
+	Type   string    `gorm:"size:40;not null" json:"type"`
+	// Data is the event payload as JSON.
+	Data      string     `gorm:"type:text" json:"-"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `gorm:"index:idx_notifications_user_created" json:"created_at"`
+}
+
+// MarshalJSON inlines Data as JSON rather than as a string.
+func (n Notification) MarshalJSON() ([]byte, error) {
+	type notification Notification
+	data := json.RawMessage(n.Data)
+	if !json.Valid(data) {
+		data = json.RawMessage("null")
+	}
+	return json.Marshal(struct {
+		notification
+		Data json.RawMessage `json:"data"`
+	}{notification(n), data})
+}
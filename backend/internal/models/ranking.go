@@ -14,6 +14,33 @@ const (
 	RankLegend       = "LEGEND"
 )
 
+// RankOrder lists the ranks from lowest to highest
+var RankOrder = []string{
+	RankBronze,
+	RankSilver,
+	RankGold,
+	RankPlatinum,
+	RankDiamond,
+	RankMaster,
+	RankGrandmaster,
+	RankContinental,
+	RankWorldClass,
+	RankLegend,
+}
+
+// RankLevel returns the position of rank in RankOrder, or -1 for an unknown rank
+func RankLevel(rank string) int {
+	for i, r := range RankOrder {
+		if r == rank {
+			return i
+		}
+	}
+	return -1
+}
+
+// StartingRating is the rating new players start from
+const StartingRating = 1000
+
 // Rank thresholds
 var RankThresholds = map[string]int{
 	RankBronze:       0,
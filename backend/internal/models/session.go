@@ -6,11 +6,29 @@ import (
 	"github.com/google/uuid"
 )
 
+// Session is a guest session or, for registered users, one signed-in device.
+// A device session owns the chain of refresh tokens rotated from its login.
 type Session struct {
-	ID        string    `gorm:"primaryKey;size:255" json:"id"`
-	Username  string    `gorm:"size:100;not null" json:"username"`
-	UserID    *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
-	IsGuest   bool      `gorm:"default:true" json:"is_guest"`
-	CreatedAt time.Time `json:"created_at"`
-	ExpiresAt time.Time `json:"expires_at"`
+	ID         string     `gorm:"primaryKey;size:255" json:"id"`
+	Username   string     `gorm:"size:100;not null" json:"username"`
+	UserID     *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	IsGuest    bool       `gorm:"default:true" json:"is_guest"`
+	UserAgent  string     `gorm:"size:255" json:"user_agent,omitempty"`
+	IPAddress  string     `gorm:"size:64" json:"ip_address,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
+
+// RefreshToken is one link in a device session's rotation chain. Only the
+// SHA-256 hash of the opaque token handed to the client is stored.
+type RefreshToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SessionID string     `gorm:"size:255;not null;index" json:"session_id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	TokenHash string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
 }
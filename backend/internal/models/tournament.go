@@ -0,0 +1,118 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tournament formats.
+const (
+	TournamentSingleElimination = "single_elimination"
+	TournamentDoubleElimination = "double_elimination"
+	TournamentSwiss             = "swiss"
+)
+
+// Tournament states. Check-in is not a state of its own: it is open between
+// CheckInOpensAt and StartsAt while the tournament is still registering.
+const (
+	TournamentRegistering = "registering"
+	TournamentInProgress  = "in_progress"
+	TournamentCompleted   = "completed"
+	TournamentCancelled   = "cancelled"
+)
+
+// Bracket sections a match belongs to.
+const (
+	BracketWinners    = "winners"
+	BracketLosers     = "losers"
+	BracketGrandFinal = "grand_final"
+	BracketSwiss      = "swiss"
+)
+
+// Tournament match states. A pending match is waiting for its entrants, a
+// live one has a room open.
+const (
+	TournamentMatchPending   = "pending"
+	TournamentMatchLive      = "live"
+	TournamentMatchCompleted = "completed"
+)
+
+// Tournament is an organized competition played in dedicated rooms with fixed
+// settings. Entrants are teams of TeamSize players; a team of one is a solo
+// player.
+type Tournament struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name           string     `gorm:"size:100;not null" json:"name"`
+	Format         string     `gorm:"size:30;not null" json:"format"`
+	GameMode       string     `gorm:"size:20;not null" json:"game_mode"`
+	Rounds         int        `gorm:"not null" json:"rounds"`
+	RoundTimeLimit int        `gorm:"not null" json:"round_time_limit"`
+	TeamSize       int        `gorm:"not null;default:1" json:"team_size"`
+	MaxEntrants    int        `gorm:"not null" json:"max_entrants"`
+	SwissRounds    int        `gorm:"default:0" json:"swiss_rounds,omitempty"`
+	Status         string     `gorm:"size:20;not null;default:registering;index" json:"status"`
+	CurrentRound   int        `gorm:"default:0" json:"current_round"`
+	CreatedBy      uuid.UUID  `gorm:"type:uuid;not null;index" json:"created_by"`
+	CheckInOpensAt time.Time  `json:"check_in_opens_at"`
+	StartsAt       time.Time  `gorm:"index" json:"starts_at"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	WinnerID       *uuid.UUID `gorm:"type:uuid" json:"winner_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TournamentEntrant is a team registered for a tournament. Its captain
+// registers, checks in and withdraws it.
+type TournamentEntrant struct {
+	ID           uuid.UUID                 `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TournamentID uuid.UUID                 `gorm:"type:uuid;not null;index" json:"tournament_id"`
+	Name         string                    `gorm:"size:100;not null" json:"name"`
+	CaptainID    uuid.UUID                 `gorm:"type:uuid;not null" json:"captain_id"`
+	Seed         int                       `gorm:"default:0" json:"seed"`
+	Rating       int                       `gorm:"default:0" json:"rating"`
+	CheckedInAt  *time.Time                `json:"checked_in_at,omitempty"`
+	Wins         int                       `gorm:"default:0" json:"wins"`
+	Losses       int                       `gorm:"default:0" json:"losses"`
+	Eliminated   bool                      `gorm:"default:false" json:"eliminated"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	Members      []TournamentEntrantMember `gorm:"foreignKey:EntrantID;constraint:OnDelete:CASCADE" json:"members"`
+}
+
+// TournamentEntrantMember is a player on an entrant's team. A player is on
+// at most one team per tournament.
+type TournamentEntrantMember struct {
+	EntrantID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	UserID       uuid.UUID `gorm:"type:uuid;primaryKey;uniqueIndex:idx_tournament_members_user,priority:2" json:"user_id"`
+	TournamentID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_tournament_members_user,priority:1" json:"-"`
+	Username     string    `gorm:"size:100;not null" json:"username"`
+}
+
+// TournamentMatch is one game of a bracket between entrants A and B. Winners
+// move on to NextMatchID and, in double elimination, losers drop to
+// LoserNextMatchID; the slot fields say which side they take there.
+type TournamentMatch struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TournamentID uuid.UUID  `gorm:"type:uuid;not null;index" json:"tournament_id"`
+	Bracket      string     `gorm:"size:20;not null" json:"bracket"`
+	Round        int        `gorm:"not null" json:"round"`
+	Position     int        `gorm:"not null" json:"position"`
+	EntrantAID   *uuid.UUID `gorm:"type:uuid" json:"entrant_a_id,omitempty"`
+	EntrantBID   *uuid.UUID `gorm:"type:uuid" json:"entrant_b_id,omitempty"`
+	// SlotADecided and SlotBDecided are set once it is known who, if anyone,
+	// plays on that side. A decided empty slot is a bye.
+	SlotADecided     bool       `gorm:"default:false" json:"-"`
+	SlotBDecided     bool       `gorm:"default:false" json:"-"`
+	ScoreA           int        `gorm:"default:0" json:"score_a"`
+	ScoreB           int        `gorm:"default:0" json:"score_b"`
+	WinnerID         *uuid.UUID `gorm:"type:uuid" json:"winner_id,omitempty"`
+	Status           string     `gorm:"size:20;not null;default:pending" json:"status"`
+	RoomCode         string     `gorm:"size:32;index" json:"room_code,omitempty"`
+	NextMatchID      *uuid.UUID `gorm:"type:uuid" json:"next_match_id,omitempty"`
+	NextSlot         int        `gorm:"default:0" json:"next_slot"`
+	LoserNextMatchID *uuid.UUID `gorm:"type:uuid" json:"loser_next_match_id,omitempty"`
+	LoserNextSlot    int        `gorm:"default:0" json:"loser_next_slot"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+}
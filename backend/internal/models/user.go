@@ -7,6 +7,32 @@ import (
 	"gorm.io/gorm"
 )
 
+// Profile visibility settings. Private profiles show only the player card.
+const (
+	ProfileVisibilityPublic  = "public"
+	ProfileVisibilityPrivate = "private"
+)
+
+// Roles, from least to most trusted. Each role may do everything the roles
+// before it may.
+const (
+	RolePlayer    = "player"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleLevels = map[string]int{
+	RolePlayer:    0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+// ValidRole reports whether role is one of the roles above.
+func ValidRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
 type User struct {
 	ID                       uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	Username                 string     `gorm:"uniqueIndex:idx_users_username;size:32;not null" json:"username"`
@@ -22,10 +48,19 @@ type User struct {
 	SessionID                string     `gorm:"size:255;index" json:"session_id,omitempty"`
 	LastActive               *time.Time `json:"last_active,omitempty"`
 	IsActive                 bool       `gorm:"default:true" json:"is_active"`
+	Role                     string     `gorm:"size:20;not null;default:player" json:"role,omitempty"`
 	EmailVerified            bool       `gorm:"default:false" json:"email_verified"`
 	VerificationToken        string     `gorm:"size:64" json:"-"`
+	VerificationTokenExpiry  *time.Time `json:"-"`
 	ResetToken               string     `gorm:"size:64" json:"-"`
 	ResetTokenExpiry         time.Time  `json:"-"`
+	TwoFactorEnabled         bool       `gorm:"default:false" json:"two_factor_enabled"`
+	TwoFactorSecret          string     `gorm:"size:64" json:"-"`
+	TwoFactorLastStep        int64      `gorm:"default:0" json:"-"`
+	DeletionScheduledAt      *time.Time `json:"deletion_scheduled_at,omitempty"`
+	AnonymizedAt             *time.Time `json:"-"`
+	StorageTier              string     `gorm:"size:20;default:free" json:"storage_tier"`
+	ProfileVisibility        string     `gorm:"size:20;default:public" json:"profile_visibility"`
 	TotalPoints              int        `gorm:"default:0" json:"total_points"`
 	TotalGames               int        `gorm:"default:0" json:"total_games"`
 	TotalWins                int        `gorm:"default:0" json:"total_wins"`
@@ -40,17 +75,46 @@ type User struct {
 	SeasonID            *uuid.UUID `gorm:"type:uuid" json:"season_id,omitempty"`
 	PlacementMatches    int        `gorm:"default:0" json:"placement_matches"`
 	IsPlacementComplete bool       `gorm:"default:false" json:"is_placement_complete"`
-	CreatedAt           time.Time  `json:"created_at"`
-	UpdatedAt           time.Time  `json:"updated_at"`
+	// LastRankedAt is when the player last finished a ranked game. Players
+	// above the decay threshold lose rating once it is too long ago.
+	LastRankedAt  *time.Time `gorm:"index" json:"last_ranked_at,omitempty"`
+	DecayWarnedAt *time.Time `json:"-"`
+	LastDecayAt   *time.Time `json:"-"`
+	// ShadowExcludedAt is set while the player is kept off the leaderboards
+	// for suspected cheating.
+	ShadowExcludedAt *time.Time `json:"-"`
+	// BannedAt is set while the player is banned: until BannedUntil, or for
+	// good when BannedUntil is nil.
+	BannedAt    *time.Time `json:"-"`
+	BannedUntil *time.Time `json:"-"`
+	BanReason   string     `gorm:"size:255" json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == uuid.Nil {
 		u.ID = uuid.New()
 	}
+	if u.Rating == 0 {
+		u.Rating = StartingRating
+	}
+	// New players start on the rank their rating gives them, like everyone else
+	u.Rank, u.RankTier = GetRankFromRating(u.Rating)
 	return nil
 }
 
+// HasRole reports whether u has role or a more trusted one.
+func (u *User) HasRole(role string) bool {
+	level, ok := roleLevels[role]
+	return ok && roleLevels[u.Role] >= level
+}
+
+// Banned reports whether u is banned at now.
+func (u *User) Banned(now time.Time) bool {
+	return u.BannedAt != nil && (u.BannedUntil == nil || now.Before(*u.BannedUntil))
+}
+
 type Room struct {
 	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	RoomCode       string     `gorm:"uniqueIndex;size:8;not null" json:"room_code"`
@@ -78,6 +142,13 @@ type GameSession struct {
 	EndedAt   *time.Time `json:"ended_at,omitempty"`
 }
 
+// Reasons a player's rating changed
+const (
+	RankChangeGame        = "game"
+	RankChangeDecay       = "decay"
+	RankChangeSeasonReset = "season_reset"
+)
+
 type RankHistory struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
@@ -86,6 +157,7 @@ type RankHistory struct {
 	NewRank   string    `gorm:"size:20" json:"new_rank"`
 	OldRating int       `json:"old_rating"`
 	NewRating int       `json:"new_rating"`
+	Reason    string    `gorm:"size:20;not null;default:'game'" json:"reason"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -93,6 +165,9 @@ type UserResponse struct {
 	ID                       uuid.UUID `json:"id"`
 	Username                 string    `json:"username"`
 	Email                    string    `json:"email"`
+	Role                     string    `json:"role"`
+	EmailVerified            bool      `json:"email_verified"`
+	TwoFactorEnabled         bool      `json:"two_factor_enabled"`
 	AvatarURL                string    `json:"avatar_url,omitempty"`
 	AvatarType               string    `json:"avatar_type,omitempty"`
 	BannerURL                string    `json:"banner_url,omitempty"`
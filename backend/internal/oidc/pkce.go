@@ -0,0 +1,31 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// NewVerifier returns a random PKCE code verifier (RFC 7636, 43 characters).
+func NewVerifier() (string, error) {
+	return randomString(32)
+}
+
+// NewNonce returns a random value for the state and nonce parameters.
+func NewNonce() (string, error) {
+	return randomString(24)
+}
+
+// S256Challenge derives the code challenge for verifier.
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
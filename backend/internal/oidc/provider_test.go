@@ -0,0 +1,233 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIssuer is a local OIDC provider that hands out codes directly instead
+// of showing a login page.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	m := &mockIssuer{key: key, codes: make(map[string]mockGrant)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Metadata{
+			Issuer:                m.server.URL,
+			AuthorizationEndpoint: m.server.URL + "/authorize",
+			TokenEndpoint:         m.server.URL + "/token",
+			JWKSURI:               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwkSet{Keys: []jwk{{
+			Kid: "test-key",
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		m.mu.Lock()
+		grant, ok := m.codes[r.PostForm.Get("code")]
+		delete(m.codes, r.PostForm.Get("code"))
+		m.mu.Unlock()
+
+		if !ok || S256Challenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		json.NewEncoder(w).Encode(Token{
+			AccessToken: "access",
+			TokenType:   "Bearer",
+			IDToken:     m.sign(t, grant.claims),
+			ExpiresIn:   3600,
+		})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// login simulates the user approving the authorization request at authURL.
+func (m *mockIssuer) login(t *testing.T, authURL string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("invalid auth URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("Expected S256 PKCE, got %q", query.Get("code_challenge_method"))
+	}
+
+	full := jwt.MapClaims{
+		"iss":   m.server.URL,
+		"aud":   query.Get("client_id"),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for k, v := range claims {
+		full[k] = v
+	}
+
+	code, _ := NewNonce()
+	m.mu.Lock()
+	m.codes[code] = mockGrant{challenge: query.Get("code_challenge"), claims: full}
+	m.mu.Unlock()
+	return code
+}
+
+func (m *mockIssuer) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		t.Fatalf("failed to sign id token: %v", err)
+	}
+	return signed
+}
+
+func newTestProvider(issuer *mockIssuer) *Provider {
+	return NewProvider(Config{
+		Name:         "mock",
+		DiscoveryURL: issuer.server.URL + "/.well-known/openid-configuration",
+		ClientID:     "briworld",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/api/v2/auth/oidc/mock/callback",
+	})
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newTestProvider(issuer)
+	ctx := context.Background()
+
+	verifier, _ := NewVerifier()
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", S256Challenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+
+	code := issuer.login(t, authURL, jwt.MapClaims{
+		"sub":            "user-42",
+		"email":          "player@example.com",
+		"email_verified": "true",
+		"name":           "Player One",
+	})
+
+	token, err := provider.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken failed: %v", err)
+	}
+
+	if claims.Subject != "user-42" || claims.Email != "player@example.com" || !claims.EmailVerified {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newTestProvider(issuer)
+	ctx := context.Background()
+
+	verifier, _ := NewVerifier()
+	authURL, _ := provider.AuthCodeURL(ctx, "state", "nonce", S256Challenge(verifier))
+	code := issuer.login(t, authURL, jwt.MapClaims{"sub": "user-42"})
+
+	other, _ := NewVerifier()
+	if _, err := provider.Exchange(ctx, code, other); err == nil {
+		t.Error("Expected exchange with the wrong verifier to fail")
+	}
+}
+
+func TestVerifyIDTokenRejections(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newTestProvider(issuer)
+	ctx := context.Background()
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   issuer.server.URL,
+			"aud":   "briworld",
+			"sub":   "user-42",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "nonce",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+		nonce  string
+	}{
+		{"wrong nonce", func(c jwt.MapClaims) {}, "other"},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "someone-else" }, "nonce"},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }, "nonce"},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, "nonce"},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }, "nonce"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.mutate(claims)
+			_, err := provider.VerifyIDToken(ctx, issuer.sign(t, claims), tt.nonce)
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("Expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestS256Challenge(t *testing.T) {
+	// Example from RFC 7636 appendix B.
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	expected := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	if got := S256Challenge(verifier); got != expected {
+		t.Errorf("S256Challenge() = %s, want %s", got, expected)
+	}
+}
@@ -12,6 +12,9 @@ import (
 
 var Client *redis.Client
 
+// available is set once InitRedis has reached the server.
+var available bool
+
 func InitRedis(addr, password string, db int, useTLS bool) error {
 	log.Printf("Initializing Redis with addr=%s, TLS=%v", addr, useTLS)
 
@@ -42,9 +45,15 @@ func InitRedis(addr, password string, db int, useTLS bool) error {
 	if err := Client.Ping(ctx).Err(); err != nil {
 		return fmt.Errorf("redis connection failed: %w", err)
 	}
+	available = true
 	return nil
 }
 
+// Available reports whether InitRedis connected successfully.
+func Available() bool {
+	return available
+}
+
 func Close() error {
 	if Client != nil {
 		return Client.Close()
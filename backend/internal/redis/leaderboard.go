@@ -0,0 +1,139 @@
+package redis
+
+import (
+	"briworld/internal/services"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// LeaderboardStore keeps leaderboards as sorted sets of user IDs. Next to a
+// board's sorted set, key:ready marks it filled and, on region boards,
+// key:counts holds each player's correct and answered totals.
+type LeaderboardStore struct {
+	client *redis.Client
+}
+
+func NewLeaderboardStore(client *redis.Client) *LeaderboardStore {
+	return &LeaderboardStore{client: client}
+}
+
+// addScript adds to a filled board and keeps its keys expiring together. A
+// region score matches services.leaderboardScore.
+var addScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ARGV[2] == 'mode' then
+	redis.call('ZINCRBY', KEYS[2], ARGV[3], ARGV[1])
+else
+	local correct = redis.call('HINCRBY', KEYS[3], ARGV[1] .. ':c', ARGV[4])
+	local answered = redis.call('HINCRBY', KEYS[3], ARGV[1] .. ':a', ARGV[5])
+	if ttl > 0 then
+		redis.call('PEXPIRE', KEYS[3], ttl)
+	end
+	if answered >= tonumber(ARGV[6]) then
+		redis.call('ZADD', KEYS[2], math.floor(correct * 10000 / answered) * 1000000000 + correct, ARGV[1])
+	end
+end
+if ttl > 0 and redis.call('EXISTS', KEYS[2]) == 1 then
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return 1
+`)
+
+func (s *LeaderboardStore) Ready(ctx context.Context, key string) (bool, error) {
+	n, err := s.client.Exists(ctx, key+":ready").Result()
+	return n == 1, err
+}
+
+func (s *LeaderboardStore) Fill(ctx context.Context, key, kind string, rows []services.LeaderboardRow, minAnswered int, ttl time.Duration) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key, key+":counts")
+		members := make([]redis.Z, 0, len(rows))
+		counts := make([]interface{}, 0, 4*len(rows))
+		for _, row := range rows {
+			member := row.UserID.String()
+			if kind == services.BoardRegion {
+				counts = append(counts, member+":c", row.Correct, member+":a", row.Answered)
+				if row.Answered < minAnswered {
+					continue
+				}
+			}
+			members = append(members, redis.Z{Score: row.Score, Member: member})
+		}
+		if len(members) > 0 {
+			pipe.ZAdd(ctx, key, members...)
+			pipe.Expire(ctx, key, ttl)
+		}
+		if len(counts) > 0 {
+			pipe.HSet(ctx, key+":counts", counts...)
+			pipe.Expire(ctx, key+":counts", ttl)
+		}
+		pipe.Set(ctx, key+":ready", 1, ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *LeaderboardStore) Add(ctx context.Context, key, kind string, row services.LeaderboardRow, minAnswered int) error {
+	return addScript.Run(ctx, s.client, []string{key + ":ready", key, key + ":counts"},
+		row.UserID.String(), kind, row.Points, row.Correct, row.Answered, minAnswered).Err()
+}
+
+func (s *LeaderboardStore) Range(ctx context.Context, key, kind string, start, stop int64) ([]services.LeaderboardRow, error) {
+	members, err := s.client.ZRevRangeWithScores(ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]services.LeaderboardRow, 0, len(members))
+	fields := make([]string, 0, 2*len(members))
+	for _, z := range members {
+		member, _ := z.Member.(string)
+		userID, err := uuid.Parse(member)
+		if err != nil {
+			return nil, err
+		}
+		row := services.LeaderboardRow{UserID: userID, Score: z.Score}
+		if kind == services.BoardMode {
+			row.Points = int(z.Score)
+		}
+		rows = append(rows, row)
+		fields = append(fields, member+":c", member+":a")
+	}
+	if kind != services.BoardRegion || len(rows) == 0 {
+		return rows, nil
+	}
+
+	counts, err := s.client.HMGet(ctx, key+":counts", fields...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].Correct = countValue(counts[2*i])
+		rows[i].Answered = countValue(counts[2*i+1])
+	}
+	return rows, nil
+}
+
+func countValue(value interface{}) int {
+	raw, _ := value.(string)
+	n, _ := strconv.Atoi(raw)
+	return n
+}
+
+func (s *LeaderboardStore) Position(ctx context.Context, key string, userID uuid.UUID) (int64, bool, error) {
+	position, err := s.client.ZRevRank(ctx, key, userID.String()).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return position, true, nil
+}
@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"briworld/internal/services"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OIDCStateStore keeps pending social logins in Redis so the provider can
+// redirect back to any server instance.
+type OIDCStateStore struct {
+	client *redis.Client
+}
+
+func NewOIDCStateStore(client *redis.Client) *OIDCStateStore {
+	return &OIDCStateStore{client: client}
+}
+
+func oidcStateKey(state string) string {
+	return fmt.Sprintf("oidc:state:%s", state)
+}
+
+func (s *OIDCStateStore) Save(ctx context.Context, state string, pending services.OIDCPendingLogin, ttl time.Duration) error {
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, oidcStateKey(state), data, ttl).Err()
+}
+
+// Take reads and deletes the state in one step so a callback can only be
+// completed once.
+func (s *OIDCStateStore) Take(ctx context.Context, state string) (services.OIDCPendingLogin, bool, error) {
+	data, err := s.client.GetDel(ctx, oidcStateKey(state)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return services.OIDCPendingLogin{}, false, nil
+	}
+	if err != nil {
+		return services.OIDCPendingLogin{}, false, err
+	}
+	var pending services.OIDCPendingLogin
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return services.OIDCPendingLogin{}, false, err
+	}
+	return pending, true, nil
+}
@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"briworld/internal/services"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// PresenceStore keeps player presence in Redis so friends connected to any
+// server instance see it.
+type PresenceStore struct {
+	client *redis.Client
+}
+
+func NewPresenceStore(client *redis.Client) *PresenceStore {
+	return &PresenceStore{client: client}
+}
+
+func presenceKey(userID uuid.UUID) string {
+	return fmt.Sprintf("presence:%s", userID)
+}
+
+func (s *PresenceStore) Set(ctx context.Context, userID uuid.UUID, presence services.Presence, ttl time.Duration) error {
+	data, err := json.Marshal(presence)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, presenceKey(userID), data, ttl).Err()
+}
+
+func (s *PresenceStore) Get(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]services.Presence, error) {
+	result := make(map[uuid.UUID]services.Presence, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = presenceKey(id)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var presence services.Presence
+		if err := json.Unmarshal([]byte(raw), &presence); err == nil {
+			result[userIDs[i]] = presence
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SessionStatusCache shares whether device sessions are still signed in
+// between server instances, so each checks the database once per session
+// rather than once per request.
+type SessionStatusCache struct {
+	client *redis.Client
+}
+
+func NewSessionStatusCache(client *redis.Client) *SessionStatusCache {
+	return &SessionStatusCache{client: client}
+}
+
+func sessionStatusKey(key string) string {
+	return fmt.Sprintf("session:status:%s", key)
+}
+
+func (c *SessionStatusCache) Get(ctx context.Context, key string) (bool, bool, error) {
+	value, err := c.client.Get(ctx, sessionStatusKey(key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	return value == "1", true, nil
+}
+
+func (c *SessionStatusCache) Set(ctx context.Context, key string, active bool, ttl time.Duration) error {
+	value := "0"
+	if active {
+		value = "1"
+	}
+	return c.client.Set(ctx, sessionStatusKey(key), value, ttl).Err()
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"archive/zip"
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrDeletionNotScheduled = errors.New("account deletion is not scheduled")
+	ErrAccountDeleted       = errors.New("account has been deleted")
+)
+
+// UploadPurger removes the files a user uploaded. AvatarHandler implements it
+// so deletion goes through the same asset store as uploads.
+type UploadPurger interface {
+	PurgeUserUploads(ctx context.Context, assets []models.ProfileAsset) error
+}
+
+// ExportedAchievement is an unlocked achievement with its definition.
+type ExportedAchievement struct {
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	UnlockedAt  time.Time `json:"unlocked_at"`
+}
+
+// AccountExport is everything stored about one user.
+type AccountExport struct {
+	ExportedAt           time.Time                    `json:"exported_at"`
+	User                 models.User                  `json:"user"`
+	Matches              []models.MatchResult         `json:"matches"`
+	CountryMastery       []models.CountryMastery      `json:"country_mastery"`
+	Achievements         []ExportedAchievement        `json:"achievements"`
+	ChallengeCompletions []models.ChallengeCompletion `json:"challenge_completions"`
+	DailyStreak          *models.DailyStreak          `json:"daily_streak"`
+	RankHistory          []models.RankHistory         `json:"rank_history"`
+	SeasonRanks          []models.SeasonRank          `json:"season_ranks"`
+	SeasonResults        []models.SeasonResult        `json:"season_results"`
+	ProfileAssets        []models.ProfileAsset        `json:"profile_assets"`
+	ProfileDecorations   []models.ProfileDecoration   `json:"profile_decorations"`
+	LinkedAccounts       []models.UserIdentity        `json:"linked_accounts"`
+	LoginEvents          []models.LoginEvent          `json:"login_events"`
+	Friendships          []models.Friendship          `json:"friendships"`
+	Blocks               []models.UserBlock           `json:"blocks"`
+	Notifications        []models.Notification        `json:"notifications"`
+}
+
+// WriteZip writes the export as a ZIP archive with one JSON file per section.
+func (e *AccountExport) WriteZip(w io.Writer) error {
+	sections := []struct {
+		name string
+		data interface{}
+	}{
+		{"user.json", e.User},
+		{"matches.json", e.Matches},
+		{"country_mastery.json", e.CountryMastery},
+		{"achievements.json", e.Achievements},
+		{"challenge_completions.json", e.ChallengeCompletions},
+		{"daily_streak.json", e.DailyStreak},
+		{"rank_history.json", e.RankHistory},
+		{"season_ranks.json", e.SeasonRanks},
+		{"season_results.json", e.SeasonResults},
+		{"profile_assets.json", e.ProfileAssets},
+		{"profile_decorations.json", e.ProfileDecorations},
+		{"linked_accounts.json", e.LinkedAccounts},
+		{"login_events.json", e.LoginEvents},
+		{"friendships.json", e.Friendships},
+		{"blocks.json", e.Blocks},
+		{"notifications.json", e.Notifications},
+	}
+
+	archive := zip.NewWriter(w)
+	for _, section := range sections {
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     section.name,
+			Method:   zip.Deflate,
+			Modified: e.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(section.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// AccountService exports a user's data and runs grace-period account deletion.
+type AccountService struct {
+	db            *database.GormDB
+	uploads       UploadPurger
+	refreshTokens *RefreshTokenService
+	grace         time.Duration
+}
+
+func NewAccountService(db *database.GormDB, uploads UploadPurger, refreshTokens *RefreshTokenService, graceDays int) *AccountService {
+	if graceDays < 0 {
+		graceDays = 0
+	}
+	return &AccountService{
+		db:            db,
+		uploads:       uploads,
+		refreshTokens: refreshTokens,
+		grace:         time.Duration(graceDays) * 24 * time.Hour,
+	}
+}
+
+// Export collects every record kept about userID.
+func (s *AccountService) Export(ctx context.Context, userID uuid.UUID) (*AccountExport, error) {
+	db := s.db.DB.WithContext(ctx)
+	export := &AccountExport{ExportedAt: time.Now().UTC()}
+
+	if err := db.First(&export.User, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	if export.User.AnonymizedAt != nil {
+		return nil, ErrAccountDeleted
+	}
+
+	queries := []struct {
+		dest  interface{}
+		order string
+	}{
+		{&export.Matches, "played_at DESC"},
+		{&export.CountryMastery, "country_code ASC"},
+		{&export.ChallengeCompletions, "completed_at DESC"},
+		{&export.RankHistory, "created_at DESC"},
+		{&export.SeasonRanks, "season_id ASC"},
+		{&export.SeasonResults, "created_at DESC"},
+		{&export.ProfileAssets, "created_at DESC"},
+		{&export.ProfileDecorations, "created_at ASC"},
+		{&export.LinkedAccounts, "created_at ASC"},
+		{&export.LoginEvents, "created_at DESC"},
+		{&export.Notifications, "created_at DESC"},
+	}
+	for _, q := range queries {
+		if err := db.Where("user_id = ?", userID).Order(q.order).Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var streak models.DailyStreak
+	if err := db.Where("user_id = ?", userID).Limit(1).Find(&streak).Error; err != nil {
+		return nil, err
+	}
+	if streak.UserID != uuid.Nil {
+		export.DailyStreak = &streak
+	}
+
+	if err := db.Table("user_achievements").
+		Select("achievements.code, achievements.name, achievements.description, user_achievements.unlocked_at").
+		Joins("JOIN achievements ON achievements.id = user_achievements.achievement_id").
+		Where("user_achievements.user_id = ?", userID).
+		Order("user_achievements.unlocked_at ASC").
+		Scan(&export.Achievements).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("requester_id = ? OR addressee_id = ?", userID, userID).Order("created_at ASC").Find(&export.Friendships).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("blocker_id = ?", userID).Order("created_at ASC").Find(&export.Blocks).Error; err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// ScheduleDeletion marks userID for deletion once the grace period is over
+// and signs out every device except keepSessionID.
+func (s *AccountService) ScheduleDeletion(ctx context.Context, userID uuid.UUID, keepSessionID string) (time.Time, error) {
+	scheduledAt := time.Now().Add(s.grace)
+
+	result := s.db.DB.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND anonymized_at IS NULL", userID).
+		Update("deletion_scheduled_at", scheduledAt)
+	if result.Error != nil {
+		return time.Time{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return time.Time{}, ErrAccountDeleted
+	}
+
+	if err := s.refreshTokens.RevokeAllSessions(ctx, userID, keepSessionID); err != nil {
+		log.Printf("Failed to revoke sessions of %s after deletion request: %v", userID, err)
+	}
+
+	return scheduledAt, nil
+}
+
+// CancelDeletion restores an account whose deletion is still pending.
+func (s *AccountService) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	result := s.db.DB.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL AND anonymized_at IS NULL", userID).
+		Update("deletion_scheduled_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeletionNotScheduled
+	}
+	return nil
+}
+
+// ProcessDueDeletions finishes every deletion whose grace period has ended
+// and returns how many accounts were deleted.
+func (s *AccountService) ProcessDueDeletions(ctx context.Context) (int, error) {
+	var ids []uuid.UUID
+	if err := s.db.DB.WithContext(ctx).Model(&models.User{}).
+		Where("deletion_scheduled_at <= ? AND anonymized_at IS NULL", time.Now()).
+		Limit(100).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, id := range ids {
+		done, err := s.deleteAccount(ctx, id)
+		if err != nil {
+			log.Printf("Failed to delete account %s: %v", id, err)
+			continue
+		}
+		if done {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// StartDeletionJob runs ProcessDueDeletions every interval in the background.
+func (s *AccountService) StartDeletionJob(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			deleted, err := s.ProcessDueDeletions(ctx)
+			cancel()
+			if err != nil {
+				log.Printf("Account deletion job failed: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Account deletion job: deleted %d account(s)", deleted)
+			}
+		}
+	}()
+}
+
+// deleteAccount removes the personal data of userID and anonymizes what other
+// players still see: the user row behind leaderboards and season ranks, and
+// the name on shared match results. Uploaded files are removed after commit.
+// It returns false when another instance already handled the account.
+func (s *AccountService) deleteAccount(ctx context.Context, userID uuid.UUID) (bool, error) {
+	tx := s.db.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+
+	var user models.User
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("id = ? AND deletion_scheduled_at <= ? AND anonymized_at IS NULL", userID, time.Now()).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		tx.Rollback()
+		return false, nil
+	}
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	var assets []models.ProfileAsset
+	if err := tx.Where("user_id = ?", userID).Find(&assets).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	personal := []interface{}{
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.UserIdentity{},
+		&models.LoginEvent{},
+		&models.TwoFactorRecoveryCode{},
+		&models.ProfileAsset{},
+		&models.ProfileDecoration{},
+		&models.CountryMastery{},
+		&models.ChallengeCompletion{},
+		&models.DailyStreak{},
+		&models.UserAchievement{},
+		&models.AchievementProgress{},
+		&models.RankHistory{},
+		&models.LeaderboardScore{},
+		&models.CheatFlag{},
+		&models.Notification{},
+	}
+	for _, model := range personal {
+		if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	if err := tx.Where("requester_id = ? OR addressee_id = ?", userID, userID).Delete(&models.Friendship{}).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tx.Where("blocker_id = ? OR blocked_id = ?", userID, userID).Delete(&models.UserBlock{}).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	anonymousName := AnonymizedUsername(userID)
+	if err := tx.Model(&models.MatchResult{}).Where("user_id = ?", userID).
+		Update("username", anonymousName).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tx.Model(&models.TournamentEntrantMember{}).Where("user_id = ?", userID).
+		Update("username", anonymousName).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	now := time.Now()
+	if err := tx.Model(&user).Updates(map[string]interface{}{
+		"username":                   anonymousName,
+		"email":                      fmt.Sprintf("deleted+%s@deleted.invalid", userID),
+		"password_hash":              "",
+		"avatar_url":                 "",
+		"banner_url":                 "",
+		"avatar_decoration_preset":   "",
+		"avatar_decoration_url":      "",
+		"profile_customization_json": "",
+		"session_id":                 "",
+		"last_active":                nil,
+		"is_active":                  false,
+		"email_verified":             false,
+		"verification_token":         "",
+		"verification_token_expiry":  nil,
+		"reset_token":                "",
+		"two_factor_enabled":         false,
+		"two_factor_secret":          "",
+		"anonymized_at":              now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return false, err
+	}
+
+	if s.uploads != nil {
+		if err := s.uploads.PurgeUserUploads(ctx, assets); err != nil {
+			log.Printf("Failed to remove uploads of deleted account %s: %v", userID, err)
+		}
+	}
+
+	return true, nil
+}
+
+// AnonymizedUsername is the name a deleted account keeps on leaderboards and
+// past matches.
+func AnonymizedUsername(userID uuid.UUID) string {
+	id := userID.String()
+	return "deleted_" + id[:8] + id[9:13]
+}
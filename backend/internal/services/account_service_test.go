@@ -0,0 +1,71 @@
+package services
+
+import (
+	"archive/zip"
+	"briworld/internal/models"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAccountExportWriteZip(t *testing.T) {
+	userID := uuid.New()
+	export := &AccountExport{
+		ExportedAt: time.Now().UTC(),
+		User:       models.User{ID: userID, Username: "player", PasswordHash: "secret-hash"},
+		Matches:    []models.MatchResult{{UserID: userID, Score: 120, Won: true}},
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteZip(&buf); err != nil {
+		t.Fatalf("WriteZip() error: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"user.json", "matches.json", "country_mastery.json", "achievements.json", "rank_history.json", "profile_assets.json", "profile_decorations.json"} {
+		if files[name] == nil {
+			t.Errorf("Expected %s in archive", name)
+		}
+	}
+
+	rc, err := files["user.json"].Open()
+	if err != nil {
+		t.Fatalf("failed to open user.json: %v", err)
+	}
+	defer rc.Close()
+
+	var user map[string]interface{}
+	if err := json.NewDecoder(rc).Decode(&user); err != nil {
+		t.Fatalf("user.json is not JSON: %v", err)
+	}
+	if user["username"] != "player" {
+		t.Errorf("Expected username in export, got %v", user["username"])
+	}
+	if _, leaked := user["password_hash"]; leaked {
+		t.Error("Password hash must not be exported")
+	}
+}
+
+func TestAnonymizedUsername(t *testing.T) {
+	id := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	name := AnonymizedUsername(id)
+
+	if name != "deleted_123e4567e89b" {
+		t.Errorf("AnonymizedUsername() = %s", name)
+	}
+	if len(name) > 32 || !strings.HasPrefix(name, "deleted_") {
+		t.Errorf("Anonymized name %q must fit the username column", name)
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const achievementEventBuffer = 256
+
+// achievementPublishWait is how long Publish waits for room in a full queue
+// before it gives up on an event.
+const achievementPublishWait = 2 * time.Second
+
+// AchievementPusher sends a message to a player in whatever room they are in.
+type AchievementPusher interface {
+	SendToPlayer(userID uuid.UUID, messageType string, payload interface{})
+}
+
+// AchievementEngine unlocks achievements from gameplay events by the rules it
+// is given. Events are handled one at a time off the game loop.
+type AchievementEngine struct {
+	db     *database.GormDB
+	rules  []AchievementRule
+	room   AchievementPusher
+	events chan AchievementEvent
+}
+
+func NewAchievementEngine(db *database.GormDB, rules []AchievementRule, room AchievementPusher) *AchievementEngine {
+	return &AchievementEngine{
+		db:     db,
+		rules:  rules,
+		room:   room,
+		events: make(chan AchievementEvent, achievementEventBuffer),
+	}
+}
+
+// Start handles published events until the process exits.
+func (e *AchievementEngine) Start() {
+	go func() {
+		for evt := range e.events {
+			e.handle(evt)
+		}
+	}()
+}
+
+// Publish queues evt. When the queue is full it waits up to
+// achievementPublishWait for the engine to catch up, so a burst of events
+// slows the publisher down instead of losing progress; only an engine stuck
+// for that long drops the event.
+func (e *AchievementEngine) Publish(evt AchievementEvent) {
+	select {
+	case e.events <- evt:
+		return
+	default:
+	}
+
+	timer := time.NewTimer(achievementPublishWait)
+	defer timer.Stop()
+	select {
+	case e.events <- evt:
+	case <-timer.C:
+		log.Printf("Achievement queue stuck for %s, dropping %s event for %s", achievementPublishWait, evt.Kind, evt.UserID)
+	}
+}
+
+func (e *AchievementEngine) handle(evt AchievementEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var unlocked []string
+	if err := e.db.DB.WithContext(ctx).Table("user_achievements").
+		Joins("JOIN achievements ON achievements.id = user_achievements.achievement_id").
+		Where("user_achievements.user_id = ?", evt.UserID).
+		Pluck("achievements.code", &unlocked).Error; err != nil {
+		log.Printf("Error loading achievements of %s: %v", evt.UserID, err)
+		return
+	}
+	has := make(map[string]bool, len(unlocked))
+	for _, code := range unlocked {
+		has[code] = true
+	}
+
+	for _, rule := range e.rules {
+		if has[rule.Code] || !rule.Matches(evt) {
+			continue
+		}
+		achievement, err := e.apply(ctx, evt.UserID, rule)
+		if err != nil {
+			log.Printf("Error unlocking %s for %s: %v", rule.Code, evt.UserID, err)
+			continue
+		}
+		if achievement != nil {
+			e.announce(evt.UserID, achievement)
+		}
+	}
+}
+
+// apply counts a matching event towards rule and unlocks its achievement
+// once the rule is met. Both happen in one transaction, so a failed unlock
+// does not leave the event counted. It returns nil when nothing was unlocked.
+func (e *AchievementEngine) apply(ctx context.Context, userID uuid.UUID, rule AchievementRule) (*models.Achievement, error) {
+	var achievement *models.Achievement
+	err := e.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if rule.Times > 1 {
+			progress, err := advance(tx, userID, rule.Code)
+			if err != nil {
+				return err
+			}
+			if progress < rule.Times {
+				return nil
+			}
+		}
+		var err error
+		achievement, err = unlockAchievement(tx, userID, rule.Code)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return achievement, nil
+}
+
+// advance counts one more event towards code and returns the new count.
+func advance(tx *gorm.DB, userID uuid.UUID, code string) (int, error) {
+	var progress int
+	err := tx.Raw(`INSERT INTO achievement_progresses (user_id, code, progress, updated_at)
+		VALUES (?, ?, 1, NOW())
+		ON CONFLICT (user_id, code) DO UPDATE
+		SET progress = achievement_progresses.progress + 1, updated_at = NOW()
+		RETURNING progress`, userID, code).Scan(&progress).Error
+	return progress, err
+}
+
+// unlock gives userID the achievement code and its reward in one
+// transaction. It returns nil when they already had it.
+func (e *AchievementEngine) unlock(ctx context.Context, userID uuid.UUID, code string) (*models.Achievement, error) {
+	var achievement *models.Achievement
+	err := e.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		achievement, err = unlockAchievement(tx, userID, code)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return achievement, nil
+}
+
+// unlockAchievement records the achievement code for userID and pays its
+// reward within tx. It returns nil when they already had it.
+func unlockAchievement(tx *gorm.DB, userID uuid.UUID, code string) (*models.Achievement, error) {
+	var achievement models.Achievement
+	if err := tx.Where("code = ?", code).First(&achievement).Error; err != nil {
+		return nil, err
+	}
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "achievement_id"}},
+		DoNothing: true,
+	}).Create(&models.UserAchievement{
+		UserID:        userID,
+		AchievementID: achievement.ID,
+		UnlockedAt:    time.Now(),
+	})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).
+		Update("total_points", gorm.Expr("total_points + ?", achievement.Reward)).Error; err != nil {
+		return nil, err
+	}
+	return &achievement, nil
+}
+
+// announce tells the player about an unlock, both in their inbox and in the
+// room they are playing in.
+func (e *AchievementEngine) announce(userID uuid.UUID, achievement *models.Achievement) {
+	payload := map[string]interface{}{
+		"code":        achievement.Code,
+		"name":        achievement.Name,
+		"description": achievement.Description,
+		"icon":        achievement.Icon,
+		"rarity":      achievement.Rarity,
+		"reward":      achievement.Reward,
+	}
+	notifyUser(userID, NotificationAchievementUnlocked, payload)
+	if e.room != nil {
+		e.room.SendToPlayer(userID, "achievement_unlocked", payload)
+	}
+}
+
+// achievements is the engine gameplay events are published to. Events are
+// dropped while it is nil.
+var achievements *AchievementEngine
+
+// UseAchievements makes e the engine PublishAchievementEvent sends to.
+func UseAchievements(e *AchievementEngine) {
+	achievements = e
+}
+
+// PublishAchievementEvent hands evt to the shared engine, if any. Guests
+// cannot earn achievements, so their events are ignored.
+func PublishAchievementEvent(evt AchievementEvent) {
+	if achievements == nil || evt.UserID == uuid.Nil {
+		return
+	}
+	achievements.Publish(evt)
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPublishWaitsForRoomInAFullQueue(t *testing.T) {
+	e := &AchievementEngine{events: make(chan AchievementEvent, 1)}
+	e.Publish(AchievementEvent{Kind: EventRoundAnswered, UserID: uuid.New()})
+
+	late := AchievementEvent{Kind: EventGameCompleted, UserID: uuid.New()}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		<-e.events
+	}()
+	e.Publish(late)
+
+	select {
+	case got := <-e.events:
+		if got.UserID != late.UserID {
+			t.Errorf("queued %s event for %s, want the one published into the full queue", got.Kind, got.UserID)
+		}
+	default:
+		t.Fatal("the event published into a full queue was dropped")
+	}
+}
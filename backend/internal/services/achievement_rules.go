@@ -0,0 +1,100 @@
+package services
+
+import (
+	"briworld/internal/game"
+	"briworld/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Gameplay events achievements are unlocked by.
+const (
+	EventRoundAnswered   = "round_answered"
+	EventGameCompleted   = "game_completed"
+	EventStreakChanged   = "streak_changed"
+	EventRankChanged     = "rank_changed"
+	EventCountryMastered = "country_mastered"
+)
+
+// AchievementEvent is something a player did that may unlock achievements.
+// Stats holds the numbers the rules of its kind look at.
+type AchievementEvent struct {
+	Kind     string
+	UserID   uuid.UUID
+	GameMode string
+	Region   string
+	Stats    map[string]int
+}
+
+// AchievementCondition holds when the stat Stat of an event is at least
+// AtLeast and below Below. A zero bound is not checked, but the stat must
+// always be present.
+type AchievementCondition struct {
+	Stat    string
+	AtLeast int
+	Below   int
+}
+
+func (c AchievementCondition) holds(stats map[string]int) bool {
+	v, ok := stats[c.Stat]
+	if !ok {
+		return false
+	}
+	if c.AtLeast != 0 && v < c.AtLeast {
+		return false
+	}
+	if c.Below != 0 && v >= c.Below {
+		return false
+	}
+	return true
+}
+
+// AchievementRule unlocks the achievement Code once Times events of kind On
+// have met all of its conditions. Mode and Region, when set, narrow the
+// events it counts to those game modes and regions.
+type AchievementRule struct {
+	Code   string
+	On     string
+	Mode   string
+	Region string
+	When   []AchievementCondition
+	Times  int
+}
+
+// Matches reports whether evt counts towards the rule.
+func (r AchievementRule) Matches(evt AchievementEvent) bool {
+	if evt.Kind != r.On {
+		return false
+	}
+	if r.Mode != "" && evt.GameMode != r.Mode {
+		return false
+	}
+	if r.Region != "" && evt.Region != r.Region {
+		return false
+	}
+	for _, c := range r.When {
+		if !c.holds(evt.Stats) {
+			return false
+		}
+	}
+	return true
+}
+
+// AchievementRules unlock the seeded achievements.
+var AchievementRules = []AchievementRule{
+	{Code: "FIRST_WIN", On: EventGameCompleted, When: []AchievementCondition{{Stat: "won", AtLeast: 1}}},
+	{Code: "WIN_STREAK_5", On: EventGameCompleted, When: []AchievementCondition{{Stat: "win_streak", AtLeast: 5}, {Stat: "players", AtLeast: 2}}},
+	{Code: "WIN_STREAK_10", On: EventGameCompleted, When: []AchievementCondition{{Stat: "win_streak", AtLeast: 10}, {Stat: "players", AtLeast: 2}}},
+	{Code: "AFRICA_EXPERT", On: EventRoundAnswered, Region: game.RegionAfrica, When: []AchievementCondition{{Stat: "correct", AtLeast: 1}}, Times: 50},
+	{Code: "ASIA_EXPERT", On: EventRoundAnswered, Region: game.RegionAsia, When: []AchievementCondition{{Stat: "correct", AtLeast: 1}}, Times: 50},
+	{Code: "EUROPE_EXPERT", On: EventRoundAnswered, Region: game.RegionEurope, When: []AchievementCondition{{Stat: "correct", AtLeast: 1}}, Times: 50},
+	{Code: "AMERICAS_EXPERT", On: EventRoundAnswered, Region: game.RegionAmericas, When: []AchievementCondition{{Stat: "correct", AtLeast: 1}}, Times: 50},
+	{Code: "SPEED_DEMON", On: EventGameCompleted, When: []AchievementCondition{{Stat: "correct_answers", AtLeast: 5}, {Stat: "avg_response_ms", Below: 5000}}},
+	{Code: "PERFECTIONIST", On: EventGameCompleted, When: []AchievementCondition{{Stat: "rounds", AtLeast: 5}, {Stat: "accuracy", AtLeast: 100}}},
+	{Code: "WORLD_TRAVELER", On: EventCountryMastered, When: []AchievementCondition{{Stat: "mastered_countries", AtLeast: 100}}},
+	{Code: "DAILY_GRIND", On: EventStreakChanged, When: []AchievementCondition{{Stat: "daily_streak", AtLeast: 7}}},
+	{Code: "DAILY_DEVOTEE", On: EventStreakChanged, When: []AchievementCondition{{Stat: "daily_streak", AtLeast: 30}}},
+	{Code: "DAILY_LEGEND", On: EventStreakChanged, When: []AchievementCondition{{Stat: "daily_streak", AtLeast: 100}}},
+	{Code: "RANKED_WARRIOR", On: EventRankChanged, When: []AchievementCondition{{Stat: "rank", AtLeast: models.RankLevel(models.RankGold)}}},
+	{Code: "DIAMOND_LEAGUE", On: EventRankChanged, When: []AchievementCondition{{Stat: "rank", AtLeast: models.RankLevel(models.RankDiamond)}}},
+}
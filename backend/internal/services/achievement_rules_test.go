@@ -0,0 +1,60 @@
+package services
+
+import (
+	"briworld/internal/game"
+	"testing"
+)
+
+func TestEverySeededAchievementHasARule(t *testing.T) {
+	ruled := make(map[string]bool, len(AchievementRules))
+	for _, rule := range AchievementRules {
+		ruled[rule.Code] = true
+	}
+	for _, achievement := range seedAchievements {
+		if !ruled[achievement.Code] {
+			t.Errorf("%s cannot be unlocked", achievement.Code)
+		}
+	}
+}
+
+func TestAchievementRuleMatches(t *testing.T) {
+	speed := AchievementRule{
+		Code: "SPEED_DEMON",
+		On:   EventGameCompleted,
+		When: []AchievementCondition{{Stat: "correct_answers", AtLeast: 5}, {Stat: "avg_response_ms", Below: 5000}},
+	}
+	cases := []struct {
+		name string
+		evt  AchievementEvent
+		want bool
+	}{
+		{"fast", AchievementEvent{Kind: EventGameCompleted, Stats: map[string]int{"correct_answers": 6, "avg_response_ms": 3200}}, true},
+		{"slow", AchievementEvent{Kind: EventGameCompleted, Stats: map[string]int{"correct_answers": 6, "avg_response_ms": 5000}}, false},
+		{"too few answers", AchievementEvent{Kind: EventGameCompleted, Stats: map[string]int{"correct_answers": 4, "avg_response_ms": 1000}}, false},
+		{"no answers timed", AchievementEvent{Kind: EventGameCompleted, Stats: map[string]int{"correct_answers": 6}}, false},
+		{"other event", AchievementEvent{Kind: EventRoundAnswered, Stats: map[string]int{"correct_answers": 6, "avg_response_ms": 3200}}, false},
+	}
+	for _, tc := range cases {
+		if got := speed.Matches(tc.evt); got != tc.want {
+			t.Errorf("%s: Matches = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestAchievementRuleRegion(t *testing.T) {
+	rule := AchievementRule{
+		Code:   "AFRICA_EXPERT",
+		On:     EventRoundAnswered,
+		Region: game.RegionAfrica,
+		When:   []AchievementCondition{{Stat: "correct", AtLeast: 1}},
+		Times:  50,
+	}
+	answered := AchievementEvent{Kind: EventRoundAnswered, Region: game.RegionAfrica, Stats: map[string]int{"correct": 1}}
+	if !rule.Matches(answered) {
+		t.Fatal("an African answer should count")
+	}
+	answered.Region = game.RegionEurope
+	if rule.Matches(answered) {
+		t.Fatal("a European answer should not count")
+	}
+}
@@ -0,0 +1,514 @@
+package services
+
+import (
+	"briworld/internal/game"
+	"briworld/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxAuditLogPage    = 100
+	maxChallengeRounds = 30
+)
+
+var (
+	ErrRoomNotFound           = errors.New("room not found")
+	ErrCannotModerate         = errors.New("you cannot act on yourself or on a user with the same or a higher role")
+	ErrInvalidRole            = errors.New("role must be player, moderator or admin")
+	ErrInvalidBan             = errors.New("a ban must end in the future")
+	ErrAchievementNotFound    = errors.New("achievement not found")
+	ErrInvalidAchievement     = errors.New("invalid achievement")
+	ErrInvalidDailyChallenge  = errors.New("invalid daily challenge")
+	ErrDailyChallengeAttempts = errors.New("the daily challenge has already been played")
+)
+
+// achievementRarities are the rarities an achievement can have.
+var achievementRarities = []string{"COMMON", "RARE", "EPIC", "LEGENDARY"}
+
+// LiveRoom is a room being played on this instance, as moderators see it.
+type LiveRoom struct {
+	Code       string   `json:"code"`
+	Owner      string   `json:"owner"`
+	GameMode   string   `json:"game_mode"`
+	RoomType   string   `json:"room_type"`
+	Status     string   `json:"status"`
+	Round      int      `json:"round"`
+	Rounds     int      `json:"rounds"`
+	Players    []string `json:"players"`
+	Spectators int      `json:"spectators"`
+}
+
+// AdminRooms lists and closes the live rooms on this instance.
+type AdminRooms interface {
+	LiveRooms() []LiveRoom
+	// CloseRoom closes a room for everyone in it, returning false when there
+	// is no such room.
+	CloseRoom(code, reason string) bool
+	// DisconnectUser drops a user's connections to every room and returns
+	// how many there were.
+	DisconnectUser(userID uuid.UUID, reason string) int
+}
+
+// AchievementEdit is a change to an achievement. Nil fields are left alone.
+// An achievement's code cannot change, since its unlock rule refers to it.
+type AchievementEdit struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Icon        *string `json:"icon"`
+	Reward      *int    `json:"reward"`
+	Rarity      *string `json:"rarity"`
+}
+
+// DailyChallengeEdit sets what a date's daily challenge is played on. Its
+// countries are picked for the mode from the date's seed, as for a generated
+// challenge, and its time limit comes from the difficulty.
+type DailyChallengeEdit struct {
+	GameMode   string `json:"game_mode"`
+	Difficulty string `json:"difficulty"`
+	Rounds     int    `json:"rounds"`
+	Reward     int    `json:"reward"`
+}
+
+// UserStanding is a user's role and ban as moderators see them.
+type UserStanding struct {
+	ID          uuid.UUID  `json:"id"`
+	Username    string     `json:"username"`
+	Role        string     `json:"role"`
+	Banned      bool       `json:"banned"`
+	BannedAt    *time.Time `json:"banned_at,omitempty"`
+	BannedUntil *time.Time `json:"banned_until,omitempty"`
+	BanReason   string     `json:"ban_reason,omitempty"`
+}
+
+func standingOf(user *models.User) *UserStanding {
+	return &UserStanding{
+		ID:          user.ID,
+		Username:    user.Username,
+		Role:        user.Role,
+		Banned:      user.Banned(time.Now()),
+		BannedAt:    user.BannedAt,
+		BannedUntil: user.BannedUntil,
+		BanReason:   user.BanReason,
+	}
+}
+
+// AuditEntry is an audit log entry with who took the action.
+type AuditEntry struct {
+	models.AdminAuditLog
+	ActorUsername string `json:"actor_username"`
+}
+
+// AdminService carries out what moderators and admins do through the admin
+// API and writes each action to the audit log.
+type AdminService struct {
+	db            *gorm.DB
+	rooms         AdminRooms
+	refreshTokens *RefreshTokenService
+	seasons       *SeasonService
+	antiCheat     *AntiCheatService
+}
+
+func NewAdminService(db *gorm.DB, rooms AdminRooms, refreshTokens *RefreshTokenService, seasons *SeasonService, antiCheat *AntiCheatService) *AdminService {
+	return &AdminService{db: db, rooms: rooms, refreshTokens: refreshTokens, seasons: seasons, antiCheat: antiCheat}
+}
+
+// audit records that actorID took action on a target. detail is stored as
+// JSON.
+func audit(tx *gorm.DB, actorID uuid.UUID, action, targetType, targetID string, detail interface{}) error {
+	entry := models.AdminAuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+	if detail != nil {
+		encoded, err := json.Marshal(detail)
+		if err != nil {
+			return err
+		}
+		entry.Detail = string(encoded)
+	}
+	return tx.Create(&entry).Error
+}
+
+// LiveRooms lists the rooms being played on this instance.
+func (s *AdminService) LiveRooms() []LiveRoom {
+	return s.rooms.LiveRooms()
+}
+
+// CloseRoom closes a live room and kicks everyone in it.
+func (s *AdminService) CloseRoom(ctx context.Context, actorID uuid.UUID, code, reason string) error {
+	if !s.rooms.CloseRoom(code, reason) {
+		return ErrRoomNotFound
+	}
+	return audit(s.db.WithContext(ctx), actorID, models.AdminActionCloseRoom, "room", code, map[string]string{"reason": reason})
+}
+
+// UserStanding returns userID's role and ban.
+func (s *AdminService) UserStanding(ctx context.Context, userID uuid.UUID) (*UserStanding, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).
+		Select("id", "username", "role", "banned_at", "banned_until", "ban_reason").
+		First(&user, "id = ? AND anonymized_at IS NULL", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlayerNotFound
+		}
+		return nil, err
+	}
+	return standingOf(&user), nil
+}
+
+// moderatable locks and returns the user actorID wants to act on. Nobody can
+// act on themselves or on a user whose role is as trusted as their own.
+func moderatable(tx *gorm.DB, actorID, userID uuid.UUID) (*models.User, error) {
+	if actorID == userID {
+		return nil, ErrCannotModerate
+	}
+	var actor models.User
+	if err := tx.Select("id", "role").First(&actor, "id = ?", actorID).Error; err != nil {
+		return nil, err
+	}
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "username", "role", "banned_at", "banned_until", "ban_reason").
+		First(&user, "id = ? AND anonymized_at IS NULL", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlayerNotFound
+		}
+		return nil, err
+	}
+	if user.HasRole(actor.Role) {
+		return nil, ErrCannotModerate
+	}
+	return &user, nil
+}
+
+// BanUser bans a user until until, or for good when it is nil. They are
+// signed out of every device and dropped from the rooms they are in.
+func (s *AdminService) BanUser(ctx context.Context, actorID, userID uuid.UUID, reason string, until *time.Time) (*UserStanding, error) {
+	now := time.Now()
+	if until != nil && !until.After(now) {
+		return nil, ErrInvalidBan
+	}
+
+	var user *models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if user, err = moderatable(tx, actorID, userID); err != nil {
+			return err
+		}
+		user.BannedAt, user.BannedUntil, user.BanReason = &now, until, reason
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"banned_at":    user.BannedAt,
+			"banned_until": user.BannedUntil,
+			"ban_reason":   user.BanReason,
+		}).Error; err != nil {
+			return err
+		}
+		return audit(tx, actorID, models.AdminActionBanUser, "user", userID.String(), map[string]interface{}{
+			"reason": reason,
+			"until":  until,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshTokens.RevokeAllSessions(ctx, userID, ""); err != nil {
+		return nil, err
+	}
+	s.rooms.DisconnectUser(userID, "You have been banned")
+	return standingOf(user), nil
+}
+
+// UnbanUser lifts a user's ban.
+func (s *AdminService) UnbanUser(ctx context.Context, actorID, userID uuid.UUID) (*UserStanding, error) {
+	var user *models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if user, err = moderatable(tx, actorID, userID); err != nil {
+			return err
+		}
+		user.BannedAt, user.BannedUntil, user.BanReason = nil, nil, ""
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"banned_at":    nil,
+			"banned_until": nil,
+			"ban_reason":   "",
+		}).Error; err != nil {
+			return err
+		}
+		return audit(tx, actorID, models.AdminActionUnbanUser, "user", userID.String(), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return standingOf(user), nil
+}
+
+// SetRole gives a user role.
+func (s *AdminService) SetRole(ctx context.Context, actorID, userID uuid.UUID, role string) (*UserStanding, error) {
+	if !models.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	var user *models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if user, err = moderatable(tx, actorID, userID); err != nil {
+			return err
+		}
+		from := user.Role
+		user.Role = role
+		if err := tx.Model(user).Update("role", role).Error; err != nil {
+			return err
+		}
+		return audit(tx, actorID, models.AdminActionSetRole, "user", userID.String(), map[string]string{
+			"from": from,
+			"to":   role,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return standingOf(user), nil
+}
+
+// UpdateAchievement applies edit to the achievement with code.
+func (s *AdminService) UpdateAchievement(ctx context.Context, actorID uuid.UUID, code string, edit AchievementEdit) (*models.Achievement, error) {
+	updates := map[string]interface{}{}
+	if edit.Name != nil {
+		if *edit.Name == "" || utf8.RuneCountInString(*edit.Name) > 100 {
+			return nil, fmt.Errorf("%w: name must be 1 to 100 characters", ErrInvalidAchievement)
+		}
+		updates["name"] = *edit.Name
+	}
+	if edit.Description != nil {
+		if utf8.RuneCountInString(*edit.Description) > 255 {
+			return nil, fmt.Errorf("%w: description must be at most 255 characters", ErrInvalidAchievement)
+		}
+		updates["description"] = *edit.Description
+	}
+	if edit.Icon != nil {
+		if utf8.RuneCountInString(*edit.Icon) > 50 {
+			return nil, fmt.Errorf("%w: icon must be at most 50 characters", ErrInvalidAchievement)
+		}
+		updates["icon"] = *edit.Icon
+	}
+	if edit.Reward != nil {
+		if *edit.Reward < 0 {
+			return nil, fmt.Errorf("%w: reward cannot be negative", ErrInvalidAchievement)
+		}
+		updates["reward"] = *edit.Reward
+	}
+	if edit.Rarity != nil {
+		if !slices.Contains(achievementRarities, *edit.Rarity) {
+			return nil, fmt.Errorf("%w: rarity must be COMMON, RARE, EPIC or LEGENDARY", ErrInvalidAchievement)
+		}
+		updates["rarity"] = *edit.Rarity
+	}
+
+	var achievement models.Achievement
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&achievement, "code = ?", code).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAchievementNotFound
+			}
+			return err
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := tx.Model(&achievement).Updates(updates).Error; err != nil {
+			return err
+		}
+		return audit(tx, actorID, models.AdminActionUpdateAchievement, "achievement", code, edit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &achievement, nil
+}
+
+// SeedAchievements creates the built-in achievements the database is
+// missing, such as ones added since it was first set up, and returns how
+// many it created.
+func (s *AdminService) SeedAchievements(ctx context.Context, actorID uuid.UUID) (int64, error) {
+	var created int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if created, err = seedMissingAchievements(tx); err != nil {
+			return err
+		}
+		return audit(tx, actorID, models.AdminActionSeedAchievements, "achievement", "", map[string]int64{"created": created})
+	})
+	return created, err
+}
+
+// validate checks e and returns the round time limit of its difficulty.
+func (e DailyChallengeEdit) validate() (int, error) {
+	if !slices.Contains(dailyChallengeModes, e.GameMode) {
+		return 0, fmt.Errorf("%w: game mode must be one of %s", ErrInvalidDailyChallenge, strings.Join(dailyChallengeModes, ", "))
+	}
+	if e.Rounds < 1 || e.Rounds > maxChallengeRounds {
+		return 0, fmt.Errorf("%w: rounds must be 1 to %d", ErrInvalidDailyChallenge, maxChallengeRounds)
+	}
+	if e.Reward < 0 {
+		return 0, fmt.Errorf("%w: reward cannot be negative", ErrInvalidDailyChallenge)
+	}
+	for _, difficulty := range dailyChallengeDifficulties {
+		if difficulty.Name == e.Difficulty {
+			return difficulty.TimeLimit, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: difficulty must be EASY, MEDIUM or HARD", ErrInvalidDailyChallenge)
+}
+
+// SetDailyChallenge sets the daily challenge of date, creating it when it
+// has not been generated yet. A challenge cannot change once someone has
+// started it.
+func (s *AdminService) SetDailyChallenge(ctx context.Context, actorID uuid.UUID, date time.Time, edit DailyChallengeEdit) (*models.DailyChallenge, error) {
+	timeLimit, err := edit.validate()
+	if err != nil {
+		return nil, err
+	}
+	date = date.UTC().Truncate(24 * time.Hour)
+	seed, err := newDailyChallengeSeed()
+	if err != nil {
+		return nil, err
+	}
+	countries := game.Data.PickCountries(edit.GameMode, edit.Rounds, seed)
+	if len(countries) < edit.Rounds {
+		return nil, fmt.Errorf("%w: not enough countries for %d rounds of %s", ErrInvalidDailyChallenge, edit.Rounds, edit.GameMode)
+	}
+
+	challenge := models.DailyChallenge{Date: date}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("date = ?", date).First(&challenge).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			var attempts int64
+			if err := tx.Model(&models.ChallengeCompletion{}).Where("challenge_id = ?", challenge.ID).Count(&attempts).Error; err != nil {
+				return err
+			}
+			if attempts > 0 {
+				return ErrDailyChallengeAttempts
+			}
+		}
+
+		challenge.GameMode = edit.GameMode
+		challenge.Difficulty = edit.Difficulty
+		challenge.Rounds = edit.Rounds
+		challenge.RoundTimeLimit = timeLimit
+		challenge.Reward = edit.Reward
+		challenge.Countries = strings.Join(countries, ",")
+		if err := tx.Save(&challenge).Error; err != nil {
+			return err
+		}
+		return audit(tx, actorID, models.AdminActionUpdateChallenge, "daily_challenge", date.Format(time.DateOnly), edit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// StartSeason opens a season while none is active.
+func (s *AdminService) StartSeason(ctx context.Context, actorID uuid.UUID, name string, days int) (*models.Season, error) {
+	season, err := s.seasons.StartSeason(ctx, name, days)
+	if err != nil {
+		return nil, err
+	}
+	if err := audit(s.db.WithContext(ctx), actorID, models.AdminActionStartSeason, "season", season.ID.String(), map[string]interface{}{
+		"name":     season.Name,
+		"end_date": season.EndDate,
+	}); err != nil {
+		return nil, err
+	}
+	return season, nil
+}
+
+// EndSeason ends the active season now and opens the next one.
+func (s *AdminService) EndSeason(ctx context.Context, actorID uuid.UUID, nextName string, nextDays int) (*models.Season, *models.Season, error) {
+	ended, next, err := s.seasons.EndActiveSeason(ctx, nextName, nextDays)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := audit(s.db.WithContext(ctx), actorID, models.AdminActionEndSeason, "season", ended.ID.String(), map[string]interface{}{
+		"name":      ended.Name,
+		"next_id":   next.ID,
+		"next_name": next.Name,
+	}); err != nil {
+		return nil, nil, err
+	}
+	return ended, next, nil
+}
+
+// CheatReviewQueue returns a page of the anti-cheat review queue.
+func (s *AdminService) CheatReviewQueue(ctx context.Context, status string, limit, offset int) ([]CheatReview, int64, error) {
+	return s.antiCheat.ReviewQueue(ctx, status, limit, offset)
+}
+
+// ReviewCheatFlag records actorID's verdict on a flag.
+func (s *AdminService) ReviewCheatFlag(ctx context.Context, actorID, flagID uuid.UUID, verdict string) (*models.CheatFlag, error) {
+	flag, err := s.antiCheat.Review(ctx, flagID, actorID, verdict)
+	if err != nil {
+		return nil, err
+	}
+	if err := audit(s.db.WithContext(ctx), actorID, models.AdminActionReviewCheatFlag, "cheat_flag", flagID.String(), map[string]interface{}{
+		"user_id": flag.UserID,
+		"signal":  flag.Signal,
+		"verdict": verdict,
+	}); err != nil {
+		return nil, err
+	}
+	return flag, nil
+}
+
+// AuditLog returns a page of the audit log, newest first, and how many
+// entries match. Empty filters match everything.
+func (s *AdminService) AuditLog(ctx context.Context, action, targetID string, limit, offset int) ([]AuditEntry, int64, error) {
+	if limit <= 0 || limit > maxAuditLogPage {
+		limit = maxAuditLogPage
+	}
+	offset = max(0, offset)
+
+	query := s.db.WithContext(ctx).Table("admin_audit_logs AS a")
+	if action != "" {
+		query = query.Where("a.action = ?", action)
+	}
+	if targetID != "" {
+		query = query.Where("a.target_id = ?", targetID)
+	}
+
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	entries := []AuditEntry{}
+	if err := query.
+		Select("a.*, COALESCE(u.username, '') AS actor_username").
+		Joins("LEFT JOIN users u ON u.id = a.actor_id").
+		Order("a.created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
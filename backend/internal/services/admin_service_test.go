@@ -0,0 +1,77 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRolesIncludeLessTrustedOnes(t *testing.T) {
+	cases := []struct {
+		role string
+		need string
+		want bool
+	}{
+		{models.RoleAdmin, models.RoleModerator, true},
+		{models.RoleAdmin, models.RoleAdmin, true},
+		{models.RoleModerator, models.RoleModerator, true},
+		{models.RoleModerator, models.RoleAdmin, false},
+		{models.RolePlayer, models.RoleModerator, false},
+		{"", models.RolePlayer, true},
+		{"", models.RoleModerator, false},
+		{models.RoleAdmin, "owner", false},
+	}
+	for _, tc := range cases {
+		user := models.User{Role: tc.role}
+		if got := user.HasRole(tc.need); got != tc.want {
+			t.Errorf("%q HasRole(%q) = %v, want %v", tc.role, tc.need, got, tc.want)
+		}
+	}
+}
+
+func TestBanned(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	cases := []struct {
+		name string
+		user models.User
+		want bool
+	}{
+		{"never banned", models.User{}, false},
+		{"banned for good", models.User{BannedAt: &earlier}, true},
+		{"banned until later", models.User{BannedAt: &earlier, BannedUntil: &later}, true},
+		{"ban ran out", models.User{BannedAt: &earlier, BannedUntil: &earlier}, false},
+	}
+	for _, tc := range cases {
+		if got := tc.user.Banned(now); got != tc.want {
+			t.Errorf("%s: Banned() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDailyChallengeEditValidate(t *testing.T) {
+	valid := DailyChallengeEdit{GameMode: "FLAG", Difficulty: "HARD", Rounds: 10, Reward: 150}
+	limit, err := valid.validate()
+	if err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+	if limit != 10 {
+		t.Errorf("HARD time limit = %d, want 10", limit)
+	}
+
+	invalid := map[string]DailyChallengeEdit{
+		"mode not played on fixed countries": {GameMode: "WORLD_MAP", Difficulty: "EASY", Rounds: 10},
+		"unknown difficulty":                 {GameMode: "FLAG", Difficulty: "EXTREME", Rounds: 10},
+		"no rounds":                          {GameMode: "FLAG", Difficulty: "EASY", Rounds: 0},
+		"too many rounds":                    {GameMode: "FLAG", Difficulty: "EASY", Rounds: maxChallengeRounds + 1},
+		"negative reward":                    {GameMode: "FLAG", Difficulty: "EASY", Rounds: 10, Reward: -1},
+	}
+	for name, edit := range invalid {
+		if _, err := edit.validate(); !errors.Is(err, ErrInvalidDailyChallenge) {
+			t.Errorf("%s: validate() error = %v, want ErrInvalidDailyChallenge", name, err)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// cheatFastAnswers is how many answers under the minimum response time
+	// it takes to flag a game. One can be a lucky guess on a typed answer.
+	cheatFastAnswers = 3
+	// cheatRegularAnswers is how many correct answers a game needs before
+	// its timing is judged. Timing whose standard deviation is under
+	// cheatMaxTimingSpread of its mean is too even to be a person's.
+	cheatRegularAnswers  = 6
+	cheatMaxTimingSpread = 0.05
+	// cheatOutlierRounds is how many rounds a game needs before its accuracy
+	// is judged. A game is an outlier when its accuracy is at least
+	// cheatMinOutlierAccuracy and cheatOutlierDeviations standard deviations
+	// above the rank's baseline.
+	cheatOutlierRounds      = 8
+	cheatMinOutlierAccuracy = 0.9
+	cheatOutlierDeviations  = 3.0
+	// A rank's baseline is built from its games in the game mode over the
+	// last cheatBaselineDays, once there are cheatBaselineGames of them, and
+	// is kept for cheatBaselineTTL.
+	cheatBaselineDays  = 30
+	cheatBaselineGames = 30
+	cheatBaselineTTL   = time.Hour
+	maxCheatReviewPage = 100
+)
+
+var (
+	ErrCheatFlagNotFound = errors.New("cheat flag not found")
+	ErrInvalidVerdict    = errors.New("verdict must be dismissed or confirmed")
+)
+
+// AntiCheatPolicy says what the pipeline treats as too fast and whether
+// flagged players are kept off the leaderboards.
+type AntiCheatPolicy struct {
+	MinResponseMs int
+	ShadowExclude bool
+}
+
+// GameTimings is one signed-in player's answers in a finished game.
+// ResponseMs holds the time each correct answer took from the start of its
+// round, as the server measured it, and MissMs the same for wrong answers.
+type GameTimings struct {
+	UserID     uuid.UUID
+	RoomCode   string
+	GameMode   string
+	Rounds     int
+	ResponseMs []int
+	MissMs     []int
+}
+
+// accuracy is the share of the game's answers that were right. A player who
+// guessed their way to an answer is less accurate than one who knew it.
+func (g GameTimings) accuracy() float64 {
+	return answerAccuracy(len(g.ResponseMs), len(g.MissMs), g.Rounds)
+}
+
+// answerAccuracy is correct answers over attempts, counting at least one
+// attempt per round.
+func answerAccuracy(correct, misses, rounds int) float64 {
+	attempts := max(rounds, correct+misses)
+	if attempts == 0 {
+		return 0
+	}
+	return float64(correct) / float64(attempts)
+}
+
+// cheatSignal is something about a game no person should manage.
+type cheatSignal struct {
+	signal string
+	detail string
+}
+
+// timingSignals checks a game's answer times for sub-human speed and
+// machine-like regularity. Wrong answers typed faster than a person could
+// count as fast too: they are guesses fired off by a script.
+func (p AntiCheatPolicy) timingSignals(responseMs, missMs []int) []cheatSignal {
+	var signals []cheatSignal
+
+	fast, fastest := 0, math.MaxInt
+	for _, answers := range [][]int{responseMs, missMs} {
+		for _, ms := range answers {
+			if ms < p.MinResponseMs {
+				fast++
+			}
+			fastest = min(fastest, ms)
+		}
+	}
+	if fast >= cheatFastAnswers {
+		signals = append(signals, cheatSignal{
+			signal: models.CheatSignalFastAnswers,
+			detail: fmt.Sprintf("%d of %d answers under %dms, fastest %dms", fast, len(responseMs)+len(missMs), p.MinResponseMs, fastest),
+		})
+	}
+
+	if len(responseMs) >= cheatRegularAnswers {
+		mean, stddev := meanAndDeviation(responseMs)
+		if mean > 0 && stddev/mean < cheatMaxTimingSpread {
+			signals = append(signals, cheatSignal{
+				signal: models.CheatSignalRegularTiming,
+				detail: fmt.Sprintf("%d correct answers in %.0fms ± %.0fms", len(responseMs), mean, stddev),
+			})
+		}
+	}
+	return signals
+}
+
+// meanAndDeviation returns the mean and population standard deviation of
+// values.
+func meanAndDeviation(values []int) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += float64(v)
+	}
+	mean := sum / float64(len(values))
+
+	variance := 0.0
+	for _, v := range values {
+		variance += (float64(v) - mean) * (float64(v) - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+// accuracyBaseline is how accurate players of one rank are in one game mode.
+type accuracyBaseline struct {
+	Games  int64
+	Mean   float64
+	Stddev float64
+	at     time.Time
+}
+
+// outlier reports whether accuracy is far enough above b to flag.
+func (b accuracyBaseline) outlier(accuracy float64) bool {
+	return b.Games >= cheatBaselineGames &&
+		accuracy >= cheatMinOutlierAccuracy &&
+		accuracy > b.Mean+cheatOutlierDeviations*b.Stddev
+}
+
+// AntiCheatService runs finished games through the cheat detection pipeline
+// and keeps the queue of flags waiting for review.
+type AntiCheatService struct {
+	db     *gorm.DB
+	policy AntiCheatPolicy
+
+	mu        sync.Mutex
+	baselines map[string]accuracyBaseline
+}
+
+func NewAntiCheatService(db *gorm.DB, policy AntiCheatPolicy) *AntiCheatService {
+	return &AntiCheatService{db: db, policy: policy, baselines: make(map[string]accuracyBaseline)}
+}
+
+// baseline returns how accurate players of rank are in gameMode, building
+// it from their recent match results when the cached one is stale.
+func (s *AntiCheatService) baseline(ctx context.Context, rank, gameMode string) (accuracyBaseline, error) {
+	key := rank + ":" + gameMode
+	s.mu.Lock()
+	cached, ok := s.baselines[key]
+	s.mu.Unlock()
+	if ok && time.Since(cached.at) < cheatBaselineTTL {
+		return cached, nil
+	}
+
+	var b accuracyBaseline
+	if err := s.db.WithContext(ctx).Raw(`
+		SELECT COUNT(*) AS games,
+			COALESCE(AVG(m.correct::float / GREATEST(m.rounds, m.correct + m.misses)), 0) AS mean,
+			COALESCE(STDDEV_POP(m.correct::float / GREATEST(m.rounds, m.correct + m.misses)), 0) AS stddev
+		FROM match_results m JOIN users u ON u.id = m.user_id
+		WHERE u.rank = ? AND m.game_mode = ? AND m.rounds >= ? AND m.played_at >= ?
+	`, rank, gameMode, cheatOutlierRounds, time.Now().AddDate(0, 0, -cheatBaselineDays)).Scan(&b).Error; err != nil {
+		return accuracyBaseline{}, err
+	}
+	b.at = time.Now()
+
+	s.mu.Lock()
+	s.baselines[key] = b
+	s.mu.Unlock()
+	return b, nil
+}
+
+// AnalyseGame checks a player's game and queues a flag for each signal it
+// raises. With ShadowExclude on, the player is also kept off the
+// leaderboards until their flags are reviewed.
+func (s *AntiCheatService) AnalyseGame(ctx context.Context, game GameTimings) ([]models.CheatFlag, error) {
+	if game.UserID == uuid.Nil {
+		return nil, nil
+	}
+	signals := s.policy.timingSignals(game.ResponseMs, game.MissMs)
+
+	if game.Rounds >= cheatOutlierRounds {
+		var user models.User
+		if err := s.db.WithContext(ctx).Select("id", "rank").First(&user, "id = ?", game.UserID).Error; err != nil {
+			return nil, err
+		}
+		baseline, err := s.baseline(ctx, user.Rank, game.GameMode)
+		if err != nil {
+			return nil, err
+		}
+		accuracy := game.accuracy()
+		if baseline.outlier(accuracy) {
+			signals = append(signals, cheatSignal{
+				signal: models.CheatSignalAccuracyOutlier,
+				detail: fmt.Sprintf("%.0f%% accuracy over %d rounds, %s players average %.0f%% ± %.0f%%",
+					accuracy*100, game.Rounds, user.Rank, baseline.Mean*100, baseline.Stddev*100),
+			})
+		}
+	}
+	if len(signals) == 0 {
+		return nil, nil
+	}
+
+	flags := make([]models.CheatFlag, 0, len(signals))
+	for _, signal := range signals {
+		flags = append(flags, models.CheatFlag{
+			UserID:   game.UserID,
+			Signal:   signal.signal,
+			Detail:   signal.detail,
+			RoomCode: game.RoomCode,
+			GameMode: game.GameMode,
+			Status:   models.CheatFlagOpen,
+		})
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&flags).Error; err != nil {
+			return err
+		}
+		if !s.policy.ShadowExclude {
+			return nil
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ? AND shadow_excluded_at IS NULL", game.UserID).
+			Update("shadow_excluded_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// CheatReview is a flag in the review queue along with who it is about.
+type CheatReview struct {
+	models.CheatFlag
+	Username       string `json:"username"`
+	Rating         int    `json:"rating"`
+	ShadowExcluded bool   `json:"shadow_excluded"`
+}
+
+// ReviewQueue returns a page of flags with status, oldest first, and how
+// many there are. An empty status means open flags.
+func (s *AntiCheatService) ReviewQueue(ctx context.Context, status string, limit, offset int) ([]CheatReview, int64, error) {
+	if status == "" {
+		status = models.CheatFlagOpen
+	}
+	if limit <= 0 || limit > maxCheatReviewPage {
+		limit = maxCheatReviewPage
+	}
+	offset = max(0, offset)
+
+	db := s.db.WithContext(ctx)
+	var total int64
+	if err := db.Model(&models.CheatFlag{}).Where("status = ?", status).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	reviews := []CheatReview{}
+	if err := db.Table("cheat_flags AS f").
+		Select("f.*, u.username, u.rating, u.shadow_excluded_at IS NOT NULL AS shadow_excluded").
+		Joins("JOIN users u ON u.id = f.user_id").
+		Where("f.status = ?", status).
+		Order("f.created_at ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&reviews).Error; err != nil {
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+// Review records reviewerID's verdict on a flag, dismissed or confirmed.
+// Once none of a player's flags stand, they are back on the leaderboards.
+func (s *AntiCheatService) Review(ctx context.Context, flagID, reviewerID uuid.UUID, verdict string) (*models.CheatFlag, error) {
+	if verdict != models.CheatFlagDismissed && verdict != models.CheatFlagConfirmed {
+		return nil, ErrInvalidVerdict
+	}
+
+	var flag models.CheatFlag
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&flag, "id = ?", flagID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCheatFlagNotFound
+			}
+			return err
+		}
+
+		now := time.Now()
+		flag.Status = verdict
+		flag.ReviewedBy = &reviewerID
+		flag.ReviewedAt = &now
+		if err := tx.Model(&flag).Updates(map[string]interface{}{
+			"status":      flag.Status,
+			"reviewed_by": flag.ReviewedBy,
+			"reviewed_at": flag.ReviewedAt,
+		}).Error; err != nil {
+			return err
+		}
+		if verdict != models.CheatFlagDismissed {
+			return nil
+		}
+
+		return tx.Exec(`
+			UPDATE users SET shadow_excluded_at = NULL
+			WHERE id = ? AND NOT EXISTS (
+				SELECT 1 FROM cheat_flags WHERE user_id = ? AND status <> ?
+			)
+		`, flag.UserID, flag.UserID, models.CheatFlagDismissed).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// antiCheat is where finished games are analysed. Games are not checked
+// while it is nil.
+var antiCheat *AntiCheatService
+
+// UseAntiCheat makes s the service finished games are analysed by.
+func UseAntiCheat(s *AntiCheatService) {
+	antiCheat = s
+}
+
+// AnalyseGameTimings runs each player's game through the shared service, if
+// any, and logs what it flags.
+func AnalyseGameTimings(games []GameTimings) {
+	if antiCheat == nil {
+		return
+	}
+	for _, game := range games {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		flags, err := antiCheat.AnalyseGame(ctx, game)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to check %s game of %s for cheating: %v", game.GameMode, game.UserID, err)
+			continue
+		}
+		for _, flag := range flags {
+			log.Printf("Flagged %s for %s in room %s: %s", game.UserID, flag.Signal, game.RoomCode, flag.Detail)
+		}
+	}
+}
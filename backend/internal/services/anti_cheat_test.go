@@ -0,0 +1,84 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"testing"
+)
+
+func signalNames(signals []cheatSignal) map[string]bool {
+	names := make(map[string]bool, len(signals))
+	for _, s := range signals {
+		names[s.signal] = true
+	}
+	return names
+}
+
+func TestTimingSignals(t *testing.T) {
+	policy := AntiCheatPolicy{MinResponseMs: 400}
+
+	human := []int{2100, 3400, 1800, 5200, 2600, 4100, 2900}
+	if signals := policy.timingSignals(human, nil); len(signals) != 0 {
+		t.Fatalf("human timing flagged: %+v", signals)
+	}
+
+	lucky := []int{350, 2400, 390, 3100, 2800}
+	if signals := policy.timingSignals(lucky, nil); len(signals) != 0 {
+		t.Fatalf("two quick guesses flagged: %+v", signals)
+	}
+
+	instant := []int{120, 95, 3000, 140, 2500}
+	if names := signalNames(policy.timingSignals(instant, nil)); !names[models.CheatSignalFastAnswers] {
+		t.Fatalf("instant answers not flagged: %v", names)
+	}
+
+	scripted := []int{1500, 1510, 1495, 1502, 1498, 1505}
+	if names := signalNames(policy.timingSignals(scripted, nil)); !names[models.CheatSignalRegularTiming] {
+		t.Fatalf("scripted timing not flagged: %v", names)
+	}
+	if signals := policy.timingSignals(scripted[:5], nil); len(signals) != 0 {
+		t.Fatalf("too few answers to judge timing flagged: %+v", signals)
+	}
+
+	guessing := []int{2100, 3400}
+	if names := signalNames(policy.timingSignals(guessing, []int{90, 130, 110})); !names[models.CheatSignalFastAnswers] {
+		t.Fatalf("instant wrong guesses not flagged: %v", names)
+	}
+	if signals := policy.timingSignals(guessing, []int{1900, 2600, 4200}); len(signals) != 0 {
+		t.Fatalf("typed wrong answers flagged: %+v", signals)
+	}
+}
+
+func TestAnswerAccuracyCountsMisses(t *testing.T) {
+	cases := []struct {
+		correct, misses, rounds int
+		want                    float64
+	}{
+		{10, 0, 10, 1},
+		{8, 0, 10, 0.8},
+		{9, 1, 10, 0.9},
+		{10, 10, 10, 0.5},
+		{0, 0, 0, 0},
+	}
+	for _, tc := range cases {
+		if got := answerAccuracy(tc.correct, tc.misses, tc.rounds); got != tc.want {
+			t.Errorf("answerAccuracy(%d, %d, %d) = %v, want %v", tc.correct, tc.misses, tc.rounds, got, tc.want)
+		}
+	}
+}
+
+func TestAccuracyOutlier(t *testing.T) {
+	baseline := accuracyBaseline{Games: 200, Mean: 0.45, Stddev: 0.12}
+	if !baseline.outlier(1.0) {
+		t.Fatal("perfect game against a 45% baseline should be an outlier")
+	}
+	if baseline.outlier(0.8) {
+		t.Fatal("80% accuracy is good, not implausible")
+	}
+
+	if (accuracyBaseline{Games: 200, Mean: 0.85, Stddev: 0.1}).outlier(1.0) {
+		t.Fatal("perfect game should be within reach of a strong rank")
+	}
+	if (accuracyBaseline{Games: 5, Mean: 0.3, Stddev: 0.05}).outlier(1.0) {
+		t.Fatal("baseline from too few games should not flag anyone")
+	}
+}
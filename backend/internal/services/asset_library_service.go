@@ -0,0 +1,269 @@
+package services
+
+import (
+	"briworld/internal/config"
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxAssetNameLength = 120
+	unusedAssetBatch   = 200
+)
+
+var (
+	ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+	ErrAssetNotFound        = errors.New("asset not found")
+	ErrInvalidAssetName     = errors.New("asset name must be 1 to 120 characters")
+)
+
+// StorageUsage reports how much of their quota a user has used.
+type StorageUsage struct {
+	Tier       string `json:"tier"`
+	UsedBytes  int64  `json:"used_bytes"`
+	AssetCount int    `json:"asset_count"`
+	// MaxBytes and MaxAssets are zero when the tier is unlimited.
+	MaxBytes  int64 `json:"max_bytes"`
+	MaxAssets int   `json:"max_assets"`
+}
+
+// Allows reports whether one more asset of size bytes fits the quota.
+func (u StorageUsage) Allows(size int64) bool {
+	if u.MaxAssets > 0 && u.AssetCount+1 > u.MaxAssets {
+		return false
+	}
+	if u.MaxBytes > 0 && u.UsedBytes+size > u.MaxBytes {
+		return false
+	}
+	return true
+}
+
+// AssetLibraryService enforces per-tier storage quotas on profile assets and
+// removes assets nothing uses any more.
+type AssetLibraryService struct {
+	db          *database.GormDB
+	quotas      map[string]config.StorageQuota
+	defaultTier string
+	unusedAfter time.Duration
+}
+
+func NewAssetLibraryService(db *database.GormDB, cfg config.StorageConfig) *AssetLibraryService {
+	return &AssetLibraryService{
+		db:          db,
+		quotas:      cfg.Quotas,
+		defaultTier: cfg.DefaultTier,
+		unusedAfter: time.Duration(cfg.UnusedAssetDays) * 24 * time.Hour,
+	}
+}
+
+// QuotaFor returns the quota of tier, falling back to the default tier.
+func (s *AssetLibraryService) QuotaFor(tier string) (string, config.StorageQuota) {
+	if quota, ok := s.quotas[tier]; ok {
+		return tier, quota
+	}
+	return s.defaultTier, s.quotas[s.defaultTier]
+}
+
+func (s *AssetLibraryService) usage(db *gorm.DB, userID uuid.UUID, tier string) (StorageUsage, error) {
+	var totals struct {
+		Bytes int64
+		Count int
+	}
+	if err := db.Model(&models.ProfileAsset{}).
+		Select("COALESCE(SUM(file_size), 0) AS bytes, COUNT(*) AS count").
+		Where("user_id = ?", userID).
+		Scan(&totals).Error; err != nil {
+		return StorageUsage{}, err
+	}
+
+	tier, quota := s.QuotaFor(tier)
+	return StorageUsage{
+		Tier:       tier,
+		UsedBytes:  totals.Bytes,
+		AssetCount: totals.Count,
+		MaxBytes:   quota.MaxBytes,
+		MaxAssets:  quota.MaxAssets,
+	}, nil
+}
+
+// Usage returns the storage used by userID and the limits of their tier.
+func (s *AssetLibraryService) Usage(ctx context.Context, userID uuid.UUID) (StorageUsage, error) {
+	db := s.db.DB.WithContext(ctx)
+
+	var user models.User
+	if err := db.Select("id", "storage_tier").First(&user, "id = ?", userID).Error; err != nil {
+		return StorageUsage{}, err
+	}
+	return s.usage(db, userID, user.StorageTier)
+}
+
+// CheckQuota tells early whether an upload of size bytes can fit, so files
+// that cannot be kept are never sent to the asset store. CreateAsset makes
+// the binding check.
+func (s *AssetLibraryService) CheckQuota(ctx context.Context, userID uuid.UUID, size int64) (StorageUsage, error) {
+	usage, err := s.Usage(ctx, userID)
+	if err != nil {
+		return usage, err
+	}
+	if !usage.Allows(size) {
+		return usage, ErrStorageQuotaExceeded
+	}
+	return usage, nil
+}
+
+// CreateAsset records asset if it fits the owner's quota. The user row is
+// locked while usage is summed, so concurrent uploads cannot both squeeze
+// into the last free slot.
+func (s *AssetLibraryService) CreateAsset(ctx context.Context, asset *models.ProfileAsset) (StorageUsage, error) {
+	tx := s.db.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return StorageUsage{}, tx.Error
+	}
+
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "storage_tier").
+		First(&user, "id = ?", asset.UserID).Error; err != nil {
+		tx.Rollback()
+		return StorageUsage{}, err
+	}
+
+	usage, err := s.usage(tx, asset.UserID, user.StorageTier)
+	if err != nil {
+		tx.Rollback()
+		return usage, err
+	}
+	if !usage.Allows(asset.FileSize) {
+		tx.Rollback()
+		return usage, ErrStorageQuotaExceeded
+	}
+
+	if err := tx.Create(asset).Error; err != nil {
+		tx.Rollback()
+		return usage, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return usage, err
+	}
+
+	usage.UsedBytes += asset.FileSize
+	usage.AssetCount++
+	return usage, nil
+}
+
+// RenameAsset changes the display name of one of userID's assets.
+func (s *AssetLibraryService) RenameAsset(ctx context.Context, userID, assetID uuid.UUID, name string) (*models.ProfileAsset, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxAssetNameLength {
+		return nil, ErrInvalidAssetName
+	}
+
+	db := s.db.DB.WithContext(ctx)
+	result := db.Model(&models.ProfileAsset{}).
+		Where("id = ? AND user_id = ?", assetID, userID).
+		Update("name", name)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAssetNotFound
+	}
+
+	var asset models.ProfileAsset
+	if err := db.First(&asset, "id = ?", assetID).Error; err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// DeleteUnusedAssets removes assets older than the unused-asset period that
+// no profile decoration, avatar, banner, avatar decoration or saved
+// customization refers to, then deletes their files. Assets whose files could
+// not be deleted are put back, so the next run tries again. It returns how
+// many assets were removed.
+func (s *AssetLibraryService) DeleteUnusedAssets(ctx context.Context, uploads UploadPurger) (int, error) {
+	if s.unusedAfter <= 0 {
+		return 0, nil
+	}
+
+	// One statement selects and deletes, so an asset that gets referenced in
+	// the meantime is not removed.
+	var assets []models.ProfileAsset
+	err := s.db.DB.WithContext(ctx).Raw(`
+		DELETE FROM profile_assets
+		WHERE id IN (
+			SELECT pa.id
+			FROM profile_assets pa
+			JOIN users u ON u.id = pa.user_id
+			WHERE pa.created_at < ?
+			  AND pa.url NOT IN (COALESCE(u.avatar_url, ''), COALESCE(u.banner_url, ''), COALESCE(u.avatar_decoration_url, ''))
+			  AND POSITION(pa.url IN COALESCE(u.profile_customization_json, '')) = 0
+			  AND NOT EXISTS (
+				SELECT 1 FROM profile_decorations pd
+				WHERE pd.user_id = pa.user_id AND (pd.asset_id = pa.id OR pd.asset_url = pa.url)
+			  )
+			ORDER BY pa.created_at
+			LIMIT ?
+		)
+		RETURNING *
+	`, time.Now().Add(-s.unusedAfter), unusedAssetBatch).Scan(&assets).Error
+	if err != nil {
+		return 0, err
+	}
+
+	if len(assets) == 0 || uploads == nil {
+		return len(assets), nil
+	}
+
+	failed := purgeAssetFiles(ctx, uploads, assets)
+	if len(failed) > 0 {
+		if err := s.db.DB.WithContext(ctx).Create(&failed).Error; err != nil {
+			return len(assets) - len(failed), err
+		}
+	}
+	return len(assets) - len(failed), nil
+}
+
+// purgeAssetFiles deletes the files of each asset and returns the assets
+// whose files are still there.
+func purgeAssetFiles(ctx context.Context, uploads UploadPurger, assets []models.ProfileAsset) []models.ProfileAsset {
+	var failed []models.ProfileAsset
+	for _, asset := range assets {
+		if err := uploads.PurgeUserUploads(ctx, []models.ProfileAsset{asset}); err != nil {
+			log.Printf("Failed to remove files of unused asset %s: %v", asset.ID, err)
+			failed = append(failed, asset)
+		}
+	}
+	return failed
+}
+
+// StartUnusedAssetJob runs DeleteUnusedAssets every interval in the background.
+func (s *AssetLibraryService) StartUnusedAssetJob(uploads UploadPurger, interval time.Duration) {
+	if s.unusedAfter <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			deleted, err := s.DeleteUnusedAssets(ctx, uploads)
+			cancel()
+			if err != nil {
+				log.Printf("Unused asset job failed: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Unused asset job: deleted %d asset(s)", deleted)
+			}
+		}
+	}()
+}
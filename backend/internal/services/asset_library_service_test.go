@@ -0,0 +1,72 @@
+package services
+
+import (
+	"briworld/internal/config"
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestStorageUsageAllows(t *testing.T) {
+	usage := StorageUsage{UsedBytes: 900, AssetCount: 9, MaxBytes: 1000, MaxAssets: 10}
+
+	if !usage.Allows(100) {
+		t.Fatal("an upload that exactly fills the quota should be allowed")
+	}
+	if usage.Allows(101) {
+		t.Fatal("an upload over the byte quota should be refused")
+	}
+
+	usage.AssetCount = 10
+	if usage.Allows(1) {
+		t.Fatal("an upload over the asset count should be refused")
+	}
+
+	if !(StorageUsage{UsedBytes: 1 << 40, AssetCount: 1 << 20}).Allows(1 << 30) {
+		t.Fatal("a tier without limits should allow everything")
+	}
+}
+
+func TestAssetLibraryQuotaForFallsBackToDefaultTier(t *testing.T) {
+	library := NewAssetLibraryService(nil, config.StorageConfig{
+		DefaultTier: "free",
+		Quotas: map[string]config.StorageQuota{
+			"free":      {MaxBytes: 100, MaxAssets: 2},
+			"supporter": {MaxBytes: 1000, MaxAssets: 20},
+		},
+	})
+
+	if tier, quota := library.QuotaFor("supporter"); tier != "supporter" || quota.MaxAssets != 20 {
+		t.Fatalf("QuotaFor(supporter) = %s, %+v", tier, quota)
+	}
+	if tier, quota := library.QuotaFor("retired-tier"); tier != "free" || quota.MaxBytes != 100 {
+		t.Fatalf("QuotaFor(retired-tier) = %s, %+v", tier, quota)
+	}
+}
+
+// stuckPurger fails to delete the files of the assets in stuck.
+type stuckPurger struct {
+	stuck map[uuid.UUID]bool
+}
+
+func (p stuckPurger) PurgeUserUploads(ctx context.Context, assets []models.ProfileAsset) error {
+	for _, asset := range assets {
+		if p.stuck[asset.ID] {
+			return errors.New("provider mismatch")
+		}
+	}
+	return nil
+}
+
+func TestPurgeAssetFilesReturnsTheAssetsItCouldNotRemove(t *testing.T) {
+	assets := []models.ProfileAsset{{ID: uuid.New()}, {ID: uuid.New()}, {ID: uuid.New()}}
+	purger := stuckPurger{stuck: map[uuid.UUID]bool{assets[1].ID: true}}
+
+	failed := purgeAssetFiles(context.Background(), purger, assets)
+	if len(failed) != 1 || failed[0].ID != assets[1].ID {
+		t.Fatalf("failed = %+v, want only the second asset", failed)
+	}
+}
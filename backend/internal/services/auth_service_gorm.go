@@ -5,6 +5,7 @@ import (
 	"briworld/internal/models"
 	"briworld/internal/utils"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -12,6 +13,10 @@ import (
 	"github.com/google/uuid"
 )
 
+const verificationTokenTTL = 24 * time.Hour
+
+var ErrVerificationTokenInvalid = errors.New("invalid or expired verification token")
+
 type AuthServiceGorm struct {
 	db *database.GormDB
 }
@@ -82,3 +87,46 @@ func (s *AuthServiceGorm) ResetPassword(ctx context.Context, token, newPasswordH
 		"reset_token_expiry": nil,
 	}).Error
 }
+
+// CreateVerificationToken replaces any pending email verification token of
+// userID and returns the new raw token. Only its hash is stored.
+func (s *AuthServiceGorm) CreateVerificationToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	token, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	expiry := time.Now().Add(verificationTokenTTL)
+	if err := s.db.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"verification_token":        utils.HashToken(token),
+		"verification_token_expiry": expiry,
+	}).Error; err != nil {
+		return "", fmt.Errorf("failed to store verification token: %w", err)
+	}
+
+	return token, nil
+}
+
+// VerifyEmail marks the owner of token as verified and consumes the token.
+func (s *AuthServiceGorm) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrVerificationTokenInvalid
+	}
+
+	var user models.User
+	if err := s.db.DB.WithContext(ctx).
+		Where("verification_token = ? AND verification_token_expiry > ?", utils.HashToken(token), time.Now()).
+		First(&user).Error; err != nil {
+		return nil, ErrVerificationTokenInvalid
+	}
+
+	if err := s.db.DB.WithContext(ctx).Model(&user).Updates(map[string]interface{}{
+		"email_verified":            true,
+		"verification_token":        "",
+		"verification_token_expiry": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
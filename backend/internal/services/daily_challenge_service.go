@@ -0,0 +1,309 @@
+package services
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DailyRoomType is the room type daily challenge games are played in.
+const DailyRoomType = "DAILY"
+
+const maxDailyLeaderboardSize = 100
+
+var (
+	ErrDailyChallengeUnavailable = errors.New("no daily challenge is available")
+	ErrDailyChallengePlayed      = errors.New("you have already played today's challenge")
+	ErrDailyChallengeNotFound    = errors.New("no daily challenge on that date")
+)
+
+// DailyAttempt is a user's one game of the daily challenge.
+type DailyAttempt struct {
+	ChallengeID    uuid.UUID `json:"challenge_id"`
+	RoomCode       string    `json:"room_code"`
+	GameMode       string    `json:"game_mode"`
+	Rounds         int       `json:"rounds"`
+	RoundTimeLimit int       `json:"round_time_limit"`
+	// Resumed is set when the user rejoins a game they already started.
+	Resumed bool `json:"resumed"`
+}
+
+// DailyLeaderboardEntry is one finished attempt on the daily leaderboard.
+type DailyLeaderboardEntry struct {
+	Rank        int       `json:"rank"`
+	UserID      uuid.UUID `json:"user_id"`
+	Username    string    `json:"username"`
+	Score       int       `json:"score"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// DailyLeaderboard ranks the finished attempts of one daily challenge by
+// score, then by who finished first.
+type DailyLeaderboard struct {
+	Challenge *models.DailyChallenge  `json:"challenge"`
+	Entries   []DailyLeaderboardEntry `json:"entries"`
+	Me        *DailyLeaderboardEntry  `json:"me,omitempty"`
+}
+
+// DailyRoomOpener opens daily challenge rooms and tells whether one is still
+// open.
+type DailyRoomOpener interface {
+	MatchRoomOpener
+	MatchRoomOpen(code string) bool
+}
+
+// DailyChallengeView is today's challenge as shown to a user.
+type DailyChallengeView struct {
+	*models.DailyChallenge
+	Played bool         `json:"played"`
+	Streak *StreakState `json:"streak,omitempty"`
+}
+
+// DailyChallengeService runs the daily challenge as a server-side game. Each
+// user gets one attempt per day, scored from the game itself, and builds a
+// streak of days played in loc.
+type DailyChallengeService struct {
+	db    *database.GormDB
+	meta  *MetaService
+	rooms DailyRoomOpener
+	loc   *time.Location
+}
+
+func NewDailyChallengeService(db *database.GormDB, meta *MetaService, rooms DailyRoomOpener, loc *time.Location) *DailyChallengeService {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &DailyChallengeService{db: db, meta: meta, rooms: rooms, loc: loc}
+}
+
+// Start opens a room for userID's attempt at today's challenge. A user whose
+// game is still running gets its room back instead; one who abandoned it
+// until the room closed has used their attempt.
+func (s *DailyChallengeService) Start(ctx context.Context, userID uuid.UUID) (*DailyAttempt, error) {
+	challenge, err := s.meta.GetTodayChallenge()
+	if err != nil || challenge == nil || challenge.Countries == "" {
+		return nil, ErrDailyChallengeUnavailable
+	}
+
+	attempt := &DailyAttempt{
+		ChallengeID:    challenge.ID,
+		GameMode:       challenge.GameMode,
+		Rounds:         challenge.Rounds,
+		RoundTimeLimit: challenge.RoundTimeLimit,
+	}
+
+	now := time.Now()
+	completion := models.ChallengeCompletion{
+		UserID:      userID,
+		ChallengeID: challenge.ID,
+		StartedAt:   &now,
+	}
+	result := s.db.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "challenge_id"}, {Name: "user_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Neq{Column: "user_id", Value: uuid.Nil}}},
+		DoNothing:   true,
+	}).Create(&completion)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		var existing models.ChallengeCompletion
+		if err := s.db.DB.WithContext(ctx).
+			Where("challenge_id = ? AND user_id = ?", challenge.ID, userID).
+			First(&existing).Error; err != nil {
+			return nil, err
+		}
+		if existing.CompletedAt != nil || existing.RoomCode == "" {
+			return nil, ErrDailyChallengePlayed
+		}
+		if !s.rooms.MatchRoomOpen(existing.RoomCode) {
+			// The game was abandoned and its room has closed
+			s.forfeit(existing.ID)
+			return nil, ErrDailyChallengePlayed
+		}
+		attempt.RoomCode = existing.RoomCode
+		attempt.Resumed = true
+		return attempt, nil
+	}
+
+	completionID := completion.ID
+	code, err := s.rooms.OpenMatchRoom(MatchRoomSpec{
+		GameMode:       challenge.GameMode,
+		Rounds:         challenge.Rounds,
+		RoundTimeLimit: challenge.RoundTimeLimit,
+		Sides:          map[uuid.UUID]int{userID: 0},
+		RoomType:       DailyRoomType,
+		Countries:      strings.Split(challenge.Countries, ","),
+		OnComplete: func(scores [2]int) {
+			s.finish(completionID, userID, challenge.Reward, scores[0])
+		},
+	})
+	if err == nil {
+		err = s.db.DB.WithContext(ctx).Model(&completion).Update("room_code", code).Error
+	}
+	if err != nil {
+		// Give the attempt back when its game could not be set up
+		s.db.DB.Delete(&models.ChallengeCompletion{}, "id = ?", completionID)
+		return nil, err
+	}
+
+	attempt.RoomCode = code
+	return attempt, nil
+}
+
+// finish records the score of a finished attempt, extends userID's streak
+// and grants the challenge's reward.
+func (s *DailyChallengeService) finish(completionID, userID uuid.UUID, reward, score int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var streak models.DailyStreak
+	err := s.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ChallengeCompletion{}).
+			Where("id = ? AND completed_at IS NULL", completionID).
+			Updates(map[string]interface{}{"score": score, "completed_at": now})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).
+			Limit(1).Find(&streak).Error; err != nil {
+			return err
+		}
+		streak.UserID = userID
+		extendStreak(&streak, streakDay(now, s.loc))
+		if err := tx.Save(&streak).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("total_points", gorm.Expr("total_points + ?", reward)).Error
+	})
+	if err != nil {
+		log.Printf("Error recording daily challenge score: %v", err)
+		return
+	}
+
+	PublishAchievementEvent(AchievementEvent{
+		Kind:   EventStreakChanged,
+		UserID: userID,
+		Stats:  map[string]int{"daily_streak": streak.Current},
+	})
+}
+
+// forfeit closes an abandoned attempt without a reward.
+func (s *DailyChallengeService) forfeit(completionID uuid.UUID) {
+	if err := s.db.DB.Model(&models.ChallengeCompletion{}).
+		Where("id = ? AND completed_at IS NULL", completionID).
+		Update("completed_at", time.Now()).Error; err != nil {
+		log.Printf("Error closing abandoned daily challenge: %v", err)
+	}
+}
+
+// Today returns today's challenge with userID's streak and whether they have
+// played it. Guests get the challenge alone.
+func (s *DailyChallengeService) Today(ctx context.Context, userID uuid.UUID) (*DailyChallengeView, error) {
+	challenge, err := s.meta.GetTodayChallenge()
+	if err != nil || challenge == nil {
+		return nil, ErrDailyChallengeUnavailable
+	}
+	view := &DailyChallengeView{DailyChallenge: challenge}
+	if userID == uuid.Nil {
+		return view, nil
+	}
+
+	var played int64
+	if err := s.db.DB.WithContext(ctx).Model(&models.ChallengeCompletion{}).
+		Where("challenge_id = ? AND user_id = ? AND completed_at IS NOT NULL", challenge.ID, userID).
+		Count(&played).Error; err != nil {
+		return nil, err
+	}
+	view.Played = played > 0
+
+	var streak models.DailyStreak
+	if err := s.db.DB.WithContext(ctx).Where("user_id = ?", userID).Limit(1).Find(&streak).Error; err != nil {
+		return nil, err
+	}
+	state := streakState(streak, streakDay(time.Now(), s.loc), s.loc)
+	view.Streak = &state
+	return view, nil
+}
+
+// Leaderboard returns the best finished attempts at the challenge of date,
+// along with viewerID's own placing when they finished it.
+func (s *DailyChallengeService) Leaderboard(ctx context.Context, date time.Time, viewerID uuid.UUID, limit int) (*DailyLeaderboard, error) {
+	if limit <= 0 || limit > maxDailyLeaderboardSize {
+		limit = maxDailyLeaderboardSize
+	}
+
+	var challenge models.DailyChallenge
+	if err := s.db.DB.WithContext(ctx).
+		Where("date = ?", date.UTC().Truncate(24*time.Hour)).
+		First(&challenge).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDailyChallengeNotFound
+		}
+		return nil, err
+	}
+
+	// Only attempts played through the server count; completions reported
+	// by clients before the challenge was run server-side have no room.
+	// Players kept off the leaderboards for cheating are left out.
+	finished := func() *gorm.DB {
+		return s.db.DB.WithContext(ctx).Table("challenge_completions AS c").
+			Joins("JOIN users u ON u.id = c.user_id").
+			Where("c.challenge_id = ? AND c.completed_at IS NOT NULL AND c.room_code <> ''", challenge.ID).
+			Where("u.shadow_excluded_at IS NULL")
+	}
+
+	board := &DailyLeaderboard{Challenge: &challenge, Entries: []DailyLeaderboardEntry{}}
+	if err := finished().
+		Select("c.user_id, u.username, c.score, c.completed_at").
+		Order("c.score DESC, c.completed_at ASC").
+		Limit(limit).
+		Scan(&board.Entries).Error; err != nil {
+		return nil, err
+	}
+	for i := range board.Entries {
+		board.Entries[i].Rank = i + 1
+		if board.Entries[i].UserID == viewerID {
+			board.Me = &board.Entries[i]
+		}
+	}
+
+	if board.Me != nil || viewerID == uuid.Nil {
+		return board, nil
+	}
+
+	var mine DailyLeaderboardEntry
+	if err := finished().
+		Select("c.user_id, u.username, c.score, c.completed_at").
+		Where("c.user_id = ?", viewerID).
+		Scan(&mine).Error; err != nil {
+		return nil, err
+	}
+	if mine.UserID == uuid.Nil {
+		return board, nil
+	}
+
+	var ahead int64
+	if err := finished().
+		Where("(c.score > ? OR (c.score = ? AND c.completed_at < ?))", mine.Score, mine.Score, mine.CompletedAt).
+		Count(&ahead).Error; err != nil {
+		return nil, err
+	}
+	mine.Rank = int(ahead) + 1
+	board.Me = &mine
+	return board, nil
+}
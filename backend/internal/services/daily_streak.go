@@ -0,0 +1,85 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"time"
+)
+
+const (
+	// streakFreezeEvery is how many days in a row earn a streak freeze.
+	streakFreezeEvery = 7
+	maxStreakFreezes  = 2
+)
+
+// StreakState is a user's daily challenge streak as of today.
+type StreakState struct {
+	Current     int        `json:"current"`
+	Longest     int        `json:"longest"`
+	Freezes     int        `json:"freezes"`
+	LastDay     *time.Time `json:"last_day,omitempty"`
+	PlayedToday bool       `json:"played_today"`
+	Timezone    string     `json:"timezone"`
+}
+
+// streakDay is the calendar day of t in loc, as midnight UTC.
+func streakDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// extendStreak counts a completed challenge on day. Days missed since the
+// last one are covered by freezes when there are enough of them; otherwise
+// the streak starts over. It reports whether a freeze was earned.
+func extendStreak(streak *models.DailyStreak, day time.Time) bool {
+	if streak.LastDay != nil {
+		gap := daysBetween(*streak.LastDay, day)
+		switch {
+		case gap <= 0:
+			// This day already counts
+			return false
+		case gap == 1:
+			streak.Current++
+		case gap-1 <= streak.Freezes:
+			streak.Freezes -= gap - 1
+			streak.FreezesUsed += gap - 1
+			streak.Current++
+		default:
+			streak.Current = 1
+		}
+	} else {
+		streak.Current = 1
+	}
+
+	streak.LastDay = &day
+	if streak.Current > streak.Longest {
+		streak.Longest = streak.Current
+	}
+	if streak.Current%streakFreezeEvery == 0 && streak.Freezes < maxStreakFreezes {
+		streak.Freezes++
+		return true
+	}
+	return false
+}
+
+// streakState shows streak as of today. A streak is still alive while the
+// days missed before today can be covered by freezes.
+func streakState(streak models.DailyStreak, today time.Time, loc *time.Location) StreakState {
+	state := StreakState{
+		Longest:  streak.Longest,
+		Freezes:  streak.Freezes,
+		LastDay:  streak.LastDay,
+		Timezone: loc.String(),
+	}
+	if streak.LastDay != nil {
+		gap := daysBetween(*streak.LastDay, today)
+		state.PlayedToday = gap == 0
+		if gap-1 <= streak.Freezes {
+			state.Current = streak.Current
+		}
+	}
+	return state
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"testing"
+	"time"
+)
+
+func day(n int) time.Time {
+	return time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+func TestStreakDayUsesTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	late := time.Date(2026, time.October, 1, 20, 0, 0, 0, time.UTC)
+	if got := streakDay(late, time.UTC); !got.Equal(day(0)) {
+		t.Fatalf("UTC day = %v", got)
+	}
+	if got := streakDay(late, tokyo); !got.Equal(day(1)) {
+		t.Fatalf("Tokyo day = %v", got)
+	}
+}
+
+func TestExtendStreakEarnsAndSpendsFreezes(t *testing.T) {
+	var streak models.DailyStreak
+	for i := 0; i < streakFreezeEvery; i++ {
+		earned := extendStreak(&streak, day(i))
+		if earned != (i == streakFreezeEvery-1) {
+			t.Fatalf("day %d: earned = %v", i, earned)
+		}
+	}
+	if streak.Current != 7 || streak.Freezes != 1 {
+		t.Fatalf("after a week: %+v", streak)
+	}
+
+	// Playing twice on one day counts once
+	extendStreak(&streak, day(6))
+	if streak.Current != 7 {
+		t.Fatalf("same day counted again: %d", streak.Current)
+	}
+
+	// One missed day is covered by the freeze
+	extendStreak(&streak, day(8))
+	if streak.Current != 8 || streak.Freezes != 0 || streak.FreezesUsed != 1 {
+		t.Fatalf("after a frozen day: %+v", streak)
+	}
+
+	// Without freezes a missed day starts over
+	extendStreak(&streak, day(10))
+	if streak.Current != 1 || streak.Longest != 8 {
+		t.Fatalf("after a missed day: %+v", streak)
+	}
+}
+
+func TestStreakStateShowsBrokenStreaks(t *testing.T) {
+	last := day(5)
+	streak := models.DailyStreak{Current: 4, Longest: 9, LastDay: &last, Freezes: 1}
+
+	if state := streakState(streak, day(5), time.UTC); state.Current != 4 || !state.PlayedToday {
+		t.Fatalf("played today: %+v", state)
+	}
+	if state := streakState(streak, day(7), time.UTC); state.Current != 4 || state.PlayedToday {
+		t.Fatalf("one missed day with a freeze: %+v", state)
+	}
+	if state := streakState(streak, day(8), time.UTC); state.Current != 0 || state.Longest != 9 {
+		t.Fatalf("two missed days with one freeze: %+v", state)
+	}
+}
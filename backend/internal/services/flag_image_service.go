@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	flagImageBaseURL = "https://flagcdn.com/w640/"
+	// maxFlagImageBytes bounds what one upstream flag may take in the cache.
+	maxFlagImageBytes = 1 << 20
+)
+
+var (
+	ErrFlagUnknown = errors.New("unknown flag")
+
+	flagCodePattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]{2,3})?$`)
+)
+
+// FlagImageService serves flag images from the server so round questions can
+// point at an opaque URL instead of naming the country. Images are fetched
+// once from the flag CDN and kept in memory.
+type FlagImageService struct {
+	client  *http.Client
+	baseURL string
+
+	mu    sync.RWMutex
+	cache map[string][]byte
+}
+
+func NewFlagImageService() *FlagImageService {
+	return &FlagImageService{
+		client:  &http.Client{Timeout: 10 * time.Second},
+		baseURL: flagImageBaseURL,
+		cache:   make(map[string][]byte),
+	}
+}
+
+// Image returns the PNG flag of countryCode.
+func (s *FlagImageService) Image(ctx context.Context, countryCode string) ([]byte, error) {
+	code := strings.ToLower(countryCode)
+	if !flagCodePattern.MatchString(code) {
+		return nil, ErrFlagUnknown
+	}
+
+	s.mu.RLock()
+	image, ok := s.cache[code]
+	s.mu.RUnlock()
+	if ok {
+		return image, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+code+".png", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrFlagUnknown
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("flag CDN returned %d for %s", resp.StatusCode, code)
+	}
+	image, err = io.ReadAll(io.LimitReader(resp.Body, maxFlagImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(image) > maxFlagImageBytes {
+		return nil, fmt.Errorf("flag %s is larger than %d bytes", code, maxFlagImageBytes)
+	}
+
+	s.mu.Lock()
+	s.cache[code] = image
+	s.mu.Unlock()
+	return image, nil
+}
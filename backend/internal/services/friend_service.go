@@ -0,0 +1,371 @@
+package services
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCannotFriendSelf      = errors.New("you cannot add yourself as a friend")
+	ErrAlreadyFriends        = errors.New("already friends")
+	ErrFriendRequestNotFound = errors.New("friend request not found")
+	ErrFriendNotFound        = errors.New("friend not found")
+	ErrPlayerBlocked         = errors.New("unblock this player first")
+)
+
+// Friend is an accepted friend with their current presence.
+type Friend struct {
+	PlayerCard
+	Since    time.Time `json:"since"`
+	Presence Presence  `json:"presence"`
+}
+
+// FriendRequest is a pending request, sent or received.
+type FriendRequest struct {
+	ID     uuid.UUID  `json:"id"`
+	Player PlayerCard `json:"player"`
+	SentAt time.Time  `json:"sent_at"`
+}
+
+// FriendList is everything the friends page shows.
+type FriendList struct {
+	Friends  []Friend        `json:"friends"`
+	Incoming []FriendRequest `json:"incoming"`
+	Outgoing []FriendRequest `json:"outgoing"`
+}
+
+// FriendService manages friend requests, friendships and blocks.
+type FriendService struct {
+	db       *database.GormDB
+	presence *PresenceService
+}
+
+func NewFriendService(db *database.GormDB, presence *PresenceService) *FriendService {
+	return &FriendService{db: db, presence: presence}
+}
+
+func (s *FriendService) findPlayer(db *gorm.DB, username string) (*models.User, error) {
+	var user models.User
+	err := db.Where("LOWER(username) = LOWER(?) AND anonymized_at IS NULL", strings.TrimSpace(username)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPlayerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// pairQuery matches the friendship between a and b, whoever sent it.
+func pairQuery(db *gorm.DB, a, b uuid.UUID) *gorm.DB {
+	return db.Where("((requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?))", a, b, b, a)
+}
+
+// blocked reports whether either player blocked the other, and whether it
+// was blockerID who did.
+func (s *FriendService) blocked(db *gorm.DB, blockerID, otherID uuid.UUID) (byMe bool, byThem bool, err error) {
+	var blocks []models.UserBlock
+	if err := db.Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)",
+		blockerID, otherID, otherID, blockerID).Find(&blocks).Error; err != nil {
+		return false, false, err
+	}
+	for _, block := range blocks {
+		if block.BlockerID == blockerID {
+			byMe = true
+		} else {
+			byThem = true
+		}
+	}
+	return byMe, byThem, nil
+}
+
+// Request sends a friend request from userID to username. A request the
+// other player already sent is accepted instead.
+func (s *FriendService) Request(ctx context.Context, userID uuid.UUID, username string) (*models.Friendship, error) {
+	tx := s.db.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	target, err := s.findPlayer(tx, username)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if target.ID == userID {
+		tx.Rollback()
+		return nil, ErrCannotFriendSelf
+	}
+
+	byMe, byThem, err := s.blocked(tx, userID, target.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if byMe {
+		tx.Rollback()
+		return nil, ErrPlayerBlocked
+	}
+	// Players who blocked someone are not revealed to them.
+	if byThem {
+		tx.Rollback()
+		return nil, ErrPlayerNotFound
+	}
+
+	var existing models.Friendship
+	err = pairQuery(tx, userID, target.ID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&existing).Error
+	switch {
+	case err == nil:
+		if existing.Status == models.FriendshipAccepted {
+			tx.Rollback()
+			return nil, ErrAlreadyFriends
+		}
+		if existing.RequesterID == userID {
+			tx.Rollback()
+			return &existing, nil
+		}
+		now := time.Now()
+		if err := tx.Model(&existing).Updates(map[string]interface{}{
+			"status":      models.FriendshipAccepted,
+			"accepted_at": now,
+		}).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		existing = models.Friendship{
+			RequesterID: userID,
+			AddresseeID: target.ID,
+			Status:      models.FriendshipPending,
+		}
+		if err := tx.Create(&existing).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	default:
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	if existing.Status == models.FriendshipPending {
+		var requester models.User
+		if err := s.db.DB.WithContext(ctx).First(&requester, "id = ?", userID).Error; err == nil {
+			notifyUser(target.ID, NotificationFriendRequest, map[string]interface{}{
+				"request_id": existing.ID,
+				"from":       playerCard(&requester),
+			})
+		}
+	}
+	return &existing, nil
+}
+
+// Accept accepts a request sent to userID.
+func (s *FriendService) Accept(ctx context.Context, userID, requestID uuid.UUID) error {
+	result := s.db.DB.WithContext(ctx).Model(&models.Friendship{}).
+		Where("id = ? AND addressee_id = ? AND status = ?", requestID, userID, models.FriendshipPending).
+		Updates(map[string]interface{}{
+			"status":      models.FriendshipAccepted,
+			"accepted_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFriendRequestNotFound
+	}
+	return nil
+}
+
+// Decline removes a pending request. The addressee declines it, the
+// requester cancels it.
+func (s *FriendService) Decline(ctx context.Context, userID, requestID uuid.UUID) error {
+	result := s.db.DB.WithContext(ctx).
+		Where("id = ? AND status = ? AND (addressee_id = ? OR requester_id = ?)", requestID, models.FriendshipPending, userID, userID).
+		Delete(&models.Friendship{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFriendRequestNotFound
+	}
+	return nil
+}
+
+// Remove ends the friendship between userID and friendID.
+func (s *FriendService) Remove(ctx context.Context, userID, friendID uuid.UUID) error {
+	result := pairQuery(s.db.DB.WithContext(ctx), userID, friendID).
+		Where("status = ?", models.FriendshipAccepted).
+		Delete(&models.Friendship{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFriendNotFound
+	}
+	return nil
+}
+
+// AreFriends reports whether a and b are friends. Blocking ends a
+// friendship, so friends never block each other.
+func (s *FriendService) AreFriends(ctx context.Context, a, b uuid.UUID) (bool, error) {
+	var count int64
+	if err := pairQuery(s.db.DB.WithContext(ctx).Model(&models.Friendship{}), a, b).
+		Where("status = ?", models.FriendshipAccepted).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Block blocks username for userID and ends any friendship or request
+// between them.
+func (s *FriendService) Block(ctx context.Context, userID uuid.UUID, username string) (*models.User, error) {
+	tx := s.db.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	target, err := s.findPlayer(tx, username)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if target.ID == userID {
+		tx.Rollback()
+		return nil, ErrCannotFriendSelf
+	}
+
+	if err := pairQuery(tx, userID, target.ID).Delete(&models.Friendship{}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.UserBlock{BlockerID: userID, BlockedID: target.ID}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// Unblock lifts a block userID placed on blockedID.
+func (s *FriendService) Unblock(ctx context.Context, userID, blockedID uuid.UUID) error {
+	result := s.db.DB.WithContext(ctx).
+		Where("blocker_id = ? AND blocked_id = ?", userID, blockedID).
+		Delete(&models.UserBlock{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPlayerNotFound
+	}
+	return nil
+}
+
+// Blocked lists the players userID blocked.
+func (s *FriendService) Blocked(ctx context.Context, userID uuid.UUID) ([]PlayerCard, error) {
+	var users []models.User
+	if err := s.db.DB.WithContext(ctx).
+		Joins("JOIN user_blocks ON user_blocks.blocked_id = users.id").
+		Where("user_blocks.blocker_id = ?", userID).
+		Order("user_blocks.created_at DESC").
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	cards := make([]PlayerCard, 0, len(users))
+	for i := range users {
+		cards = append(cards, playerCard(&users[i]))
+	}
+	return cards, nil
+}
+
+// List returns userID's friends with their presence and pending requests.
+func (s *FriendService) List(ctx context.Context, userID uuid.UUID) (*FriendList, error) {
+	db := s.db.DB.WithContext(ctx)
+
+	var friendships []models.Friendship
+	if err := db.Where("requester_id = ? OR addressee_id = ?", userID, userID).
+		Order("created_at DESC").
+		Find(&friendships).Error; err != nil {
+		return nil, err
+	}
+
+	otherIDs := make([]uuid.UUID, 0, len(friendships))
+	for _, f := range friendships {
+		if f.RequesterID == userID {
+			otherIDs = append(otherIDs, f.AddresseeID)
+		} else {
+			otherIDs = append(otherIDs, f.RequesterID)
+		}
+	}
+
+	players := make(map[uuid.UUID]*models.User, len(otherIDs))
+	if len(otherIDs) > 0 {
+		var users []models.User
+		if err := db.Where("id IN ? AND anonymized_at IS NULL", otherIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for i := range users {
+			players[users[i].ID] = &users[i]
+		}
+	}
+
+	list := &FriendList{Friends: []Friend{}, Incoming: []FriendRequest{}, Outgoing: []FriendRequest{}}
+	var friendIDs []uuid.UUID
+	for i, f := range friendships {
+		player, ok := players[otherIDs[i]]
+		if !ok {
+			continue
+		}
+		switch {
+		case f.Status == models.FriendshipAccepted:
+			since := f.CreatedAt
+			if f.AcceptedAt != nil {
+				since = *f.AcceptedAt
+			}
+			list.Friends = append(list.Friends, Friend{PlayerCard: playerCard(player), Since: since})
+			friendIDs = append(friendIDs, player.ID)
+		case f.RequesterID == userID:
+			list.Outgoing = append(list.Outgoing, FriendRequest{ID: f.ID, Player: playerCard(player), SentAt: f.CreatedAt})
+		default:
+			list.Incoming = append(list.Incoming, FriendRequest{ID: f.ID, Player: playerCard(player), SentAt: f.CreatedAt})
+		}
+	}
+
+	presence := map[uuid.UUID]Presence{}
+	if s.presence != nil && len(friendIDs) > 0 {
+		var err error
+		if presence, err = s.presence.Lookup(ctx, friendIDs); err != nil {
+			log.Printf("Failed to load friend presence: %v", err)
+			presence = map[uuid.UUID]Presence{}
+		}
+	}
+	for i := range list.Friends {
+		if p, ok := presence[list.Friends[i].ID]; ok {
+			list.Friends[i].Presence = p
+		} else {
+			list.Friends[i].Presence = Presence{Status: PresenceOffline}
+		}
+	}
+
+	return list, nil
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFriendRequestAcceptAndRemove(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	friends := NewFriendService(db, nil)
+	ana := createTestUser(t, db, "ana")
+	ben := createTestUser(t, db, "ben")
+
+	request, err := friends.Request(ctx, ana.ID, "BEN")
+	if err != nil {
+		t.Fatalf("Request: %v", err)
+	}
+	if request.Status != models.FriendshipPending || request.RequesterID != ana.ID || request.AddresseeID != ben.ID {
+		t.Fatalf("request = %+v, want pending from ana to ben", request)
+	}
+	again, err := friends.Request(ctx, ana.ID, "ben")
+	if err != nil || again.ID != request.ID {
+		t.Errorf("sending the request twice gave %+v, %v, want the same request", again, err)
+	}
+	if _, err := friends.Request(ctx, ana.ID, "ana"); !errors.Is(err, ErrCannotFriendSelf) {
+		t.Errorf("request to self: err = %v, want ErrCannotFriendSelf", err)
+	}
+
+	if err := friends.Accept(ctx, ana.ID, request.ID); !errors.Is(err, ErrFriendRequestNotFound) {
+		t.Errorf("requester accepting their own request: err = %v, want ErrFriendRequestNotFound", err)
+	}
+	if err := friends.Accept(ctx, ben.ID, request.ID); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if ok, err := friends.AreFriends(ctx, ben.ID, ana.ID); err != nil || !ok {
+		t.Fatalf("AreFriends after accepting = %v, %v", ok, err)
+	}
+	if _, err := friends.Request(ctx, ben.ID, "ana"); !errors.Is(err, ErrAlreadyFriends) {
+		t.Errorf("request between friends: err = %v, want ErrAlreadyFriends", err)
+	}
+
+	if err := friends.Remove(ctx, ben.ID, ana.ID); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if ok, _ := friends.AreFriends(ctx, ana.ID, ben.ID); ok {
+		t.Error("still friends after unfriending")
+	}
+	if err := friends.Remove(ctx, ana.ID, ben.ID); !errors.Is(err, ErrFriendNotFound) {
+		t.Errorf("unfriending twice: err = %v, want ErrFriendNotFound", err)
+	}
+}
+
+func TestCrossedFriendRequestsBecomeAFriendship(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	friends := NewFriendService(db, nil)
+	ana := createTestUser(t, db, "ana")
+	ben := createTestUser(t, db, "ben")
+
+	if _, err := friends.Request(ctx, ana.ID, "ben"); err != nil {
+		t.Fatalf("Request: %v", err)
+	}
+	back, err := friends.Request(ctx, ben.ID, "ana")
+	if err != nil {
+		t.Fatalf("Request back: %v", err)
+	}
+	if back.Status != models.FriendshipAccepted {
+		t.Errorf("request back = %+v, want the first request accepted", back)
+	}
+	var rows int64
+	db.DB.Model(&models.Friendship{}).Count(&rows)
+	if rows != 1 {
+		t.Errorf("%d friendships between the two, want 1", rows)
+	}
+}
+
+func TestBlockingEndsFriendshipsAndRefusesRequests(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	friends := NewFriendService(db, nil)
+	ana := createTestUser(t, db, "ana")
+	ben := createTestUser(t, db, "ben")
+	cal := createTestUser(t, db, "cal")
+
+	request, err := friends.Request(ctx, ana.ID, "ben")
+	if err != nil {
+		t.Fatalf("Request: %v", err)
+	}
+	if err := friends.Accept(ctx, ben.ID, request.ID); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if _, err := friends.Block(ctx, ben.ID, "ana"); err != nil {
+		t.Fatalf("Block: %v", err)
+	}
+	if ok, _ := friends.AreFriends(ctx, ana.ID, ben.ID); ok {
+		t.Error("still friends after a block")
+	}
+
+	// The blocked player is not told they were blocked
+	if _, err := friends.Request(ctx, ana.ID, "ben"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("request to a player who blocked you: err = %v, want ErrPlayerNotFound", err)
+	}
+	if _, err := friends.Request(ctx, ben.ID, "ana"); !errors.Is(err, ErrPlayerBlocked) {
+		t.Errorf("request to a player you blocked: err = %v, want ErrPlayerBlocked", err)
+	}
+
+	// Blocking someone who sent you a request takes it back, so it can no
+	// longer be accepted by either side.
+	pending, err := friends.Request(ctx, cal.ID, "ana")
+	if err != nil {
+		t.Fatalf("Request: %v", err)
+	}
+	if _, err := friends.Block(ctx, cal.ID, "ana"); err != nil {
+		t.Fatalf("Block: %v", err)
+	}
+	if err := friends.Accept(ctx, ana.ID, pending.ID); !errors.Is(err, ErrFriendRequestNotFound) {
+		t.Errorf("accepting a request from a player who then blocked you: err = %v, want ErrFriendRequestNotFound", err)
+	}
+	if ok, _ := friends.AreFriends(ctx, ana.ID, cal.ID); ok {
+		t.Error("became friends with a player who blocked you")
+	}
+
+	if err := friends.Unblock(ctx, ben.ID, ana.ID); err != nil {
+		t.Fatalf("Unblock: %v", err)
+	}
+	if _, err := friends.Request(ctx, ana.ID, "ben"); err != nil {
+		t.Errorf("request after the block was lifted: %v", err)
+	}
+}
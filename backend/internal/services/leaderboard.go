@@ -1,87 +1,617 @@
 package services
 
 import (
+	"briworld/internal/game"
+	"briworld/internal/models"
 	"context"
+	"errors"
 	"log"
+	"strings"
 	"time"
 
-	"briworld/internal/database"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// LeaderboardEntry represents a player on the leaderboard
+// Leaderboard kinds. Mode boards rank players by the points they scored in
+// one game mode. Region boards rank them by their accuracy on the countries
+// of one region.
+const (
+	BoardMode   = "mode"
+	BoardRegion = "region"
+)
+
+// Leaderboard windows. Days and weeks are UTC, and weeks start on Monday.
+const (
+	WindowDaily   = "daily"
+	WindowWeekly  = "weekly"
+	WindowSeason  = "season"
+	WindowAllTime = "all_time"
+)
+
+const (
+	// minRegionAnswers is how many answers about a region a player needs in
+	// a window before they are ranked on their accuracy there.
+	minRegionAnswers = 20
+	// leaderboardCacheTTL is how long a board cached in the store is kept
+	// before it is rebuilt from the database.
+	leaderboardCacheTTL = 10 * time.Minute
+	maxLeaderboardPage  = 100
+	// leaderboardNeighbours is how many players on either side of the viewer
+	// around_me shows.
+	leaderboardNeighbours = 5
+)
+
+var (
+	ErrUnknownLeaderboard = errors.New("unknown leaderboard")
+	ErrUnknownWindow      = errors.New("window must be daily, weekly, season or all_time")
+	ErrNoActiveSeason     = errors.New("no active season")
+)
+
+// LeaderboardRow is one player's totals on a board in a window. Boards are
+// ordered by Score, highest first, and then by user ID, highest first.
+type LeaderboardRow struct {
+	UserID   uuid.UUID
+	Points   int
+	Correct  int
+	Answered int
+	Score    float64
+}
+
+// LeaderboardStore keeps boards as sorted sets shared by every instance. A
+// board is read from the store once it has been filled from the database,
+// until it expires. Add only changes boards that are filled.
+type LeaderboardStore interface {
+	Ready(ctx context.Context, key string) (bool, error)
+	// Fill replaces the board at key with rows. On region boards, rows with
+	// fewer than minAnswered answers are counted but not ranked.
+	Fill(ctx context.Context, key, kind string, rows []LeaderboardRow, minAnswered int, ttl time.Duration) error
+	// Add adds row's points or answers to the player's totals on the board.
+	Add(ctx context.Context, key, kind string, row LeaderboardRow, minAnswered int) error
+	// Range returns the players ranked start to stop, counting from 0.
+	Range(ctx context.Context, key, kind string, start, stop int64) ([]LeaderboardRow, error)
+	// Position returns where userID is ranked, counting from 0.
+	Position(ctx context.Context, key string, userID uuid.UUID) (int64, bool, error)
+}
+
+// LeaderboardEntry is a player's place on a board. Mode boards fill in
+// Points, region boards Correct, Answered and Accuracy, in percent.
 type LeaderboardEntry struct {
-	Rank                 int       `json:"rank"`
-	Username             string    `json:"username"`
-	CountriesExplored    int       `json:"countries_explored"`
-	TotalSessionTime     int       `json:"total_session_time"`
-	MultiplayerSessions  int       `json:"multiplayer_sessions"`
-	LongestSession       int       `json:"longest_session"`
-	AverageSessionTime   int       `json:"average_session_time"`
-	LastSessionTime      time.Time `json:"last_session_time"`
-}
-
-// GetFreeRoamLeaderboard retrieves top players by countries explored
-func GetFreeRoamLeaderboard(limit int) ([]LeaderboardEntry, error) {
-	db := database.GetDB()
-	if db == nil {
+	Position  int64     `json:"position"`
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	AvatarURL string    `json:"avatar_url"`
+	Rank      string    `json:"rank"`
+	Points    int       `json:"points"`
+	Correct   int       `json:"correct,omitempty"`
+	Answered  int       `json:"answered,omitempty"`
+	Accuracy  float64   `json:"accuracy,omitempty"`
+}
+
+// Leaderboard is the top of a board along with where the viewer stands.
+type Leaderboard struct {
+	Board    string             `json:"board"`
+	Key      string             `json:"key"`
+	Window   string             `json:"window"`
+	From     *time.Time         `json:"from,omitempty"`
+	Entries  []LeaderboardEntry `json:"entries"`
+	Me       *LeaderboardEntry  `json:"me,omitempty"`
+	AroundMe []LeaderboardEntry `json:"around_me,omitempty"`
+}
+
+// leaderboardWindow is the stretch of days a board covers.
+type leaderboardWindow struct {
+	name string
+	from time.Time
+	// tag tells this window's board apart from the same window's earlier
+	// ones in store keys.
+	tag string
+}
+
+// LeaderboardService keeps per-mode and per-region leaderboards. Daily
+// totals live in the database, which boards can always be read from. When
+// a store is set, boards are cached in it and kept up to date as games are
+// played.
+type LeaderboardService struct {
+	db    *gorm.DB
+	store LeaderboardStore
+	// requireVerified keeps unverified accounts off the boards.
+	requireVerified bool
+}
+
+func NewLeaderboardService(db *gorm.DB, store LeaderboardStore, requireVerified bool) *LeaderboardService {
+	return &LeaderboardService{db: db, store: store, requireVerified: requireVerified}
+}
+
+// leaderboardBoard checks kind and key and returns the board's name. Game
+// modes are upper case and regions capitalised, as in the game data.
+func leaderboardBoard(kind, key string) (string, string, error) {
+	switch kind {
+	case BoardMode:
+		key = strings.ToUpper(key)
+		if !game.IsValidMode(key) || key == string(game.ModePractice) {
+			return "", "", ErrUnknownLeaderboard
+		}
+	case BoardRegion:
+		found := false
+		for _, region := range game.Regions {
+			if strings.EqualFold(region, key) {
+				key, found = region, true
+				break
+			}
+		}
+		if !found {
+			return "", "", ErrUnknownLeaderboard
+		}
+	default:
+		return "", "", ErrUnknownLeaderboard
+	}
+	return kind + ":" + key, key, nil
+}
+
+// leaderboardScore is what a row is ranked by: points on mode boards, and
+// accuracy in hundredths of a percent on region boards, with ties going to
+// whoever answered more correctly. The Redis store computes it the same way.
+func leaderboardScore(kind string, row LeaderboardRow) float64 {
+	if kind == BoardMode {
+		return float64(row.Points)
+	}
+	if row.Answered == 0 {
+		return 0
+	}
+	return float64(row.Correct*10000/row.Answered)*1e9 + float64(row.Correct)
+}
+
+// dayStart returns the start of t's UTC day.
+func dayStart(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// windowStart returns the first day of window at now. season is the active
+// season, needed only for the season window.
+func windowStart(window string, now time.Time, season *models.Season) (time.Time, error) {
+	today := dayStart(now)
+	switch window {
+	case WindowDaily:
+		return today, nil
+	case WindowWeekly:
+		return today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7)), nil
+	case WindowSeason:
+		if season == nil {
+			return time.Time{}, ErrNoActiveSeason
+		}
+		return dayStart(season.StartDate), nil
+	case WindowAllTime:
+		return time.Time{}, nil
+	}
+	return time.Time{}, ErrUnknownWindow
+}
+
+func (s *LeaderboardService) activeSeason(ctx context.Context) (*models.Season, error) {
+	var season models.Season
+	err := s.db.WithContext(ctx).Where("is_active = ?", true).First(&season).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
+	if err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func (s *LeaderboardService) window(ctx context.Context, name string, now time.Time) (leaderboardWindow, error) {
+	var season *models.Season
+	if name == WindowSeason {
+		var err error
+		if season, err = s.activeSeason(ctx); err != nil {
+			return leaderboardWindow{}, err
+		}
+	}
+	from, err := windowStart(name, now, season)
+	if err != nil {
+		return leaderboardWindow{}, err
+	}
+
+	w := leaderboardWindow{name: name, from: from}
+	switch name {
+	case WindowDaily, WindowWeekly:
+		w.tag = from.Format("2006-01-02")
+	case WindowSeason:
+		w.tag = season.ID.String()
+	}
+	return w, nil
+}
 
-	var entries []LeaderboardEntry
-	query := `
-		SELECT 
-			ROW_NUMBER() OVER (ORDER BY countries_explored DESC) as rank,
-			username,
-			COALESCE(countries_explored, 0) as countries_explored,
-			COALESCE(total_session_time, 0) as total_session_time,
-			COALESCE(multiplayer_sessions, 0) as multiplayer_sessions,
-			COALESCE(longest_session, 0) as longest_session,
-			CASE 
-				WHEN (multiplayer_sessions + 1) > 0 
-				THEN total_session_time / (multiplayer_sessions + 1)
-				ELSE 0
-			END as average_session_time,
-			COALESCE(last_session_time, NOW()) as last_session_time
-		FROM users
-		WHERE countries_explored > 0
-		ORDER BY countries_explored DESC
-		LIMIT ?
-	`
-
-	if err := db.DB.WithContext(ctx).Raw(query, limit).Scan(&entries).Error; err != nil {
-		log.Printf("Error fetching leaderboard: %v", err)
+func leaderboardKey(board string, w leaderboardWindow) string {
+	key := "leaderboard:" + board + ":" + w.name
+	if w.tag != "" {
+		key += ":" + w.tag
+	}
+	return key
+}
+
+// RecordGame adds the points each player scored in a game of mode to the
+// mode's boards. Practice games are not ranked.
+func (s *LeaderboardService) RecordGame(ctx context.Context, mode string, points map[uuid.UUID]int) error {
+	board, _, err := leaderboardBoard(BoardMode, mode)
+	if err != nil {
+		return nil
+	}
+	rows := make([]LeaderboardRow, 0, len(points))
+	for userID, p := range points {
+		rows = append(rows, LeaderboardRow{UserID: userID, Points: p})
+	}
+	return s.record(ctx, board, BoardMode, rows)
+}
+
+// RecordAnswers adds one answer about a country of region for each player,
+// correct or not.
+func (s *LeaderboardService) RecordAnswers(ctx context.Context, region string, answers map[uuid.UUID]bool) error {
+	board, _, err := leaderboardBoard(BoardRegion, region)
+	if err != nil {
+		return nil
+	}
+	rows := make([]LeaderboardRow, 0, len(answers))
+	for userID, correct := range answers {
+		row := LeaderboardRow{UserID: userID, Answered: 1}
+		if correct {
+			row.Correct = 1
+		}
+		rows = append(rows, row)
+	}
+	return s.record(ctx, board, BoardRegion, rows)
+}
+
+// record adds rows to today's totals and to every cached board they count
+// towards. Guests, and unverified accounts when verification is required,
+// are left out. Players kept off the leaderboards for cheating keep their
+// totals, but are not added to cached boards.
+func (s *LeaderboardService) record(ctx context.Context, board, kind string, rows []LeaderboardRow) error {
+	userIDs := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		if row.UserID != uuid.Nil {
+			userIDs = append(userIDs, row.UserID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+	db := s.db.WithContext(ctx)
+	eligible := db.Model(&models.User{}).Where("id IN ? AND anonymized_at IS NULL", userIDs)
+	if s.requireVerified {
+		eligible = eligible.Where("email_verified = ?", true)
+	}
+	var ranked []models.User
+	if err := eligible.Select("id", "shadow_excluded_at").Find(&ranked).Error; err != nil {
+		return err
+	}
+	isRanked := make(map[uuid.UUID]bool, len(ranked))
+	hidden := make(map[uuid.UUID]bool)
+	for _, user := range ranked {
+		isRanked[user.ID] = true
+		if user.ShadowExcludedAt != nil {
+			hidden[user.ID] = true
+		}
+	}
+
+	now := time.Now()
+	scores := make([]models.LeaderboardScore, 0, len(ranked))
+	kept := make([]LeaderboardRow, 0, len(ranked))
+	for _, row := range rows {
+		if !isRanked[row.UserID] {
+			continue
+		}
+		if !hidden[row.UserID] {
+			kept = append(kept, row)
+		}
+		scores = append(scores, models.LeaderboardScore{
+			UserID:   row.UserID,
+			Board:    board,
+			Day:      dayStart(now),
+			Points:   row.Points,
+			Correct:  row.Correct,
+			Answered: row.Answered,
+		})
+	}
+	if len(scores) == 0 {
+		return nil
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "board"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"points":   gorm.Expr("leaderboard_scores.points + EXCLUDED.points"),
+			"correct":  gorm.Expr("leaderboard_scores.correct + EXCLUDED.correct"),
+			"answered": gorm.Expr("leaderboard_scores.answered + EXCLUDED.answered"),
+		}),
+	}).Create(&scores).Error; err != nil {
+		return err
+	}
+
+	if s.store == nil {
+		return nil
+	}
+	for _, name := range []string{WindowDaily, WindowWeekly, WindowSeason, WindowAllTime} {
+		w, err := s.window(ctx, name, now)
+		if errors.Is(err, ErrNoActiveSeason) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		key := leaderboardKey(board, w)
+		for _, row := range kept {
+			if err := s.store.Add(ctx, key, kind, row, minRegionAnswers); err != nil {
+				// The board is rebuilt from the database when it expires.
+				log.Printf("Failed to add to leaderboard %s: %v", key, err)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Leaderboard returns the top limit players on the kind board for key in
+// window, and where viewerID stands on it when they are ranked.
+func (s *LeaderboardService) Leaderboard(ctx context.Context, kind, key, window string, viewerID uuid.UUID, limit int) (*Leaderboard, error) {
+	board, key, err := leaderboardBoard(kind, key)
+	if err != nil {
 		return nil, err
 	}
+	if window == "" {
+		window = WindowAllTime
+	}
+	w, err := s.window(ctx, window, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > maxLeaderboardPage {
+		limit = maxLeaderboardPage
+	}
+
+	result := &Leaderboard{Board: kind, Key: key, Window: window}
+	if !w.from.IsZero() {
+		result.From = &w.from
+	}
+
+	source := &sqlLeaderboard{db: s.db, board: board, kind: kind, from: w.from, requireVerified: s.requireVerified}
+	var reader leaderboardReader = source
+	if s.store != nil {
+		cached, err := s.cached(ctx, leaderboardKey(board, w), source)
+		if err != nil {
+			log.Printf("Reading leaderboard %s from the database: %v", board, err)
+		} else {
+			reader = cached
+		}
+	}
+
+	top, err := reader.rows(ctx, 0, int64(limit-1))
+	if err != nil {
+		return nil, err
+	}
+	if result.Entries, err = s.entries(ctx, kind, top, 0); err != nil {
+		return nil, err
+	}
+	if viewerID == uuid.Nil {
+		return result, nil
+	}
 
+	position, ok, err := reader.position(ctx, viewerID)
+	if err != nil || !ok {
+		return result, err
+	}
+	start := max(0, position-leaderboardNeighbours)
+	around, err := reader.rows(ctx, start, position+leaderboardNeighbours)
+	if err != nil {
+		return nil, err
+	}
+	if result.AroundMe, err = s.entries(ctx, kind, around, start); err != nil {
+		return nil, err
+	}
+	for i := range result.AroundMe {
+		if result.AroundMe[i].UserID == viewerID {
+			me := result.AroundMe[i]
+			result.Me = &me
+		}
+	}
+	return result, nil
+}
+
+// cached returns the store's copy of the board at key, filling it from the
+// database first if it is not there.
+func (s *LeaderboardService) cached(ctx context.Context, key string, source *sqlLeaderboard) (*storeLeaderboard, error) {
+	ready, err := s.store.Ready(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ready {
+		rows, err := source.totals(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.store.Fill(ctx, key, source.kind, rows, minRegionAnswers, leaderboardCacheTTL); err != nil {
+			return nil, err
+		}
+	}
+	return &storeLeaderboard{store: s.store, key: key, kind: source.kind}, nil
+}
+
+// entries turns rows ranked from start on into entries with who the
+// players are. Players who have since deleted their account are skipped.
+func (s *LeaderboardService) entries(ctx context.Context, kind string, rows []LeaderboardRow, start int64) ([]LeaderboardEntry, error) {
+	entries := make([]LeaderboardEntry, 0, len(rows))
+	if len(rows) == 0 {
+		return entries, nil
+	}
+	userIDs := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		userIDs[i] = row.UserID
+	}
+	var users []models.User
+	if err := s.db.WithContext(ctx).
+		Select("id", "username", "avatar_url", "rank").
+		Where("id IN ? AND anonymized_at IS NULL", userIDs).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	for i, row := range rows {
+		user, ok := byID[row.UserID]
+		if !ok {
+			continue
+		}
+		entry := LeaderboardEntry{
+			Position:  start + int64(i) + 1,
+			UserID:    row.UserID,
+			Username:  user.Username,
+			AvatarURL: user.AvatarURL,
+			Rank:      user.Rank,
+			Points:    row.Points,
+		}
+		if kind == BoardRegion {
+			entry.Correct = row.Correct
+			entry.Answered = row.Answered
+			if row.Answered > 0 {
+				entry.Accuracy = float64(row.Correct*10000/row.Answered) / 100
+			}
+		}
+		entries = append(entries, entry)
+	}
 	return entries, nil
 }
 
-// GetPlayerRank retrieves a specific player's rank
-func GetPlayerRank(username string) (int, error) {
-	db := database.GetDB()
-	if db == nil {
-		return 0, nil
+// leaderboardReader reads a ranked board, counting positions from 0.
+type leaderboardReader interface {
+	rows(ctx context.Context, start, stop int64) ([]LeaderboardRow, error)
+	position(ctx context.Context, userID uuid.UUID) (int64, bool, error)
+}
+
+// sqlLeaderboard ranks a board straight from the daily totals.
+type sqlLeaderboard struct {
+	db              *gorm.DB
+	board           string
+	kind            string
+	from            time.Time
+	requireVerified bool
+}
+
+// totalsSQL returns a query summing each player's days on the board from
+// s.from on.
+func (s *sqlLeaderboard) totalsSQL() string {
+	verified := ""
+	if s.requireVerified {
+		verified = " AND u.email_verified"
 	}
+	return `SELECT s.user_id, SUM(s.points) AS points, SUM(s.correct) AS correct, SUM(s.answered) AS answered
+		FROM leaderboard_scores s JOIN users u ON u.id = s.user_id
+		WHERE s.board = ? AND s.day >= ? AND u.anonymized_at IS NULL AND u.shadow_excluded_at IS NULL` + verified + `
+		GROUP BY s.user_id`
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// rankedSQL returns a query for the ranked board, with each player's
+// position counted from 1.
+func (s *sqlLeaderboard) rankedSQL() (string, []interface{}) {
+	score := "points"
+	where := "TRUE"
+	args := []interface{}{s.board, s.from}
+	if s.kind == BoardRegion {
+		score = "(correct * 10000 / answered) * 1000000000 + correct"
+		where = "answered >= ?"
+		args = append(args, minRegionAnswers)
+	}
+	return `WITH totals AS (` + s.totalsSQL() + `)
+		SELECT user_id, points, correct, answered,
+			ROW_NUMBER() OVER (ORDER BY ` + score + ` DESC, user_id DESC) AS position
+		FROM totals WHERE ` + where, args
+}
+
+// totals returns every player's totals, ranked or not.
+func (s *sqlLeaderboard) totals(ctx context.Context) ([]LeaderboardRow, error) {
+	var rows []LeaderboardRow
+	if err := s.db.WithContext(ctx).Raw(s.totalsSQL(), s.board, s.from).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].Score = leaderboardScore(s.kind, rows[i])
+	}
+	return rows, nil
+}
+
+func (s *sqlLeaderboard) rows(ctx context.Context, start, stop int64) ([]LeaderboardRow, error) {
+	ranked, args := s.rankedSQL()
+	var rows []LeaderboardRow
+	err := s.db.WithContext(ctx).
+		Raw(`SELECT user_id, points, correct, answered FROM (`+ranked+`) r
+			WHERE position BETWEEN ? AND ? ORDER BY position`, append(args, start+1, stop+1)...).
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (s *sqlLeaderboard) position(ctx context.Context, userID uuid.UUID) (int64, bool, error) {
+	ranked, args := s.rankedSQL()
+	var positions []int64
+	if err := s.db.WithContext(ctx).
+		Raw(`SELECT position FROM (`+ranked+`) r WHERE user_id = ?`, append(args, userID)...).
+		Scan(&positions).Error; err != nil {
+		return 0, false, err
+	}
+	if len(positions) == 0 {
+		return 0, false, nil
+	}
+	return positions[0] - 1, true, nil
+}
+
+// storeLeaderboard reads a board cached in the store.
+type storeLeaderboard struct {
+	store LeaderboardStore
+	key   string
+	kind  string
+}
+
+func (s *storeLeaderboard) rows(ctx context.Context, start, stop int64) ([]LeaderboardRow, error) {
+	return s.store.Range(ctx, s.key, s.kind, start, stop)
+}
 
-	var rank int
-	query := `
-		SELECT COUNT(*) + 1
-		FROM users
-		WHERE countries_explored > (
-			SELECT COALESCE(countries_explored, 0) FROM users WHERE username = ?
-		)
-	`
+func (s *storeLeaderboard) position(ctx context.Context, userID uuid.UUID) (int64, bool, error) {
+	return s.store.Position(ctx, s.key, userID)
+}
+
+// leaderboards is where gameplay records leaderboard results. Results are
+// dropped while it is nil.
+var leaderboards *LeaderboardService
+
+// UseLeaderboards makes s the service games record their results with.
+func UseLeaderboards(s *LeaderboardService) {
+	leaderboards = s
+}
 
-	if err := db.DB.WithContext(ctx).Raw(query, username).Scan(&rank).Error; err != nil {
-		log.Printf("Error fetching player rank: %v", err)
-		return 0, err
+// RecordLeaderboardGame adds a finished game's points to the shared service,
+// if any.
+func RecordLeaderboardGame(mode string, points map[uuid.UUID]int) {
+	if leaderboards == nil {
+		return
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := leaderboards.RecordGame(ctx, mode, points); err != nil {
+		log.Printf("Failed to record %s game on the leaderboards: %v", mode, err)
+	}
+}
 
-	return rank, nil
+// RecordLeaderboardAnswers adds a round's answers about a country to the
+// shared service, if any.
+func RecordLeaderboardAnswers(countryCode string, answers map[uuid.UUID]bool) {
+	if leaderboards == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	region := game.GetRegionForCountry(countryCode)
+	if err := leaderboards.RecordAnswers(ctx, region, answers); err != nil {
+		log.Printf("Failed to record answers about %s on the leaderboards: %v", region, err)
+	}
 }
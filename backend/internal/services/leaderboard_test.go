@@ -0,0 +1,185 @@
+package services
+
+import (
+	"briworld/internal/game"
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLeaderboardBoardNames(t *testing.T) {
+	cases := []struct {
+		kind, key, board string
+	}{
+		{BoardMode, "flag", "mode:FLAG"},
+		{BoardMode, "BORDER_LOGIC", "mode:BORDER_LOGIC"},
+		{BoardRegion, "europe", "region:Europe"},
+		{BoardRegion, "Oceania", "region:Oceania"},
+	}
+	for _, tc := range cases {
+		board, _, err := leaderboardBoard(tc.kind, tc.key)
+		if err != nil || board != tc.board {
+			t.Errorf("leaderboardBoard(%s, %s) = %q, %v, want %q", tc.kind, tc.key, board, err, tc.board)
+		}
+	}
+
+	for _, bad := range [][2]string{{BoardMode, "PRACTICE"}, {BoardMode, "CHESS"}, {BoardRegion, "World"}, {"rating", "FLAG"}} {
+		if _, _, err := leaderboardBoard(bad[0], bad[1]); !errors.Is(err, ErrUnknownLeaderboard) {
+			t.Errorf("leaderboardBoard(%s, %s) should be unknown, got %v", bad[0], bad[1], err)
+		}
+	}
+}
+
+func TestWindowStart(t *testing.T) {
+	// A Sunday evening, after midnight UTC in Tokyo
+	now := time.Date(2026, time.October, 18, 22, 30, 0, 0, time.UTC)
+	season := &models.Season{StartDate: time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC)}
+
+	want := map[string]time.Time{
+		WindowDaily:   time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC),
+		WindowWeekly:  time.Date(2026, time.October, 12, 0, 0, 0, 0, time.UTC),
+		WindowSeason:  time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC),
+		WindowAllTime: {},
+	}
+	for window, from := range want {
+		got, err := windowStart(window, now.In(time.FixedZone("JST", 9*3600)), season)
+		if err != nil || !got.Equal(from) {
+			t.Errorf("windowStart(%s) = %v, %v, want %v", window, got, err, from)
+		}
+	}
+
+	monday := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	if got, _ := windowStart(WindowWeekly, monday, nil); !got.Equal(monday) {
+		t.Errorf("a week starts on Monday, got %v", got)
+	}
+	if _, err := windowStart(WindowSeason, now, nil); !errors.Is(err, ErrNoActiveSeason) {
+		t.Errorf("season window without a season: %v", err)
+	}
+	if _, err := windowStart("monthly", now, nil); !errors.Is(err, ErrUnknownWindow) {
+		t.Errorf("unknown window: %v", err)
+	}
+}
+
+func TestRegionScoreRanksAccuracyThenCorrect(t *testing.T) {
+	sharp := LeaderboardRow{Correct: 19, Answered: 20}
+	busy := LeaderboardRow{Correct: 90, Answered: 100}
+	busier := LeaderboardRow{Correct: 180, Answered: 200}
+
+	if !(leaderboardScore(BoardRegion, sharp) > leaderboardScore(BoardRegion, busier)) {
+		t.Fatal("higher accuracy should rank first")
+	}
+	if !(leaderboardScore(BoardRegion, busier) > leaderboardScore(BoardRegion, busy)) {
+		t.Fatal("equal accuracy should go to whoever answered more correctly")
+	}
+	if got := leaderboardScore(BoardMode, LeaderboardRow{Points: 1250, Correct: 9, Answered: 10}); got != 1250 {
+		t.Fatalf("mode score = %v, want the points", got)
+	}
+}
+
+// boardUsers returns the usernames on entries, in order.
+func boardUsers(entries []LeaderboardEntry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Username
+	}
+	return names
+}
+
+func TestModeLeaderboardFromRecordedGames(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	boards := NewLeaderboardService(db.DB, nil, false)
+	ana := createTestUser(t, db, "ana")
+	ben := createTestUser(t, db, "ben")
+	cal := createTestUser(t, db, "cal")
+	cheat := createTestUser(t, db, "cheat")
+	db.DB.Model(cheat).Update("shadow_excluded_at", time.Now())
+
+	if err := boards.RecordGame(ctx, "flag", map[uuid.UUID]int{ana.ID: 300, ben.ID: 500, cal.ID: 100, cheat.ID: 900, uuid.Nil: 50}); err != nil {
+		t.Fatalf("RecordGame: %v", err)
+	}
+	if err := boards.RecordGame(ctx, "FLAG", map[uuid.UUID]int{ana.ID: 400}); err != nil {
+		t.Fatalf("RecordGame: %v", err)
+	}
+	// An old game counts all time, but not today
+	old := models.LeaderboardScore{UserID: ben.ID, Board: "mode:FLAG", Day: dayStart(time.Now()).AddDate(0, 0, -10), Points: 1000}
+	if err := db.DB.Create(&old).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var guests int64
+	db.DB.Model(&models.LeaderboardScore{}).Where("user_id = ?", uuid.Nil).Count(&guests)
+	if guests != 0 {
+		t.Error("a guest's points were recorded")
+	}
+
+	daily, err := boards.Leaderboard(ctx, BoardMode, "flag", WindowDaily, cal.ID, 2)
+	if err != nil {
+		t.Fatalf("daily board: %v", err)
+	}
+	if got := boardUsers(daily.Entries); len(got) != 2 || got[0] != "ana" || got[1] != "ben" {
+		t.Fatalf("daily top = %v, want [ana ben]", got)
+	}
+	if daily.Entries[0].Points != 700 || daily.Entries[1].Points != 500 {
+		t.Errorf("daily points = %d, %d, want 700, 500", daily.Entries[0].Points, daily.Entries[1].Points)
+	}
+	if daily.Me == nil || daily.Me.Position != 3 || daily.Me.Points != 100 {
+		t.Errorf("viewer = %+v, want cal third with 100", daily.Me)
+	}
+	if got := boardUsers(daily.AroundMe); len(got) != 3 || got[2] != "cal" {
+		t.Errorf("around the viewer = %v, want [ana ben cal]", got)
+	}
+
+	allTime, err := boards.Leaderboard(ctx, BoardMode, "flag", WindowAllTime, uuid.Nil, 10)
+	if err != nil {
+		t.Fatalf("all time board: %v", err)
+	}
+	if got := boardUsers(allTime.Entries); len(got) != 3 || got[0] != "ben" {
+		t.Fatalf("all time board = %v, want ben first and no cheat", got)
+	}
+	if allTime.Entries[0].Points != 1500 {
+		t.Errorf("all time leader has %d points, want 1500", allTime.Entries[0].Points)
+	}
+
+	if _, err := boards.Leaderboard(ctx, BoardMode, "flag", WindowSeason, uuid.Nil, 10); !errors.Is(err, ErrNoActiveSeason) {
+		t.Errorf("season board without a season: err = %v, want ErrNoActiveSeason", err)
+	}
+}
+
+func TestRegionLeaderboardRanksAccuracyOnceEnoughAnswers(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	boards := NewLeaderboardService(db.DB, nil, false)
+	ana := createTestUser(t, db, "ana")
+	ben := createTestUser(t, db, "ben")
+	cal := createTestUser(t, db, "cal")
+
+	answer := func(userID uuid.UUID, answered, correct int) {
+		for i := 0; i < answered; i++ {
+			if err := boards.RecordAnswers(ctx, game.RegionAfrica, map[uuid.UUID]bool{userID: i < correct}); err != nil {
+				t.Fatalf("RecordAnswers: %v", err)
+			}
+		}
+	}
+	answer(ana.ID, minRegionAnswers, 15)
+	answer(ben.ID, minRegionAnswers+5, 20)
+	answer(cal.ID, 5, 5)
+
+	board, err := boards.Leaderboard(ctx, BoardRegion, "africa", WindowWeekly, cal.ID, 10)
+	if err != nil {
+		t.Fatalf("region board: %v", err)
+	}
+	if got := boardUsers(board.Entries); len(got) != 2 || got[0] != "ben" || got[1] != "ana" {
+		t.Fatalf("region board = %v, want [ben ana] without cal's few answers", got)
+	}
+	if board.Entries[0].Accuracy != 80 || board.Entries[0].Correct != 20 || board.Entries[0].Answered != 25 {
+		t.Errorf("leader = %+v, want 20 of 25 at 80%%", board.Entries[0])
+	}
+	if board.Me != nil {
+		t.Errorf("viewer = %+v, want cal unranked", board.Me)
+	}
+}
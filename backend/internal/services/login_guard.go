@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LockoutStore keeps failed-login counters and account locks. The in-memory
+// store serves a single instance; the Redis store shares the state across
+// instances.
+type LockoutStore interface {
+	// AddFailure counts a failed login for key and returns the failures seen
+	// since the counter was last reset or went quiet for window.
+	AddFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	// LockedUntil returns the zero time when key is not locked.
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// LockoutPolicy controls how quickly repeated failures lock an account.
+type LockoutPolicy struct {
+	Threshold int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Window is how long a quiet account keeps its failure count.
+	Window time.Duration
+}
+
+// LoginGuard applies progressive per-account lockout: once an account has
+// Threshold consecutive failures it is locked for BaseDelay, and every
+// further failure doubles the lock up to MaxDelay.
+type LoginGuard struct {
+	store  LockoutStore
+	policy LockoutPolicy
+}
+
+func NewLoginGuard(store LockoutStore, policy LockoutPolicy) *LoginGuard {
+	if policy.Threshold <= 0 {
+		policy.Threshold = 5
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = time.Minute
+	}
+	if policy.MaxDelay < policy.BaseDelay {
+		policy.MaxDelay = policy.BaseDelay
+	}
+	if policy.Window <= 0 {
+		policy.Window = 24 * time.Hour
+	}
+	return &LoginGuard{store: store, policy: policy}
+}
+
+func lockoutKey(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
+
+// Check returns how long account stays locked, or zero when it may log in.
+func (g *LoginGuard) Check(ctx context.Context, account string) (time.Duration, error) {
+	until, err := g.store.LockedUntil(ctx, lockoutKey(account))
+	if err != nil {
+		return 0, err
+	}
+	if remaining := time.Until(until); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+// Fail records a failed login for account and returns the lock it triggered,
+// if any.
+func (g *LoginGuard) Fail(ctx context.Context, account string) (time.Duration, error) {
+	key := lockoutKey(account)
+	failures, err := g.store.AddFailure(ctx, key, g.policy.Window)
+	if err != nil {
+		return 0, err
+	}
+
+	delay := g.delayFor(failures)
+	if delay == 0 {
+		return 0, nil
+	}
+	if err := g.store.Lock(ctx, key, time.Now().Add(delay)); err != nil {
+		return 0, err
+	}
+	return delay, nil
+}
+
+// Succeed clears the failure count of account.
+func (g *LoginGuard) Succeed(ctx context.Context, account string) error {
+	return g.store.Reset(ctx, lockoutKey(account))
+}
+
+func (g *LoginGuard) delayFor(failures int) time.Duration {
+	if failures < g.policy.Threshold {
+		return 0
+	}
+	delay := g.policy.BaseDelay
+	for i := g.policy.Threshold; i < failures; i++ {
+		delay *= 2
+		if delay >= g.policy.MaxDelay {
+			return g.policy.MaxDelay
+		}
+	}
+	return delay
+}
+
+type memoryLockout struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// MemoryLockoutStore is a LockoutStore for a single server instance.
+type MemoryLockoutStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryLockout
+}
+
+func NewMemoryLockoutStore() *MemoryLockoutStore {
+	return &MemoryLockoutStore{entries: make(map[string]*memoryLockout)}
+}
+
+func (s *MemoryLockoutStore) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range s.entries {
+		if now.Sub(entry.lastFailure) > window && now.After(entry.lockedUntil) {
+			delete(s.entries, k)
+		}
+	}
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryLockout{}
+		s.entries[key] = entry
+	}
+	entry.failures++
+	entry.lastFailure = now
+	return entry.failures, nil
+}
+
+func (s *MemoryLockoutStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryLockout{lastFailure: time.Now()}
+		s.entries[key] = entry
+	}
+	entry.lockedUntil = until
+	return nil
+}
+
+func (s *MemoryLockoutStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok {
+		return entry.lockedUntil, nil
+	}
+	return time.Time{}, nil
+}
+
+func (s *MemoryLockoutStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestGuard() *LoginGuard {
+	return NewLoginGuard(NewMemoryLockoutStore(), LockoutPolicy{
+		Threshold: 3,
+		BaseDelay: time.Minute,
+		MaxDelay:  5 * time.Minute,
+	})
+}
+
+func TestLoginGuardProgressiveLockout(t *testing.T) {
+	guard := newTestGuard()
+	ctx := context.Background()
+
+	expected := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, want := range expected {
+		got, err := guard.Fail(ctx, "player@example.com")
+		if err != nil {
+			t.Fatalf("Fail() error: %v", err)
+		}
+		if got != want {
+			t.Errorf("failure %d: lock = %v, want %v", i+1, got, want)
+		}
+	}
+
+	remaining, _ := guard.Check(ctx, "Player@Example.com")
+	if remaining <= 4*time.Minute || remaining > 5*time.Minute {
+		t.Errorf("Expected the account to be locked for about 5m regardless of case, got %v", remaining)
+	}
+}
+
+func TestLoginGuardSucceedResets(t *testing.T) {
+	guard := newTestGuard()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		guard.Fail(ctx, "player@example.com")
+	}
+	if remaining, _ := guard.Check(ctx, "player@example.com"); remaining == 0 {
+		t.Fatal("Expected account to be locked")
+	}
+
+	if err := guard.Succeed(ctx, "player@example.com"); err != nil {
+		t.Fatalf("Succeed() error: %v", err)
+	}
+	if remaining, _ := guard.Check(ctx, "player@example.com"); remaining != 0 {
+		t.Errorf("Expected lock to be cleared, got %v", remaining)
+	}
+	if locked, _ := guard.Fail(ctx, "player@example.com"); locked != 0 {
+		t.Errorf("Expected failure count to restart, got lock %v", locked)
+	}
+}
+
+func TestLoginGuardIsPerAccount(t *testing.T) {
+	guard := newTestGuard()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		guard.Fail(ctx, "attacked@example.com")
+	}
+	if remaining, _ := guard.Check(ctx, "other@example.com"); remaining != 0 {
+		t.Errorf("Expected other accounts to stay unlocked, got %v", remaining)
+	}
+}
+
+func TestMemoryLockoutStoreForgetsQuietFailures(t *testing.T) {
+	store := NewMemoryLockoutStore()
+	ctx := context.Background()
+
+	store.AddFailure(ctx, "a", time.Hour)
+	store.entries["a"].lastFailure = time.Now().Add(-2 * time.Hour)
+
+	if failures, _ := store.AddFailure(ctx, "a", time.Hour); failures != 1 {
+		t.Errorf("Expected stale failures to be dropped, got %d", failures)
+	}
+}
@@ -5,8 +5,31 @@ import (
 	"briworld/internal/models"
 	"log"
 	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// seedAchievements are the achievements every server has. Each one is
+// unlocked by a rule in AchievementRules.
+var seedAchievements = []models.Achievement{
+	{Code: "FIRST_WIN", Name: "First Victory", Description: "Win your first game", Icon: "🏆", Reward: 50, Rarity: "COMMON"},
+	{Code: "WIN_STREAK_5", Name: "Hot Streak", Description: "Win 5 games in a row", Icon: "🔥", Reward: 100, Rarity: "RARE"},
+	{Code: "WIN_STREAK_10", Name: "Unstoppable", Description: "Win 10 games in a row", Icon: "⚡", Reward: 200, Rarity: "EPIC"},
+	{Code: "AFRICA_EXPERT", Name: "Africa Expert", Description: "Answer 50 African countries correctly", Icon: "🌍", Reward: 150, Rarity: "RARE"},
+	{Code: "ASIA_EXPERT", Name: "Asia Expert", Description: "Answer 50 Asian countries correctly", Icon: "🌏", Reward: 150, Rarity: "RARE"},
+	{Code: "EUROPE_EXPERT", Name: "Europe Expert", Description: "Answer 50 European countries correctly", Icon: "🌍", Reward: 150, Rarity: "RARE"},
+	{Code: "AMERICAS_EXPERT", Name: "Americas Expert", Description: "Answer 50 American countries correctly", Icon: "🌎", Reward: 150, Rarity: "RARE"},
+	{Code: "SPEED_DEMON", Name: "Speed Demon", Description: "Average response time under 5 seconds", Icon: "⚡", Reward: 100, Rarity: "RARE"},
+	{Code: "PERFECTIONIST", Name: "Perfectionist", Description: "Complete a game with 100% accuracy", Icon: "💯", Reward: 200, Rarity: "EPIC"},
+	{Code: "WORLD_TRAVELER", Name: "World Traveler", Description: "Master 100 countries", Icon: "✈️", Reward: 300, Rarity: "LEGENDARY"},
+	{Code: "DAILY_GRIND", Name: "Daily Grind", Description: "Complete 7 daily challenges in a row", Icon: "📅", Reward: 150, Rarity: "RARE"},
+	{Code: "DAILY_DEVOTEE", Name: "Daily Devotee", Description: "Complete 30 daily challenges in a row", Icon: "🗓️", Reward: 300, Rarity: "EPIC"},
+	{Code: "DAILY_LEGEND", Name: "Daily Legend", Description: "Complete 100 daily challenges in a row", Icon: "👑", Reward: 750, Rarity: "LEGENDARY"},
+	{Code: "RANKED_WARRIOR", Name: "Ranked Warrior", Description: "Reach Gold rank", Icon: "🥇", Reward: 250, Rarity: "EPIC"},
+	{Code: "DIAMOND_LEAGUE", Name: "Diamond League", Description: "Reach Diamond rank", Icon: "💎", Reward: 500, Rarity: "LEGENDARY"},
+}
+
 func SeedMetaSystem() {
 	db := database.GetDB()
 	if db == nil {
@@ -27,28 +50,21 @@ func SeedMetaSystem() {
 		log.Println("✓ Created Season 1")
 	}
 
-	// Seed achievements
-	achievements := []models.Achievement{
-		{Code: "FIRST_WIN", Name: "First Victory", Description: "Win your first game", Icon: "🏆", Reward: 50, Rarity: "COMMON"},
-		{Code: "WIN_STREAK_5", Name: "Hot Streak", Description: "Win 5 games in a row", Icon: "🔥", Reward: 100, Rarity: "RARE"},
-		{Code: "WIN_STREAK_10", Name: "Unstoppable", Description: "Win 10 games in a row", Icon: "⚡", Reward: 200, Rarity: "EPIC"},
-		{Code: "AFRICA_EXPERT", Name: "Africa Expert", Description: "Answer 50 African countries correctly", Icon: "🌍", Reward: 150, Rarity: "RARE"},
-		{Code: "ASIA_EXPERT", Name: "Asia Expert", Description: "Answer 50 Asian countries correctly", Icon: "🌏", Reward: 150, Rarity: "RARE"},
-		{Code: "EUROPE_EXPERT", Name: "Europe Expert", Description: "Answer 50 European countries correctly", Icon: "🌍", Reward: 150, Rarity: "RARE"},
-		{Code: "AMERICAS_EXPERT", Name: "Americas Expert", Description: "Answer 50 American countries correctly", Icon: "🌎", Reward: 150, Rarity: "RARE"},
-		{Code: "SPEED_DEMON", Name: "Speed Demon", Description: "Average response time under 5 seconds", Icon: "⚡", Reward: 100, Rarity: "RARE"},
-		{Code: "PERFECTIONIST", Name: "Perfectionist", Description: "Complete a game with 100% accuracy", Icon: "💯", Reward: 200, Rarity: "EPIC"},
-		{Code: "WORLD_TRAVELER", Name: "World Traveler", Description: "Master 100 countries", Icon: "✈️", Reward: 300, Rarity: "LEGENDARY"},
-		{Code: "DAILY_GRIND", Name: "Daily Grind", Description: "Complete 7 daily challenges in a row", Icon: "📅", Reward: 150, Rarity: "RARE"},
-		{Code: "RANKED_WARRIOR", Name: "Ranked Warrior", Description: "Reach Gold rank", Icon: "🥇", Reward: 250, Rarity: "EPIC"},
-		{Code: "DIAMOND_LEAGUE", Name: "Diamond League", Description: "Reach Diamond rank", Icon: "💎", Reward: 500, Rarity: "LEGENDARY"},
-	}
-
-	for _, ach := range achievements {
-		var existing models.Achievement
-		if err := db.DB.Where("code = ?", ach.Code).First(&existing).Error; err != nil {
-			db.DB.Create(&ach)
-		}
+	if _, err := seedMissingAchievements(db.DB); err != nil {
+		log.Printf("⚠️  Failed to seed achievements: %v", err)
+		return
 	}
 	log.Println("✓ Achievements seeded")
 }
+
+// seedMissingAchievements creates the seed achievements db does not have yet
+// and returns how many it created. Achievements already there keep any edits.
+func seedMissingAchievements(db *gorm.DB) (int64, error) {
+	achievements := make([]models.Achievement, len(seedAchievements))
+	copy(achievements, seedAchievements)
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoNothing: true,
+	}).Create(&achievements)
+	return result.RowsAffected, result.Error
+}
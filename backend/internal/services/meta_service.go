@@ -2,10 +2,17 @@ package services
 
 import (
 	"briworld/internal/database"
+	"briworld/internal/game"
 	"briworld/internal/models"
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
 	"math/rand"
+	"strings"
 	"time"
 	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
 )
 
 type MetaService struct{}
@@ -15,119 +22,116 @@ func NewMetaService() *MetaService {
 }
 
 // Daily Challenges
+
+// dailyChallengeModes are the modes the daily challenge rotates through: the
+// ones played question by question on a fixed set of countries.
+var dailyChallengeModes = []string{"FLAG", "SILHOUETTE", "BORDER_LOGIC"}
+
+// dailyChallengeDifficulties sets the round time limit of each difficulty.
+var dailyChallengeDifficulties = []struct {
+	Name      string
+	TimeLimit int
+}{
+	{"EASY", 20},
+	{"MEDIUM", 15},
+	{"HARD", 10},
+}
+
+const dailyChallengeRounds = 10
+
 func (s *MetaService) GetTodayChallenge() (*models.DailyChallenge, error) {
 	db := database.GetDB()
 	if db == nil {
 		return nil, nil
 	}
-	today := time.Now().Truncate(24 * time.Hour)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
 	
 	var challenge models.DailyChallenge
 	if err := db.DB.Where("date = ?", today).First(&challenge).Error; err != nil {
 		return s.GenerateDailyChallenge(today)
 	}
+	// Challenges generated before they were played on fixed countries. The
+	// first instance to fill one in wins.
+	if challenge.Countries == "" {
+		seeded := challenge
+		if err := seedDailyChallenge(&seeded); err != nil {
+			return nil, err
+		}
+		if err := db.DB.Model(&models.DailyChallenge{}).
+			Where("id = ? AND (countries IS NULL OR countries = '')", challenge.ID).
+			Updates(map[string]interface{}{
+				"game_mode":        seeded.GameMode,
+				"difficulty":       seeded.Difficulty,
+				"rounds":           seeded.Rounds,
+				"round_time_limit": seeded.RoundTimeLimit,
+				"countries":        seeded.Countries,
+				"reward":           seeded.Reward,
+			}).Error; err != nil {
+			return nil, err
+		}
+		if err := db.DB.First(&challenge, "id = ?", challenge.ID).Error; err != nil {
+			return nil, err
+		}
+	}
 	return &challenge, nil
 }
 
+// GenerateDailyChallenge creates the challenge of date. When instances race,
+// the first one stored is the one everybody plays.
 func (s *MetaService) GenerateDailyChallenge(date time.Time) (*models.DailyChallenge, error) {
-	// Emoji mode is intentionally disabled for now, but the implementation stays in the codebase.
-	modes := []string{"FLAG", "WORLD_MAP", "CAPITAL_RUSH", "SILHOUETTE", "TEAM_BATTLE"}
-	difficulties := []string{"EASY", "MEDIUM", "HARD"}
-	
-	challenge := &models.DailyChallenge{
-		Date:       date,
-		GameMode:   modes[rand.Intn(len(modes))],
-		Difficulty: difficulties[rand.Intn(len(difficulties))],
-		Reward:     100 + rand.Intn(200),
-	}
-	
-	db := database.GetDB()
-	if err := db.DB.Create(challenge).Error; err != nil {
+	challenge := &models.DailyChallenge{Date: date}
+	if err := seedDailyChallenge(challenge); err != nil {
 		return nil, err
 	}
-	return challenge, nil
-}
-
-func (s *MetaService) CompleteChallenge(userID, challengeID uuid.UUID, score int) error {
-	completion := &models.ChallengeCompletion{
-		UserID:      userID,
-		ChallengeID: challengeID,
-		Score:       score,
-		CompletedAt: time.Now(),
-	}
 	
 	db := database.GetDB()
-	return db.DB.Create(completion).Error
-}
-
-// Ranked Seasons
-func (s *MetaService) GetActiveSeason() (*models.Season, error) {
-	db := database.GetDB()
-	if db == nil {
-		return nil, nil
+	if err := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(challenge).Error; err != nil {
+		return nil, err
 	}
-	var season models.Season
-	if err := db.DB.Where("is_active = ?", true).First(&season).Error; err != nil {
+	// Another instance may have created it first
+	var stored models.DailyChallenge
+	if err := db.DB.Where("date = ?", date).First(&stored).Error; err != nil {
 		return nil, err
 	}
-	return &season, nil
+	return &stored, nil
 }
 
-func (s *MetaService) GetUserRank(userID, seasonID uuid.UUID) (*models.SeasonRank, error) {
-	db := database.GetDB()
-	if db == nil {
-		return &models.SeasonRank{Rank: "BRONZE", Points: 0}, nil
-	}
-	var rank models.SeasonRank
-	if err := db.DB.Where("user_id = ? AND season_id = ?", userID, seasonID).First(&rank).Error; err != nil {
-		rank = models.SeasonRank{
-			UserID:   userID,
-			SeasonID: seasonID,
-			Rank:     "BRONZE",
-			Points:   0,
-		}
-		db.DB.Create(&rank)
+// seedDailyChallenge fills in a challenge from a fresh random seed.
+func seedDailyChallenge(challenge *models.DailyChallenge) error {
+	seed, err := newDailyChallengeSeed()
+	if err != nil {
+		return err
 	}
-	return &rank, nil
-}
+	rng := rand.New(rand.NewSource(seed))
 
-func (s *MetaService) UpdateRank(userID, seasonID uuid.UUID, points int, won bool) error {
-	db := database.GetDB()
-	rank, _ := s.GetUserRank(userID, seasonID)
-	
-	rank.Points += points
-	if won {
-		rank.Wins++
-	} else {
-		rank.Losses++
+	mode := dailyChallengeModes[rng.Intn(len(dailyChallengeModes))]
+	difficulty := dailyChallengeDifficulties[rng.Intn(len(dailyChallengeDifficulties))]
+	countries := game.Data.PickCountries(mode, dailyChallengeRounds, seed)
+	if len(countries) < dailyChallengeRounds {
+		return errors.New("not enough countries for the daily challenge")
 	}
-	
-	rank.Rank = s.CalculateRank(rank.Points)
-	return db.DB.Save(rank).Error
+
+	challenge.GameMode = mode
+	challenge.Difficulty = difficulty.Name
+	challenge.Rounds = dailyChallengeRounds
+	challenge.RoundTimeLimit = difficulty.TimeLimit
+	challenge.Countries = strings.Join(countries, ",")
+	challenge.Reward = 100 + rng.Intn(200)
+	return nil
 }
 
-func (s *MetaService) CalculateRank(points int) string {
-	if points >= 2000 { return "DIAMOND" }
-	if points >= 1500 { return "PLATINUM" }
-	if points >= 1000 { return "GOLD" }
-	if points >= 500 { return "SILVER" }
-	return "BRONZE"
+// newDailyChallengeSeed draws the seed a challenge is picked from. It comes
+// from crypto/rand so nobody can work out a future challenge's countries.
+func newDailyChallengeSeed() (int64, error) {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b[:]) >> 1), nil
 }
 
 // Country Mastery
-func (s *MetaService) UpdateMastery(userID uuid.UUID, countryCode string, correct bool) error {
-	db := database.GetDB()
-	var mastery models.CountryMastery
-	
-	if err := db.DB.Where("user_id = ? AND country_code = ?", userID, countryCode).First(&mastery).Error; err != nil {
-		mastery = models.CountryMastery{
-			UserID:      userID,
-			CountryCode: countryCode,
-			Level:       1,
-			XP:          0,
-		}
-	}
-	
+func applyMasteryResult(mastery *models.CountryMastery, correct bool) {
 	if correct {
 		mastery.Correct++
 		mastery.XP += 10
@@ -135,11 +139,19 @@ func (s *MetaService) UpdateMastery(userID uuid.UUID, countryCode string, correc
 		mastery.Incorrect++
 		mastery.XP += 2
 	}
-	
-	mastery.Level = 1 + (mastery.XP / 100)
+
+	mastery.Level = masteryLevel(mastery.XP)
 	mastery.UpdatedAt = time.Now()
-	
-	return db.DB.Save(&mastery).Error
+
+	quality := qualityMissed
+	if correct {
+		quality = qualityCorrect
+	}
+	scheduleReview(mastery, quality, mastery.UpdatedAt)
+}
+
+func masteryLevel(xp int) int {
+	return 1 + (xp / 100)
 }
 
 func (s *MetaService) GetUserMastery(userID uuid.UUID) ([]models.CountryMastery, error) {
@@ -156,53 +168,32 @@ func (s *MetaService) GetUserMastery(userID uuid.UUID) ([]models.CountryMastery,
 }
 
 // Achievements
-func (s *MetaService) CheckAchievements(userID uuid.UUID, stats map[string]int) []string {
-	unlocked := []string{}
-	
-	achievements := map[string]func(int) bool{
-		"FIRST_WIN":      func(v int) bool { return stats["wins"] >= 1 },
-		"WIN_STREAK_5":   func(v int) bool { return stats["streak"] >= 5 },
-		"AFRICA_EXPERT":  func(v int) bool { return stats["africa_correct"] >= 50 },
-		"SPEED_DEMON":    func(v int) bool { return stats["avg_time"] < 5000 },
-		"PERFECTIONIST": func(v int) bool { return stats["perfect_games"] >= 1 },
-	}
-	
-	for code, check := range achievements {
-		if check(0) && !s.HasAchievement(userID, code) {
-			s.UnlockAchievement(userID, code)
-			unlocked = append(unlocked, code)
-		}
-	}
-	
-	return unlocked
-}
 
+// HasAchievement reports whether userID has unlocked the achievement code.
 func (s *MetaService) HasAchievement(userID uuid.UUID, code string) bool {
 	db := database.GetDB()
-	var achievement models.Achievement
-	if err := db.DB.Where("code = ?", code).First(&achievement).Error; err != nil {
-		return false
-	}
-	
-	var userAch models.UserAchievement
-	err := db.DB.Where("user_id = ? AND achievement_id = ?", userID, achievement.ID).First(&userAch).Error
-	return err == nil
+	var count int64
+	err := db.DB.Table("user_achievements").
+		Joins("JOIN achievements ON achievements.id = user_achievements.achievement_id").
+		Where("user_achievements.user_id = ? AND achievements.code = ?", userID, code).
+		Count(&count).Error
+	return err == nil && count > 0
 }
 
+// UnlockAchievement gives userID the achievement code and its reward and
+// tells them about it, the same way a rule of the achievement engine does.
+// It does nothing when they already have it.
 func (s *MetaService) UnlockAchievement(userID uuid.UUID, code string) error {
-	db := database.GetDB()
-	var achievement models.Achievement
-	if err := db.DB.Where("code = ?", code).First(&achievement).Error; err != nil {
-		return err
+	engine := achievements
+	if engine == nil {
+		engine = &AchievementEngine{db: database.GetDB()}
 	}
-	
-	userAch := &models.UserAchievement{
-		UserID:        userID,
-		AchievementID: achievement.ID,
-		UnlockedAt:    time.Now(),
+	achievement, err := engine.unlock(context.Background(), userID, code)
+	if err != nil || achievement == nil {
+		return err
 	}
-	
-	return db.DB.Create(userAch).Error
+	engine.announce(userID, achievement)
+	return nil
 }
 
 func (s *MetaService) GetUserAchievements(userID uuid.UUID) ([]models.Achievement, error) {
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification types pushed to the notification socket.
+const (
+	NotificationFriendInvite        = "friend_invite"
+	NotificationFriendRequest       = "friend_request"
+	NotificationAchievementUnlocked = "achievement_unlocked"
+	NotificationSeasonEnded         = "season_ended"
+	NotificationDailyChallengeReset = "daily_challenge_reset"
+	NotificationMatchFound          = "match_found"
+	NotificationPartyInvite         = "party_invite"
+	NotificationTournamentMatch     = "tournament_match"
+)
+
+const maxNotificationPage = 100
+
+// NotificationPusher delivers notifications to open notification sockets.
+type NotificationPusher interface {
+	// PushToUser reaches userID on every server instance.
+	PushToUser(userID uuid.UUID, notification *models.Notification)
+	// PushToAll reaches everyone connected to this instance.
+	PushToAll(notification *models.Notification)
+}
+
+// NotificationService stores notifications in users' inboxes and pushes them
+// to whoever is connected.
+type NotificationService struct {
+	db     *database.GormDB
+	pusher NotificationPusher
+}
+
+func NewNotificationService(db *database.GormDB, pusher NotificationPusher) *NotificationService {
+	return &NotificationService{db: db, pusher: pusher}
+}
+
+// notifications is the service other services notify users through. It
+// stays nil until UseNotifications is called, and nothing is sent then.
+var notifications *NotificationService
+
+// UseNotifications makes s the service that MetaService, SeasonService and
+// FriendService send notifications through.
+func UseNotifications(s *NotificationService) {
+	notifications = s
+}
+
+// notifyUser sends a notification through the shared service, if any.
+func notifyUser(userID uuid.UUID, kind string, data interface{}) {
+	if notifications == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := notifications.Notify(ctx, userID, kind, data); err != nil {
+		log.Printf("Failed to send %s notification: %v", kind, err)
+	}
+}
+
+func encodeNotificationData(data interface{}) string {
+	if data == nil {
+		return "{}"
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to encode notification data: %v", err)
+		return "{}"
+	}
+	return string(encoded)
+}
+
+// Notify stores a notification for userID and pushes it live.
+func (s *NotificationService) Notify(ctx context.Context, userID uuid.UUID, kind string, data interface{}) (*models.Notification, error) {
+	notification := &models.Notification{
+		UserID: userID,
+		Type:   kind,
+		Data:   encodeNotificationData(data),
+	}
+	if err := s.db.DB.WithContext(ctx).Create(notification).Error; err != nil {
+		return nil, err
+	}
+	if s.pusher != nil {
+		s.pusher.PushToUser(userID, notification)
+	}
+	return notification, nil
+}
+
+// NotifyMany stores the same notification for each of userIDs and pushes it
+// live.
+func (s *NotificationService) NotifyMany(ctx context.Context, userIDs []uuid.UUID, kind string, data interface{}) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	encoded := encodeNotificationData(data)
+	batch := make([]models.Notification, len(userIDs))
+	for i, id := range userIDs {
+		batch[i] = models.Notification{UserID: id, Type: kind, Data: encoded}
+	}
+	if err := s.db.DB.WithContext(ctx).CreateInBatches(batch, 500).Error; err != nil {
+		return err
+	}
+	if s.pusher != nil {
+		for i := range batch {
+			s.pusher.PushToUser(batch[i].UserID, &batch[i])
+		}
+	}
+	return nil
+}
+
+// Broadcast pushes a notification to everyone connected to this instance
+// without storing it. It suits events anyone can look up again, such as a
+// new daily challenge.
+func (s *NotificationService) Broadcast(kind string, data interface{}) {
+	if s.pusher == nil {
+		return
+	}
+	s.pusher.PushToAll(&models.Notification{
+		Type:      kind,
+		Data:      encodeNotificationData(data),
+		CreatedAt: time.Now(),
+	})
+}
+
+// List returns userID's notifications, newest first, created before before
+// when it is set.
+func (s *NotificationService) List(ctx context.Context, userID uuid.UUID, unreadOnly bool, before time.Time, limit int) ([]models.Notification, error) {
+	if limit <= 0 || limit > maxNotificationPage {
+		limit = 50
+	}
+
+	query := s.db.DB.WithContext(ctx).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if !before.IsZero() {
+		query = query.Where("created_at < ?", before)
+	}
+
+	notifications := []models.Notification{}
+	if err := query.Order("created_at DESC").Limit(limit).Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// UnreadCount returns how many of userID's notifications are unread.
+func (s *NotificationService) UnreadCount(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := s.db.DB.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks the given notifications of userID as read, or all of them
+// when ids is empty. It returns how many changed.
+func (s *NotificationService) MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error) {
+	query := s.db.DB.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	result := query.Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// StartDailyResetJob announces the new daily challenge to connected players
+// right after each UTC midnight, when GetTodayChallenge rolls over.
+func (s *NotificationService) StartDailyResetJob(meta *MetaService) {
+	go func() {
+		for {
+			now := time.Now().UTC()
+			next := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+			time.Sleep(next.Sub(now) + 5*time.Second)
+
+			challenge, err := meta.GetTodayChallenge()
+			if err != nil || challenge == nil {
+				log.Printf("Daily challenge reset: no challenge for today: %v", err)
+				continue
+			}
+			s.Broadcast(NotificationDailyChallengeReset, dailyChallengeNotice(challenge))
+		}
+	}()
+}
+
+// dailyChallengeNotice is the part of a daily challenge announced on reset.
+func dailyChallengeNotice(challenge *models.DailyChallenge) map[string]interface{} {
+	return map[string]interface{}{
+		"challenge_id": challenge.ID,
+		"date":         challenge.Date,
+		"game_mode":    challenge.GameMode,
+		"difficulty":   challenge.Difficulty,
+		"reward":       challenge.Reward,
+	}
+}
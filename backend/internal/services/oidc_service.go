@@ -0,0 +1,499 @@
+package services
+
+import (
+	"briworld/internal/config"
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"briworld/internal/oidc"
+	"briworld/internal/utils"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	ErrOIDCProviderUnknown       = errors.New("unknown identity provider")
+	ErrOIDCStateInvalid          = errors.New("login request expired or invalid")
+	ErrOIDCEmailUnverified       = errors.New("provider did not return a verified email")
+	ErrIdentityLinkedToOther     = errors.New("this account is already linked to another user")
+	ErrOIDCProviderAlreadyLinked = errors.New("a different account from this provider is already linked")
+	ErrIdentityNotLinked         = errors.New("provider is not linked")
+	ErrLastLoginMethod           = errors.New("cannot unlink the only way to sign in")
+)
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// OIDCResult is the outcome of a completed authorization code flow.
+type OIDCResult struct {
+	User *models.User
+	// Linked is true when the flow attached a provider to a signed-in user
+	// rather than signing someone in.
+	Linked  bool
+	Created bool
+}
+
+// OIDCPendingLogin is a login or link waiting for the provider to redirect
+// back.
+type OIDCPendingLogin struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	// BindingHash is the hash of the nonce the starting browser holds in a
+	// cookie, so a callback from any other browser is refused.
+	BindingHash string    `json:"binding_hash"`
+	LinkUser    uuid.UUID `json:"link_user"`
+}
+
+// OIDCStateStore keeps pending logins, keyed by their state parameter, until
+// the provider redirects back.
+type OIDCStateStore interface {
+	Save(ctx context.Context, state string, pending OIDCPendingLogin, ttl time.Duration) error
+	// Take removes the login saved under state. ok is false when there is
+	// none or it has expired.
+	Take(ctx context.Context, state string) (pending OIDCPendingLogin, ok bool, err error)
+}
+
+// OIDCService runs social login against the configured OIDC providers and
+// manages the identities linked to each user.
+type OIDCService struct {
+	db        *database.GormDB
+	providers map[string]*oidc.Provider
+	states    OIDCStateStore
+}
+
+func NewOIDCService(db *database.GormDB, cfg config.OIDCConfig, states OIDCStateStore) *OIDCService {
+	providers := make(map[string]*oidc.Provider, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		providers[p.Name] = oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			DiscoveryURL: p.DiscoveryURL,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  cfg.RedirectBaseURL + "/api/v2/auth/oidc/" + p.Name + "/callback",
+			Scopes:       p.Scopes,
+		})
+	}
+	return &OIDCService{
+		db:        db,
+		providers: providers,
+		states:    states,
+	}
+}
+
+// Providers returns the names of the configured providers.
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OIDCStart is a flow ready to send the browser to the provider. Binding must
+// be handed back to Complete by the same browser, normally through a cookie.
+type OIDCStart struct {
+	AuthURL string
+	Binding string
+	TTL     time.Duration
+}
+
+// BeginLogin starts a sign-in with providerName.
+func (s *OIDCService) BeginLogin(ctx context.Context, providerName string) (*OIDCStart, error) {
+	return s.begin(ctx, providerName, uuid.Nil)
+}
+
+// BeginLink starts attaching providerName to userID.
+func (s *OIDCService) BeginLink(ctx context.Context, providerName string, userID uuid.UUID) (*OIDCStart, error) {
+	return s.begin(ctx, providerName, userID)
+}
+
+func (s *OIDCService) begin(ctx context.Context, providerName string, linkUser uuid.UUID) (*OIDCStart, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrOIDCProviderUnknown
+	}
+
+	state, err := oidc.NewNonce()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return nil, err
+	}
+	binding, err := oidc.NewNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oidc.S256Challenge(verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.states.Save(ctx, state, OIDCPendingLogin{
+		Provider:    providerName,
+		Verifier:    verifier,
+		Nonce:       nonce,
+		BindingHash: utils.HashToken(binding),
+		LinkUser:    linkUser,
+	}, oidcStateTTL); err != nil {
+		return nil, err
+	}
+
+	return &OIDCStart{AuthURL: authURL, Binding: binding, TTL: oidcStateTTL}, nil
+}
+
+func (s *OIDCService) takeState(ctx context.Context, state, providerName, binding string) (OIDCPendingLogin, error) {
+	if state == "" || binding == "" {
+		return OIDCPendingLogin{}, ErrOIDCStateInvalid
+	}
+	pending, ok, err := s.states.Take(ctx, state)
+	if err != nil {
+		return OIDCPendingLogin{}, err
+	}
+	if !ok || pending.Provider != providerName {
+		return OIDCPendingLogin{}, ErrOIDCStateInvalid
+	}
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(binding)), []byte(pending.BindingHash)) != 1 {
+		return OIDCPendingLogin{}, ErrOIDCStateInvalid
+	}
+	return pending, nil
+}
+
+// Complete finishes the flow started by BeginLogin or BeginLink. binding is
+// the value from OIDCStart that the browser brought back.
+func (s *OIDCService) Complete(ctx context.Context, providerName, code, state, binding string) (*OIDCResult, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrOIDCProviderUnknown
+	}
+
+	pending, err := s.takeState(ctx, state, providerName, binding)
+	if err != nil {
+		return nil, err
+	}
+	if code == "" {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	token, err := provider.Exchange(ctx, code, pending.Verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, pending.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending.LinkUser != uuid.Nil {
+		user, err := s.link(ctx, pending.LinkUser, providerName, claims)
+		if err != nil {
+			return nil, err
+		}
+		return &OIDCResult{User: user, Linked: true}, nil
+	}
+
+	return s.signIn(ctx, providerName, claims)
+}
+
+func (s *OIDCService) link(ctx context.Context, userID uuid.UUID, providerName string, claims *oidc.Claims) (*models.User, error) {
+	db := s.db.DB.WithContext(ctx)
+
+	var user models.User
+	if err := db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	var existing models.UserIdentity
+	err := db.Where("provider = ? AND subject = ?", providerName, claims.Subject).First(&existing).Error
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, ErrIdentityLinkedToOther
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err := checkProviderFree(db, userID, providerName, claims.Subject); err != nil {
+		return nil, err
+	}
+
+	identity := &models.UserIdentity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	if err := db.Create(identity).Error; err != nil {
+		return nil, fmt.Errorf("failed to link provider: %w", err)
+	}
+
+	return &user, nil
+}
+
+// checkProviderFree fails with ErrOIDCProviderAlreadyLinked when userID
+// already has an identity at providerName other than subject.
+func checkProviderFree(db *gorm.DB, userID uuid.UUID, providerName, subject string) error {
+	var identities []models.UserIdentity
+	if err := db.Where("user_id = ? AND provider = ?", userID, providerName).Find(&identities).Error; err != nil {
+		return err
+	}
+	return providerLinkConflict(identities, providerName, subject)
+}
+
+// providerLinkConflict checks subject against the identities a user already
+// has: each user can link one account per provider.
+func providerLinkConflict(identities []models.UserIdentity, providerName, subject string) error {
+	for _, identity := range identities {
+		if identity.Provider == providerName && identity.Subject != subject {
+			return ErrOIDCProviderAlreadyLinked
+		}
+	}
+	return nil
+}
+
+// reclaimUnverified hands an unverified account over to whoever proved they
+// own its email: the password and two-factor setup are dropped and every
+// device is signed out.
+func reclaimUnverified(tx *gorm.DB, user *models.User) error {
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"password_hash":        "",
+		"two_factor_enabled":   false,
+		"two_factor_secret":    "",
+		"two_factor_last_step": 0,
+		"email_verified":       true,
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Session{}).
+		Where("user_id = ? AND is_guest = ? AND revoked_at IS NULL", user.ID, false).
+		Update("revoked_at", time.Now()).Error
+}
+
+// signIn resolves the identity to a user: an existing link wins, then an
+// account with the same email is linked, otherwise a new account is created.
+// An account whose email was never verified may have been registered by
+// someone else ahead of the real owner, so it is reset before linking.
+func (s *OIDCService) signIn(ctx context.Context, providerName string, claims *oidc.Claims) (*OIDCResult, error) {
+	tx := s.db.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var identity models.UserIdentity
+	err := tx.Where("provider = ? AND subject = ?", providerName, claims.Subject).First(&identity).Error
+	if err == nil {
+		var user models.User
+		if err := tx.First(&user, "id = ?", identity.UserID).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("linked user not found")
+		}
+		if err := tx.Commit().Error; err != nil {
+			return nil, err
+		}
+		return &OIDCResult{User: &user}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		tx.Rollback()
+		return nil, ErrOIDCEmailUnverified
+	}
+
+	result := &OIDCResult{}
+	var user models.User
+	err = tx.Where("LOWER(email) = LOWER(?)", claims.Email).First(&user).Error
+	switch {
+	case err == nil:
+		if err := checkProviderFree(tx, user.ID, providerName, claims.Subject); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if !user.EmailVerified {
+			if err := reclaimUnverified(tx, &user); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		username, err := uniqueUsername(tx, claims)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		user = models.User{
+			Username:      username,
+			Email:         claims.Email,
+			EmailVerified: true,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+		result.Created = true
+	default:
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Create(&models.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to link provider: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	result.User = &user
+	return result, nil
+}
+
+// ListIdentities returns the providers linked to userID.
+func (s *OIDCService) ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := s.db.DB.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+// Unlink removes a provider from userID, refusing when it is the account's
+// only way to sign in.
+func (s *OIDCService) Unlink(ctx context.Context, userID uuid.UUID, providerName string) error {
+	db := s.db.DB.WithContext(ctx)
+
+	var user models.User
+	if err := db.First(&user, "id = ?", userID).Error; err != nil {
+		return fmt.Errorf("user not found")
+	}
+
+	var count int64
+	if err := db.Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if user.PasswordHash == "" && count <= 1 {
+		return ErrLastLoginMethod
+	}
+
+	result := db.Where("user_id = ? AND provider = ?", userID, providerName).Delete(&models.UserIdentity{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdentityNotLinked
+	}
+	return nil
+}
+
+// uniqueUsername derives a free username from the provider profile.
+func uniqueUsername(tx *gorm.DB, claims *oidc.Claims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = claims.Name
+	}
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = usernameInvalidChars.ReplaceAllString(strings.ReplaceAll(base, " ", "_"), "")
+	if len(base) > 24 {
+		base = base[:24]
+	}
+	if len(base) < 3 {
+		base = "player"
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 10; attempt++ {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s%04d", base, n.Int64())
+	}
+
+	return "", fmt.Errorf("could not find a free username")
+}
+
+// MemoryOIDCStateStore is an OIDCStateStore for a single server instance.
+type MemoryOIDCStateStore struct {
+	mu      sync.Mutex
+	pending map[string]memoryOIDCState
+}
+
+type memoryOIDCState struct {
+	login   OIDCPendingLogin
+	expires time.Time
+}
+
+func NewMemoryOIDCStateStore() *MemoryOIDCStateStore {
+	return &MemoryOIDCStateStore{pending: make(map[string]memoryOIDCState)}
+}
+
+func (s *MemoryOIDCStateStore) Save(ctx context.Context, state string, pending OIDCPendingLogin, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range s.pending {
+		if now.After(entry.expires) {
+			delete(s.pending, key)
+		}
+	}
+	s.pending[state] = memoryOIDCState{login: pending, expires: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryOIDCStateStore) Take(ctx context.Context, state string) (OIDCPendingLogin, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.pending[state]
+	if !ok {
+		return OIDCPendingLogin{}, false, nil
+	}
+	delete(s.pending, state)
+	if time.Now().After(entry.expires) {
+		return OIDCPendingLogin{}, false, nil
+	}
+	return entry.login, true, nil
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"errors"
+	"testing"
+)
+
+func TestProviderLinkConflict(t *testing.T) {
+	identities := []models.UserIdentity{
+		{Provider: "google", Subject: "google-1"},
+		{Provider: "github", Subject: "github-1"},
+	}
+
+	if err := providerLinkConflict(nil, "google", "google-2"); err != nil {
+		t.Errorf("no identities: err = %v", err)
+	}
+	if err := providerLinkConflict(identities, "google", "google-1"); err != nil {
+		t.Errorf("same subject: err = %v", err)
+	}
+	if err := providerLinkConflict(identities, "discord", "discord-1"); err != nil {
+		t.Errorf("new provider: err = %v", err)
+	}
+	if err := providerLinkConflict(identities, "google", "google-2"); !errors.Is(err, ErrOIDCProviderAlreadyLinked) {
+		t.Errorf("second account at the same provider: err = %v, want ErrOIDCProviderAlreadyLinked", err)
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	playerRatingHistoryLimit = 50
+	playerTopMasteryLimit    = 10
+	playerRecentMatchesLimit = 10
+	maxPlayerSearchPageSize  = 50
+	maxPlayerSearchQuery     = 32
+	// playerSearchSimilarity is the pg_trgm similarity a username needs to
+	// match a search fuzzily.
+	playerSearchSimilarity = 0.3
+)
+
+var (
+	ErrPlayerNotFound     = errors.New("player not found")
+	ErrInvalidSearchQuery = errors.New("search query must be 1 to 32 characters")
+)
+
+// PlayerCard is what anyone can see of a player, private or not.
+type PlayerCard struct {
+	ID                  uuid.UUID `json:"id"`
+	Username            string    `json:"username"`
+	AvatarURL           string    `json:"avatar_url,omitempty"`
+	AvatarType          string    `json:"avatar_type,omitempty"`
+	AvatarDecorationURL string    `json:"avatar_decoration_url,omitempty"`
+	Rating              int       `json:"rating"`
+	Rank                string    `json:"rank"`
+	RankTier            int       `json:"rank_tier"`
+	Private             bool      `json:"private"`
+}
+
+// PlayerAchievement is an unlocked achievement shown on a public profile.
+type PlayerAchievement struct {
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Icon        string    `json:"icon"`
+	Rarity      string    `json:"rarity"`
+	UnlockedAt  time.Time `json:"unlocked_at"`
+}
+
+// PlayerProfile is the public profile page of a player. Everything except
+// the card is left empty when the profile is private.
+type PlayerProfile struct {
+	PlayerCard
+	BannerURL                string                     `json:"banner_url,omitempty"`
+	BannerType               string                     `json:"banner_type,omitempty"`
+	AvatarDecorationPreset   string                     `json:"avatar_decoration_preset,omitempty"`
+	ProfileCustomizationJSON string                     `json:"profile_customization_json,omitempty"`
+	Decorations              []models.ProfileDecoration `json:"decorations,omitempty"`
+	TotalPoints              int                        `json:"total_points"`
+	TotalGames               int                        `json:"total_games"`
+	TotalWins                int                        `json:"total_wins"`
+	LongestWinStreak         int                        `json:"longest_win_streak"`
+	CountriesMastered        int                        `json:"countries_mastered"`
+	MemberSince              time.Time                  `json:"member_since"`
+	RatingHistory            []models.RankHistory       `json:"rating_history,omitempty"`
+	TopMastery               []models.CountryMastery    `json:"top_mastery,omitempty"`
+	Achievements             []PlayerAchievement        `json:"achievements,omitempty"`
+	RecentMatches            []models.MatchResult       `json:"recent_matches,omitempty"`
+}
+
+// PlayerSearchPage is one page of search results. Fuzzy is false when
+// pg_trgm is missing and the search only matched substrings.
+type PlayerSearchPage struct {
+	Players  []PlayerCard `json:"players"`
+	Total    int64        `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+	Fuzzy    bool         `json:"fuzzy"`
+}
+
+// PlayerService serves public player profiles and player search.
+type PlayerService struct {
+	db *database.GormDB
+	// fuzzy is true when pg_trgm is installed.
+	fuzzy bool
+}
+
+func NewPlayerService(db *database.GormDB) *PlayerService {
+	var fuzzy bool
+	if err := db.DB.Raw(`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')`).Scan(&fuzzy).Error; err != nil {
+		log.Printf("⚠️  Failed to check for pg_trgm: %v", err)
+	}
+	if !fuzzy {
+		log.Printf("⚠️  pg_trgm is not installed, player search only matches substrings")
+	}
+	return &PlayerService{db: db, fuzzy: fuzzy}
+}
+
+func playerCard(user *models.User) PlayerCard {
+	return PlayerCard{
+		ID:                  user.ID,
+		Username:            user.Username,
+		AvatarURL:           user.AvatarURL,
+		AvatarType:          user.AvatarType,
+		AvatarDecorationURL: user.AvatarDecorationURL,
+		Rating:              user.Rating,
+		Rank:                user.Rank,
+		RankTier:            user.RankTier,
+		Private:             user.ProfileVisibility == models.ProfileVisibilityPrivate,
+	}
+}
+
+// Card returns the card of the player with userID.
+func (s *PlayerService) Card(ctx context.Context, userID uuid.UUID) (*PlayerCard, error) {
+	var user models.User
+	err := s.db.DB.WithContext(ctx).Where("id = ? AND anonymized_at IS NULL", userID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPlayerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	card := playerCard(&user)
+	return &card, nil
+}
+
+// Profile returns the profile of username as viewerID sees it. Players always
+// see their own profile in full.
+func (s *PlayerService) Profile(ctx context.Context, username string, viewerID uuid.UUID) (*PlayerProfile, error) {
+	db := s.db.DB.WithContext(ctx)
+
+	var user models.User
+	err := db.Where("LOWER(username) = LOWER(?) AND anonymized_at IS NULL", strings.TrimSpace(username)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPlayerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &PlayerProfile{PlayerCard: playerCard(&user)}
+	if profile.Private && user.ID != viewerID {
+		return profile, nil
+	}
+
+	profile.BannerURL = user.BannerURL
+	profile.BannerType = user.BannerType
+	profile.AvatarDecorationPreset = user.AvatarDecorationPreset
+	profile.ProfileCustomizationJSON = user.ProfileCustomizationJSON
+	profile.TotalPoints = user.TotalPoints
+	profile.TotalGames = user.TotalGames
+	profile.TotalWins = user.TotalWins
+	profile.LongestWinStreak = user.LongestWinStreak
+	profile.CountriesMastered = user.CountriesMastered
+	profile.MemberSince = user.CreatedAt
+
+	if err := db.Where("user_id = ? AND enabled = ?", user.ID, true).
+		Order("z_index ASC, created_at ASC").
+		Find(&profile.Decorations).Error; err != nil {
+		return nil, err
+	}
+
+	// Latest entries, returned oldest first for charting.
+	if err := db.Where("user_id = ?", user.ID).
+		Order("created_at DESC").
+		Limit(playerRatingHistoryLimit).
+		Find(&profile.RatingHistory).Error; err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(profile.RatingHistory)-1; i < j; i, j = i+1, j-1 {
+		profile.RatingHistory[i], profile.RatingHistory[j] = profile.RatingHistory[j], profile.RatingHistory[i]
+	}
+
+	if err := db.Where("user_id = ?", user.ID).
+		Order("level DESC, xp DESC, country_code ASC").
+		Limit(playerTopMasteryLimit).
+		Find(&profile.TopMastery).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Table("user_achievements").
+		Select("achievements.code, achievements.name, achievements.description, achievements.icon, achievements.rarity, user_achievements.unlocked_at").
+		Joins("JOIN achievements ON achievements.id = user_achievements.achievement_id").
+		Where("user_achievements.user_id = ?", user.ID).
+		Order("user_achievements.unlocked_at DESC").
+		Scan(&profile.Achievements).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("user_id = ?", user.ID).
+		Order("played_at DESC").
+		Limit(playerRecentMatchesLimit).
+		Find(&profile.RecentMatches).Error; err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+// escapeLike escapes the LIKE wildcards in a user-supplied pattern.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// searchScope filters users to the matches of query and returns the order to
+// rank them in: the exact match, then prefix matches, then the rest.
+func (s *PlayerService) searchScope(db *gorm.DB, query string) (*gorm.DB, clause.Expr) {
+	prefix := strings.ToLower(escapeLike(query)) + "%"
+	scope := db.Model(&models.User{}).Where("anonymized_at IS NULL")
+	if s.fuzzy {
+		scope = scope.Where("(LOWER(username) LIKE ? OR similarity(username, ?) >= ?)", prefix, query, playerSearchSimilarity)
+		return scope, gorm.Expr("(LOWER(username) = LOWER(?)) DESC, (LOWER(username) LIKE ?) DESC, similarity(username, ?) DESC, username ASC", query, prefix, query)
+	}
+	scope = scope.Where("LOWER(username) LIKE ?", "%"+strings.ToLower(escapeLike(query))+"%")
+	return scope, gorm.Expr("(LOWER(username) = LOWER(?)) DESC, (LOWER(username) LIKE ?) DESC, username ASC", query, prefix)
+}
+
+// Search finds players whose username starts with query or, when pg_trgm is
+// installed, resembles it. Without pg_trgm any username containing query
+// matches. Exact and prefix matches come first.
+func (s *PlayerService) Search(ctx context.Context, query string, page, pageSize int) (*PlayerSearchPage, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || len([]rune(query)) > maxPlayerSearchQuery {
+		return nil, ErrInvalidSearchQuery
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > maxPlayerSearchPageSize {
+		pageSize = 20
+	}
+
+	scope, order := s.searchScope(s.db.DB.WithContext(ctx), query)
+	result := &PlayerSearchPage{Players: []PlayerCard{}, Page: page, PageSize: pageSize, Fuzzy: s.fuzzy}
+	if err := scope.Session(&gorm.Session{}).Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var users []models.User
+	if err := scope.
+		Select("id, username, avatar_url, avatar_type, avatar_decoration_url, rating, rank, rank_tier, profile_visibility").
+		Order(clause.OrderBy{Expression: order}).
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for i := range users {
+		result.Players = append(result.Players, playerCard(&users[i]))
+	}
+	return result, nil
+}
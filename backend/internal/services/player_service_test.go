@@ -0,0 +1,71 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestEscapeLike(t *testing.T) {
+	if got := escapeLike(`50%_off\`); got != `50\%\_off\\` {
+		t.Fatalf("escapeLike = %q", got)
+	}
+}
+
+func TestPlayerCardPrivacy(t *testing.T) {
+	user := &models.User{Username: "ada", ProfileVisibility: models.ProfileVisibilityPrivate}
+	if !playerCard(user).Private {
+		t.Fatal("a private profile should produce a private card")
+	}
+
+	user.ProfileVisibility = models.ProfileVisibilityPublic
+	if playerCard(user).Private {
+		t.Fatal("a public profile should produce a public card")
+	}
+}
+
+// searchSQL renders the query Search runs, without a database connection.
+func searchSQL(t *testing.T, fuzzy bool, query string) string {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := &PlayerService{fuzzy: fuzzy}
+	return db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		scope, order := service.searchScope(tx, query)
+		var users []models.User
+		return scope.Order(clause.OrderBy{Expression: order}).Find(&users)
+	})
+}
+
+func TestPlayerSearchFallbackRanksPrefixBeforeSubstring(t *testing.T) {
+	sql := searchSQL(t, false, "Ada_")
+
+	if !strings.Contains(sql, `LOWER(username) LIKE '%ada\_%'`) {
+		t.Errorf("fallback should match substrings: %s", sql)
+	}
+	if strings.Contains(sql, "similarity(") {
+		t.Errorf("fallback must not use pg_trgm: %s", sql)
+	}
+	exact := strings.Index(sql, "(LOWER(username) = LOWER('Ada_')) DESC")
+	prefix := strings.Index(sql, `(LOWER(username) LIKE 'ada\_%') DESC`)
+	name := strings.Index(sql, "username ASC")
+	if exact < 0 || prefix < exact || name < prefix {
+		t.Errorf("want exact, then prefix matches, then the rest by name: %s", sql)
+	}
+}
+
+func TestPlayerSearchFuzzyRanksBySimilarity(t *testing.T) {
+	sql := searchSQL(t, true, "ada")
+
+	prefix := strings.Index(sql, "(LOWER(username) LIKE 'ada%') DESC")
+	similar := strings.Index(sql, "similarity(username, 'ada') DESC")
+	if prefix < 0 || similar < prefix {
+		t.Errorf("want prefix matches before similar names: %s", sql)
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// SM-2 review schedule of a country's mastery.
+const (
+	defaultEase = 2.5
+	minEase     = 1.3
+
+	// Answer qualities on SM-2's 0-5 scale. Anything below 3 is a lapse.
+	qualityCorrect = 4
+	qualityMissed  = 1
+)
+
+// Weights practice games draw countries by.
+const (
+	newCountryWeight  = 1.0
+	notDueWeight      = 0.1
+	dueWeight         = 3.0
+	maxOverdueWeight  = 7.0
+	easePenaltyWeight = 4.0
+)
+
+// scheduleReview updates the SM-2 schedule of mastery after an answer of
+// quality at now. A lapse starts the country over and makes it harder; a
+// recall pushes its next review further out.
+func scheduleReview(mastery *models.CountryMastery, quality int, now time.Time) {
+	if mastery.Ease == 0 {
+		mastery.Ease = defaultEase
+	}
+
+	if quality < 3 {
+		mastery.Repetitions = 0
+		mastery.IntervalDays = 1
+	} else {
+		switch mastery.Repetitions {
+		case 0:
+			mastery.IntervalDays = 1
+		case 1:
+			mastery.IntervalDays = 6
+		default:
+			mastery.IntervalDays = int(math.Round(float64(mastery.IntervalDays) * mastery.Ease))
+		}
+		mastery.Repetitions++
+	}
+
+	miss := float64(5 - quality)
+	mastery.Ease = math.Max(minEase, mastery.Ease+0.1-miss*(0.08+miss*0.02))
+
+	due := now.AddDate(0, 0, mastery.IntervalDays)
+	mastery.DueAt = &due
+}
+
+// practiceWeight is how likely a practice game is to ask about a country.
+// Due countries outweigh new ones, and more so the longer they are overdue
+// and the more often they were missed; countries not due yet rarely come up.
+func practiceWeight(mastery *models.CountryMastery, now time.Time) float64 {
+	if mastery == nil {
+		return newCountryWeight
+	}
+	if mastery.DueAt != nil && mastery.DueAt.After(now) {
+		return notDueWeight
+	}
+
+	weight := dueWeight
+	if mastery.DueAt != nil {
+		weight += math.Min(now.Sub(*mastery.DueAt).Hours()/24, maxOverdueWeight)
+	}
+	ease := mastery.Ease
+	if ease == 0 {
+		ease = defaultEase
+	}
+	return weight + (defaultEase-ease)*easePenaltyWeight
+}
+
+// pickPracticeCountry draws one of candidates by practiceWeight. Countries
+// already asked in the game are skipped until every candidate has been.
+func pickPracticeCountry(masteries map[string]*models.CountryMastery, candidates []string, used map[string]bool, now time.Time, rng *rand.Rand) string {
+	pool := make([]string, 0, len(candidates))
+	for _, code := range candidates {
+		if !used[code] {
+			pool = append(pool, code)
+		}
+	}
+	if len(pool) == 0 {
+		pool = candidates
+	}
+	if len(pool) == 0 {
+		return ""
+	}
+
+	weights := make([]float64, len(pool))
+	total := 0.0
+	for i, code := range pool {
+		weights[i] = practiceWeight(masteries[code], now)
+		total += weights[i]
+	}
+
+	pick := rng.Float64() * total
+	for i, weight := range weights {
+		if pick < weight {
+			return pool[i]
+		}
+		pick -= weight
+	}
+	return pool[len(pool)-1]
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestScheduleReviewSpacesOutRecalls(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	var mastery models.CountryMastery
+
+	for i, want := range []int{1, 6, 15} {
+		scheduleReview(&mastery, qualityCorrect, now)
+		if mastery.IntervalDays != want {
+			t.Fatalf("recall %d: interval = %d, want %d", i+1, mastery.IntervalDays, want)
+		}
+	}
+	if !mastery.DueAt.Equal(now.AddDate(0, 0, 15)) {
+		t.Fatalf("due at %v", mastery.DueAt)
+	}
+
+	ease := mastery.Ease
+	scheduleReview(&mastery, qualityMissed, now)
+	if mastery.IntervalDays != 1 || mastery.Repetitions != 0 {
+		t.Fatalf("lapse: interval = %d, repetitions = %d", mastery.IntervalDays, mastery.Repetitions)
+	}
+	if mastery.Ease >= ease {
+		t.Fatalf("lapse should lower ease from %.2f, got %.2f", ease, mastery.Ease)
+	}
+
+	for i := 0; i < 10; i++ {
+		scheduleReview(&mastery, qualityMissed, now)
+	}
+	if mastery.Ease != minEase {
+		t.Fatalf("ease = %.2f, want floor %.2f", mastery.Ease, minEase)
+	}
+}
+
+func TestPracticeWeightFavoursWeakAndOverdue(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	later := now.AddDate(0, 0, 3)
+	dueToday := now
+	overdue := now.AddDate(0, 0, -5)
+
+	notDue := &models.CountryMastery{Ease: defaultEase, DueAt: &later}
+	due := &models.CountryMastery{Ease: defaultEase, DueAt: &dueToday}
+	late := &models.CountryMastery{Ease: defaultEase, DueAt: &overdue}
+	weak := &models.CountryMastery{Ease: minEase, DueAt: &dueToday}
+
+	if !(practiceWeight(notDue, now) < practiceWeight(nil, now)) {
+		t.Fatal("a country not due should come up less than a new one")
+	}
+	if !(practiceWeight(nil, now) < practiceWeight(due, now)) {
+		t.Fatal("a due country should come up more than a new one")
+	}
+	if !(practiceWeight(due, now) < practiceWeight(late, now)) {
+		t.Fatal("an overdue country should come up more than one due today")
+	}
+	if !(practiceWeight(due, now) < practiceWeight(weak, now)) {
+		t.Fatal("a weak country should come up more than a known one")
+	}
+}
+
+func TestPickPracticeCountrySkipsUsed(t *testing.T) {
+	now := time.Now()
+	rng := rand.New(rand.NewSource(1))
+	candidates := []string{"FR", "DE", "IT"}
+	used := map[string]bool{"FR": true, "DE": true}
+
+	for i := 0; i < 20; i++ {
+		if got := pickPracticeCountry(nil, candidates, used, now, rng); got != "IT" {
+			t.Fatalf("picked %s, want the only unused country", got)
+		}
+	}
+
+	used["IT"] = true
+	if got := pickPracticeCountry(nil, candidates, used, now, rng); got == "" {
+		t.Fatal("should start over once every country was asked")
+	}
+}
+
+func TestPickPracticeCountryPrefersMissedFlags(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	rng := rand.New(rand.NewSource(7))
+	masteries := map[string]*models.CountryMastery{}
+	for _, code := range []string{"FR", "DE"} {
+		m := &models.CountryMastery{CountryCode: code}
+		scheduleReview(m, qualityCorrect, now.AddDate(0, 0, -1))
+		masteries[code] = m
+	}
+	missed := &models.CountryMastery{CountryCode: "BT"}
+	scheduleReview(missed, qualityMissed, now.AddDate(0, 0, -2))
+	masteries["BT"] = missed
+
+	picks := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		picks[pickPracticeCountry(masteries, []string{"FR", "DE", "BT"}, nil, now, rng)]++
+	}
+	if picks["BT"] < picks["FR"] || picks["BT"] < picks["DE"] {
+		t.Fatalf("missed flag should come up most: %v", picks)
+	}
+}
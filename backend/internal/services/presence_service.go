@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Presence states shown to friends.
+const (
+	PresenceOffline = "offline"
+	PresenceOnline  = "online"
+	PresenceInLobby = "in_lobby"
+	PresenceInGame  = "in_game"
+)
+
+const (
+	// presenceOnlineTTL is how long a player counts as online after their
+	// last sign of life outside a room.
+	presenceOnlineTTL = 2 * time.Minute
+	// presenceRoomTTL bounds how long a room entry outlives a server that
+	// crashed without sending the leave event.
+	presenceRoomTTL = 6 * time.Hour
+)
+
+// Presence is where a player currently is.
+type Presence struct {
+	Status    string    `json:"status"`
+	GameMode  string    `json:"game_mode,omitempty"`
+	RoomCode  string    `json:"room_code,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PresenceStore keeps presence entries until they expire. The in-memory
+// store serves a single instance; the Redis store shares presence across
+// instances.
+type PresenceStore interface {
+	Set(ctx context.Context, userID uuid.UUID, presence Presence, ttl time.Duration) error
+	// Get returns the live entries among userIDs; missing users are offline.
+	Get(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]Presence, error)
+}
+
+// PresenceService tracks which players are online and which room they are
+// in. Rooms report join, leave and game status changes; other activity only
+// keeps a player online.
+type PresenceService struct {
+	store PresenceStore
+}
+
+func NewPresenceService(store PresenceStore) *PresenceService {
+	return &PresenceService{store: store}
+}
+
+func (s *PresenceService) current(ctx context.Context, userID uuid.UUID) (Presence, error) {
+	entries, err := s.store.Get(ctx, []uuid.UUID{userID})
+	if err != nil {
+		return Presence{}, err
+	}
+	if presence, ok := entries[userID]; ok {
+		return presence, nil
+	}
+	return Presence{Status: PresenceOffline}, nil
+}
+
+// Touch marks userID as online unless they are already in a room.
+func (s *PresenceService) Touch(ctx context.Context, userID uuid.UUID) error {
+	presence, err := s.current(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if presence.RoomCode != "" {
+		return nil
+	}
+	return s.store.Set(ctx, userID, Presence{Status: PresenceOnline, UpdatedAt: time.Now()}, presenceOnlineTTL)
+}
+
+// Disconnect marks userID offline when their last connection closes, unless
+// they are still in a room.
+func (s *PresenceService) Disconnect(ctx context.Context, userID uuid.UUID) error {
+	presence, err := s.current(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if presence.RoomCode != "" {
+		return nil
+	}
+	return s.store.Set(ctx, userID, Presence{Status: PresenceOffline, UpdatedAt: time.Now()}, presenceOnlineTTL)
+}
+
+// EnterRoom records that userID is in roomCode, either waiting in its lobby
+// or playing.
+func (s *PresenceService) EnterRoom(ctx context.Context, userID uuid.UUID, roomCode, gameMode string, inGame bool) error {
+	status := PresenceInLobby
+	if inGame {
+		status = PresenceInGame
+	}
+	return s.store.Set(ctx, userID, Presence{
+		Status:    status,
+		GameMode:  gameMode,
+		RoomCode:  roomCode,
+		UpdatedAt: time.Now(),
+	}, presenceRoomTTL)
+}
+
+// LeaveRoom puts userID back to online when they leave roomCode. Leaving a
+// room the player already moved on from changes nothing.
+func (s *PresenceService) LeaveRoom(ctx context.Context, userID uuid.UUID, roomCode string) error {
+	presence, err := s.current(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if presence.RoomCode != "" && presence.RoomCode != roomCode {
+		return nil
+	}
+	return s.store.Set(ctx, userID, Presence{Status: PresenceOnline, UpdatedAt: time.Now()}, presenceOnlineTTL)
+}
+
+// Lookup returns the presence of every user in userIDs.
+func (s *PresenceService) Lookup(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]Presence, error) {
+	entries, err := s.store.Get(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uuid.UUID]Presence, len(userIDs))
+	for _, id := range userIDs {
+		if presence, ok := entries[id]; ok {
+			result[id] = presence
+		} else {
+			result[id] = Presence{Status: PresenceOffline}
+		}
+	}
+	return result, nil
+}
+
+type memoryPresence struct {
+	presence  Presence
+	expiresAt time.Time
+}
+
+// MemoryPresenceStore is a PresenceStore for a single server instance.
+type MemoryPresenceStore struct {
+	mu      sync.Mutex
+	entries map[uuid.UUID]memoryPresence
+}
+
+func NewMemoryPresenceStore() *MemoryPresenceStore {
+	return &MemoryPresenceStore{entries: make(map[uuid.UUID]memoryPresence)}
+}
+
+func (s *MemoryPresenceStore) Set(ctx context.Context, userID uuid.UUID, presence Presence, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, id)
+		}
+	}
+	s.entries[userID] = memoryPresence{presence: presence, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryPresenceStore) Get(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]Presence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	result := make(map[uuid.UUID]Presence, len(userIDs))
+	for _, id := range userIDs {
+		if entry, ok := s.entries[id]; ok && now.Before(entry.expiresAt) {
+			result[id] = entry.presence
+		}
+	}
+	return result, nil
+}
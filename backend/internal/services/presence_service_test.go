@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPresenceRoomLifecycle(t *testing.T) {
+	ctx := context.Background()
+	presence := NewPresenceService(NewMemoryPresenceStore())
+	userID := uuid.New()
+
+	lookup := func() Presence {
+		t.Helper()
+		entries, err := presence.Lookup(ctx, []uuid.UUID{userID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entries[userID]
+	}
+
+	if got := lookup(); got.Status != PresenceOffline {
+		t.Fatalf("unknown user status = %s, want offline", got.Status)
+	}
+
+	if err := presence.Touch(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if got := lookup(); got.Status != PresenceOnline {
+		t.Fatalf("status after touch = %s, want online", got.Status)
+	}
+
+	if err := presence.EnterRoom(ctx, userID, "ROOM1", "FLAG", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := presence.Touch(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if got := lookup(); got.Status != PresenceInLobby || got.RoomCode != "ROOM1" {
+		t.Fatalf("touch should not take a player out of their room, got %+v", got)
+	}
+
+	if err := presence.EnterRoom(ctx, userID, "ROOM1", "FLAG", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := lookup(); got.Status != PresenceInGame || got.GameMode != "FLAG" {
+		t.Fatalf("status during game = %+v, want in_game FLAG", got)
+	}
+
+	// Joining another room before the old one reports the leave.
+	if err := presence.EnterRoom(ctx, userID, "ROOM2", "SILHOUETTE", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := presence.LeaveRoom(ctx, userID, "ROOM1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := lookup(); got.RoomCode != "ROOM2" {
+		t.Fatalf("leaving an old room moved the player out of ROOM2: %+v", got)
+	}
+
+	if err := presence.LeaveRoom(ctx, userID, "ROOM2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := lookup(); got.Status != PresenceOnline || got.RoomCode != "" {
+		t.Fatalf("status after leaving = %+v, want online", got)
+	}
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrGuestSessionRequired = errors.New("guest session id is required")
+	ErrGuestAlreadyClaimed  = errors.New("guest session was already claimed")
+)
+
+// ProgressOwner identifies whose progress a record belongs to: a registered
+// user or, until they sign up, a guest session.
+type ProgressOwner struct {
+	UserID         uuid.UUID
+	GuestSessionID string
+}
+
+func (o ProgressOwner) IsGuest() bool {
+	return o.UserID == uuid.Nil
+}
+
+func (o ProgressOwner) scope(db *gorm.DB) *gorm.DB {
+	if o.IsGuest() {
+		return db.Where("user_id = ? AND guest_session_id = ?", uuid.Nil, o.GuestSessionID)
+	}
+	return db.Where("user_id = ?", o.UserID)
+}
+
+// ClaimSummary reports what a guest claim moved onto the account.
+type ClaimSummary struct {
+	Matches    int64 `json:"matches"`
+	Countries  int64 `json:"countries"`
+	Challenges int64 `json:"challenges"`
+	Points     int   `json:"points"`
+	Wins       int   `json:"wins"`
+}
+
+// ProgressService records match results, country mastery and daily challenge
+// completions for users and guests, and merges guest history into an account.
+type ProgressService struct {
+	db *database.GormDB
+}
+
+func NewProgressService(db *database.GormDB) *ProgressService {
+	return &ProgressService{db: db}
+}
+
+// RecordMatch stores one player's result of a finished game.
+func (s *ProgressService) RecordMatch(owner ProgressOwner, result models.MatchResult) error {
+	if owner.IsGuest() && owner.GuestSessionID == "" {
+		return ErrGuestSessionRequired
+	}
+	result.UserID = owner.UserID
+	result.GuestSessionID = owner.GuestSessionID
+	if result.PlayedAt.IsZero() {
+		result.PlayedAt = time.Now()
+	}
+	return s.db.DB.Create(&result).Error
+}
+
+// RecordMastery adds one answer about countryCode to the owner's mastery and
+// schedules the country's next review. It reports whether the answer made
+// the owner master the country, which also counts towards the user's
+// countries_mastered.
+func (s *ProgressService) RecordMastery(owner ProgressOwner, countryCode string, correct bool) (bool, error) {
+	if owner.IsGuest() && owner.GuestSessionID == "" {
+		return false, ErrGuestSessionRequired
+	}
+
+	var mastery models.CountryMastery
+	if err := owner.scope(s.db.DB).Where("country_code = ?", countryCode).First(&mastery).Error; err != nil {
+		mastery = models.CountryMastery{
+			UserID:         owner.UserID,
+			GuestSessionID: owner.GuestSessionID,
+			CountryCode:    countryCode,
+			Level:          1,
+		}
+	}
+
+	before := mastery.Level
+	applyMasteryResult(&mastery, correct)
+	mastered := before < models.MasteredLevel && mastery.Level >= models.MasteredLevel
+
+	err := s.db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&mastery).Error; err != nil {
+			return err
+		}
+		if !mastered || owner.IsGuest() {
+			return nil
+		}
+		return tx.Model(&models.User{}).Where("id = ?", owner.UserID).
+			Update("countries_mastered", gorm.Expr("countries_mastered + 1")).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return mastered, nil
+}
+
+// MasteredCount returns how many countries the owner has mastered.
+func (s *ProgressService) MasteredCount(owner ProgressOwner) (int, error) {
+	var count int64
+	err := owner.scope(s.db.DB.Model(&models.CountryMastery{})).
+		Where("level >= ?", models.MasteredLevel).
+		Count(&count).Error
+	return int(count), err
+}
+
+// NextPracticeCountry picks the country a practice game asks the owner about
+// next, out of candidates. Countries due for review come up most, weak and
+// overdue ones above all. Countries in used were already asked this game.
+func (s *ProgressService) NextPracticeCountry(owner ProgressOwner, candidates []string, used map[string]bool) (string, error) {
+	var records []models.CountryMastery
+	if err := owner.scope(s.db.DB).Find(&records).Error; err != nil {
+		return "", err
+	}
+	masteries := make(map[string]*models.CountryMastery, len(records))
+	for i := range records {
+		masteries[records[i].CountryCode] = &records[i]
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return pickPracticeCountry(masteries, candidates, used, time.Now(), rng), nil
+}
+
+// ClaimGuest moves everything recorded for guestSessionID onto userID in one
+// transaction. Match totals are added to the user's stats, mastery for the
+// same country is summed and a challenge completed both ways keeps the better
+// score. A session can be claimed once; claiming an unknown session claims
+// nothing.
+func (s *ProgressService) ClaimGuest(ctx context.Context, userID uuid.UUID, guestSessionID string) (*ClaimSummary, error) {
+	if guestSessionID == "" {
+		return nil, ErrGuestSessionRequired
+	}
+
+	guest := ProgressOwner{GuestSessionID: guestSessionID}
+	summary := &ClaimSummary{}
+
+	tx := s.db.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", userID).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	claim := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.GuestClaim{
+		SessionID: guestSessionID,
+		UserID:    userID,
+		ClaimedAt: time.Now(),
+	})
+	if claim.Error != nil {
+		tx.Rollback()
+		return nil, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		tx.Rollback()
+		return nil, ErrGuestAlreadyClaimed
+	}
+
+	// Matches
+	var matches []models.MatchResult
+	if err := guest.scope(tx).Find(&matches).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for _, match := range matches {
+		summary.Points += match.Score
+		if match.Won {
+			summary.Wins++
+		}
+	}
+	summary.Matches = int64(len(matches))
+
+	if len(matches) > 0 {
+		if err := guest.scope(tx.Model(&models.MatchResult{})).Updates(map[string]interface{}{
+			"user_id":  userID,
+			"username": user.Username,
+		}).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"total_points": gorm.Expr("total_points + ?", summary.Points),
+			"total_games":  gorm.Expr("total_games + ?", summary.Matches),
+			"total_wins":   gorm.Expr("total_wins + ?", summary.Wins),
+		}).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// Country mastery
+	var masteries []models.CountryMastery
+	if err := guest.scope(tx).Find(&masteries).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for _, guestMastery := range masteries {
+		var existing models.CountryMastery
+		err := tx.Where("user_id = ? AND country_code = ?", userID, guestMastery.CountryCode).First(&existing).Error
+		switch {
+		case err == nil:
+			existing.Correct += guestMastery.Correct
+			existing.Incorrect += guestMastery.Incorrect
+			existing.XP += guestMastery.XP
+			existing.Level = masteryLevel(existing.XP)
+			existing.UpdatedAt = time.Now()
+			if err := tx.Save(&existing).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			if err := tx.Delete(&guestMastery).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Model(&guestMastery).Update("user_id", userID).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		default:
+			tx.Rollback()
+			return nil, err
+		}
+		summary.Countries++
+	}
+
+	if summary.Countries > 0 {
+		if err := tx.Model(&user).Update("countries_mastered", tx.Model(&models.CountryMastery{}).
+			Select("COUNT(*)").
+			Where("user_id = ? AND level >= ?", userID, models.MasteredLevel)).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// Daily challenge completions
+	var completions []models.ChallengeCompletion
+	if err := guest.scope(tx).Find(&completions).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for _, guestCompletion := range completions {
+		var existing models.ChallengeCompletion
+		err := tx.Where("user_id = ? AND challenge_id = ?", userID, guestCompletion.ChallengeID).First(&existing).Error
+		switch {
+		case err == nil:
+			if guestCompletion.Score > existing.Score {
+				if err := tx.Model(&existing).Update("score", guestCompletion.Score).Error; err != nil {
+					tx.Rollback()
+					return nil, err
+				}
+			}
+			if err := tx.Delete(&guestCompletion).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Model(&guestCompletion).Update("user_id", userID).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		default:
+			tx.Rollback()
+			return nil, err
+		}
+		summary.Challenges++
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
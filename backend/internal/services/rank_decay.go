@@ -0,0 +1,285 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rankDecayBatchSize is how many players each warning and decay pass covers.
+const rankDecayBatchSize = 500
+
+// inactiveSinceSQL is when a player last played a ranked game, falling back
+// to their last sign-in and then their sign-up for players who never have.
+const inactiveSinceSQL = "COALESCE(last_ranked_at, last_active, created_at)"
+
+// RankDecayPolicy says who loses rating for not playing. Players rated
+// above FloorRating lose PointsPerDay a day, but never below FloorRating,
+// once they have gone InactiveDays without a ranked game. They are warned
+// WarningDays before the first decay.
+type RankDecayPolicy struct {
+	FloorRating  int
+	InactiveDays int
+	WarningDays  int
+	PointsPerDay int
+}
+
+// Enabled reports whether anyone can decay under p.
+func (p RankDecayPolicy) Enabled() bool {
+	return p.InactiveDays > 0 && p.PointsPerDay > 0
+}
+
+// ActiveScope limits db to players who played a ranked game within the
+// last InactiveDays. Everyone counts as active while decay is off.
+func (p RankDecayPolicy) ActiveScope(db *gorm.DB, now time.Time) *gorm.DB {
+	if !p.Enabled() {
+		return db
+	}
+	return db.Where(inactiveSinceSQL+" >= ?", now.AddDate(0, 0, -p.InactiveDays))
+}
+
+// warnAt returns when a player inactive since inactiveSince is due a
+// warning.
+func (p RankDecayPolicy) warnAt(inactiveSince time.Time) time.Time {
+	return inactiveSince.AddDate(0, 0, p.InactiveDays-p.WarningDays)
+}
+
+// decayStartsAt returns when a player inactive since inactiveSince first
+// loses rating. Decay waits WarningDays after the warning, so players who
+// are warned late still get the full notice.
+func (p RankDecayPolicy) decayStartsAt(inactiveSince time.Time, warnedAt *time.Time, now time.Time) time.Time {
+	startsAt := inactiveSince.AddDate(0, 0, p.InactiveDays)
+	warned := p.warnAt(inactiveSince)
+	if warnedAt != nil {
+		warned = *warnedAt
+	} else if warned.Before(now) {
+		warned = now
+	}
+	if notice := warned.AddDate(0, 0, p.WarningDays); notice.After(startsAt) {
+		return notice
+	}
+	return startsAt
+}
+
+// decayedRating returns rating after one day of decay.
+func (p RankDecayPolicy) decayedRating(rating int) int {
+	return max(p.FloorRating, rating-p.PointsPerDay)
+}
+
+// RankDecayStatus tells a player whether they are losing rating for not
+// playing, or when they will start to.
+type RankDecayStatus struct {
+	Applies       bool       `json:"applies"`
+	FloorRating   int        `json:"floor_rating"`
+	PointsPerDay  int        `json:"points_per_day"`
+	InactiveDays  int        `json:"inactive_days"`
+	InactiveSince *time.Time `json:"inactive_since,omitempty"`
+	StartsAt      *time.Time `json:"starts_at,omitempty"`
+	Decaying      bool       `json:"decaying"`
+}
+
+// Status returns user's decay status at now. It is nil while decay is off.
+func (p RankDecayPolicy) Status(user models.User, now time.Time) *RankDecayStatus {
+	if !p.Enabled() {
+		return nil
+	}
+	status := &RankDecayStatus{
+		Applies:      user.Rating > p.FloorRating,
+		FloorRating:  p.FloorRating,
+		PointsPerDay: p.PointsPerDay,
+		InactiveDays: p.InactiveDays,
+	}
+	if !status.Applies {
+		return status
+	}
+
+	since := user.CreatedAt
+	if user.LastRankedAt != nil {
+		since = *user.LastRankedAt
+	} else if user.LastActive != nil {
+		since = *user.LastActive
+	}
+	startsAt := p.decayStartsAt(since, user.DecayWarnedAt, now)
+	status.InactiveSince = &since
+	status.StartsAt = &startsAt
+	status.Decaying = !now.Before(startsAt)
+	return status
+}
+
+// RankDecayMailer sends the warning that a player's rank is about to decay.
+type RankDecayMailer interface {
+	SendRankDecayWarning(to, username, rank string, startsAt time.Time, pointsPerDay int) error
+}
+
+// RankDecayService warns and decays inactive high ranked players. Each pass
+// claims its players with SKIP LOCKED, so any number of instances can run it.
+type RankDecayService struct {
+	db     *gorm.DB
+	policy RankDecayPolicy
+	mailer RankDecayMailer
+}
+
+func NewRankDecayService(db *gorm.DB, policy RankDecayPolicy, mailer RankDecayMailer) *RankDecayService {
+	return &RankDecayService{db: db, policy: policy, mailer: mailer}
+}
+
+// StartScheduler runs warnings and decay every interval while decay is on.
+func (s *RankDecayService) StartScheduler(interval time.Duration) {
+	if !s.policy.Enabled() {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			warned, decayed, err := s.RunDue(ctx, time.Now())
+			cancel()
+			if err != nil {
+				log.Printf("Rank decay job failed: %v", err)
+			} else if warned > 0 || decayed > 0 {
+				log.Printf("Rank decay job: warned %d player(s), decayed %d", warned, decayed)
+			}
+		}
+	}()
+}
+
+// RunDue sends the warnings that are due and decays everyone whose notice
+// has run out, at most once a day each.
+func (s *RankDecayService) RunDue(ctx context.Context, now time.Time) (int, int, error) {
+	warned, decayed := 0, 0
+	for {
+		n, err := s.warnBatch(ctx, now)
+		if err != nil {
+			return warned, decayed, err
+		}
+		if n == 0 {
+			break
+		}
+		warned += n
+	}
+	for {
+		n, err := s.decayBatch(ctx, now)
+		if err != nil || n == 0 {
+			return warned, decayed, err
+		}
+		decayed += n
+	}
+}
+
+// decayWarning is a player claimed for a warning.
+type decayWarning struct {
+	ID            uuid.UUID
+	Username      string
+	Email         string
+	Rank          string
+	InactiveSince time.Time
+}
+
+// warnBatch marks up to rankDecayBatchSize players as warned and then emails
+// them. A warning that fails to send is not retried.
+func (s *RankDecayService) warnBatch(ctx context.Context, now time.Time) (int, error) {
+	cutoff := now.AddDate(0, 0, s.policy.WarningDays-s.policy.InactiveDays)
+	due := s.db.Model(&models.User{}).Select("id").
+		Where("anonymized_at IS NULL AND rating > ? AND decay_warned_at IS NULL AND "+inactiveSinceSQL+" <= ?", s.policy.FloorRating, cutoff).
+		Order("id").
+		Limit(rankDecayBatchSize).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var players []decayWarning
+	if err := s.db.WithContext(ctx).Raw(`
+		UPDATE users SET decay_warned_at = ?
+		WHERE id IN (?)
+		RETURNING id, username, email, rank, `+inactiveSinceSQL+` AS inactive_since
+	`, now, due).Scan(&players).Error; err != nil {
+		return 0, err
+	}
+
+	for _, player := range players {
+		if player.Email == "" {
+			continue
+		}
+		warnedAt := now
+		startsAt := s.policy.decayStartsAt(player.InactiveSince, &warnedAt, now)
+		if err := s.mailer.SendRankDecayWarning(player.Email, player.Username, strings.ReplaceAll(player.Rank, "_", " "), startsAt, s.policy.PointsPerDay); err != nil {
+			log.Printf("Failed to send rank decay warning to %s: %v", player.ID, err)
+		}
+	}
+	return len(players), nil
+}
+
+// decayBatch takes a day's decay off up to rankDecayBatchSize players whose
+// notice has run out and who have not decayed in the last day, recording
+// each change in their rank history.
+func (s *RankDecayService) decayBatch(ctx context.Context, now time.Time) (int, error) {
+	decayed := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var users []models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Select("id", "rating", "rank").
+			Where("anonymized_at IS NULL AND rating > ?", s.policy.FloorRating).
+			Where(inactiveSinceSQL+" <= ?", now.AddDate(0, 0, -s.policy.InactiveDays)).
+			Where("decay_warned_at <= ?", now.AddDate(0, 0, -s.policy.WarningDays)).
+			Where("last_decay_at IS NULL OR last_decay_at <= ?", now.Add(-24*time.Hour)).
+			Order("id").
+			Limit(rankDecayBatchSize).
+			Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+
+		seasonID := uuid.Nil
+		var season models.Season
+		err := tx.Where("is_active = ?", true).First(&season).Error
+		if err == nil {
+			seasonID = season.ID
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		history := make([]models.RankHistory, 0, len(users))
+		rows := make([]string, 0, len(users))
+		args := []interface{}{now}
+		for _, user := range users {
+			rating := s.policy.decayedRating(user.Rating)
+			rank, tier := models.GetRankFromRating(rating)
+			history = append(history, models.RankHistory{
+				ID:        uuid.New(),
+				UserID:    user.ID,
+				SeasonID:  seasonID,
+				OldRank:   user.Rank,
+				NewRank:   rank,
+				OldRating: user.Rating,
+				NewRating: rating,
+				Reason:    models.RankChangeDecay,
+			})
+			rows = append(rows, "(?::uuid, ?::int, ?, ?::int)")
+			args = append(args, user.ID, rating, rank, tier)
+		}
+
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+			UPDATE users
+			SET rating = v.rating, rank = v.rank, rank_tier = v.tier, last_decay_at = ?
+			FROM (VALUES `+strings.Join(rows, ", ")+`) AS v(id, rating, rank, tier)
+			WHERE users.id = v.id
+		`, args...).Error; err != nil {
+			return err
+		}
+		decayed = len(users)
+		return nil
+	})
+	return decayed, err
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"context"
+	"testing"
+	"time"
+)
+
+var testDecayPolicy = RankDecayPolicy{FloorRating: 1600, InactiveDays: 14, WarningDays: 3, PointsPerDay: 25}
+
+func TestDecayWaitsForTheFullWarning(t *testing.T) {
+	inactiveSince := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	onTime := inactiveSince.AddDate(0, 0, 14)
+
+	warnedAt := inactiveSince.AddDate(0, 0, 11)
+	if got := testDecayPolicy.decayStartsAt(inactiveSince, &warnedAt, warnedAt); !got.Equal(onTime) {
+		t.Fatalf("warned on time: decay starts %v, want %v", got, onTime)
+	}
+
+	warnedAt = inactiveSince.AddDate(0, 0, 20)
+	if got, want := testDecayPolicy.decayStartsAt(inactiveSince, &warnedAt, warnedAt), warnedAt.AddDate(0, 0, 3); !got.Equal(want) {
+		t.Fatalf("warned late: decay starts %v, want %v", got, want)
+	}
+
+	now := inactiveSince.AddDate(0, 0, 30)
+	if got, want := testDecayPolicy.decayStartsAt(inactiveSince, nil, now), now.AddDate(0, 0, 3); !got.Equal(want) {
+		t.Fatalf("not warned yet: decay starts %v, want %v", got, want)
+	}
+}
+
+func TestDecayedRatingStopsAtTheFloor(t *testing.T) {
+	if got := testDecayPolicy.decayedRating(1700); got != 1675 {
+		t.Fatalf("decayedRating(1700) = %d, want 1675", got)
+	}
+	if got := testDecayPolicy.decayedRating(1610); got != 1600 {
+		t.Fatalf("decayedRating(1610) = %d, want the floor", got)
+	}
+}
+
+func TestRankDecayStatus(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	lastRanked := now.AddDate(0, 0, -20)
+	warnedAt := now.AddDate(0, 0, -4)
+	user := models.User{Rating: 1800, LastRankedAt: &lastRanked, DecayWarnedAt: &warnedAt}
+
+	status := testDecayPolicy.Status(user, now)
+	if !status.Applies || !status.Decaying {
+		t.Fatalf("inactive and warned: applies = %v, decaying = %v", status.Applies, status.Decaying)
+	}
+	if !status.InactiveSince.Equal(lastRanked) {
+		t.Fatalf("inactive since %v, want %v", status.InactiveSince, lastRanked)
+	}
+
+	recent := now.AddDate(0, 0, -2)
+	user = models.User{Rating: 1800, LastRankedAt: &recent}
+	if status := testDecayPolicy.Status(user, now); status.Decaying || !status.StartsAt.Equal(recent.AddDate(0, 0, 14)) {
+		t.Fatalf("active player: decaying = %v, starts %v", status.Decaying, status.StartsAt)
+	}
+
+	user = models.User{Rating: 1500, LastRankedAt: &lastRanked}
+	if status := testDecayPolicy.Status(user, now); status.Applies || status.StartsAt != nil {
+		t.Fatalf("below the floor: %+v", status)
+	}
+
+	if status := (RankDecayPolicy{}).Status(user, now); status != nil {
+		t.Fatalf("decay off: %+v", status)
+	}
+}
+
+// recordingDecayMailer keeps the addresses rank decay warnings went to.
+type recordingDecayMailer struct {
+	sent []string
+}
+
+func (m *recordingDecayMailer) SendRankDecayWarning(to, username, rank string, startsAt time.Time, pointsPerDay int) error {
+	m.sent = append(m.sent, to)
+	return nil
+}
+
+func TestRankDecayRunDueWarnsThenDecays(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	now := time.Now()
+	players := map[string]map[string]interface{}{
+		"idle":     {"rating": 1800, "last_ranked_at": now.AddDate(0, 0, -20)},
+		"notified": {"rating": 1700, "last_ranked_at": now.AddDate(0, 0, -20), "decay_warned_at": now.AddDate(0, 0, -4)},
+		"active":   {"rating": 1800, "last_ranked_at": now.AddDate(0, 0, -2)},
+		"low":      {"rating": 1500, "last_ranked_at": now.AddDate(0, 0, -30)},
+	}
+	users := make(map[string]*models.User, len(players))
+	for name, fields := range players {
+		users[name] = createTestUser(t, db, name)
+		if err := db.DB.Model(users[name]).Updates(fields).Error; err != nil {
+			t.Fatalf("set up %s: %v", name, err)
+		}
+	}
+	mailer := &recordingDecayMailer{}
+	decay := NewRankDecayService(db.DB, testDecayPolicy, mailer)
+
+	warned, decayed, err := decay.RunDue(ctx, now)
+	if err != nil {
+		t.Fatalf("RunDue: %v", err)
+	}
+	if warned != 1 || decayed != 1 {
+		t.Fatalf("first run warned %d and decayed %d, want 1 and 1", warned, decayed)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0] != "idle@example.com" {
+		t.Errorf("warnings went to %v, want only idle", mailer.sent)
+	}
+
+	rating := func(name string) int {
+		var user models.User
+		db.DB.Select("rating").First(&user, "id = ?", users[name].ID)
+		return user.Rating
+	}
+	want := map[string]int{"idle": 1800, "notified": 1675, "active": 1800, "low": 1500}
+	for name, r := range want {
+		if got := rating(name); got != r {
+			t.Errorf("%s rated %d after the first run, want %d", name, got, r)
+		}
+	}
+
+	// Decay happens at most once a day
+	if warned, decayed, err := decay.RunDue(ctx, now.Add(time.Hour)); err != nil || warned != 0 || decayed != 0 {
+		t.Errorf("run an hour later warned %d, decayed %d (%v), want nothing", warned, decayed, err)
+	}
+	if _, decayed, err := decay.RunDue(ctx, now.Add(25*time.Hour)); err != nil || decayed != 1 {
+		t.Errorf("run a day later decayed %d (%v), want only notified", decayed, err)
+	}
+	if got := rating("notified"); got != 1650 {
+		t.Errorf("notified rated %d after two days of decay, want 1650", got)
+	}
+
+	var history []models.RankHistory
+	db.DB.Where("user_id = ? AND reason = ?", users["notified"].ID, models.RankChangeDecay).Order("created_at").Find(&history)
+	if len(history) != 2 || history[0].OldRating != 1700 || history[0].NewRating != 1675 || history[1].NewRating != 1650 {
+		t.Errorf("decay history = %+v, want 1700 -> 1675 -> 1650", history)
+	}
+}
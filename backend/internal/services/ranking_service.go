@@ -0,0 +1,183 @@
+package services
+
+import (
+	"briworld/internal/domain"
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RankedGame is one player's result in a game that counts towards the ladder.
+type RankedGame struct {
+	UserID uuid.UUID
+	Score  int
+	Won    bool
+}
+
+// RankChange is how a ranked game moved a player's rating and rank.
+type RankChange struct {
+	OldRating int    `json:"old_rating"`
+	NewRating int    `json:"new_rating"`
+	OldRank   string `json:"old_rank"`
+	NewRank   string `json:"new_rank"`
+	RankTier  int    `json:"rank_tier"`
+}
+
+// RankStatus is a player's place on the ladder and their record in the
+// active season, if there is one.
+type RankStatus struct {
+	Rating              int                `json:"rating"`
+	Rank                string             `json:"rank"`
+	RankTier            int                `json:"rank_tier"`
+	Position            int64              `json:"position"`
+	PlacementMatches    int                `json:"placement_matches"`
+	IsPlacementComplete bool               `json:"is_placement_complete"`
+	Season              *models.SeasonRank `json:"season"`
+	Decay               *RankDecayStatus   `json:"decay,omitempty"`
+}
+
+// RankingService owns the ladder. Rank and tier always come from the
+// player's rating through models.GetRankFromRating, both on the user and on
+// their SeasonRank.
+type RankingService struct {
+	db     *gorm.DB
+	rating domain.RatingService
+	// requireVerified keeps unverified accounts off the ladder.
+	requireVerified bool
+}
+
+func NewRankingService(db *gorm.DB, requireVerified bool) *RankingService {
+	return &RankingService{db: db, requireVerified: requireVerified}
+}
+
+// LeaderboardScope limits db to the players who are placed on the ladder.
+// Players kept off the leaderboards for cheating are left out.
+func (rs *RankingService) LeaderboardScope(db *gorm.DB) *gorm.DB {
+	db = db.Where("(total_games > 0 OR total_points > 0 OR rating > 1000) AND shadow_excluded_at IS NULL")
+	if rs.requireVerified {
+		db = db.Where("email_verified = ?", true)
+	}
+	return db
+}
+
+// rankAfter returns the rating, rank and tier a player with rating ends up
+// with after scoring score in a ranked game.
+func (rs *RankingService) rankAfter(rating, score int, won bool) (int, string, int) {
+	rating = max(0, rating+rs.rating.CalculateRatingChange(score, won))
+	rank, tier := models.GetRankFromRating(rating)
+	return rating, rank, tier
+}
+
+// RecordGame applies a ranked game to the player's rating and rank, and
+// adds it to their wins or losses and points in the active season. Rating
+// changes are kept in the player's rank history.
+func (rs *RankingService) RecordGame(ctx context.Context, game RankedGame) (RankChange, error) {
+	var change RankChange
+	err := rs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "rating", "rank").
+			First(&user, "id = ?", game.UserID).Error; err != nil {
+			return err
+		}
+
+		change = RankChange{OldRating: user.Rating, OldRank: user.Rank}
+		change.NewRating, change.NewRank, change.RankTier = rs.rankAfter(user.Rating, game.Score, game.Won)
+		now := time.Now()
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"rating":          change.NewRating,
+			"rank":            change.NewRank,
+			"rank_tier":       change.RankTier,
+			"last_ranked_at":  now,
+			"last_active":     now,
+			"decay_warned_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		var season models.Season
+		err := tx.Where("is_active = ?", true).First(&season).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		wins, losses := 0, 1
+		if game.Won {
+			wins, losses = 1, 0
+		}
+		if err := tx.Exec(`
+			INSERT INTO season_ranks (id, user_id, season_id, rating, rank, rank_tier, points, wins, losses, updated_at)
+			VALUES (gen_random_uuid(), ?, ?, ?, ?, ?, ?, ?, ?, NOW())
+			ON CONFLICT (user_id, season_id) DO UPDATE SET
+				rating = EXCLUDED.rating,
+				rank = EXCLUDED.rank,
+				rank_tier = EXCLUDED.rank_tier,
+				points = season_ranks.points + EXCLUDED.points,
+				wins = season_ranks.wins + EXCLUDED.wins,
+				losses = season_ranks.losses + EXCLUDED.losses,
+				updated_at = EXCLUDED.updated_at
+		`, user.ID, season.ID, change.NewRating, change.NewRank, change.RankTier, game.Score, wins, losses).Error; err != nil {
+			return err
+		}
+
+		if change.NewRating == change.OldRating {
+			return nil
+		}
+		return tx.Create(&models.RankHistory{
+			UserID:    user.ID,
+			SeasonID:  season.ID,
+			OldRank:   change.OldRank,
+			NewRank:   change.NewRank,
+			OldRating: change.OldRating,
+			NewRating: change.NewRating,
+			Reason:    models.RankChangeGame,
+		}).Error
+	})
+	return change, err
+}
+
+// UserRank returns userID's place on the ladder, their active season record
+// and how decay treats them. Season is nil when there is no active season
+// or they have not played a ranked game in it.
+func (rs *RankingService) UserRank(ctx context.Context, userID uuid.UUID, decay RankDecayPolicy) (*RankStatus, error) {
+	db := rs.db.WithContext(ctx)
+
+	var user models.User
+	if err := db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+
+	status := &RankStatus{
+		Rating:              user.Rating,
+		Rank:                user.Rank,
+		RankTier:            user.RankTier,
+		PlacementMatches:    user.PlacementMatches,
+		IsPlacementComplete: user.IsPlacementComplete,
+		Decay:               decay.Status(user, time.Now()),
+	}
+	if err := rs.LeaderboardScope(db.Model(&models.User{})).
+		Where("rating > ?", user.Rating).
+		Count(&status.Position).Error; err != nil {
+		return nil, err
+	}
+	status.Position++
+
+	var season models.SeasonRank
+	err := db.Joins("JOIN seasons ON seasons.id = season_ranks.season_id AND seasons.is_active").
+		Where("season_ranks.user_id = ?", userID).
+		First(&season).Error
+	if err == nil {
+		status.Season = &season
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return status, nil
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRankAfterDerivesRankFromRating(t *testing.T) {
+	rs := NewRankingService(nil, false)
+
+	rating, rank, tier := rs.rankAfter(1190, 500, true)
+	if rating != 1220 {
+		t.Fatalf("rating = %d, want 1220", rating)
+	}
+	if wantRank, wantTier := models.GetRankFromRating(rating); rank != wantRank || tier != wantTier {
+		t.Fatalf("rank = %s %d, want %s %d", rank, tier, wantRank, wantTier)
+	}
+	if rank != models.RankGold {
+		t.Fatalf("rank = %s, want a promotion to %s", rank, models.RankGold)
+	}
+
+	rating, rank, _ = rs.rankAfter(5, 0, false)
+	if rating != 0 || rank != models.RankBronze {
+		t.Fatalf("loss at the floor: rating = %d, rank = %s", rating, rank)
+	}
+}
+
+func TestNewPlayersStartOnTheirRatingsRank(t *testing.T) {
+	var user models.User
+	if err := user.BeforeCreate(nil); err != nil {
+		t.Fatal(err)
+	}
+	rank, tier := models.GetRankFromRating(models.StartingRating)
+	if user.Rating != models.StartingRating || user.Rank != rank || user.RankTier != tier {
+		t.Fatalf("new player: rating = %d, rank = %s %d", user.Rating, user.Rank, user.RankTier)
+	}
+}
+
+// startTestSeason stores a season that is running now.
+func startTestSeason(t *testing.T, db *database.GormDB) models.Season {
+	t.Helper()
+	now := time.Now()
+	season := models.Season{
+		ID:        uuid.New(),
+		Name:      "Season 1",
+		StartDate: now.AddDate(0, 0, -1),
+		EndDate:   now.AddDate(0, 0, 30),
+		IsActive:  true,
+	}
+	if err := db.DB.Create(&season).Error; err != nil {
+		t.Fatalf("create season: %v", err)
+	}
+	return season
+}
+
+func TestRecordGameKeepsTheSeasonRecord(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	season := startTestSeason(t, db)
+	player := createTestUser(t, db, "climber")
+	if err := db.DB.Model(player).Update("rating", 1190).Error; err != nil {
+		t.Fatal(err)
+	}
+	rs := NewRankingService(db.DB, false)
+
+	win, err := rs.RecordGame(ctx, RankedGame{UserID: player.ID, Score: 500, Won: true})
+	if err != nil {
+		t.Fatalf("RecordGame win: %v", err)
+	}
+	loss, err := rs.RecordGame(ctx, RankedGame{UserID: player.ID, Score: 120, Won: false})
+	if err != nil {
+		t.Fatalf("RecordGame loss: %v", err)
+	}
+	if win.OldRating != 1190 || win.NewRating != 1220 || loss.OldRating != 1220 || loss.NewRating != 1210 {
+		t.Fatalf("changes = %+v then %+v", win, loss)
+	}
+
+	var user models.User
+	db.DB.First(&user, "id = ?", player.ID)
+	rank, tier := models.GetRankFromRating(1210)
+	if user.Rating != 1210 || user.Rank != rank || user.RankTier != tier || user.LastRankedAt == nil {
+		t.Errorf("player after two games: rating %d, %s %d, last ranked %v", user.Rating, user.Rank, user.RankTier, user.LastRankedAt)
+	}
+
+	var records []models.SeasonRank
+	db.DB.Where("user_id = ? AND season_id = ?", player.ID, season.ID).Find(&records)
+	if len(records) != 1 {
+		t.Fatalf("%d season ranks, want one per player and season", len(records))
+	}
+	got := records[0]
+	if got.Points != 620 || got.Wins != 1 || got.Losses != 1 || got.Rating != 1210 || got.Rank != rank || got.RankTier != tier {
+		t.Errorf("season rank = %+v, want 620 points, 1-1 at %d %s %d", got, 1210, rank, tier)
+	}
+
+	var history []models.RankHistory
+	db.DB.Where("user_id = ?", player.ID).Order("created_at, old_rating").Find(&history)
+	if len(history) != 2 {
+		t.Fatalf("%d rank history entries, want one per rating change", len(history))
+	}
+	for i, change := range []RankChange{win, loss} {
+		entry := history[i]
+		if entry.SeasonID != season.ID || entry.Reason != models.RankChangeGame ||
+			entry.OldRating != change.OldRating || entry.NewRating != change.NewRating ||
+			entry.OldRank != change.OldRank || entry.NewRank != change.NewRank {
+			t.Errorf("history %d = %+v, want %+v", i, entry, change)
+		}
+	}
+}
+
+func TestRecordGameWithoutASeasonOnlyMovesTheRating(t *testing.T) {
+	db := testDB(t)
+	player := createTestUser(t, db, "offseason")
+	rs := NewRankingService(db.DB, false)
+
+	change, err := rs.RecordGame(context.Background(), RankedGame{UserID: player.ID, Score: 0, Won: true})
+	if err != nil {
+		t.Fatalf("RecordGame: %v", err)
+	}
+	var user models.User
+	db.DB.First(&user, "id = ?", player.ID)
+	if user.Rating != change.NewRating || change.NewRating != change.OldRating+25 {
+		t.Errorf("rating = %d after %+v", user.Rating, change)
+	}
+	var records, history int64
+	db.DB.Model(&models.SeasonRank{}).Where("user_id = ?", player.ID).Count(&records)
+	db.DB.Model(&models.RankHistory{}).Where("user_id = ?", player.ID).Count(&history)
+	if records != 0 || history != 0 {
+		t.Errorf("%d season ranks and %d history entries with no season running", records, history)
+	}
+}
+
+func TestLeaderboardScopeLeavesOutShadowExcludedPlayers(t *testing.T) {
+	db := testDB(t)
+	placed := createTestUser(t, db, "placed")
+	excluded := createTestUser(t, db, "excluded")
+	createTestUser(t, db, "unplaced")
+	now := time.Now()
+	db.DB.Model(&models.User{}).Where("id IN ?", []uuid.UUID{placed.ID, excluded.ID}).Update("total_games", 3)
+	db.DB.Model(excluded).Update("shadow_excluded_at", now)
+
+	var usernames []string
+	if err := NewRankingService(db.DB, false).LeaderboardScope(db.DB.Model(&models.User{})).
+		Order("username").Pluck("username", &usernames).Error; err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(usernames) != 1 || usernames[0] != "placed" {
+		t.Errorf("ladder = %v, want only the placed player", usernames)
+	}
+
+	usernames = nil
+	NewRankingService(db.DB, true).LeaderboardScope(db.DB.Model(&models.User{})).Pluck("username", &usernames)
+	if len(usernames) != 0 {
+		t.Errorf("ladder of verified players = %v, want nobody", usernames)
+	}
+}
+
+func TestMigrateUnifiedRanksMergesAndReratesSeasonRanks(t *testing.T) {
+	db := testDB(t)
+	now := time.Now()
+	ended := models.Season{ID: uuid.New(), Name: "Season 1", StartDate: now.AddDate(0, 0, -60), EndDate: now.AddDate(0, 0, -30)}
+	active := startTestSeason(t, db)
+	if err := db.DB.Create(&ended).Error; err != nil {
+		t.Fatal(err)
+	}
+	player := createTestUser(t, db, "veteran")
+
+	// Put the table back the way the old rank system left it: duplicate
+	// season ranks and ranks that do not match ratings.
+	for _, stmt := range []string{
+		`DROP INDEX IF EXISTS idx_season_ranks_player`,
+		`DELETE FROM schema_migration_versions WHERE version = '2026_10_18_unified_ranks'`,
+	} {
+		if err := db.DB.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if err := db.DB.Exec(`UPDATE users SET rating = 1300, rank = ?, rank_tier = 1 WHERE id = ?`, models.RankLegend, player.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB.Create(&models.SeasonResult{SeasonID: ended.ID, UserID: player.ID, Position: 1, Rating: 1600, Rank: models.RankBronze}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []models.SeasonRank{
+		{UserID: player.ID, SeasonID: active.ID, Rating: 900, Rank: models.RankGold, Points: 100, Wins: 1, Losses: 0},
+		{UserID: player.ID, SeasonID: active.ID, Rating: 950, Rank: models.RankGold, Points: 50, Wins: 2, Losses: 1},
+		{UserID: player.ID, SeasonID: ended.ID, Rating: 1000, Rank: models.RankGold, Points: 70, Wins: 1, Losses: 1},
+	} {
+		if err := db.DB.Create(&row).Error; err != nil {
+			t.Fatalf("create season rank: %v", err)
+		}
+	}
+
+	if err := database.MigrateUnifiedRanks(db); err != nil {
+		t.Fatalf("MigrateUnifiedRanks: %v", err)
+	}
+
+	var user models.User
+	db.DB.First(&user, "id = ?", player.ID)
+	if rank, tier := models.GetRankFromRating(1300); user.Rank != rank || user.RankTier != tier {
+		t.Errorf("player rank = %s %d, want %s %d from their rating", user.Rank, user.RankTier, rank, tier)
+	}
+
+	var current []models.SeasonRank
+	db.DB.Where("user_id = ? AND season_id = ?", player.ID, active.ID).Find(&current)
+	if len(current) != 1 {
+		t.Fatalf("%d season ranks in the active season, want the duplicates merged", len(current))
+	}
+	rank, tier := models.GetRankFromRating(1300)
+	if got := current[0]; got.Points != 150 || got.Wins != 3 || got.Losses != 1 || got.Rating != 1300 || got.Rank != rank || got.RankTier != tier {
+		t.Errorf("active season rank = %+v, want the sums at the current rating", got)
+	}
+
+	var past models.SeasonRank
+	db.DB.Where("user_id = ? AND season_id = ?", player.ID, ended.ID).First(&past)
+	rank, tier = models.GetRankFromRating(1600)
+	if past.Rating != 1600 || past.Rank != rank || past.RankTier != tier {
+		t.Errorf("ended season rank = %+v, want the archived rating %d as %s %d", past, 1600, rank, tier)
+	}
+
+	if err := db.DB.Create(&models.SeasonRank{UserID: player.ID, SeasonID: active.ID}).Error; err == nil {
+		t.Error("a second season rank for the same player and season was accepted")
+	}
+}
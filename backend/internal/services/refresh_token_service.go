@@ -0,0 +1,294 @@
+package services
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"briworld/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const refreshTokenBytes = 32
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrAccountBanned       = errors.New("account banned")
+)
+
+// DeviceInfo describes the client a device session was opened from.
+type DeviceInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// IssuedRefreshToken is the raw refresh token handed to a client. The raw
+// value is never persisted.
+type IssuedRefreshToken struct {
+	SessionID string
+	Token     string
+	ExpiresAt time.Time
+}
+
+// RefreshTokenService issues opaque refresh tokens, rotates them on every use
+// and revokes the whole device session when a rotated token is replayed.
+type RefreshTokenService struct {
+	db  *database.GormDB
+	ttl time.Duration
+}
+
+func NewRefreshTokenService(db *database.GormDB, ttlSeconds int) *RefreshTokenService {
+	if ttlSeconds <= 0 {
+		ttlSeconds = 30 * 24 * 60 * 60
+	}
+	return &RefreshTokenService{db: db, ttl: time.Duration(ttlSeconds) * time.Second}
+}
+
+// StartSession opens a new device session for user and returns its first
+// refresh token. Banned users get ErrAccountBanned.
+func (s *RefreshTokenService) StartSession(ctx context.Context, user *models.User, device DeviceInfo) (*IssuedRefreshToken, error) {
+	if user.Banned(time.Now()) {
+		return nil, ErrAccountBanned
+	}
+
+	sessionID, err := utils.GenerateOpaqueToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session id: %w", err)
+	}
+
+	now := time.Now()
+	userID := user.ID
+	session := &models.Session{
+		ID:         sessionID,
+		Username:   user.Username,
+		UserID:     &userID,
+		IsGuest:    false,
+		UserAgent:  truncate(device.UserAgent, 255),
+		IPAddress:  truncate(device.IPAddress, 64),
+		LastUsedAt: &now,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(s.ttl),
+	}
+
+	tx := s.db.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	if err := tx.Create(session).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	issued, err := s.issueToken(tx, session.ID, user.ID, now)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return issued, nil
+}
+
+// Rotate exchanges a refresh token for a new one. Presenting a token that was
+// already rotated revokes its session and returns ErrRefreshTokenReused.
+func (s *RefreshTokenService) Rotate(ctx context.Context, rawToken string, device DeviceInfo) (*models.User, *IssuedRefreshToken, error) {
+	if rawToken == "" {
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+
+	now := time.Now()
+	tx := s.db.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, nil, tx.Error
+	}
+
+	var token models.RefreshToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", utils.HashToken(rawToken)).
+		First(&token).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+
+	var session models.Session
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", token.SessionID).
+		First(&session).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+
+	if session.RevokedAt != nil {
+		tx.Rollback()
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+
+	if token.UsedAt != nil {
+		// A rotated token came back: whoever holds it is not the legitimate
+		// client, so the whole family is burned.
+		if err := revokeSessionTx(tx, session.ID, now); err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+		if err := tx.Commit().Error; err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	if now.After(session.ExpiresAt) || now.After(token.ExpiresAt) {
+		tx.Rollback()
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+
+	var user models.User
+	if err := tx.First(&user, "id = ?", token.UserID).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+	if !user.IsActive {
+		tx.Rollback()
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+	if user.Banned(now) {
+		tx.Rollback()
+		return nil, nil, ErrAccountBanned
+	}
+
+	if err := tx.Model(&token).Update("used_at", now).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	issued, err := s.issueToken(tx, session.ID, user.ID, now)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	updates := map[string]interface{}{
+		"last_used_at": now,
+		"expires_at":   issued.ExpiresAt,
+	}
+	if device.UserAgent != "" {
+		updates["user_agent"] = truncate(device.UserAgent, 255)
+	}
+	if device.IPAddress != "" {
+		updates["ip_address"] = truncate(device.IPAddress, 64)
+	}
+	if err := tx.Model(&session).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, nil, err
+	}
+
+	return &user, issued, nil
+}
+
+// RevokeToken signs out the device session the refresh token belongs to.
+func (s *RefreshTokenService) RevokeToken(ctx context.Context, rawToken string) error {
+	var token models.RefreshToken
+	if err := s.db.DB.WithContext(ctx).Where("token_hash = ?", utils.HashToken(rawToken)).First(&token).Error; err != nil {
+		return ErrRefreshTokenInvalid
+	}
+	return revokeSessionTx(s.db.DB.WithContext(ctx), token.SessionID, time.Now())
+}
+
+// RevokeSession signs out one of userID's device sessions.
+func (s *RefreshTokenService) RevokeSession(ctx context.Context, userID uuid.UUID, sessionID string) error {
+	var session models.Session
+	if err := s.db.DB.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		First(&session).Error; err != nil {
+		return ErrSessionNotFound
+	}
+	return revokeSessionTx(s.db.DB.WithContext(ctx), session.ID, time.Now())
+}
+
+// RevokeAllSessions signs out every device of userID, optionally keeping one session.
+func (s *RefreshTokenService) RevokeAllSessions(ctx context.Context, userID uuid.UUID, keepSessionID string) error {
+	now := time.Now()
+	query := s.db.DB.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND is_guest = ? AND revoked_at IS NULL", userID, false)
+	if keepSessionID != "" {
+		query = query.Where("id <> ?", keepSessionID)
+	}
+	return query.Update("revoked_at", now).Error
+}
+
+// SessionStartedAt returns when userID signed in on the device session
+// sessionID. Signed out and expired sessions give ErrSessionNotFound.
+func (s *RefreshTokenService) SessionStartedAt(ctx context.Context, userID uuid.UUID, sessionID string) (time.Time, error) {
+	var session models.Session
+	if err := s.db.DB.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND is_guest = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, false, time.Now()).
+		First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return time.Time{}, ErrSessionNotFound
+		}
+		return time.Time{}, err
+	}
+	return session.CreatedAt, nil
+}
+
+// ListSessions returns the signed-in devices of userID, most recently used first.
+func (s *RefreshTokenService) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.db.DB.WithContext(ctx).
+		Where("user_id = ? AND is_guest = ? AND revoked_at IS NULL AND expires_at > ?", userID, false, time.Now()).
+		Order("last_used_at DESC NULLS LAST, created_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (s *RefreshTokenService) issueToken(tx *gorm.DB, sessionID string, userID uuid.UUID, now time.Time) (*IssuedRefreshToken, error) {
+	raw, err := utils.GenerateOpaqueToken(refreshTokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	row := &models.RefreshToken{
+		SessionID: sessionID,
+		UserID:    userID,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: now.Add(s.ttl),
+		CreatedAt: now,
+	}
+	if err := tx.Create(row).Error; err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &IssuedRefreshToken{SessionID: sessionID, Token: raw, ExpiresAt: row.ExpiresAt}, nil
+}
+
+func revokeSessionTx(tx *gorm.DB, sessionID string, now time.Time) error {
+	if err := tx.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND used_at IS NULL", sessionID).
+		Update("used_at", now).Error
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max]
+}
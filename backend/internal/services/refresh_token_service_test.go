@@ -0,0 +1,97 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRotateIssuesANewTokenAndRetiresTheOld(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	tokens := NewRefreshTokenService(db, 3600)
+	user := createTestUser(t, db, "rotator")
+
+	first, err := tokens.StartSession(ctx, user, DeviceInfo{UserAgent: "laptop"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	rotatedFor, second, err := tokens.Rotate(ctx, first.Token, DeviceInfo{UserAgent: "laptop"})
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if rotatedFor.ID != user.ID {
+		t.Errorf("rotated for %s, want %s", rotatedFor.ID, user.ID)
+	}
+	if second.SessionID != first.SessionID || second.Token == first.Token {
+		t.Errorf("rotation gave %+v after %+v, want a new token in the same session", second, first)
+	}
+
+	var live int64
+	db.DB.Model(&models.RefreshToken{}).Where("session_id = ? AND used_at IS NULL", first.SessionID).Count(&live)
+	if live != 1 {
+		t.Errorf("%d unused tokens in the session, want only the new one", live)
+	}
+	if _, _, err := tokens.Rotate(ctx, second.Token, DeviceInfo{}); err != nil {
+		t.Errorf("rotating the new token: %v", err)
+	}
+}
+
+func TestReplayingARotatedTokenRevokesTheSession(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	tokens := NewRefreshTokenService(db, 3600)
+	user := createTestUser(t, db, "replayed")
+
+	first, err := tokens.StartSession(ctx, user, DeviceInfo{})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	_, second, err := tokens.Rotate(ctx, first.Token, DeviceInfo{})
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	if _, _, err := tokens.Rotate(ctx, first.Token, DeviceInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replaying the rotated token: err = %v, want ErrRefreshTokenReused", err)
+	}
+	var session models.Session
+	if err := db.DB.First(&session, "id = ?", first.SessionID).Error; err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	if session.RevokedAt == nil {
+		t.Error("session survived the replay")
+	}
+	if _, _, err := tokens.Rotate(ctx, second.Token, DeviceInfo{}); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("rotating the newest token after the replay: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+}
+
+func TestSessionActiveRejectsARevokedSession(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	tokens := NewRefreshTokenService(db, 3600)
+	user := createTestUser(t, db, "signedout")
+	UseSessionGuard(NewSessionGuard(db.DB, NewMemorySessionStatusCache()))
+	t.Cleanup(func() { UseSessionGuard(nil) })
+
+	kept, err := tokens.StartSession(ctx, user, DeviceInfo{})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	revoked, err := tokens.StartSession(ctx, user, DeviceInfo{})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	if err := tokens.RevokeSession(ctx, user.ID, revoked.SessionID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+
+	if active, err := SessionActive(ctx, user.ID, revoked.SessionID); err != nil || active {
+		t.Errorf("revoked session: SessionActive() = %v, %v, want false", active, err)
+	}
+	if active, err := SessionActive(ctx, user.ID, kept.SessionID); err != nil || !active {
+		t.Errorf("other session: SessionActive() = %v, %v, want true", active, err)
+	}
+}
@@ -2,12 +2,56 @@ package services
 
 import (
 	"briworld/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const (
+	// seasonBatchSize is how many players each reward and reset transaction
+	// covers.
+	seasonBatchSize = 500
+	// seasonLease is how long an instance owns a season's rollover without
+	// making progress before another instance may take it over.
+	seasonLease       = 5 * time.Minute
+	defaultSeasonDays = 90
+	maxStandingsPage  = 100
+)
+
+var (
+	ErrSeasonNotFound = errors.New("season not found")
+	ErrSeasonActive   = errors.New("a season is already active")
+	ErrSeasonEnding   = errors.New("the active season is already ending")
+)
+
+// seasonRewards are the points paid at the end of a season by final rank.
+var seasonRewards = map[string]int{
+	models.RankBronze:      50,
+	models.RankSilver:      100,
+	models.RankGold:        200,
+	models.RankPlatinum:    350,
+	models.RankDiamond:     500,
+	models.RankMaster:      750,
+	models.RankGrandmaster: 1000,
+	models.RankContinental: 1500,
+	models.RankWorldClass:  2000,
+	models.RankLegend:      3000,
+}
+
+// SeasonStanding is a player's archived result with who they are.
+type SeasonStanding struct {
+	models.SeasonResult
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+}
+
 type SeasonService struct {
 	db *gorm.DB
 }
@@ -16,24 +60,447 @@ func NewSeasonService(db *gorm.DB) *SeasonService {
 	return &SeasonService{db: db}
 }
 
-// CreateSeason creates a new ranked season
-func (ss *SeasonService) CreateSeason(name string, durationDays int) (*models.Season, error) {
-	// End current active season
-	ss.db.Model(&models.Season{}).Where("is_active = ?", true).Update("is_active", false)
-	
-	season := &models.Season{
-		ID:        uuid.New(),
-		Name:      name,
-		StartDate: time.Now(),
-		EndDate:   time.Now().AddDate(0, 0, durationDays),
-		IsActive:  true,
-	}
-	
-	if err := ss.db.Create(season).Error; err != nil {
+// softResetRating pulls a rating towards 1200 for a new season.
+func softResetRating(rating int) int {
+	return int(float64(rating)*0.75) + 300
+}
+
+// nextSeasonWindow is when the season after ended runs: straight after it,
+// for as long, unless that window is already over by now.
+func nextSeasonWindow(ended models.Season, now time.Time) (time.Time, time.Time) {
+	length := ended.EndDate.Sub(ended.StartDate)
+	if length <= 0 {
+		length = defaultSeasonDays * 24 * time.Hour
+	}
+	start := ended.EndDate
+	if !start.Add(length).After(now) {
+		start = now
+	}
+	return start, start.Add(length)
+}
+
+// StartScheduler runs RunDue every interval in the background.
+func (ss *SeasonService) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+			ss.RunDue(ctx)
+			cancel()
+		}
+	}()
+}
+
+// RunDue rolls over every active season whose end date has passed. Instances
+// take a lease on a season before working on it, so each season is rolled
+// over once; a rollover left unfinished resumes once its lease expires.
+func (ss *SeasonService) RunDue(ctx context.Context) {
+	var due []models.Season
+	if err := ss.db.WithContext(ctx).
+		Where("is_active = ? AND ended_at IS NULL AND end_date <= ?", true, time.Now()).
+		Find(&due).Error; err != nil {
+		log.Printf("Season scheduler failed: %v", err)
+		return
+	}
+
+	for _, season := range due {
+		claimed, err := ss.renewLease(ctx, season.ID, true)
+		if err != nil {
+			log.Printf("Failed to claim season %s: %v", season.Name, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if err := ss.rollover(ctx, season); err != nil {
+			log.Printf("Season %s rollover failed: %v", season.Name, err)
+		}
+	}
+}
+
+// renewLease extends this instance's lease on a season's rollover. Claiming
+// only succeeds when no other instance holds a live lease.
+func (ss *SeasonService) renewLease(ctx context.Context, seasonID uuid.UUID, claim bool) (bool, error) {
+	now := time.Now()
+	query := ss.db.WithContext(ctx).Model(&models.Season{}).Where("id = ? AND ended_at IS NULL", seasonID)
+	if claim {
+		query = query.Where("rollover_lease_until IS NULL OR rollover_lease_until < ?", now)
+	}
+	result := query.Update("rollover_lease_until", now.Add(seasonLease))
+	return result.RowsAffected == 1, result.Error
+}
+
+// rollover ends season. Every step can be run again after a crash: results
+// are archived once, rewards are marked paid in the batch that pays them and
+// ratings are only reset for players not yet in the next season.
+func (ss *SeasonService) rollover(ctx context.Context, season models.Season) error {
+	log.Printf("Rolling over season %s", season.Name)
+
+	if err := ss.archiveStandings(ctx, season); err != nil {
+		return fmt.Errorf("archive standings: %w", err)
+	}
+
+	for {
+		paid, err := ss.payRewardBatch(ctx, season.ID)
+		if err != nil {
+			return fmt.Errorf("pay rewards: %w", err)
+		}
+		if paid == 0 {
+			break
+		}
+		if _, err := ss.renewLease(ctx, season.ID, false); err != nil {
+			return err
+		}
+	}
+
+	next, err := ss.openNextSeason(ctx, season)
+	if err != nil {
+		return fmt.Errorf("open next season: %w", err)
+	}
+
+	for {
+		reset, err := ss.resetRatingBatch(ctx, next.ID)
+		if err != nil {
+			return fmt.Errorf("reset ratings: %w", err)
+		}
+		if reset == 0 {
+			break
+		}
+		if _, err := ss.renewLease(ctx, season.ID, false); err != nil {
+			return err
+		}
+	}
+
+	finished := false
+	err = ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.Season{}).
+			Where("id = ? AND ended_at IS NULL", season.ID).
+			Updates(map[string]interface{}{"is_active": false, "ended_at": now, "rollover_lease_until": nil})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		finished = true
+		return tx.Model(next).Update("is_active", true).Error
+	})
+	if err != nil {
+		return fmt.Errorf("finish: %w", err)
+	}
+	if finished {
+		log.Printf("Season %s ended, %s started", season.Name, next.Name)
+		ss.notifySeasonEnded([]models.Season{season}, next)
+	}
+	return nil
+}
+
+// archiveStandings records the final standing of everyone who played a
+// ranked game during season, from their record in season_ranks, along with
+// the reward their final rank in the season earns.
+func (ss *SeasonService) archiveStandings(ctx context.Context, season models.Season) error {
+	rewards := make([]string, 0, len(seasonRewards))
+	args := []interface{}{season.ID}
+	for _, rank := range models.RankOrder {
+		rewards = append(rewards, "WHEN ? THEN ?")
+		args = append(args, rank, seasonRewards[rank])
+	}
+	args = append(args, season.ID)
+
+	return ss.db.WithContext(ctx).Exec(`
+		INSERT INTO season_results (id, season_id, user_id, position, rating, rank, rank_tier, games, wins, reward, created_at)
+		SELECT gen_random_uuid(), ?, sr.user_id,
+			ROW_NUMBER() OVER (ORDER BY sr.rating DESC, sr.points DESC, u.username ASC),
+			sr.rating, sr.rank, sr.rank_tier, sr.wins + sr.losses, sr.wins,
+			CASE sr.rank `+strings.Join(rewards, " ")+` ELSE 0 END,
+			NOW()
+		FROM season_ranks sr
+		JOIN users u ON u.id = sr.user_id
+		WHERE sr.season_id = ? AND u.anonymized_at IS NULL
+		ON CONFLICT (season_id, user_id) DO NOTHING
+	`, args...).Error
+}
+
+// payRewardBatch pays the rewards archived with up to seasonBatchSize unpaid
+// results in one transaction and returns how many it paid.
+func (ss *SeasonService) payRewardBatch(ctx context.Context, seasonID uuid.UUID) (int, error) {
+	paid := 0
+	err := ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var batch []models.SeasonResult
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("season_id = ? AND rewarded_at IS NULL", seasonID).
+			Order("position ASC").
+			Limit(seasonBatchSize).
+			Find(&batch).Error; err != nil {
+			return err
+		}
+
+		byReward := make(map[int][]uuid.UUID)
+		ids := make([]uuid.UUID, 0, len(batch))
+		for _, result := range batch {
+			ids = append(ids, result.ID)
+			if result.Reward > 0 {
+				byReward[result.Reward] = append(byReward[result.Reward], result.UserID)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		for reward, userIDs := range byReward {
+			if err := tx.Model(&models.User{}).Where("id IN ?", userIDs).
+				Update("total_points", gorm.Expr("total_points + ?", reward)).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.SeasonResult{}).Where("id IN ?", ids).
+			Update("rewarded_at", time.Now()).Error; err != nil {
+			return err
+		}
+		paid = len(ids)
+		return nil
+	})
+	return paid, err
+}
+
+// openNextSeason creates the season that follows ended, or returns it when an
+// earlier attempt already did. It stays inactive until the rollover is done.
+func (ss *SeasonService) openNextSeason(ctx context.Context, ended models.Season) (*models.Season, error) {
+	var next models.Season
+	err := ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.Season
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, "id = ?", ended.ID).Error; err != nil {
+			return err
+		}
+		if current.NextSeasonID != nil {
+			return tx.First(&next, "id = ?", *current.NextSeasonID).Error
+		}
+
+		var count int64
+		if err := tx.Model(&models.Season{}).Count(&count).Error; err != nil {
+			return err
+		}
+		start, end := nextSeasonWindow(current, time.Now())
+		next = models.Season{
+			ID:        uuid.New(),
+			Name:      fmt.Sprintf("Season %d", count+1),
+			StartDate: start,
+			EndDate:   end,
+		}
+		if err := tx.Create(&next).Error; err != nil {
+			return err
+		}
+		return tx.Model(&current).Update("next_season_id", next.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// ResetUserRatings soft resets every rated player into seasonID, one batch
+// per transaction.
+func (ss *SeasonService) ResetUserRatings(seasonID uuid.UUID) error {
+	for {
+		reset, err := ss.resetRatingBatch(context.Background(), seasonID)
+		if err != nil || reset == 0 {
+			return err
+		}
+	}
+}
+
+// resetRatingBatch soft resets up to seasonBatchSize players who are not in
+// seasonID yet, recording each change in their rank history, and returns
+// how many it reset.
+func (ss *SeasonService) resetRatingBatch(ctx context.Context, seasonID uuid.UUID) (int, error) {
+	reset := 0
+	err := ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var users []models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "rating", "rank").
+			Where("total_games > 0 AND (season_id IS NULL OR season_id <> ?)", seasonID).
+			Order("id").
+			Limit(seasonBatchSize).
+			Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+
+		history := make([]models.RankHistory, 0, len(users))
+		rows := make([]string, 0, len(users))
+		args := []interface{}{seasonID}
+		for _, user := range users {
+			rating := softResetRating(user.Rating)
+			rank, tier := models.GetRankFromRating(rating)
+			history = append(history, models.RankHistory{
+				ID:        uuid.New(),
+				UserID:    user.ID,
+				SeasonID:  seasonID,
+				OldRank:   user.Rank,
+				NewRank:   rank,
+				OldRating: user.Rating,
+				NewRating: rating,
+				Reason:    models.RankChangeSeasonReset,
+			})
+			rows = append(rows, "(?::uuid, ?::int, ?, ?::int)")
+			args = append(args, user.ID, rating, rank, tier)
+		}
+
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+			UPDATE users
+			SET rating = v.rating, rank = v.rank, rank_tier = v.tier, season_id = ?,
+				placement_matches = 0, is_placement_complete = false
+			FROM (VALUES `+strings.Join(rows, ", ")+`) AS v(id, rating, rank, tier)
+			WHERE users.id = v.id
+		`, args...).Error; err != nil {
+			return err
+		}
+		reset = len(users)
+		return nil
+	})
+	return reset, err
+}
+
+// notifySeasonEnded tells everyone who played in the ended seasons that they
+// are over and which season follows.
+func (ss *SeasonService) notifySeasonEnded(ended []models.Season, next *models.Season) {
+	if notifications == nil {
+		return
+	}
+
+	for _, season := range ended {
+		ranked := ss.db.Model(&models.SeasonRank{}).Select("user_id").Where("season_id = ?", season.ID)
+		archived := ss.db.Model(&models.SeasonResult{}).Select("user_id").Where("season_id = ?", season.ID)
+		var userIDs []uuid.UUID
+		if err := ss.db.Model(&models.User{}).
+			Where("anonymized_at IS NULL AND (season_id = ? OR id IN (?) OR id IN (?))", season.ID, ranked, archived).
+			Pluck("id", &userIDs).Error; err != nil {
+			log.Printf("Failed to find players of season %s: %v", season.Name, err)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := notifications.NotifyMany(ctx, userIDs, NotificationSeasonEnded, map[string]interface{}{
+			"season_id":   season.ID,
+			"season_name": season.Name,
+			"next_season": map[string]interface{}{
+				"id":       next.ID,
+				"name":     next.Name,
+				"end_date": next.EndDate,
+			},
+		})
+		cancel()
+		if err != nil {
+			log.Printf("Failed to notify players of season %s: %v", season.Name, err)
+		}
+	}
+}
+
+// StartSeason opens a season named name that runs for days from now. It
+// fails with ErrSeasonActive while another season is active. An empty name
+// numbers the season after the ones before it.
+func (ss *SeasonService) StartSeason(ctx context.Context, name string, days int) (*models.Season, error) {
+	if days <= 0 {
+		days = defaultSeasonDays
+	}
+	var season models.Season
+	err := ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var active int64
+		if err := tx.Model(&models.Season{}).Where("is_active = ?", true).Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			return ErrSeasonActive
+		}
+		if name == "" {
+			var count int64
+			if err := tx.Model(&models.Season{}).Count(&count).Error; err != nil {
+				return err
+			}
+			name = fmt.Sprintf("Season %d", count+1)
+		}
+
+		now := time.Now()
+		season = models.Season{
+			ID:        uuid.New(),
+			Name:      name,
+			StartDate: now,
+			EndDate:   now.AddDate(0, 0, days),
+			IsActive:  true,
+		}
+		return tx.Create(&season).Error
+	})
+	if err != nil {
 		return nil, err
 	}
-	
-	return season, nil
+	return &season, nil
+}
+
+// EndActiveSeason ends the active season now and returns it along with the
+// season that follows it, named nextName and running for nextDays. Zero
+// values name and time the next season the way a scheduled rollover would.
+// The rollover itself runs in the background.
+func (ss *SeasonService) EndActiveSeason(ctx context.Context, nextName string, nextDays int) (*models.Season, *models.Season, error) {
+	var ending, next models.Season
+	err := ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("is_active = ?", true).
+			First(&ending).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNoActiveSeason
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if ending.EndedAt != nil || ending.NextSeasonID != nil || !ending.EndDate.After(now) {
+			return ErrSeasonEnding
+		}
+
+		if nextName == "" {
+			var count int64
+			if err := tx.Model(&models.Season{}).Count(&count).Error; err != nil {
+				return err
+			}
+			nextName = fmt.Sprintf("Season %d", count+1)
+		}
+		length := ending.EndDate.Sub(ending.StartDate)
+		if nextDays > 0 {
+			length = time.Duration(nextDays) * 24 * time.Hour
+		} else if length <= 0 {
+			length = defaultSeasonDays * 24 * time.Hour
+		}
+		next = models.Season{
+			ID:        uuid.New(),
+			Name:      nextName,
+			StartDate: now,
+			EndDate:   now.Add(length),
+		}
+		if err := tx.Create(&next).Error; err != nil {
+			return err
+		}
+		ending.EndDate = now
+		ending.NextSeasonID = &next.ID
+		return tx.Model(&ending).Updates(map[string]interface{}{
+			"end_date":       ending.EndDate,
+			"next_season_id": ending.NextSeasonID,
+		}).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		ss.RunDue(ctx)
+	}()
+	return &ending, &next, nil
 }
 
 // GetActiveSeason returns the current active season
@@ -43,43 +510,46 @@ func (ss *SeasonService) GetActiveSeason() (*models.Season, error) {
 	return &season, err
 }
 
-// ResetUserRatings performs soft reset on all users for new season
-func (ss *SeasonService) ResetUserRatings(seasonID uuid.UUID) error {
-	var users []models.User
-	if err := ss.db.Find(&users).Error; err != nil {
-		return err
-	}
-	
-	for _, user := range users {
-		oldRating := user.Rating
-		oldRank := user.Rank
-		
-		// Soft reset formula: (oldRating * 0.75) + 300
-		newRating := int(float64(oldRating)*0.75) + 300
-		newRank, newTier := models.GetRankFromRating(newRating)
-		
-		// Save rank history
-		history := models.RankHistory{
-			ID:        uuid.New(),
-			UserID:    user.ID,
-			SeasonID:  seasonID,
-			OldRank:   oldRank,
-			NewRank:   newRank,
-			OldRating: oldRating,
-			NewRating: newRating,
-		}
-		ss.db.Create(&history)
-		
-		// Update user
-		user.Rating = newRating
-		user.Rank = newRank
-		user.RankTier = newTier
-		user.SeasonID = &seasonID
-		user.PlacementMatches = 0
-		user.IsPlacementComplete = false
-		
-		ss.db.Save(&user)
-	}
-	
-	return nil
+// ListSeasons returns every season, newest first.
+func (ss *SeasonService) ListSeasons(ctx context.Context) ([]models.Season, error) {
+	seasons := []models.Season{}
+	err := ss.db.WithContext(ctx).Order("start_date DESC").Find(&seasons).Error
+	return seasons, err
+}
+
+// Standings returns a page of the final standings archived for a season,
+// with the total number of players in them.
+func (ss *SeasonService) Standings(ctx context.Context, seasonID uuid.UUID, limit, offset int) (*models.Season, []SeasonStanding, int64, error) {
+	if limit <= 0 || limit > maxStandingsPage {
+		limit = maxStandingsPage
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var season models.Season
+	if err := ss.db.WithContext(ctx).First(&season, "id = ?", seasonID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, 0, ErrSeasonNotFound
+		}
+		return nil, nil, 0, err
+	}
+
+	var total int64
+	if err := ss.db.WithContext(ctx).Model(&models.SeasonResult{}).
+		Where("season_id = ?", seasonID).Count(&total).Error; err != nil {
+		return nil, nil, 0, err
+	}
+
+	standings := []SeasonStanding{}
+	if err := ss.db.WithContext(ctx).Model(&models.SeasonResult{}).
+		Select("season_results.*, users.username, users.avatar_url").
+		Joins("JOIN users ON users.id = season_results.user_id").
+		Where("season_results.season_id = ?", seasonID).
+		Order("season_results.position ASC").
+		Limit(limit).Offset(offset).
+		Scan(&standings).Error; err != nil {
+		return nil, nil, 0, err
+	}
+	return &season, standings, total, nil
 }
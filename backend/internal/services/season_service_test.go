@@ -0,0 +1,192 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNextSeasonWindow(t *testing.T) {
+	start := time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC)
+	ended := models.Season{StartDate: start, EndDate: start.AddDate(0, 0, 90)}
+
+	// Rolled over on time: the next season follows straight on
+	now := ended.EndDate.Add(time.Minute)
+	from, to := nextSeasonWindow(ended, now)
+	if !from.Equal(ended.EndDate) || !to.Equal(ended.EndDate.AddDate(0, 0, 90)) {
+		t.Fatalf("window = %v - %v", from, to)
+	}
+
+	// Rolled over after the next season would already be over
+	now = ended.EndDate.AddDate(0, 0, 200)
+	from, to = nextSeasonWindow(ended, now)
+	if !from.Equal(now) || !to.Equal(now.AddDate(0, 0, 90)) {
+		t.Fatalf("late window = %v - %v", from, to)
+	}
+}
+
+func TestSoftResetRating(t *testing.T) {
+	cases := map[int]int{1000: 1050, 1200: 1200, 2000: 1800, 0: 300}
+	for rating, want := range cases {
+		if got := softResetRating(rating); got != want {
+			t.Errorf("softResetRating(%d) = %d, want %d", rating, got, want)
+		}
+	}
+}
+
+func TestEveryRankHasASeasonReward(t *testing.T) {
+	last := 0
+	for _, rank := range models.RankOrder {
+		reward, ok := seasonRewards[rank]
+		if !ok {
+			t.Fatalf("%s has no reward", rank)
+		}
+		if reward <= last {
+			t.Fatalf("%s reward %d should beat the rank below's %d", rank, reward, last)
+		}
+		last = reward
+	}
+}
+
+func TestRunDueRollsOverFromTheSeasonStandings(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	now := time.Now()
+	season := models.Season{
+		ID:        uuid.New(),
+		Name:      "Season 1",
+		StartDate: now.AddDate(0, 0, -30),
+		EndDate:   now.Add(-time.Minute),
+		IsActive:  true,
+	}
+	if err := db.DB.Create(&season).Error; err != nil {
+		t.Fatalf("create season: %v", err)
+	}
+
+	// The leader's global rating is far above where they finished the
+	// season: the reward has to follow the season standing.
+	leader := createTestUser(t, db, "leader")
+	runnerUp := createTestUser(t, db, "runnerup")
+	casual := createTestUser(t, db, "casual")
+	for user, rating := range map[*models.User]int{leader: 2400, runnerUp: 1100, casual: 1300} {
+		rank, tier := models.GetRankFromRating(rating)
+		if err := db.DB.Model(user).Updates(map[string]interface{}{
+			"rating": rating, "rank": rank, "rank_tier": tier, "total_games": 10, "season_id": season.ID,
+		}).Error; err != nil {
+			t.Fatalf("rate %s: %v", user.Username, err)
+		}
+		user.Rating = rating
+	}
+	standings := []models.SeasonRank{
+		{UserID: leader.ID, SeasonID: season.ID, Rating: 1500, Points: 900, Wins: 7, Losses: 3},
+		{UserID: runnerUp.ID, SeasonID: season.ID, Rating: 1100, Points: 400, Wins: 2, Losses: 4},
+	}
+	for i := range standings {
+		standings[i].Rank, standings[i].RankTier = models.GetRankFromRating(standings[i].Rating)
+		if err := db.DB.Create(&standings[i]).Error; err != nil {
+			t.Fatalf("create season rank: %v", err)
+		}
+	}
+
+	seasons := NewSeasonService(db.DB)
+	seasons.RunDue(ctx)
+
+	var results []models.SeasonResult
+	if err := db.DB.Where("season_id = ?", season.ID).Order("position").Find(&results).Error; err != nil {
+		t.Fatalf("load results: %v", err)
+	}
+	if len(results) != len(standings) {
+		t.Fatalf("archived %d results, want one per season rank (%d)", len(results), len(standings))
+	}
+	for i, result := range results {
+		want := standings[i]
+		if result.UserID != want.UserID || result.Position != i+1 || result.Rating != want.Rating ||
+			result.Rank != want.Rank || result.Games != want.Wins+want.Losses || result.Wins != want.Wins {
+			t.Errorf("result %d = %+v, want the standing %+v", i+1, result, want)
+		}
+		if result.Reward != seasonRewards[want.Rank] || result.RewardedAt == nil {
+			t.Errorf("result %d reward = %d paid at %v, want %d paid", i+1, result.Reward, result.RewardedAt, seasonRewards[want.Rank])
+		}
+	}
+
+	var ended models.Season
+	if err := db.DB.First(&ended, "id = ?", season.ID).Error; err != nil {
+		t.Fatalf("load season: %v", err)
+	}
+	if ended.IsActive || ended.EndedAt == nil || ended.NextSeasonID == nil || ended.RolloverLeaseUntil != nil {
+		t.Fatalf("season after rollover = %+v, want it ended with a next season", ended)
+	}
+	var next models.Season
+	if err := db.DB.First(&next, "id = ?", *ended.NextSeasonID).Error; err != nil || !next.IsActive {
+		t.Fatalf("next season = %+v, %v, want it active", next, err)
+	}
+
+	for _, user := range []*models.User{leader, runnerUp, casual} {
+		var got models.User
+		if err := db.DB.First(&got, "id = ?", user.ID).Error; err != nil {
+			t.Fatalf("load %s: %v", user.Username, err)
+		}
+		wantPoints := 0
+		for i, standing := range standings {
+			if standing.UserID == user.ID {
+				wantPoints = results[i].Reward
+			}
+		}
+		if got.TotalPoints != wantPoints {
+			t.Errorf("%s has %d points, want the %d reward", user.Username, got.TotalPoints, wantPoints)
+		}
+		wantRating := softResetRating(user.Rating)
+		if got.Rating != wantRating || got.SeasonID == nil || *got.SeasonID != next.ID {
+			t.Errorf("%s after reset: rating %d in season %v, want %d in %s", user.Username, got.Rating, got.SeasonID, wantRating, next.ID)
+		}
+		var resets int64
+		db.DB.Model(&models.RankHistory{}).
+			Where("user_id = ? AND season_id = ? AND reason = ?", user.ID, next.ID, models.RankChangeSeasonReset).
+			Count(&resets)
+		if resets != 1 {
+			t.Errorf("%s has %d season reset entries, want 1", user.Username, resets)
+		}
+	}
+
+	// The season is over: running again must not pay or reset twice
+	seasons.RunDue(ctx)
+	var leaderAfter models.User
+	db.DB.First(&leaderAfter, "id = ?", leader.ID)
+	if leaderAfter.TotalPoints != results[0].Reward || leaderAfter.Rating != softResetRating(leader.Rating) {
+		t.Errorf("second run changed the leader to %d points, rating %d", leaderAfter.TotalPoints, leaderAfter.Rating)
+	}
+}
+
+func TestRunDueLeavesALeasedSeasonAlone(t *testing.T) {
+	db := testDB(t)
+	now := time.Now()
+	leased := now.Add(time.Minute)
+	season := models.Season{
+		ID:                 uuid.New(),
+		Name:               "Season 1",
+		StartDate:          now.AddDate(0, 0, -30),
+		EndDate:            now.Add(-time.Minute),
+		IsActive:           true,
+		RolloverLeaseUntil: &leased,
+	}
+	if err := db.DB.Create(&season).Error; err != nil {
+		t.Fatalf("create season: %v", err)
+	}
+	player := createTestUser(t, db, "waiting")
+	if err := db.DB.Create(&models.SeasonRank{UserID: player.ID, SeasonID: season.ID, Rating: 1100, Rank: models.RankBronze}).Error; err != nil {
+		t.Fatalf("create season rank: %v", err)
+	}
+
+	NewSeasonService(db.DB).RunDue(context.Background())
+
+	var results int64
+	db.DB.Model(&models.SeasonResult{}).Where("season_id = ?", season.ID).Count(&results)
+	var got models.Season
+	db.DB.First(&got, "id = ?", season.ID)
+	if results != 0 || got.EndedAt != nil || got.NextSeasonID != nil {
+		t.Errorf("another instance's lease was ignored: %d results, season %+v", results, got)
+	}
+}
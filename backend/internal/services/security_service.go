@@ -0,0 +1,113 @@
+package services
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"briworld/internal/utils"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxLoginEventsPage = 100
+
+// DeviceFingerprint condenses the user agent that identifies a browser into
+// a short stable value.
+func DeviceFingerprint(userAgent string) string {
+	return utils.HashToken(userAgent)[:32]
+}
+
+// SecurityService keeps the login audit trail and decides when a sign-in
+// deserves an alert.
+type SecurityService struct {
+	db *database.GormDB
+}
+
+func NewSecurityService(db *database.GormDB) *SecurityService {
+	return &SecurityService{db: db}
+}
+
+// RecordSuccess stores a successful sign-in and reports whether it came
+// from an IP address or device never seen in an earlier successful sign-in.
+// The first sign-in of an account is never reported as new.
+func (s *SecurityService) RecordSuccess(ctx context.Context, userID uuid.UUID, device DeviceInfo) (bool, error) {
+	db := s.db.DB.WithContext(ctx)
+	fingerprint := DeviceFingerprint(device.UserAgent)
+
+	var previous int64
+	if err := db.Model(&models.LoginEvent{}).
+		Where("user_id = ? AND success = ?", userID, true).
+		Count(&previous).Error; err != nil {
+		return false, err
+	}
+
+	newDevice := false
+	if previous > 0 {
+		var knownIP, knownDevice int64
+		if err := db.Model(&models.LoginEvent{}).
+			Where("user_id = ? AND success = ? AND ip_address = ?", userID, true, device.IPAddress).
+			Count(&knownIP).Error; err != nil {
+			return false, err
+		}
+		if err := db.Model(&models.LoginEvent{}).
+			Where("user_id = ? AND success = ? AND device_fingerprint = ?", userID, true, fingerprint).
+			Count(&knownDevice).Error; err != nil {
+			return false, err
+		}
+		newDevice = knownIP == 0 || knownDevice == 0
+	}
+
+	event := &models.LoginEvent{
+		UserID:            userID,
+		IPAddress:         device.IPAddress,
+		UserAgent:         truncate(device.UserAgent, 255),
+		DeviceFingerprint: fingerprint,
+		Success:           true,
+	}
+	if err := db.Create(event).Error; err != nil {
+		return false, err
+	}
+
+	return newDevice, nil
+}
+
+// RecordFailure stores a failed sign-in for the account with the given email.
+// Attempts against unknown emails are not recorded.
+func (s *SecurityService) RecordFailure(ctx context.Context, email string, device DeviceInfo, reason string) error {
+	db := s.db.DB.WithContext(ctx)
+
+	var user models.User
+	err := db.Select("id").Where("email = ?", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return db.Create(&models.LoginEvent{
+		UserID:            user.ID,
+		IPAddress:         device.IPAddress,
+		UserAgent:         truncate(device.UserAgent, 255),
+		DeviceFingerprint: DeviceFingerprint(device.UserAgent),
+		Success:           false,
+		FailureReason:     reason,
+	}).Error
+}
+
+// ListEvents returns the latest sign-in attempts against userID, newest first.
+func (s *SecurityService) ListEvents(ctx context.Context, userID uuid.UUID, limit int) ([]models.LoginEvent, error) {
+	if limit <= 0 || limit > maxLoginEventsPage {
+		limit = maxLoginEventsPage
+	}
+
+	var events []models.LoginEvent
+	err := s.db.DB.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
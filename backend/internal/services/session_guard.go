@@ -0,0 +1,142 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// An access token of a signed-in session keeps working for up to
+	// sessionActiveTTL after the session is revoked. Revoked sessions never
+	// come back, so that answer is kept longer.
+	sessionActiveTTL  = 15 * time.Second
+	sessionRevokedTTL = time.Hour
+
+	// MemorySessionStatusCache sweeps out expired entries once every
+	// sessionStatusSweepEvery writes rather than on each one.
+	sessionStatusSweepEvery = 1024
+)
+
+// SessionStatusCache remembers for a while whether a device session is still
+// signed in.
+type SessionStatusCache interface {
+	// Get reports whether key is active; found is false when it is not cached.
+	Get(ctx context.Context, key string) (active, found bool, err error)
+	Set(ctx context.Context, key string, active bool, ttl time.Duration) error
+}
+
+// SessionGuard tells whether the device session an access token was issued
+// for is still signed in, so logging out, revoking a device, refresh token
+// reuse and bans cut off access tokens too rather than waiting for them to
+// expire.
+type SessionGuard struct {
+	db    *gorm.DB
+	cache SessionStatusCache
+}
+
+func NewSessionGuard(db *gorm.DB, cache SessionStatusCache) *SessionGuard {
+	return &SessionGuard{db: db, cache: cache}
+}
+
+// Active reports whether sessionID is a live session of userID. Tokens not
+// bound to a session are never active.
+func (g *SessionGuard) Active(ctx context.Context, userID uuid.UUID, sessionID string) (bool, error) {
+	if userID == uuid.Nil || sessionID == "" {
+		return false, nil
+	}
+	key := fmt.Sprintf("%s:%s", userID, sessionID)
+	if active, found, err := g.cache.Get(ctx, key); err == nil && found {
+		return active, nil
+	}
+
+	var count int64
+	if err := g.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND is_guest = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, false, time.Now()).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	active := count > 0
+	ttl := sessionActiveTTL
+	if !active {
+		ttl = sessionRevokedTTL
+	}
+	// A cache that cannot be written only costs another lookup next time
+	_ = g.cache.Set(ctx, key, active, ttl)
+	return active, nil
+}
+
+// sessionGuard checks access tokens against their sessions. Tokens are only
+// checked for their signature while it is nil.
+var sessionGuard *SessionGuard
+
+// UseSessionGuard makes g the guard access tokens are checked by.
+func UseSessionGuard(g *SessionGuard) {
+	sessionGuard = g
+}
+
+// SessionActive reports whether the session an access token was issued for
+// is still signed in, using the shared guard if there is one.
+func SessionActive(ctx context.Context, userID uuid.UUID, sessionID string) (bool, error) {
+	if sessionGuard == nil {
+		return true, nil
+	}
+	return sessionGuard.Active(ctx, userID, sessionID)
+}
+
+// MemorySessionStatusCache is a SessionStatusCache for a single server
+// instance. Expired entries are dropped when they are read and in a sweep
+// every sessionStatusSweepEvery writes.
+type MemorySessionStatusCache struct {
+	mu      sync.Mutex
+	entries map[string]memorySessionStatus
+	writes  int
+}
+
+type memorySessionStatus struct {
+	active  bool
+	expires time.Time
+}
+
+func NewMemorySessionStatusCache() *MemorySessionStatusCache {
+	return &MemorySessionStatusCache{entries: make(map[string]memorySessionStatus)}
+}
+
+func (c *MemorySessionStatusCache) Get(ctx context.Context, key string) (bool, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return false, false, nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return false, false, nil
+	}
+	return entry.active, true, nil
+}
+
+func (c *MemorySessionStatusCache) Set(ctx context.Context, key string, active bool, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.writes++
+	if c.writes >= sessionStatusSweepEvery {
+		c.writes = 0
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = memorySessionStatus{active: active, expires: now.Add(ttl)}
+	return nil
+}
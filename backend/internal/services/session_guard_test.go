@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSessionGuardUsesCachedStatus(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemorySessionStatusCache()
+	// No database: every answer below has to come from the cache
+	guard := NewSessionGuard(nil, cache)
+	userID := uuid.New()
+
+	if active, err := guard.Active(ctx, userID, ""); err != nil || active {
+		t.Errorf("token without a session: Active() = %v, %v, want false", active, err)
+	}
+
+	cache.Set(ctx, userID.String()+":live", true, time.Minute)
+	cache.Set(ctx, userID.String()+":revoked", false, time.Minute)
+	if active, err := guard.Active(ctx, userID, "live"); err != nil || !active {
+		t.Errorf("live session: Active() = %v, %v, want true", active, err)
+	}
+	if active, err := guard.Active(ctx, userID, "revoked"); err != nil || active {
+		t.Errorf("revoked session: Active() = %v, %v, want false", active, err)
+	}
+}
+
+func TestMemorySessionStatusCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemorySessionStatusCache()
+
+	cache.Set(ctx, "gone", true, -time.Second)
+	if _, found, _ := cache.Get(ctx, "gone"); found {
+		t.Error("expired status was still cached")
+	}
+	if _, found, _ := cache.Get(ctx, "never"); found {
+		t.Error("unknown session was cached")
+	}
+}
+
+func TestMemorySessionStatusCacheSweepsExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemorySessionStatusCache()
+
+	cache.Set(ctx, "stale", true, -time.Second)
+	for i := 0; i < sessionStatusSweepEvery; i++ {
+		cache.Set(ctx, "live", true, time.Minute)
+	}
+	if _, ok := cache.entries["stale"]; ok {
+		t.Error("expired entry survived the sweep")
+	}
+	if _, ok := cache.entries["live"]; !ok {
+		t.Error("sweep dropped a live entry")
+	}
+}
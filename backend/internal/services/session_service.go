@@ -5,9 +5,11 @@ import (
 	"briworld/internal/models"
 	"crypto/rand"
 	"encoding/hex"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
 )
 
 type SessionService struct {
@@ -49,6 +51,50 @@ func (s *SessionService) CreateGuestSession(username string) (*models.Session, e
 	return session, nil
 }
 
+// guestSessionTTL is how long a guest session stays bound to the connection
+// that registered it after its last use.
+const guestSessionTTL = 24 * time.Hour
+
+// RegisterGuestSession records sessionID, chosen by a guest's client, as
+// username's guest session. It returns false when the ID was already
+// registered, so each guest session is handed out only once. The binding
+// lasts while the guest keeps playing; a session unused for guestSessionTTL
+// is removed by the cleanup job and can then be registered afresh.
+func (s *SessionService) RegisterGuestSession(sessionID, username string) (bool, error) {
+	session := &models.Session{
+		ID:        sessionID,
+		Username:  username,
+		IsGuest:   true,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(guestSessionTTL),
+	}
+
+	result := s.db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(session)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// TouchGuestSession extends the binding of a guest session whose token was
+// presented, recording it again if the cleanup job already removed it.
+func (s *SessionService) TouchGuestSession(sessionID, username string) error {
+	now := time.Now()
+	session := &models.Session{
+		ID:        sessionID,
+		Username:  username,
+		IsGuest:   true,
+		CreatedAt: now,
+		ExpiresAt: now.Add(guestSessionTTL),
+	}
+
+	return s.db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Eq{Column: "sessions.is_guest", Value: true}}},
+	}).Create(session).Error
+}
+
 // CreateUserSession creates a session for a logged-in user
 func (s *SessionService) CreateUserSession(userID uuid.UUID, username string) (*models.Session, error) {
 	sessionID, err := s.GenerateSessionID()
@@ -107,6 +153,21 @@ func (s *SessionService) CleanupExpiredSessions() error {
 	return s.db.DB.Where("expires_at < ?", time.Now()).Delete(&models.Session{}).Error
 }
 
+// StartCleanupJob runs CleanupExpiredSessions every interval in the
+// background.
+func (s *SessionService) StartCleanupJob(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.CleanupExpiredSessions(); err != nil {
+				log.Printf("Session cleanup job failed: %v", err)
+			}
+		}
+	}()
+}
+
 // UpdateSessionActivity updates the last active time
 func (s *SessionService) UpdateSessionActivity(sessionID string) error {
 	return s.db.DB.Model(&models.Session{}).Where("id = ?", sessionID).Update("expires_at", time.Now().Add(24*time.Hour)).Error
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB opens the Postgres database in TEST_DATABASE_URL inside a fresh
+// schema, migrated the way the app migrates on startup, and drops the schema
+// when t ends. It also becomes database.GetDB(). Tests that need it are
+// skipped when TEST_DATABASE_URL is not set.
+func testDB(t *testing.T) *database.GormDB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	db, err := database.NewGorm(withSearchPath(dsn, schema+",public"))
+	if err != nil {
+		t.Fatalf("open test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	migrations := []func(*database.GormDB) error{
+		database.MigrateMetaTables,
+		database.MigrateProfileCustomization,
+		database.MigrateAuthSessions,
+		database.MigrateEmailVerification,
+		database.MigrateOIDCIdentities,
+		database.MigrateGuestProgress,
+		database.MigrateTwoFactor,
+		database.MigrateLoginEvents,
+		database.MigrateAccountDeletion,
+		database.MigrateAssetKeys,
+		database.MigrateStorageQuotas,
+		database.MigratePlayerProfiles,
+		database.MigrateFriends,
+		database.MigrateNotifications,
+		database.MigrateTournaments,
+		database.MigrateDailyChallengeAttempts,
+		database.MigrateDailyStreaks,
+		database.MigrateAchievementEngine,
+		database.MigrateMasterySchedule,
+		database.MigrateSeasonRollover,
+		database.MigrateUnifiedRanks,
+		database.MigrateLeaderboards,
+		database.MigrateRankDecay,
+		database.MigrateAntiCheat,
+		database.MigrateAdmin,
+		database.MigrateGuestClaims,
+		database.MigrateAnswerMisses,
+	}
+	for i, migrate := range migrations {
+		if err := migrate(db); err != nil {
+			t.Fatalf("migration %d: %v", i, err)
+		}
+	}
+	return db
+}
+
+// withSearchPath points dsn, in URL or keyword/value form, at searchPath.
+func withSearchPath(dsn, searchPath string) string {
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		q.Set("search_path", searchPath)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return fmt.Sprintf("%s search_path=%s", dsn, searchPath)
+}
+
+// createTestUser stores an active player called username.
+func createTestUser(t *testing.T, db *database.GormDB, username string) *models.User {
+	t.Helper()
+	user := &models.User{
+		Username:     username,
+		Email:        username + "@example.com",
+		PasswordHash: "x",
+		IsActive:     true,
+	}
+	if err := db.DB.Create(user).Error; err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	return user
+}
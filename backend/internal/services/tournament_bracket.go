@@ -0,0 +1,394 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// seedOrder returns the seeds of a bracket of size slots in match order, so
+// the top seeds meet as late as possible: 1 v 8, 4 v 5, 2 v 7, 3 v 6.
+func seedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, len(order)*2+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+func idPtr(id uuid.UUID) *uuid.UUID {
+	return &id
+}
+
+func newBracketMatch(tournamentID uuid.UUID, bracket string, round, position int) *models.TournamentMatch {
+	return &models.TournamentMatch{
+		ID:           uuid.New(),
+		TournamentID: tournamentID,
+		Bracket:      bracket,
+		Round:        round,
+		Position:     position,
+		Status:       models.TournamentMatchPending,
+	}
+}
+
+func linkWinner(from, to *models.TournamentMatch, slot int) {
+	from.NextMatchID = &to.ID
+	from.NextSlot = slot
+}
+
+func linkLoser(from, to *models.TournamentMatch, slot int) {
+	from.LoserNextMatchID = &to.ID
+	from.LoserNextSlot = slot
+}
+
+// buildElimination lays out a single or double elimination bracket for
+// entrants, ordered by seed. The bracket is padded to a power of two with
+// byes, which go to the top seeds. In double elimination, losers of the
+// winners bracket drop into the losers bracket, and its winner meets the
+// winners bracket champion in a single grand final.
+func buildElimination(tournamentID uuid.UUID, entrants []*models.TournamentEntrant, double bool) []*models.TournamentMatch {
+	rounds := 1
+	for 1<<rounds < len(entrants) {
+		rounds++
+	}
+	size := 1 << rounds
+
+	var matches []*models.TournamentMatch
+	winners := make([][]*models.TournamentMatch, rounds+1)
+	for round := 1; round <= rounds; round++ {
+		for position := 0; position < size>>round; position++ {
+			m := newBracketMatch(tournamentID, models.BracketWinners, round, position)
+			winners[round] = append(winners[round], m)
+			matches = append(matches, m)
+		}
+	}
+
+	order := seedOrder(size)
+	for position, m := range winners[1] {
+		if seed := order[2*position]; seed <= len(entrants) {
+			m.EntrantAID = idPtr(entrants[seed-1].ID)
+		}
+		if seed := order[2*position+1]; seed <= len(entrants) {
+			m.EntrantBID = idPtr(entrants[seed-1].ID)
+		}
+		m.SlotADecided, m.SlotBDecided = true, true
+	}
+	for round := 1; round < rounds; round++ {
+		for position, m := range winners[round] {
+			linkWinner(m, winners[round+1][position/2], position%2)
+		}
+	}
+
+	if !double {
+		return matches
+	}
+
+	final := newBracketMatch(tournamentID, models.BracketGrandFinal, 1, 0)
+	matches = append(matches, final)
+	linkWinner(winners[rounds][0], final, 0)
+	if rounds == 1 {
+		linkLoser(winners[1][0], final, 1)
+		return matches
+	}
+
+	// The losers bracket alternates between rounds that halve its field and
+	// rounds that take in the losers of the next winners round.
+	losersRounds := 2 * (rounds - 1)
+	losers := make([][]*models.TournamentMatch, losersRounds+1)
+	for round := 1; round <= losersRounds; round++ {
+		for position := 0; position < size>>((round+1)/2+1); position++ {
+			m := newBracketMatch(tournamentID, models.BracketLosers, round, position)
+			losers[round] = append(losers[round], m)
+			matches = append(matches, m)
+		}
+	}
+
+	for position, m := range winners[1] {
+		linkLoser(m, losers[1][position/2], position%2)
+	}
+	for round := 2; round <= rounds; round++ {
+		drop := losers[2*(round-1)]
+		for position, m := range winners[round] {
+			// Dropping losers in reverse keeps early rematches rare.
+			linkLoser(m, drop[len(drop)-1-position], 1)
+		}
+	}
+	for round := 1; round < losersRounds; round++ {
+		for position, m := range losers[round] {
+			if round%2 == 1 {
+				linkWinner(m, losers[round+1][position], 0)
+			} else {
+				linkWinner(m, losers[round+1][position/2], position%2)
+			}
+		}
+	}
+	linkWinner(losers[losersRounds][0], final, 1)
+
+	return matches
+}
+
+// bracketState advances a tournament's matches as results come in. It tracks
+// which matches changed and which are ready to be played.
+type bracketState struct {
+	matches  map[uuid.UUID]*models.TournamentMatch
+	entrants map[uuid.UUID]*models.TournamentEntrant
+	changed  map[uuid.UUID]bool
+	ready    []*models.TournamentMatch
+	queued   map[uuid.UUID]bool
+	// champion is set when the last match of an elimination bracket ends.
+	champion *uuid.UUID
+}
+
+func newBracketState(matches []*models.TournamentMatch, entrants []*models.TournamentEntrant) *bracketState {
+	b := &bracketState{
+		matches:  make(map[uuid.UUID]*models.TournamentMatch, len(matches)),
+		entrants: make(map[uuid.UUID]*models.TournamentEntrant, len(entrants)),
+		changed:  make(map[uuid.UUID]bool),
+		queued:   make(map[uuid.UUID]bool),
+	}
+	for _, m := range matches {
+		b.matches[m.ID] = m
+	}
+	for _, e := range entrants {
+		b.entrants[e.ID] = e
+	}
+	return b
+}
+
+// place puts entrant, or nobody, on one side of the match with id.
+func (b *bracketState) place(id *uuid.UUID, slot int, entrant *uuid.UUID) {
+	if id == nil {
+		return
+	}
+	m := b.matches[*id]
+	if m == nil {
+		return
+	}
+	if slot == 0 {
+		m.EntrantAID, m.SlotADecided = entrant, true
+	} else {
+		m.EntrantBID, m.SlotBDecided = entrant, true
+	}
+	b.changed[m.ID] = true
+	b.settle(m)
+}
+
+// settle moves a pending match on once both sides are known: byes are
+// decided at once and real pairings become ready to play.
+func (b *bracketState) settle(m *models.TournamentMatch) {
+	if m.Status != models.TournamentMatchPending || !m.SlotADecided || !m.SlotBDecided {
+		return
+	}
+	switch {
+	case m.EntrantAID != nil && m.EntrantBID != nil:
+		if !b.queued[m.ID] {
+			b.queued[m.ID] = true
+			b.ready = append(b.ready, m)
+		}
+	case m.EntrantAID != nil:
+		b.finish(m, m.EntrantAID, nil)
+	case m.EntrantBID != nil:
+		b.finish(m, m.EntrantBID, nil)
+	default:
+		b.finish(m, nil, nil)
+	}
+}
+
+// finish records the outcome of m and sends the winner and loser on. A loser
+// with nowhere to go is out of the tournament.
+func (b *bracketState) finish(m *models.TournamentMatch, winner, loser *uuid.UUID) {
+	m.Status = models.TournamentMatchCompleted
+	m.WinnerID = winner
+	b.changed[m.ID] = true
+
+	if winner != nil && loser != nil {
+		if e := b.entrants[*winner]; e != nil {
+			e.Wins++
+		}
+		if e := b.entrants[*loser]; e != nil {
+			e.Losses++
+			if m.LoserNextMatchID == nil && m.Bracket != models.BracketSwiss {
+				e.Eliminated = true
+			}
+		}
+	}
+	if m.Bracket == models.BracketSwiss {
+		if winner != nil && loser == nil {
+			// A bye counts as a win.
+			if e := b.entrants[*winner]; e != nil {
+				e.Wins++
+			}
+		}
+		return
+	}
+
+	if m.NextMatchID == nil {
+		b.champion = winner
+		return
+	}
+	b.place(m.NextMatchID, m.NextSlot, winner)
+	b.place(m.LoserNextMatchID, m.LoserNextSlot, loser)
+}
+
+// report records the scores of a played match. A tie goes to the better
+// seed.
+func (b *bracketState) report(m *models.TournamentMatch, scoreA, scoreB int) {
+	m.ScoreA, m.ScoreB = scoreA, scoreB
+	winner, loser := m.EntrantAID, m.EntrantBID
+	if scoreB > scoreA || (scoreA == scoreB && b.seed(m.EntrantBID) < b.seed(m.EntrantAID)) {
+		winner, loser = loser, winner
+	}
+	b.finish(m, winner, loser)
+}
+
+func (b *bracketState) seed(id *uuid.UUID) int {
+	if id == nil {
+		return math.MaxInt
+	}
+	if e := b.entrants[*id]; e != nil {
+		return e.Seed
+	}
+	return math.MaxInt
+}
+
+// swissRounds is how many Swiss rounds it takes to single out a winner among
+// n entrants.
+func swissRounds(n int) int {
+	rounds := 1
+	for 1<<rounds < n {
+		rounds++
+	}
+	return rounds
+}
+
+// swissStandings orders entrants by wins, then by the wins of the opponents
+// they played, then by seed.
+func swissStandings(entrants []*models.TournamentEntrant, matches []*models.TournamentMatch) []*models.TournamentEntrant {
+	wins := make(map[uuid.UUID]int, len(entrants))
+	for _, e := range entrants {
+		wins[e.ID] = e.Wins
+	}
+	buchholz := make(map[uuid.UUID]int, len(entrants))
+	for _, m := range matches {
+		if m.EntrantAID != nil && m.EntrantBID != nil {
+			buchholz[*m.EntrantAID] += wins[*m.EntrantBID]
+			buchholz[*m.EntrantBID] += wins[*m.EntrantAID]
+		}
+	}
+
+	standings := append([]*models.TournamentEntrant(nil), entrants...)
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if buchholz[a.ID] != buchholz[b.ID] {
+			return buchholz[a.ID] > buchholz[b.ID]
+		}
+		return a.Seed < b.Seed
+	})
+	return standings
+}
+
+// pairSwissRound pairs entrants for the given round, each against the next
+// best entrant in the standings they have not played yet. With an odd field
+// the lowest ranked entrant without a bye sits the round out with a win.
+func pairSwissRound(tournamentID uuid.UUID, round int, entrants []*models.TournamentEntrant, previous []*models.TournamentMatch) []*models.TournamentMatch {
+	played := make(map[[2]uuid.UUID]bool)
+	hadBye := make(map[uuid.UUID]bool)
+	for _, m := range previous {
+		switch {
+		case m.EntrantAID != nil && m.EntrantBID != nil:
+			played[[2]uuid.UUID{*m.EntrantAID, *m.EntrantBID}] = true
+			played[[2]uuid.UUID{*m.EntrantBID, *m.EntrantAID}] = true
+		case m.EntrantAID != nil:
+			hadBye[*m.EntrantAID] = true
+		}
+	}
+
+	standings := swissStandings(entrants, previous)
+	var matches []*models.TournamentMatch
+
+	if len(standings)%2 == 1 {
+		bye := len(standings) - 1
+		for i := len(standings) - 1; i >= 0; i-- {
+			if !hadBye[standings[i].ID] {
+				bye = i
+				break
+			}
+		}
+		m := newBracketMatch(tournamentID, models.BracketSwiss, round, 0)
+		m.EntrantAID = idPtr(standings[bye].ID)
+		m.SlotADecided, m.SlotBDecided = true, true
+		matches = append(matches, m)
+		standings = append(standings[:bye:bye], standings[bye+1:]...)
+	}
+
+	pairs := pairWithoutRematches(standings, played)
+	for _, pair := range pairs {
+		m := newBracketMatch(tournamentID, models.BracketSwiss, round, len(matches))
+		m.EntrantAID, m.EntrantBID = idPtr(standings[pair[0]].ID), idPtr(standings[pair[1]].ID)
+		m.SlotADecided, m.SlotBDecided = true, true
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// swissPairingBudget bounds the search for a pairing without rematches.
+const swissPairingBudget = 10000
+
+// pairWithoutRematches pairs an even field top down, backtracking when the
+// only opponents left have been played. If no such pairing turns up within
+// the budget, each entrant simply meets the next one in the standings.
+func pairWithoutRematches(standings []*models.TournamentEntrant, played map[[2]uuid.UUID]bool) [][2]int {
+	paired := make([]bool, len(standings))
+	pairs := make([][2]int, 0, len(standings)/2)
+	steps := 0
+
+	var search func() bool
+	search = func() bool {
+		first := -1
+		for i := range standings {
+			if !paired[i] {
+				first = i
+				break
+			}
+		}
+		if first < 0 {
+			return true
+		}
+		paired[first] = true
+		for j := first + 1; j < len(standings); j++ {
+			if paired[j] || played[[2]uuid.UUID{standings[first].ID, standings[j].ID}] {
+				continue
+			}
+			if steps++; steps > swissPairingBudget {
+				break
+			}
+			paired[j] = true
+			pairs = append(pairs, [2]int{first, j})
+			if search() {
+				return true
+			}
+			pairs = pairs[:len(pairs)-1]
+			paired[j] = false
+		}
+		paired[first] = false
+		return false
+	}
+	if search() {
+		return pairs
+	}
+
+	pairs = pairs[:0]
+	for i := 0; i+1 < len(standings); i += 2 {
+		pairs = append(pairs, [2]int{i, i + 1})
+	}
+	return pairs
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"briworld/internal/models"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func testEntrants(n int) []*models.TournamentEntrant {
+	entrants := make([]*models.TournamentEntrant, n)
+	for i := range entrants {
+		entrants[i] = &models.TournamentEntrant{ID: uuid.New(), Seed: i + 1}
+	}
+	return entrants
+}
+
+func TestSeedOrder(t *testing.T) {
+	if got := seedOrder(8); !reflect.DeepEqual(got, []int{1, 8, 4, 5, 2, 7, 3, 6}) {
+		t.Fatalf("unexpected seed order %v", got)
+	}
+}
+
+// playOut reports every ready match, the better seed always winning, and
+// returns the champion.
+func playOut(t *testing.T, matches []*models.TournamentMatch, entrants []*models.TournamentEntrant) (*bracketState, uuid.UUID) {
+	t.Helper()
+	b := newBracketState(matches, entrants)
+	for _, m := range matches {
+		b.settle(m)
+	}
+	for played := 0; len(b.ready) > 0; played++ {
+		if played > len(matches) {
+			t.Fatal("bracket did not finish")
+		}
+		m := b.ready[0]
+		b.ready = b.ready[1:]
+		m.Status = models.TournamentMatchLive
+		if b.seed(m.EntrantAID) < b.seed(m.EntrantBID) {
+			b.report(m, 10, 5)
+		} else {
+			b.report(m, 5, 10)
+		}
+	}
+	if b.champion == nil {
+		t.Fatal("bracket ended without a champion")
+	}
+	return b, *b.champion
+}
+
+func TestSingleEliminationWithByes(t *testing.T) {
+	entrants := testEntrants(5)
+	matches := buildElimination(uuid.New(), entrants, false)
+	if len(matches) != 7 {
+		t.Fatalf("a bracket of 8 slots has 7 matches, got %d", len(matches))
+	}
+
+	b, champion := playOut(t, matches, entrants)
+	if champion != entrants[0].ID {
+		t.Fatal("the top seed should win when the better seed always wins")
+	}
+	for _, e := range entrants[1:] {
+		if !e.Eliminated || e.Losses != 1 {
+			t.Fatalf("every other entrant should be out after one loss, got %+v", e)
+		}
+	}
+	for _, m := range b.matches {
+		if m.Status != models.TournamentMatchCompleted {
+			t.Fatalf("match %d/%d was left %s", m.Round, m.Position, m.Status)
+		}
+	}
+}
+
+func TestDoubleEliminationNeedsTwoLosses(t *testing.T) {
+	for _, n := range []int{2, 3, 4, 6, 8} {
+		entrants := testEntrants(n)
+		matches := buildElimination(uuid.New(), entrants, true)
+
+		_, champion := playOut(t, matches, entrants)
+		if champion != entrants[0].ID {
+			t.Fatalf("%d entrants: the top seed should win", n)
+		}
+		for _, e := range entrants[1:] {
+			if !e.Eliminated {
+				t.Fatalf("%d entrants: seed %d was never eliminated", n, e.Seed)
+			}
+			if n > 2 && e.Seed == 2 && e.Losses != 2 {
+				t.Fatalf("%d entrants: the runner-up should be out after two losses, got %d", n, e.Losses)
+			}
+		}
+	}
+}
+
+func TestSwissPairingAvoidsRematches(t *testing.T) {
+	entrants := testEntrants(5)
+	tournamentID := uuid.New()
+	var played []*models.TournamentMatch
+	byes := map[uuid.UUID]int{}
+
+	for round := 1; round <= swissRounds(len(entrants)); round++ {
+		matches := pairSwissRound(tournamentID, round, entrants, played)
+		b := newBracketState(matches, entrants)
+		for _, m := range matches {
+			b.settle(m)
+			if m.EntrantBID == nil {
+				byes[*m.EntrantAID]++
+			}
+		}
+		for _, m := range b.ready {
+			for _, previous := range played {
+				if previous.EntrantBID == nil {
+					continue
+				}
+				if (*previous.EntrantAID == *m.EntrantAID && *previous.EntrantBID == *m.EntrantBID) ||
+					(*previous.EntrantAID == *m.EntrantBID && *previous.EntrantBID == *m.EntrantAID) {
+					t.Fatalf("round %d repeats a pairing", round)
+				}
+			}
+			b.report(m, 10, 5)
+		}
+		played = append(played, matches...)
+	}
+
+	for id, count := range byes {
+		if count > 1 {
+			t.Fatalf("entrant %s had %d byes", id, count)
+		}
+	}
+	if standings := swissStandings(entrants, played); standings[0].Wins < standings[len(standings)-1].Wins {
+		t.Fatal("standings should rank by wins")
+	}
+}
@@ -0,0 +1,842 @@
+package services
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxTournamentEntrants   = 64
+	maxTournamentTeamSize   = 3
+	defaultTournamentRounds = 10
+	defaultCheckInMinutes   = 30
+)
+
+// tournamentModes are the game modes tournaments can be played in.
+var tournamentModes = map[string]bool{
+	"FLAG":          true,
+	"WORLD_MAP":     true,
+	"SILHOUETTE":    true,
+	"LAST_STANDING": true,
+	"BORDER_LOGIC":  true,
+}
+
+var (
+	ErrInvalidTournament       = errors.New("invalid tournament")
+	ErrTournamentNotFound      = errors.New("tournament not found")
+	ErrNotTournamentOrganizer  = errors.New("only the organizer can do that")
+	ErrRegistrationClosed      = errors.New("registration is closed")
+	ErrTournamentFull          = errors.New("tournament is full")
+	ErrAlreadyRegistered       = errors.New("a player on this team is already registered")
+	ErrWrongTeamSize           = errors.New("team size does not match the tournament")
+	ErrEntrantNotFound         = errors.New("you have not registered a team for this tournament")
+	ErrCheckInClosed           = errors.New("check-in is not open")
+	ErrTournamentNotStartable  = errors.New("tournament cannot be started yet")
+	ErrNotEnoughEntrants       = errors.New("fewer than 2 entrants checked in; the tournament was cancelled")
+	ErrTournamentMatchNotLive  = errors.New("match is not being played")
+	ErrTournamentMatchNotFound = errors.New("match not found")
+	ErrTournamentFinished      = errors.New("tournament has already finished")
+)
+
+// MatchRoomSpec describes the room an organized match is played in. Its
+// settings are fixed, and only the listed players play, on side 0 or 1.
+type MatchRoomSpec struct {
+	// RoomCode reopens a known room; empty opens a new one.
+	RoomCode       string
+	GameMode       string
+	Rounds         int
+	RoundTimeLimit int
+	Sides          map[uuid.UUID]int
+	// RoomType is what the room is shown and recorded as. Empty opens a
+	// private room recorded as a tournament match.
+	RoomType string
+	// Countries, when set, are asked in this order instead of random ones.
+	Countries []string
+	// OnComplete receives each side's total score when the game ends.
+	OnComplete func(scores [2]int)
+}
+
+// MatchRoomOpener opens rooms for organized matches.
+type MatchRoomOpener interface {
+	OpenMatchRoom(spec MatchRoomSpec) (string, error)
+}
+
+// LivePusher sends a message that is not stored to a user's open sockets.
+type LivePusher interface {
+	Send(userID uuid.UUID, messageType string, payload interface{})
+}
+
+// TournamentInput is what an organizer sets when creating a tournament.
+type TournamentInput struct {
+	Name           string    `json:"name"`
+	Format         string    `json:"format"`
+	GameMode       string    `json:"game_mode"`
+	Rounds         int       `json:"rounds"`
+	RoundTimeLimit int       `json:"round_time_limit"`
+	TeamSize       int       `json:"team_size"`
+	MaxEntrants    int       `json:"max_entrants"`
+	SwissRounds    int       `json:"swiss_rounds"`
+	StartsAt       time.Time `json:"starts_at"`
+	CheckInMinutes int       `json:"check_in_minutes"`
+}
+
+// TournamentBracket is a tournament with its entrants and matches.
+type TournamentBracket struct {
+	models.Tournament
+	Entrants []models.TournamentEntrant `json:"entrants"`
+	Matches  []models.TournamentMatch   `json:"matches"`
+}
+
+// TournamentService runs tournaments: registration, check-in, brackets and
+// the rooms their matches are played in. Tournament games are unrated.
+type TournamentService struct {
+	db    *database.GormDB
+	rooms MatchRoomOpener
+	live  LivePusher
+}
+
+func NewTournamentService(db *database.GormDB, rooms MatchRoomOpener, live LivePusher) *TournamentService {
+	return &TournamentService{db: db, rooms: rooms, live: live}
+}
+
+func invalidTournament(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidTournament, reason)
+}
+
+// Create validates input and schedules a tournament organized by userID.
+func (s *TournamentService) Create(ctx context.Context, userID uuid.UUID, input TournamentInput) (*models.Tournament, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if len(input.Name) < 3 || len(input.Name) > 100 {
+		return nil, invalidTournament("name must be 3 to 100 characters")
+	}
+	switch input.Format {
+	case models.TournamentSingleElimination, models.TournamentDoubleElimination, models.TournamentSwiss:
+	default:
+		return nil, invalidTournament("format must be single_elimination, double_elimination or swiss")
+	}
+	if !tournamentModes[input.GameMode] {
+		return nil, invalidTournament("game mode is not available for tournaments")
+	}
+	if input.Rounds == 0 {
+		input.Rounds = defaultTournamentRounds
+	}
+	if input.Rounds < 1 || input.Rounds > 30 {
+		return nil, invalidTournament("rounds must be 1 to 30")
+	}
+	if input.RoundTimeLimit == 0 {
+		input.RoundTimeLimit = 15
+	}
+	if input.RoundTimeLimit < 10 || input.RoundTimeLimit > 60 {
+		return nil, invalidTournament("round time limit must be 10 to 60 seconds")
+	}
+	if input.TeamSize == 0 {
+		input.TeamSize = 1
+	}
+	if input.TeamSize < 1 || input.TeamSize > maxTournamentTeamSize {
+		return nil, invalidTournament(fmt.Sprintf("team size must be 1 to %d", maxTournamentTeamSize))
+	}
+	if input.MaxEntrants < 2 || input.MaxEntrants > maxTournamentEntrants {
+		return nil, invalidTournament(fmt.Sprintf("max entrants must be 2 to %d", maxTournamentEntrants))
+	}
+	if input.Format != models.TournamentSwiss {
+		input.SwissRounds = 0
+	} else if input.SwissRounds < 0 || input.SwissRounds > 10 {
+		return nil, invalidTournament("swiss rounds must be 0 to 10")
+	}
+	if input.CheckInMinutes == 0 {
+		input.CheckInMinutes = defaultCheckInMinutes
+	}
+	if input.CheckInMinutes < 5 || input.CheckInMinutes > 24*60 {
+		return nil, invalidTournament("check-in must open 5 minutes to 24 hours before the start")
+	}
+	if !input.StartsAt.After(time.Now()) {
+		return nil, invalidTournament("start time must be in the future")
+	}
+
+	tournament := &models.Tournament{
+		Name:           input.Name,
+		Format:         input.Format,
+		GameMode:       input.GameMode,
+		Rounds:         input.Rounds,
+		RoundTimeLimit: input.RoundTimeLimit,
+		TeamSize:       input.TeamSize,
+		MaxEntrants:    input.MaxEntrants,
+		SwissRounds:    input.SwissRounds,
+		Status:         models.TournamentRegistering,
+		CreatedBy:      userID,
+		CheckInOpensAt: input.StartsAt.Add(-time.Duration(input.CheckInMinutes) * time.Minute),
+		StartsAt:       input.StartsAt,
+	}
+	if err := s.db.DB.WithContext(ctx).Create(tournament).Error; err != nil {
+		return nil, err
+	}
+	return tournament, nil
+}
+
+// List returns tournaments with the given status, soonest first. Without a
+// status it returns those that have not finished.
+func (s *TournamentService) List(ctx context.Context, status string) ([]models.Tournament, error) {
+	query := s.db.DB.WithContext(ctx)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status IN ?", []string{models.TournamentRegistering, models.TournamentInProgress})
+	}
+
+	tournaments := []models.Tournament{}
+	if err := query.Order("starts_at ASC").Limit(100).Find(&tournaments).Error; err != nil {
+		return nil, err
+	}
+	return tournaments, nil
+}
+
+// Get returns the tournament with its entrants and bracket.
+func (s *TournamentService) Get(ctx context.Context, id uuid.UUID) (*TournamentBracket, error) {
+	db := s.db.DB.WithContext(ctx)
+
+	var bracket TournamentBracket
+	err := db.First(&bracket.Tournament, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTournamentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bracket.Entrants = []models.TournamentEntrant{}
+	if err := db.Preload("Members").Where("tournament_id = ?", id).
+		Order("seed ASC, created_at ASC").Find(&bracket.Entrants).Error; err != nil {
+		return nil, err
+	}
+	bracket.Matches = []models.TournamentMatch{}
+	if err := db.Where("tournament_id = ?", id).
+		Order("bracket ASC, round ASC, position ASC").Find(&bracket.Matches).Error; err != nil {
+		return nil, err
+	}
+	return &bracket, nil
+}
+
+// lockTournament loads the tournament and locks its row until tx ends, which
+// keeps concurrent results from advancing the bracket over each other.
+func lockTournament(tx *gorm.DB, id uuid.UUID) (*models.Tournament, error) {
+	var tournament models.Tournament
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tournament, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTournamentNotFound
+	}
+	return &tournament, err
+}
+
+// Register enters captain in the tournament: alone in solo tournaments,
+// otherwise with party, the players of their team including the captain.
+func (s *TournamentService) Register(ctx context.Context, tournamentID uuid.UUID, captain PlayerCard, party []PlayerCard, name string) (*models.TournamentEntrant, error) {
+	var entrant *models.TournamentEntrant
+	err := s.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tournament, err := lockTournament(tx, tournamentID)
+		if err != nil {
+			return err
+		}
+		if tournament.Status != models.TournamentRegistering || !time.Now().Before(tournament.StartsAt) {
+			return ErrRegistrationClosed
+		}
+		members := []PlayerCard{captain}
+		if tournament.TeamSize > 1 {
+			members = party
+		}
+		if len(members) != tournament.TeamSize {
+			return ErrWrongTeamSize
+		}
+
+		var entrants int64
+		if err := tx.Model(&models.TournamentEntrant{}).Where("tournament_id = ?", tournamentID).Count(&entrants).Error; err != nil {
+			return err
+		}
+		if int(entrants) >= tournament.MaxEntrants {
+			return ErrTournamentFull
+		}
+
+		ids := make([]uuid.UUID, len(members))
+		rating := 0
+		for i, m := range members {
+			ids[i] = m.ID
+			rating += m.Rating
+		}
+		var taken int64
+		if err := tx.Model(&models.TournamentEntrantMember{}).
+			Where("tournament_id = ? AND user_id IN ?", tournamentID, ids).
+			Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrAlreadyRegistered
+		}
+
+		name = strings.TrimSpace(name)
+		if name == "" {
+			name = captain.Username
+			if len(members) > 1 {
+				name += "'s team"
+			}
+		}
+		if len(name) > 100 {
+			name = name[:100]
+		}
+
+		entrant = &models.TournamentEntrant{
+			TournamentID: tournamentID,
+			Name:         name,
+			CaptainID:    captain.ID,
+			Rating:       rating / len(members),
+		}
+		if err := tx.Create(entrant).Error; err != nil {
+			return err
+		}
+		entrant.Members = make([]models.TournamentEntrantMember, len(members))
+		for i, m := range members {
+			entrant.Members[i] = models.TournamentEntrantMember{
+				EntrantID:    entrant.ID,
+				UserID:       m.ID,
+				TournamentID: tournamentID,
+				Username:     m.Username,
+			}
+		}
+		return tx.Create(&entrant.Members).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entrant, nil
+}
+
+// captainEntrant returns the entrant captained by userID.
+func captainEntrant(tx *gorm.DB, tournamentID, userID uuid.UUID) (*models.TournamentEntrant, error) {
+	var entrant models.TournamentEntrant
+	err := tx.Where("tournament_id = ? AND captain_id = ?", tournamentID, userID).First(&entrant).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEntrantNotFound
+	}
+	return &entrant, err
+}
+
+// Withdraw removes the team captained by userID before the tournament starts.
+func (s *TournamentService) Withdraw(ctx context.Context, tournamentID, userID uuid.UUID) error {
+	return s.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tournament, err := lockTournament(tx, tournamentID)
+		if err != nil {
+			return err
+		}
+		if tournament.Status != models.TournamentRegistering {
+			return ErrRegistrationClosed
+		}
+		entrant, err := captainEntrant(tx, tournamentID, userID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("entrant_id = ?", entrant.ID).Delete(&models.TournamentEntrantMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(entrant).Error
+	})
+}
+
+// CheckIn confirms that the team captained by userID will play. Teams that
+// have not checked in when the tournament starts are dropped.
+func (s *TournamentService) CheckIn(ctx context.Context, tournamentID, userID uuid.UUID) (*models.TournamentEntrant, error) {
+	var entrant *models.TournamentEntrant
+	err := s.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tournament, err := lockTournament(tx, tournamentID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if tournament.Status != models.TournamentRegistering || now.Before(tournament.CheckInOpensAt) || !now.Before(tournament.StartsAt) {
+			return ErrCheckInClosed
+		}
+		entrant, err = captainEntrant(tx, tournamentID, userID)
+		if err != nil {
+			return err
+		}
+		if entrant.CheckedInAt == nil {
+			entrant.CheckedInAt = &now
+			return tx.Model(entrant).Update("checked_in_at", now).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entrant, nil
+}
+
+// Start drops the teams that did not check in, seeds the rest by rating and
+// opens the first matches. The organizer can start once check-in opens; the
+// scheduler starts tournaments at their start time. userID is uuid.Nil for
+// the scheduler.
+func (s *TournamentService) Start(ctx context.Context, tournamentID, userID uuid.UUID) error {
+	var ready []*models.TournamentMatch
+	cancelled := false
+	err := s.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tournament, err := lockTournament(tx, tournamentID)
+		if err != nil {
+			return err
+		}
+		if userID != uuid.Nil && tournament.CreatedBy != userID {
+			return ErrNotTournamentOrganizer
+		}
+		if tournament.Status != models.TournamentRegistering || time.Now().Before(tournament.CheckInOpensAt) {
+			return ErrTournamentNotStartable
+		}
+
+		if err := tx.Where("tournament_id = ? AND entrant_id IN (?)", tournamentID,
+			tx.Model(&models.TournamentEntrant{}).Select("id").Where("tournament_id = ? AND checked_in_at IS NULL", tournamentID),
+		).Delete(&models.TournamentEntrantMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tournament_id = ? AND checked_in_at IS NULL", tournamentID).Delete(&models.TournamentEntrant{}).Error; err != nil {
+			return err
+		}
+
+		var entrants []*models.TournamentEntrant
+		if err := tx.Where("tournament_id = ?", tournamentID).Order("rating DESC, created_at ASC").Find(&entrants).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if len(entrants) < 2 {
+			cancelled = true
+			return tx.Model(tournament).Updates(map[string]interface{}{
+				"status":       models.TournamentCancelled,
+				"completed_at": now,
+			}).Error
+		}
+
+		for i, e := range entrants {
+			e.Seed = i + 1
+			if err := tx.Model(e).Update("seed", e.Seed).Error; err != nil {
+				return err
+			}
+		}
+
+		var matches []*models.TournamentMatch
+		switch tournament.Format {
+		case models.TournamentSwiss:
+			if tournament.SwissRounds == 0 {
+				tournament.SwissRounds = swissRounds(len(entrants))
+			}
+			matches = pairSwissRound(tournament.ID, 1, entrants, nil)
+		default:
+			matches = buildElimination(tournament.ID, entrants, tournament.Format == models.TournamentDoubleElimination)
+		}
+
+		tournament.Status = models.TournamentInProgress
+		tournament.CurrentRound = 1
+		tournament.StartedAt = &now
+
+		b := newBracketState(matches, entrants)
+		for _, m := range matches {
+			b.settle(m)
+		}
+		if err := tx.Create(&matches).Error; err != nil {
+			return err
+		}
+		ready, err = s.settleRound(tx, tournament, b)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if cancelled {
+		s.publish(tournamentID)
+		return ErrNotEnoughEntrants
+	}
+	s.openMatches(ready)
+	s.publish(tournamentID)
+	return nil
+}
+
+// Cancel calls off a tournament that has not finished.
+func (s *TournamentService) Cancel(ctx context.Context, tournamentID, userID uuid.UUID) error {
+	err := s.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tournament, err := lockTournament(tx, tournamentID)
+		if err != nil {
+			return err
+		}
+		if tournament.CreatedBy != userID {
+			return ErrNotTournamentOrganizer
+		}
+		if tournament.Status == models.TournamentCompleted || tournament.Status == models.TournamentCancelled {
+			return ErrTournamentFinished
+		}
+		return tx.Model(tournament).Updates(map[string]interface{}{
+			"status":       models.TournamentCancelled,
+			"completed_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.publish(tournamentID)
+	return nil
+}
+
+// advance applies change to the bracket of the match with matchID and saves
+// whatever moved on.
+func (s *TournamentService) advance(ctx context.Context, matchID uuid.UUID, change func(b *bracketState, m *models.TournamentMatch) error) error {
+	var tournamentID uuid.UUID
+	var ready []*models.TournamentMatch
+	err := s.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var match models.TournamentMatch
+		err := tx.First(&match, "id = ?", matchID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTournamentMatchNotFound
+		}
+		if err != nil {
+			return err
+		}
+		tournamentID = match.TournamentID
+
+		tournament, err := lockTournament(tx, match.TournamentID)
+		if err != nil {
+			return err
+		}
+		if tournament.Status != models.TournamentInProgress {
+			return ErrTournamentMatchNotLive
+		}
+
+		var matches []*models.TournamentMatch
+		if err := tx.Where("tournament_id = ?", tournament.ID).Find(&matches).Error; err != nil {
+			return err
+		}
+		var entrants []*models.TournamentEntrant
+		if err := tx.Where("tournament_id = ?", tournament.ID).Find(&entrants).Error; err != nil {
+			return err
+		}
+
+		b := newBracketState(matches, entrants)
+		m := b.matches[matchID]
+		if m == nil || m.Status != models.TournamentMatchLive {
+			return ErrTournamentMatchNotLive
+		}
+		if err := change(b, m); err != nil {
+			return err
+		}
+		ready, err = s.settleRound(tx, tournament, b)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.openMatches(ready)
+	s.publish(tournamentID)
+	return nil
+}
+
+// ReportResult records the side scores of a finished match room and
+// advances the winner.
+func (s *TournamentService) ReportResult(ctx context.Context, matchID uuid.UUID, scores [2]int) error {
+	return s.advance(ctx, matchID, func(b *bracketState, m *models.TournamentMatch) error {
+		now := time.Now()
+		m.CompletedAt = &now
+		b.report(m, scores[0], scores[1])
+		return nil
+	})
+}
+
+// Resolve lets the organizer decide a live match, such as when a team does
+// not show up.
+func (s *TournamentService) Resolve(ctx context.Context, tournamentID, matchID, userID, winnerID uuid.UUID) error {
+	var tournament models.Tournament
+	if err := s.db.DB.WithContext(ctx).First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTournamentNotFound
+		}
+		return err
+	}
+	if tournament.CreatedBy != userID {
+		return ErrNotTournamentOrganizer
+	}
+
+	return s.advance(ctx, matchID, func(b *bracketState, m *models.TournamentMatch) error {
+		if m.TournamentID != tournamentID {
+			return ErrTournamentMatchNotFound
+		}
+		now := time.Now()
+		m.CompletedAt = &now
+		switch {
+		case m.EntrantAID != nil && *m.EntrantAID == winnerID:
+			b.finish(m, m.EntrantAID, m.EntrantBID)
+		case m.EntrantBID != nil && *m.EntrantBID == winnerID:
+			b.finish(m, m.EntrantBID, m.EntrantAID)
+		default:
+			return ErrEntrantNotFound
+		}
+		return nil
+	})
+}
+
+// settleRound saves the changes in b and moves the tournament on: to its
+// next Swiss round once every match of the current one is done, or to its
+// end once there is a champion. It returns the matches ready to be played.
+func (s *TournamentService) settleRound(tx *gorm.DB, tournament *models.Tournament, b *bracketState) ([]*models.TournamentMatch, error) {
+	now := time.Now()
+	ready := b.ready
+	var created []*models.TournamentMatch
+
+	if tournament.Format == models.TournamentSwiss {
+		roundDone := true
+		for _, m := range b.matches {
+			if m.Round == tournament.CurrentRound && m.Status != models.TournamentMatchCompleted {
+				roundDone = false
+				break
+			}
+		}
+		if roundDone {
+			entrants := make([]*models.TournamentEntrant, 0, len(b.entrants))
+			for _, e := range b.entrants {
+				entrants = append(entrants, e)
+			}
+			sort.Slice(entrants, func(i, j int) bool { return entrants[i].Seed < entrants[j].Seed })
+			previous := make([]*models.TournamentMatch, 0, len(b.matches))
+			for _, m := range b.matches {
+				previous = append(previous, m)
+			}
+
+			if tournament.CurrentRound < tournament.SwissRounds {
+				tournament.CurrentRound++
+				created = pairSwissRound(tournament.ID, tournament.CurrentRound, entrants, previous)
+				for _, m := range created {
+					b.matches[m.ID] = m
+					b.settle(m)
+				}
+				ready = b.ready
+			} else {
+				standings := swissStandings(entrants, previous)
+				b.champion = &standings[0].ID
+			}
+		}
+	} else {
+		round := 0
+		for _, m := range b.matches {
+			if m.Status != models.TournamentMatchCompleted && m.Bracket == models.BracketWinners && (round == 0 || m.Round < round) {
+				round = m.Round
+			}
+		}
+		if round > 0 {
+			tournament.CurrentRound = round
+		}
+	}
+
+	if b.champion != nil {
+		tournament.Status = models.TournamentCompleted
+		tournament.WinnerID = b.champion
+		tournament.CompletedAt = &now
+		for _, e := range b.entrants {
+			if e.ID != *b.champion {
+				e.Eliminated = true
+			}
+		}
+	}
+
+	if len(created) > 0 {
+		if err := tx.Create(&created).Error; err != nil {
+			return nil, err
+		}
+	}
+	isCreated := make(map[uuid.UUID]bool, len(created))
+	for _, m := range created {
+		isCreated[m.ID] = true
+	}
+	for id := range b.changed {
+		if isCreated[id] {
+			continue
+		}
+		if err := tx.Save(b.matches[id]).Error; err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range b.entrants {
+		if err := tx.Model(e).Select("wins", "losses", "eliminated").Updates(e).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Model(tournament).Select("status", "current_round", "swiss_rounds", "started_at", "completed_at", "winner_id").Updates(tournament).Error; err != nil {
+		return nil, err
+	}
+	return ready, nil
+}
+
+// openMatches opens a room for each match and tells its players where to go.
+func (s *TournamentService) openMatches(matches []*models.TournamentMatch) {
+	for _, m := range matches {
+		if err := s.openMatch(m); err != nil {
+			log.Printf("Failed to open room for tournament match %s: %v", m.ID, err)
+		}
+	}
+}
+
+func (s *TournamentService) openMatch(m *models.TournamentMatch) error {
+	if s.rooms == nil || m.EntrantAID == nil || m.EntrantBID == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	db := s.db.DB.WithContext(ctx)
+
+	var tournament models.Tournament
+	if err := db.First(&tournament, "id = ?", m.TournamentID).Error; err != nil {
+		return err
+	}
+	var entrants []models.TournamentEntrant
+	if err := db.Preload("Members").Where("id IN ?", []uuid.UUID{*m.EntrantAID, *m.EntrantBID}).Find(&entrants).Error; err != nil {
+		return err
+	}
+
+	sides := make(map[uuid.UUID]int)
+	names := [2]string{}
+	for _, e := range entrants {
+		side := 0
+		if e.ID == *m.EntrantBID {
+			side = 1
+		}
+		names[side] = e.Name
+		for _, member := range e.Members {
+			sides[member.UserID] = side
+		}
+	}
+
+	matchID := m.ID
+	code, err := s.rooms.OpenMatchRoom(MatchRoomSpec{
+		RoomCode:       m.RoomCode,
+		GameMode:       tournament.GameMode,
+		Rounds:         tournament.Rounds,
+		RoundTimeLimit: tournament.RoundTimeLimit,
+		Sides:          sides,
+		OnComplete: func(scores [2]int) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := s.ReportResult(ctx, matchID, scores); err != nil {
+				log.Printf("Failed to record result of tournament match %s: %v", matchID, err)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	reopened := m.Status == models.TournamentMatchLive && m.RoomCode == code
+	now := time.Now()
+	if err := db.Model(&models.TournamentMatch{}).Where("id = ?", m.ID).Updates(map[string]interface{}{
+		"status":     models.TournamentMatchLive,
+		"room_code":  code,
+		"started_at": gorm.Expr("COALESCE(started_at, ?)", now),
+	}).Error; err != nil {
+		return err
+	}
+	if reopened {
+		return nil
+	}
+
+	for userID, side := range sides {
+		notifyUser(userID, NotificationTournamentMatch, map[string]interface{}{
+			"tournament_id":   tournament.ID,
+			"tournament_name": tournament.Name,
+			"match_id":        m.ID,
+			"room_code":       code,
+			"game_mode":       tournament.GameMode,
+			"opponent":        names[1-side],
+		})
+	}
+	return nil
+}
+
+// publish tells every player in the tournament, and its organizer, that the
+// bracket changed.
+func (s *TournamentService) publish(tournamentID uuid.UUID) {
+	if s.live == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	db := s.db.DB.WithContext(ctx)
+
+	var tournament models.Tournament
+	if err := db.First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		return
+	}
+	var userIDs []uuid.UUID
+	if err := db.Model(&models.TournamentEntrantMember{}).Where("tournament_id = ?", tournamentID).Pluck("user_id", &userIDs).Error; err != nil {
+		log.Printf("Failed to load tournament %s players: %v", tournamentID, err)
+		return
+	}
+	userIDs = append(userIDs, tournament.CreatedBy)
+
+	update := map[string]interface{}{
+		"tournament_id": tournament.ID,
+		"status":        tournament.Status,
+		"current_round": tournament.CurrentRound,
+		"winner_id":     tournament.WinnerID,
+	}
+	seen := make(map[uuid.UUID]bool, len(userIDs))
+	for _, id := range userIDs {
+		if !seen[id] {
+			seen[id] = true
+			s.live.Send(id, "tournament_update", update)
+		}
+	}
+}
+
+// RunDue starts tournaments whose start time has come and opens rooms for
+// matches that should have one, such as after a restart.
+func (s *TournamentService) RunDue(ctx context.Context) {
+	var due []uuid.UUID
+	if err := s.db.DB.WithContext(ctx).Model(&models.Tournament{}).
+		Where("status = ? AND starts_at <= ?", models.TournamentRegistering, time.Now()).
+		Pluck("id", &due).Error; err != nil {
+		log.Printf("Tournament scheduler failed: %v", err)
+		return
+	}
+	for _, id := range due {
+		if err := s.Start(ctx, id, uuid.Nil); err != nil && !errors.Is(err, ErrNotEnoughEntrants) {
+			log.Printf("Failed to start tournament %s: %v", id, err)
+		}
+	}
+
+	var matches []*models.TournamentMatch
+	if err := s.db.DB.WithContext(ctx).
+		Joins("JOIN tournaments ON tournaments.id = tournament_matches.tournament_id").
+		Where("tournaments.status = ?", models.TournamentInProgress).
+		Where("tournament_matches.status = ? OR (tournament_matches.status = ? AND tournament_matches.entrant_a_id IS NOT NULL AND tournament_matches.entrant_b_id IS NOT NULL AND tournament_matches.slot_a_decided AND tournament_matches.slot_b_decided)",
+			models.TournamentMatchLive, models.TournamentMatchPending).
+		Find(&matches).Error; err != nil {
+		log.Printf("Tournament scheduler failed: %v", err)
+		return
+	}
+	s.openMatches(matches)
+}
+
+// StartScheduler runs RunDue every interval in the background.
+func (s *TournamentService) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			s.RunDue(ctx)
+			cancel()
+		}
+	}()
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/models"
+	"briworld/internal/utils"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	twoFactorIssuer    = "BriWorld"
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 5
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor enrollment has not been started")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorCodeInvalid    = errors.New("invalid two-factor code")
+)
+
+// TwoFactorEnrollment is what the client needs to add the account to an
+// authenticator app.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// TwoFactorService manages TOTP enrollment and checks TOTP and recovery codes.
+type TwoFactorService struct {
+	db *database.GormDB
+}
+
+func NewTwoFactorService(db *database.GormDB) *TwoFactorService {
+	return &TwoFactorService{db: db}
+}
+
+// Enroll stores a new pending secret for userID. It only takes effect once
+// confirmed with a code from the authenticator.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID uuid.UUID) (*TwoFactorEnrollment, error) {
+	var user models.User
+	if err := s.db.DB.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	if err := s.db.DB.WithContext(ctx).Model(&user).Updates(map[string]interface{}{
+		"two_factor_secret":    secret,
+		"two_factor_last_step": 0,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollment{
+		Secret: secret,
+		URI:    utils.TOTPProvisioningURI(twoFactorIssuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables 2FA once code proves the authenticator holds the pending
+// secret, and returns a fresh set of recovery codes. The codes are only ever
+// shown this once.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	tx := s.db.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var user models.User
+	if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.TwoFactorEnabled {
+		tx.Rollback()
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TwoFactorSecret == "" {
+		tx.Rollback()
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := utils.ValidateTOTP(user.TwoFactorSecret, code, time.Now())
+	if !ok {
+		tx.Rollback()
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	if err := tx.Model(&user).Updates(map[string]interface{}{
+		"two_factor_enabled":   true,
+		"two_factor_last_step": step,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		recoveryCode, err := newRecoveryCode()
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Create(&models.TwoFactorRecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(recoveryCode)),
+		}).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		codes = append(codes, recoveryCode)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns 2FA off after checking code, and drops the secret and any
+// unused recovery codes.
+func (s *TwoFactorService) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	tx := s.db.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"two_factor_enabled":   false,
+		"two_factor_secret":    "",
+		"two_factor_last_step": 0,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// IsEnabled reports whether userID has confirmed 2FA.
+func (s *TwoFactorService) IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var user models.User
+	if err := s.db.DB.WithContext(ctx).Select("two_factor_enabled").First(&user, "id = ?", userID).Error; err != nil {
+		return false, err
+	}
+	return user.TwoFactorEnabled, nil
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code for
+// userID. Each TOTP step and each recovery code can only be used once.
+func (s *TwoFactorService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	db := s.db.DB.WithContext(ctx)
+
+	var user models.User
+	if err := db.First(&user, "id = ?", userID).Error; err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := utils.ValidateTOTP(user.TwoFactorSecret, code, time.Now()); ok {
+		// Moving last_step forward conditionally makes a replayed code lose
+		// the race even when two requests arrive together.
+		result := db.Model(&models.User{}).
+			Where("id = ? AND two_factor_last_step < ?", userID, step).
+			Update("two_factor_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTwoFactorCodeInvalid
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength*2 {
+		return ErrTwoFactorCodeInvalid
+	}
+
+	result := db.Model(&models.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, utils.HashToken(normalized)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorCodeInvalid
+	}
+	return nil
+}
+
+// RemainingRecoveryCodes counts the unused recovery codes of userID.
+func (s *TwoFactorService) RemainingRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := s.db.DB.WithContext(ctx).Model(&models.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// newRecoveryCode returns a code formatted as xxxxx-xxxxx.
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	encoded := hex.EncodeToString(buf)
+	return encoded[:recoveryCodeLength] + "-" + encoded[recoveryCodeLength:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
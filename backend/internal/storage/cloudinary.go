@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"briworld/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+)
+
+var ErrCloudinaryNotConfigured = errors.New("cloudinary is not configured")
+
+// CloudinaryStore keeps objects in Cloudinary. Image public IDs drop the file
+// extension because Cloudinary appends the delivered format itself.
+type CloudinaryStore struct {
+	cld *cloudinary.Cloudinary
+}
+
+func NewCloudinaryStore(cfg config.StorageConfig) (*CloudinaryStore, error) {
+	if cfg.CloudinaryURL != "" {
+		cld, err := cloudinary.NewFromURL(cfg.CloudinaryURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Cloudinary from URL: %w", err)
+		}
+		return &CloudinaryStore{cld: cld}, nil
+	}
+
+	if cfg.CloudinaryCloudName == "" || cfg.CloudinaryAPIKey == "" || cfg.CloudinaryAPISecret == "" {
+		return nil, ErrCloudinaryNotConfigured
+	}
+	cld, err := cloudinary.NewFromParams(cfg.CloudinaryCloudName, cfg.CloudinaryAPIKey, cfg.CloudinaryAPISecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Cloudinary from params: %w", err)
+	}
+	return &CloudinaryStore{cld: cld}, nil
+}
+
+func (s *CloudinaryStore) Name() string {
+	return "cloudinary"
+}
+
+func cloudinaryResourceType(resourceType string) string {
+	if resourceType == "" {
+		return "image"
+	}
+	return resourceType
+}
+
+func cloudinaryPublicID(key, resourceType string) string {
+	if cloudinaryResourceType(resourceType) == "raw" {
+		return key
+	}
+	return strings.TrimSuffix(key, path.Ext(key))
+}
+
+func (s *CloudinaryStore) Put(ctx context.Context, obj Object) (string, error) {
+	key, err := cleanKey(obj.Key)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := s.cld.Upload.Upload(ctx, obj.Body, uploader.UploadParams{
+		PublicID:     cloudinaryPublicID(key, obj.ResourceType),
+		ResourceType: cloudinaryResourceType(obj.ResourceType),
+		Overwrite:    api.Bool(true),
+		Invalidate:   api.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.Error.Message != "" {
+		return "", errors.New(result.Error.Message)
+	}
+	return result.SecureURL, nil
+}
+
+func (s *CloudinaryStore) Delete(ctx context.Context, key, resourceType string) error {
+	result, err := s.cld.Upload.Destroy(ctx, uploader.DestroyParams{
+		PublicID:     cloudinaryPublicID(key, resourceType),
+		ResourceType: cloudinaryResourceType(resourceType),
+		Invalidate:   api.Bool(true),
+	})
+	if err != nil {
+		return err
+	}
+	if result.Error.Message != "" {
+		return errors.New(result.Error.Message)
+	}
+	return nil
+}
+
+// SignedURL returns a signed delivery URL. Cloudinary signatures do not
+// expire, so ttl is ignored.
+func (s *CloudinaryStore) SignedURL(ctx context.Context, key, resourceType string, ttl time.Duration) (string, error) {
+	publicID := cloudinaryPublicID(key, resourceType)
+	asset, err := s.cld.Image(publicID)
+	if cloudinaryResourceType(resourceType) == "raw" {
+		asset, err = s.cld.File(publicID)
+	}
+	if err != nil {
+		return "", err
+	}
+	asset.Config.URL.Secure = true
+	asset.Config.URL.SignURL = true
+	return asset.String()
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LocalStore writes objects below a directory that the server exposes as
+// static files under baseURL.
+type LocalStore struct {
+	root    string
+	baseURL string
+}
+
+func NewLocalStore(root, baseURL string) *LocalStore {
+	return &LocalStore{root: root, baseURL: baseURL}
+}
+
+func (s *LocalStore) Name() string {
+	return "local"
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func (s *LocalStore) url(key string) string {
+	return s.baseURL + "/" + key
+}
+
+func (s *LocalStore) Put(ctx context.Context, obj Object) (string, error) {
+	destination, err := s.path(obj.Key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(destination), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	// Write next to the destination and rename so readers never see a
+	// partially written file.
+	tmp, err := os.CreateTemp(filepath.Dir(destination), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, obj.Body); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), destination); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return s.url(obj.Key), nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key, resourceType string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL returns the public URL: local uploads are served to anyone.
+func (s *LocalStore) SignedURL(ctx context.Context, key, resourceType string, ttl time.Duration) (string, error) {
+	if _, err := cleanKey(key); err != nil {
+		return "", err
+	}
+	return s.url(key), nil
+}
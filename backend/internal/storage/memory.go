@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryObject is an object held by MemoryStore.
+type MemoryObject struct {
+	Data         []byte
+	ContentType  string
+	ResourceType string
+}
+
+// MemoryStore keeps objects in memory. It is meant for tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	objects map[string]MemoryObject
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{objects: make(map[string]MemoryObject)}
+}
+
+func (s *MemoryStore) Name() string {
+	return "memory"
+}
+
+func (s *MemoryStore) Put(ctx context.Context, obj Object) (string, error) {
+	key, err := cleanKey(obj.Key)
+	if err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(obj.Body)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = MemoryObject{
+		Data:         data,
+		ContentType:  obj.ContentType,
+		ResourceType: obj.ResourceType,
+	}
+	return "memory://" + key, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key, resourceType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *MemoryStore) SignedURL(ctx context.Context, key, resourceType string, ttl time.Duration) (string, error) {
+	if _, err := cleanKey(key); err != nil {
+		return "", err
+	}
+	return "memory://" + key, nil
+}
+
+// Get returns the object stored under key.
+func (s *MemoryStore) Get(key string) (MemoryObject, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[key]
+	return obj, ok
+}
+
+// Keys lists the stored keys in order.
+func (s *MemoryStore) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"briworld/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+)
+
+var ErrInvalidKey = errors.New("invalid asset key")
+
+// AssetStore keeps uploaded profile files. Keys are slash-separated paths
+// such as "briworld/avatars/<user id>.png" and are what ProfileAsset.PublicID
+// records, so an asset can be deleted with the store named in its Provider.
+type AssetStore interface {
+	// Name identifies the backend in ProfileAsset.Provider.
+	Name() string
+	// Put stores the object under obj.Key, replacing any previous object,
+	// and returns the URL clients load it from.
+	Put(ctx context.Context, obj Object) (string, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key, resourceType string) error
+	// SignedURL returns a URL that grants read access to key for ttl.
+	// Backends that serve files publicly return the public URL.
+	SignedURL(ctx context.Context, key, resourceType string, ttl time.Duration) (string, error)
+}
+
+// Object is a file handed to AssetStore.Put.
+type Object struct {
+	Key         string
+	Body        io.Reader
+	Size        int64
+	ContentType string
+	// ResourceType is "image" or "raw" (Lottie JSON). Only Cloudinary stores
+	// the two differently.
+	ResourceType string
+}
+
+// New builds the store selected by cfg.Backend.
+func New(cfg config.StorageConfig) (AssetStore, error) {
+	switch cfg.Backend {
+	case "cloudinary":
+		return NewCloudinaryStore(cfg)
+	case "local":
+		return NewLocalStore(cfg.LocalDir, cfg.LocalBaseURL), nil
+	case "s3":
+		return NewS3Store(cfg.S3)
+	case "memory":
+		return NewMemoryStore(), nil
+	case "":
+		if store, err := NewCloudinaryStore(cfg); err == nil {
+			return store, nil
+		}
+		log.Printf("Warning: Cloudinary not configured - uploads are stored in %s", cfg.LocalDir)
+		return NewLocalStore(cfg.LocalDir, cfg.LocalBaseURL), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// cleanKey rejects keys that could escape the store's root.
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || strings.HasPrefix(cleaned, "../") || cleaned == ".." {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
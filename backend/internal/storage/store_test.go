@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanKeyRejectsEscapes(t *testing.T) {
+	for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../b", "a//b", "a\\b", "."} {
+		if _, err := cleanKey(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("cleanKey(%q) error = %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if _, err := cleanKey("briworld/avatars/u.png"); err != nil {
+		t.Fatalf("cleanKey rejected a valid key: %v", err)
+	}
+}
+
+func TestLocalStorePutAndDelete(t *testing.T) {
+	root := t.TempDir()
+	store := NewLocalStore(root, "/uploads")
+	ctx := context.Background()
+
+	url, err := store.Put(ctx, Object{Key: "briworld/avatars/u.png", Body: strings.NewReader("png")})
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if url != "/uploads/briworld/avatars/u.png" {
+		t.Fatalf("url = %q", url)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "briworld", "avatars", "u.png"))
+	if err != nil || string(data) != "png" {
+		t.Fatalf("stored file = %q, %v", data, err)
+	}
+
+	if err := store.Delete(ctx, "briworld/avatars/u.png", "image"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "briworld", "avatars", "u.png")); !os.IsNotExist(err) {
+		t.Fatalf("file still present: %v", err)
+	}
+	if err := store.Delete(ctx, "briworld/avatars/u.png", "image"); err != nil {
+		t.Fatalf("Delete of a missing file: %v", err)
+	}
+}
+
+func TestMemoryStoreReplacesObjects(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	for _, body := range []string{"first", "second"} {
+		if _, err := store.Put(ctx, Object{Key: "k.json", Body: strings.NewReader(body), ResourceType: "raw"}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	obj, ok := store.Get("k.json")
+	if !ok || string(obj.Data) != "second" || obj.ResourceType != "raw" {
+		t.Fatalf("Get = %+v, %v", obj, ok)
+	}
+
+	if err := store.Delete(ctx, "k.json", "raw"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if keys := store.Keys(); len(keys) != 0 {
+		t.Fatalf("Keys = %v", keys)
+	}
+}
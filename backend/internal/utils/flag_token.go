@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+var ErrInvalidFlagToken = errors.New("invalid flag token")
+
+// flagTokenKey derives the AES-256 key for flag tokens from the server secret.
+func flagTokenKey(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret + ":flag-token"))
+	return sum[:]
+}
+
+// SealFlagToken hides countryCode in an opaque token that expires after ttl.
+// Every call gives a different token, so players cannot learn which country
+// a flag is by recognising its URL.
+func SealFlagToken(countryCode, secret string, ttl time.Duration) (string, error) {
+	block, err := aes.NewCipher(flagTokenKey(secret))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	plaintext := binary.BigEndian.AppendUint64(nil, uint64(time.Now().Add(ttl).Unix()))
+	plaintext = append(plaintext, countryCode...)
+
+	return base64.RawURLEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// OpenFlagToken returns the country code sealed in token.
+func OpenFlagToken(token, secret string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", ErrInvalidFlagToken
+	}
+	block, err := aes.NewCipher(flagTokenKey(secret))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", ErrInvalidFlagToken
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil || len(plaintext) <= 8 {
+		return "", ErrInvalidFlagToken
+	}
+	if time.Now().Unix() > int64(binary.BigEndian.Uint64(plaintext[:8])) {
+		return "", ErrInvalidFlagToken
+	}
+	return string(plaintext[8:]), nil
+}
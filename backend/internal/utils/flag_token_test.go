@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlagToken(t *testing.T) {
+	token, err := SealFlagToken("FR", "secret", time.Minute)
+	if err != nil {
+		t.Fatalf("SealFlagToken() error = %v", err)
+	}
+	again, err := SealFlagToken("FR", "secret", time.Minute)
+	if err != nil {
+		t.Fatalf("SealFlagToken() error = %v", err)
+	}
+	if token == again {
+		t.Error("the same country got the same token twice")
+	}
+
+	code, err := OpenFlagToken(token, "secret")
+	if err != nil || code != "FR" {
+		t.Errorf("OpenFlagToken() = %q, %v, want FR", code, err)
+	}
+	if _, err := OpenFlagToken(token, "other-secret"); err != ErrInvalidFlagToken {
+		t.Errorf("token opened under another secret: %v", err)
+	}
+	if _, err := OpenFlagToken(token[:len(token)-2], "secret"); err != ErrInvalidFlagToken {
+		t.Errorf("truncated token opened: %v", err)
+	}
+
+	expired, err := SealFlagToken("FR", "secret", -time.Second)
+	if err != nil {
+		t.Fatalf("SealFlagToken() error = %v", err)
+	}
+	if _, err := OpenFlagToken(expired, "secret"); err != ErrInvalidFlagToken {
+		t.Errorf("expired token opened: %v", err)
+	}
+}
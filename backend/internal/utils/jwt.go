@@ -8,17 +8,25 @@ import (
 )
 
 type Claims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func GenerateJWT(userID, username, email, secret string, expiry int) (string, error) {
+	return GenerateSessionJWT(userID, username, email, "", secret, expiry)
+}
+
+// GenerateSessionJWT issues an access token bound to a device session, so
+// handlers can tell which session a request came from.
+func GenerateSessionJWT(userID, username, email, sessionID, secret string, expiry int) (string, error) {
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
+		UserID:    userID,
+		Username:  username,
+		Email:     email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expiry) * time.Second)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -48,3 +56,42 @@ func ValidateJWT(tokenString, secret string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// twoFactorChallengeKey derives the signing key for 2FA challenge tokens so
+// they can never pass ValidateJWT as an access token.
+func twoFactorChallengeKey(secret string) []byte {
+	return []byte(secret + ":2fa-challenge")
+}
+
+// GenerateTwoFactorChallenge issues the short-lived token a client trades,
+// together with a TOTP or recovery code, for a session after the password step.
+func GenerateTwoFactorChallenge(userID, secret string, expiry int) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Subject:   userID,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expiry) * time.Second)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(twoFactorChallengeKey(secret))
+}
+
+// ValidateTwoFactorChallenge returns the user ID a challenge token was issued for.
+func ValidateTwoFactorChallenge(tokenString, secret string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return twoFactorChallengeKey(secret), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !token.Valid || claims.Subject == "" {
+		return "", fmt.Errorf("invalid token")
+	}
+
+	return claims.Subject, nil
+}
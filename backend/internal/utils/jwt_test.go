@@ -329,6 +329,29 @@ func TestJWTSecretSensitivity(t *testing.T) {
 	}
 }
 
+// TestTwoFactorChallengeIsNotAnAccessToken tests that challenge tokens only
+// validate as challenges
+func TestTwoFactorChallengeIsNotAnAccessToken(t *testing.T) {
+	challenge, err := GenerateTwoFactorChallenge("user123", testSecret, 300)
+	if err != nil {
+		t.Fatalf("GenerateTwoFactorChallenge() failed: %v", err)
+	}
+
+	userID, err := ValidateTwoFactorChallenge(challenge, testSecret)
+	if err != nil || userID != "user123" {
+		t.Errorf("ValidateTwoFactorChallenge() = %q, %v", userID, err)
+	}
+
+	if _, err := ValidateJWT(challenge, testSecret); err == nil {
+		t.Error("Challenge token validated as an access token")
+	}
+
+	access, _ := GenerateJWT("user123", "testuser", "test@example.com", testSecret, 3600)
+	if _, err := ValidateTwoFactorChallenge(access, testSecret); err == nil {
+		t.Error("Access token validated as a challenge token")
+	}
+}
+
 // BenchmarkGenerateJWT benchmarks JWT generation performance
 func BenchmarkGenerateJWT(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// GenerateOpaqueToken returns a URL-safe random token carrying size bytes of entropy.
+func GenerateOpaqueToken(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// HashToken returns the hex SHA-256 digest used to store opaque tokens at rest.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SignGuestSession returns the token that proves a client was handed
+// sessionID by the server. The key is derived from secret so the token can
+// never pass as any other signature. The token does not expire: it is valid
+// for sessionID for as long as secret is unchanged.
+func SignGuestSession(sessionID, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret+":guest-session"))
+	mac.Write([]byte(sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyGuestSession reports whether token was issued for sessionID.
+func VerifyGuestSession(sessionID, token, secret string) bool {
+	if sessionID == "" || token == "" {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(SignGuestSession(sessionID, secret)))
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestGenerateOpaqueToken(t *testing.T) {
+	first, err := GenerateOpaqueToken(32)
+	if err != nil {
+		t.Fatalf("GenerateOpaqueToken() error = %v", err)
+	}
+	second, err := GenerateOpaqueToken(32)
+	if err != nil {
+		t.Fatalf("GenerateOpaqueToken() error = %v", err)
+	}
+
+	if first == second {
+		t.Error("GenerateOpaqueToken() returned the same token twice")
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(first)
+	if err != nil {
+		t.Fatalf("token is not URL-safe base64: %v", err)
+	}
+	if len(decoded) != 32 {
+		t.Errorf("decoded token length = %d, want 32", len(decoded))
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	hash := HashToken("refresh-token")
+
+	if len(hash) != 64 {
+		t.Errorf("HashToken() length = %d, want 64", len(hash))
+	}
+	if hash != HashToken("refresh-token") {
+		t.Error("HashToken() is not deterministic")
+	}
+	if hash == HashToken("refresh-token2") {
+		t.Error("HashToken() collided for different inputs")
+	}
+	if hash == "refresh-token" {
+		t.Error("HashToken() returned the raw token")
+	}
+}
+
+func TestGuestSessionToken(t *testing.T) {
+	token := SignGuestSession("session-a", "secret")
+
+	if !VerifyGuestSession("session-a", token, "secret") {
+		t.Error("token did not verify for its own session")
+	}
+	if VerifyGuestSession("session-b", token, "secret") {
+		t.Error("token verified for another session")
+	}
+	if VerifyGuestSession("session-a", token, "other-secret") {
+		t.Error("token verified under another secret")
+	}
+	if VerifyGuestSession("session-a", "", "secret") {
+		t.Error("an empty token verified")
+	}
+}
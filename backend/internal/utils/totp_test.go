@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 test key "12345678901234567890" from RFC 6238
+// appendix B, base32 encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d) error: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret() error: %v", err)
+	}
+
+	now := time.Now()
+	code, _ := TOTPCode(secret, TOTPStep(now))
+
+	step, ok := ValidateTOTP(secret, code, now)
+	if !ok || step != TOTPStep(now) {
+		t.Errorf("Expected current code to validate at step %d, got %d (%v)", TOTPStep(now), step, ok)
+	}
+
+	if _, ok := ValidateTOTP(secret, code, now.Add(30*time.Second)); !ok {
+		t.Error("Expected previous step to be accepted for clock drift")
+	}
+
+	if _, ok := ValidateTOTP(secret, code, now.Add(2*time.Minute)); ok {
+		t.Error("Expected stale code to be rejected")
+	}
+
+	if _, ok := ValidateTOTP(secret, "12345", now); ok {
+		t.Error("Expected short code to be rejected")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("BriWorld", "player@example.com", rfc6238Secret)
+
+	if !strings.HasPrefix(uri, "otpauth://totp/BriWorld:player@example.com?") {
+		t.Errorf("Unexpected URI prefix: %s", uri)
+	}
+	if !strings.Contains(uri, "secret="+rfc6238Secret) {
+		t.Errorf("Expected secret in URI: %s", uri)
+	}
+}
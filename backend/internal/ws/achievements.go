@@ -0,0 +1,113 @@
+package ws
+
+import (
+	"briworld/internal/models"
+	"briworld/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// answerStats sums a player's answers through a game. responses and misses
+// keep each correct and wrong answer's time for the cheat detection pipeline.
+type answerStats struct {
+	correct    int
+	responseMs int
+	responses  []int
+	misses     []int
+}
+
+func (r *Room) answerStatsLocked(username string) *answerStats {
+	stats, ok := r.answerStats[username]
+	if !ok {
+		stats = &answerStats{}
+		r.answerStats[username] = stats
+	}
+	return stats
+}
+
+// recordAnswerLocked counts a correct answer given responseMs after the round
+// started. Caller must hold r.mu.
+func (r *Room) recordAnswerLocked(username string, responseMs int) {
+	stats := r.answerStatsLocked(username)
+	stats.correct++
+	stats.responseMs += responseMs
+	stats.responses = append(stats.responses, responseMs)
+}
+
+// gameStatsLocked returns each player's numbers from the game that just
+// ended. World map games have no rounds to answer, so only the player count
+// is known for them. Caller must hold r.mu.
+func (r *Room) gameStatsLocked() map[string]map[string]int {
+	rounds := r.GameState.CurrentRound
+	gameStats := make(map[string]map[string]int, len(r.GameState.Scores))
+	for username := range r.GameState.Scores {
+		stats := map[string]int{"players": len(r.GameState.Scores)}
+		gameStats[username] = stats
+		if r.GameState.GameMode == "WORLD_MAP" || rounds == 0 {
+			continue
+		}
+
+		answers := r.answerStats[username]
+		if answers == nil {
+			answers = &answerStats{}
+		}
+		stats["rounds"] = rounds
+		stats["correct_answers"] = answers.correct
+		stats["wrong_answers"] = len(answers.misses)
+		stats["accuracy"] = answers.correct * 100 / max(rounds, answers.correct+len(answers.misses))
+		if answers.correct > 0 {
+			stats["avg_response_ms"] = answers.responseMs / answers.correct
+		}
+	}
+	return gameStats
+}
+
+// publishGameAchievements reports a finished game of user to the achievement
+// engine, along with their new rank when the game changed it.
+func publishGameAchievements(user models.User, stats map[string]int, won bool, winStreak int, newRank string) {
+	gameStats := map[string]int{"win_streak": winStreak, "won": 0}
+	for stat, v := range stats {
+		gameStats[stat] = v
+	}
+	if won {
+		gameStats["won"] = 1
+	}
+	services.PublishAchievementEvent(services.AchievementEvent{
+		Kind:   services.EventGameCompleted,
+		UserID: user.ID,
+		Stats:  gameStats,
+	})
+
+	if newRank != user.Rank {
+		services.PublishAchievementEvent(services.AchievementEvent{
+			Kind:   services.EventRankChanged,
+			UserID: user.ID,
+			Stats:  map[string]int{"rank": models.RankLevel(newRank)},
+		})
+	}
+}
+
+// SendToPlayer sends a message to userID in every room they are playing in
+// on this server.
+func (h *Hub) SendToPlayer(userID uuid.UUID, messageType string, payload interface{}) {
+	if userID == uuid.Nil {
+		return
+	}
+
+	h.mu.RLock()
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	h.mu.RUnlock()
+
+	for _, room := range rooms {
+		room.mu.RLock()
+		for client := range room.Clients {
+			if !client.IsGuest && client.UserID == userID {
+				room.SendToClient(client, messageType, payload)
+			}
+		}
+		room.mu.RUnlock()
+	}
+}
@@ -0,0 +1,97 @@
+package ws
+
+import (
+	"briworld/internal/services"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LiveRooms lists every room the hub holds, for moderators.
+func (h *Hub) LiveRooms() []services.LiveRoom {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	rooms := make([]services.LiveRoom, 0, len(h.rooms))
+	for code, room := range h.rooms {
+		room.mu.RLock()
+		if room.isCleanedUp {
+			room.mu.RUnlock()
+			continue
+		}
+		live := services.LiveRoom{
+			Code:     code,
+			Owner:    room.Owner,
+			GameMode: room.GameState.GameMode,
+			RoomType: room.GameState.RoomType,
+			Status:   string(room.GameState.Status),
+			Round:    room.GameState.CurrentRound,
+			Rounds:   room.GameState.TotalRounds,
+			Players:  []string{},
+		}
+		for client := range room.Clients {
+			if client.IsSpectator {
+				live.Spectators++
+			} else {
+				live.Players = append(live.Players, client.Username)
+			}
+		}
+		room.mu.RUnlock()
+
+		sort.Strings(live.Players)
+		rooms = append(rooms, live)
+	}
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].Code < rooms[j].Code })
+	return rooms
+}
+
+// CloseRoom closes a room for everyone in it, telling them reason.
+func (h *Hub) CloseRoom(code, reason string) bool {
+	room := h.GetRoom(code)
+	if room == nil {
+		return false
+	}
+	message := "Room has been closed by a moderator"
+	if reason != "" {
+		message += ": " + reason
+	}
+	room.mu.Lock()
+	return room.closeLocked(message, "a moderator")
+}
+
+// DisconnectUser drops every connection userID has to a room, telling them
+// reason. They leave for good rather than waiting to reconnect.
+func (h *Hub) DisconnectUser(userID uuid.UUID, reason string) int {
+	h.mu.RLock()
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	h.mu.RUnlock()
+
+	dropped := 0
+	for _, room := range rooms {
+		room.mu.Lock()
+		for client := range room.Clients {
+			if client.IsGuest || client.UserID != userID {
+				continue
+			}
+			client.PermanentLeave = true
+			room.SendToClient(client, "kicked", map[string]interface{}{
+				"message": reason,
+			})
+			if conn := client.Conn; conn != nil {
+				// Give the write pump a moment to deliver the message
+				time.AfterFunc(100*time.Millisecond, func() { conn.Close() })
+			}
+			dropped++
+		}
+		room.mu.Unlock()
+	}
+	if dropped > 0 {
+		log.Printf("Disconnected %s from %d room connection(s)", userID, dropped)
+	}
+	return dropped
+}
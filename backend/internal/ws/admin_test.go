@@ -0,0 +1,83 @@
+package ws
+
+import (
+	"briworld/internal/domain"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestHubLiveRoomsListsEveryOpenRoom(t *testing.T) {
+	hub := NewHub()
+
+	private := hub.GetOrCreateRoom("PRIV1")
+	defer private.cancel()
+	private.mu.Lock()
+	private.Owner = "bob"
+	private.GameState.RoomType = "PRIVATE"
+	private.GameState.GameMode = "FLAG"
+	private.GameState.Status = domain.RoomInProgress
+	private.GameState.CurrentRound = 3
+	private.Clients[&Client{Username: "bob", Send: make(chan []byte, 10)}] = true
+	private.Clients[&Client{Username: "alice", Send: make(chan []byte, 10)}] = true
+	private.Clients[&Client{Username: "carol", IsSpectator: true, Send: make(chan []byte, 10)}] = true
+	private.mu.Unlock()
+
+	closed := hub.GetOrCreateRoom("GONE1")
+	defer closed.cancel()
+	closed.mu.Lock()
+	closed.isCleanedUp = true
+	closed.mu.Unlock()
+
+	rooms := hub.LiveRooms()
+	if len(rooms) != 1 {
+		t.Fatalf("LiveRooms() returned %d rooms, want 1", len(rooms))
+	}
+	room := rooms[0]
+	if room.Code != "PRIV1" || room.Owner != "bob" || room.RoomType != "PRIVATE" || room.Round != 3 {
+		t.Errorf("room = %+v", room)
+	}
+	if len(room.Players) != 2 || room.Players[0] != "alice" || room.Players[1] != "bob" {
+		t.Errorf("players = %v, want [alice bob]", room.Players)
+	}
+	if room.Spectators != 1 {
+		t.Errorf("spectators = %d, want 1", room.Spectators)
+	}
+}
+
+func TestHubDisconnectUserOnlyDropsThatUser(t *testing.T) {
+	hub := NewHub()
+	banned := uuid.New()
+
+	room := hub.GetOrCreateRoom("ROOM1")
+	defer room.cancel()
+	target := &Client{Username: "mallory", UserID: banned, Send: make(chan []byte, 10)}
+	other := &Client{Username: "alice", UserID: uuid.New(), Send: make(chan []byte, 10)}
+	guest := &Client{Username: "guest", IsGuest: true, Send: make(chan []byte, 10)}
+	room.mu.Lock()
+	room.Clients[target] = true
+	room.Clients[other] = true
+	room.Clients[guest] = true
+	room.mu.Unlock()
+
+	if dropped := hub.DisconnectUser(banned, "You have been banned"); dropped != 1 {
+		t.Fatalf("DisconnectUser() = %d, want 1", dropped)
+	}
+	if !target.PermanentLeave || other.PermanentLeave || guest.PermanentLeave {
+		t.Errorf("PermanentLeave: target %v, other %v, guest %v", target.PermanentLeave, other.PermanentLeave, guest.PermanentLeave)
+	}
+
+	select {
+	case data := <-target.Send:
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type != "kicked" {
+			t.Errorf("target got %s, want a kicked message", data)
+		}
+	default:
+		t.Error("target was not told why they were dropped")
+	}
+	if len(other.Send) != 0 || len(guest.Send) != 0 {
+		t.Error("other clients were sent a message")
+	}
+}
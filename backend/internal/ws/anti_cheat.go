@@ -0,0 +1,36 @@
+package ws
+
+import (
+	"briworld/internal/game"
+	"briworld/internal/services"
+)
+
+// gameTimingsLocked returns each signed-in player's answer times from the
+// game that just ended, for the cheat detection pipeline. World map and
+// practice games are not checked. Caller must hold r.mu.
+func (r *Room) gameTimingsLocked() []services.GameTimings {
+	mode := r.GameState.GameMode
+	if mode == "WORLD_MAP" || mode == string(game.ModePractice) || r.GameState.CurrentRound == 0 {
+		return nil
+	}
+
+	games := make([]services.GameTimings, 0, len(r.GameState.Scores))
+	for username := range r.GameState.Scores {
+		owner, ok := r.progressOwners[username]
+		if !ok || owner.IsGuest() {
+			continue
+		}
+		timings := services.GameTimings{
+			UserID:   owner.UserID,
+			RoomCode: r.ID,
+			GameMode: mode,
+			Rounds:   r.GameState.CurrentRound,
+		}
+		if answers := r.answerStats[username]; answers != nil {
+			timings.ResponseMs = append([]int(nil), answers.responses...)
+			timings.MissMs = append([]int(nil), answers.misses...)
+		}
+		games = append(games, timings)
+	}
+	return games
+}
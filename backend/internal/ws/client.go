@@ -2,17 +2,20 @@ package ws
 
 import (
 	"briworld/internal/domain"
+	"briworld/internal/services"
 	"context"
 	"sync"
 	"time"
 
 	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
 )
 
 type Client struct {
 	ID                  string
 	Username            string
 	SessionID           string
+	UserID              uuid.UUID
 	RoomID              string
 	Conn                *websocket.Conn
 	Send                chan []byte
@@ -31,9 +34,20 @@ type Client struct {
 	DisconnectedAt      time.Time
 	ReconnectCancelFunc context.CancelFunc
 	writeMu             sync.Mutex
+	// invitedAt throttles friend invites per friend. Only the read pump
+	// touches it.
+	invitedAt map[uuid.UUID]time.Time
 }
 
 type Message struct {
 	Type    string `json:"type"`
 	Payload any    `json:"payload"`
 }
+
+// progressOwner is who this client's results are recorded against.
+func (c *Client) progressOwner() services.ProgressOwner {
+	if c.IsGuest {
+		return services.ProgressOwner{GuestSessionID: c.SessionID}
+	}
+	return services.ProgressOwner{UserID: c.UserID}
+}
@@ -1,10 +1,12 @@
 package ws
 
 import (
-	"briworld/internal/config"
 	"briworld/internal/database"
+	"briworld/internal/game"
 	"briworld/internal/models"
+	"briworld/internal/services"
 	"briworld/internal/utils"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -22,27 +24,87 @@ func init() {
 	go GlobalHub.Run()
 }
 
-func resolveProfileMedia(token string) (string, string) {
+// player is the account a connection's token signs in.
+type player struct {
+	UserID    uuid.UUID
+	Username  string
+	AvatarURL string
+	BannerURL string
+}
+
+// resolvePlayer returns the user behind token along with their username and
+// profile media. A missing or invalid token yields a zero player: the player
+// is a guest. So does a signed-out session's or banned account's token.
+func resolvePlayer(token string) player {
 	if token == "" {
-		return "", ""
+		return player{}
+	}
+
+	claims, err := utils.ValidateJWT(token, currentSettings().jwtSecret)
+	if err != nil {
+		return player{}
 	}
 
-	claims, err := utils.ValidateJWT(token, config.Load().JWT.Secret)
+	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return "", ""
+		return player{}
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if active, err := services.SessionActive(ctx, userID, claims.SessionID); err != nil || !active {
+		return player{}
+	}
+
+	account := player{UserID: userID, Username: claims.Username}
 	db := database.GetDB()
 	if db == nil {
-		return "", ""
+		return account
 	}
 
 	var user models.User
-	if err := db.DB.Select("avatar_url", "banner_url").Where("id = ?", claims.UserID).First(&user).Error; err != nil {
-		return "", ""
+	if err := db.DB.Select("username", "avatar_url", "banner_url", "banned_at", "banned_until").Where("id = ?", userID).First(&user).Error; err != nil {
+		return account
+	}
+	if user.Banned(time.Now()) {
+		return player{}
 	}
 
-	return user.AvatarURL, user.BannerURL
+	account.Username = user.Username
+	account.AvatarURL = user.AvatarURL
+	account.BannerURL = user.BannerURL
+	return account
+}
+
+// admitGuest checks the session a guest connects with. A session the server
+// has not seen is registered to this connection, which gets its guest token
+// in room_joined. Later connections must present that token, so knowing a
+// guest's session ID is not enough to take over their progress. Presenting
+// the token keeps the session registered, so the binding only lapses once the
+// guest has been away long enough for the cleanup job to remove it.
+func admitGuest(sessionID, username, guestToken string) bool {
+	db := database.GetDB()
+	if guestToken != "" {
+		if !utils.VerifyGuestSession(sessionID, guestToken, currentSettings().jwtSecret) {
+			return false
+		}
+		if db != nil {
+			if err := services.NewSessionService(db).TouchGuestSession(sessionID, username); err != nil {
+				log.Printf("Failed to extend guest session: %v", err)
+			}
+		}
+		return true
+	}
+
+	if db == nil {
+		return true
+	}
+	registered, err := services.NewSessionService(db).RegisterGuestSession(sessionID, username)
+	if err != nil {
+		log.Printf("Failed to register guest session: %v", err)
+		return false
+	}
+	return registered
 }
 
 func HandleWebSocket(c *websocket.Conn) {
@@ -55,8 +117,6 @@ func HandleWebSocket(c *websocket.Conn) {
 	timeout := c.Query("timeout")
 	token := c.Query("token")
 
-	isAuthenticated := token != ""
-
 	if roomCode == "" || username == "" {
 		log.Println("Missing room or username")
 		c.Close()
@@ -94,6 +154,50 @@ func HandleWebSocket(c *websocket.Conn) {
 		return
 	}
 
+	account := resolvePlayer(token)
+	userID := account.UserID
+	isAuthenticated := userID != uuid.Nil
+	// Signed-in players always play under their account's name
+	if isAuthenticated && account.Username != "" {
+		username = account.Username
+	}
+
+	// Practice follows one signed-in player's review schedule
+	if gameMode == string(game.ModePractice) && (roomType != "SINGLE" || !isAuthenticated) {
+		log.Printf("Rejected practice room %s: type=%s, authenticated=%v", roomCode, roomType, isAuthenticated)
+		msg := map[string]any{
+			"type": "unsupported_game_mode",
+			"payload": map[string]any{
+				"message":   "Practice is a single player mode for signed-in players",
+				"game_mode": gameMode,
+			},
+		}
+		if data, err := json.Marshal(msg); err == nil {
+			c.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.WriteMessage(websocket.TextMessage, data)
+		}
+		time.Sleep(100 * time.Millisecond)
+		c.Close()
+		return
+	}
+
+	if !isAuthenticated && !admitGuest(sessionID, username, c.Query("guest_token")) {
+		log.Printf("Rejected guest %s in room %s: session is taken", username, roomCode)
+		msg := map[string]any{
+			"type": "session_collision",
+			"payload": map[string]any{
+				"message": "This guest session belongs to another connection. Exit to start a new one.",
+			},
+		}
+		if data, err := json.Marshal(msg); err == nil {
+			c.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.WriteMessage(websocket.TextMessage, data)
+		}
+		time.Sleep(100 * time.Millisecond)
+		c.Close()
+		return
+	}
+
 	roundsCount := 10
 	if rounds != "" {
 		if r, err := strconv.Atoi(rounds); err == nil && r > 0 {
@@ -117,6 +221,24 @@ func HandleWebSocket(c *websocket.Conn) {
 
 	log.Printf("[DEBUG] Room lookup: code=%s, exists=%v, requestedMode=%s", roomCode, existingRoom != nil, gameMode)
 
+	if closedTo(existingRoom, roomType, userID) {
+		log.Printf("Rejected %s from private room %s", username, roomCode)
+		msg := map[string]any{
+			"type": "room_unavailable",
+			"payload": map[string]any{
+				"message":   "This room is not open to you",
+				"room_code": roomCode,
+			},
+		}
+		if data, err := json.Marshal(msg); err == nil {
+			c.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.WriteMessage(websocket.TextMessage, data)
+		}
+		time.Sleep(100 * time.Millisecond)
+		c.Close()
+		return
+	}
+
 	// Validate game mode if room already exists
 	if existingRoom != nil {
 		existingRoom.mu.RLock()
@@ -221,21 +343,20 @@ func HandleWebSocket(c *websocket.Conn) {
 	}
 	room.mu.Unlock()
 
-	avatarURL, bannerURL := resolveProfileMedia(token)
-
 	client := &Client{
 		ID:             uuid.New().String(),
 		Username:       username,
 		SessionID:      sessionID,
+		UserID:         userID,
 		RoomID:         roomCode,
 		Conn:           c,
 		Send:           make(chan []byte, 512),
 		RoundsCount:    roundsCount,
 		GameMode:       gameMode,
 		RoomType:       roomType,
-		IsGuest:        sessionID != "",
-		AvatarURL:      avatarURL,
-		BannerURL:      bannerURL,
+		IsGuest:        !isAuthenticated,
+		AvatarURL:      account.AvatarURL,
+		BannerURL:      account.BannerURL,
 		TimeoutSeconds: timeoutSeconds,
 	}
 
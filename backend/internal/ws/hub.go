@@ -5,6 +5,10 @@ import (
 	"sync"
 )
 
+// maxRoomPlayers is how many players a room seats; anyone joining after that
+// watches as a spectator.
+const maxRoomPlayers = 6
+
 func getMaxPlayersForMode(mode string) int {
 	if mode == "TEAM_BATTLE" {
 		return 10
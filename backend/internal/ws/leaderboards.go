@@ -0,0 +1,40 @@
+package ws
+
+import (
+	"briworld/internal/game"
+	"briworld/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// recordRoundLeaderboards counts each signed-in player's answer about the
+// round's country towards its region's leaderboards. Practice rounds follow
+// a player's weak spots, so they do not count.
+func recordRoundLeaderboards(gameMode string, owners map[string]services.ProgressOwner, countryCode string, answered map[string]bool) {
+	if gameMode == string(game.ModePractice) || countryCode == "" {
+		return
+	}
+	answers := make(map[uuid.UUID]bool, len(owners))
+	for username, owner := range owners {
+		if !owner.IsGuest() {
+			answers[owner.UserID] = answered[username]
+		}
+	}
+	if len(answers) > 0 {
+		services.RecordLeaderboardAnswers(countryCode, answers)
+	}
+}
+
+// recordGameLeaderboards adds each signed-in player's final score to the
+// game mode's leaderboards.
+func recordGameLeaderboards(gameMode string, owners map[string]services.ProgressOwner, scores map[string]int) {
+	points := make(map[uuid.UUID]int, len(owners))
+	for username, owner := range owners {
+		if !owner.IsGuest() {
+			points[owner.UserID] = scores[username]
+		}
+	}
+	if len(points) > 0 {
+		services.RecordLeaderboardGame(gameMode, points)
+	}
+}
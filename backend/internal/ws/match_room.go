@@ -0,0 +1,175 @@
+package ws
+
+import (
+	"briworld/internal/domain"
+	"briworld/internal/game"
+	"briworld/internal/services"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// matchRoomType is the room type organized matches are recorded under.
+const matchRoomType = "TOURNAMENT"
+
+var ErrRoomCodeTaken = errors.New("room code is already in use")
+
+// matchRoom is the organized match a room was opened for, such as a
+// tournament match or a daily challenge attempt. The room keeps its settings,
+// seats only the match's players and does not change ratings.
+type matchRoom struct {
+	spec services.MatchRoomSpec
+	once sync.Once
+}
+
+// plays reports whether the signed-in user plays in the match.
+func (m *matchRoom) plays(client *Client) bool {
+	if client.IsGuest {
+		return false
+	}
+	_, ok := m.spec.Sides[client.UserID]
+	return ok
+}
+
+// private reports whether only the match's players may enter the room,
+// which keeps a daily challenge's countries from being watched in advance.
+func (m *matchRoom) private() bool {
+	return m.spec.RoomType == services.DailyRoomType
+}
+
+// closedTo reports whether userID may not connect to room, which is nil when
+// no room has that code yet. Clients cannot open daily challenge rooms
+// themselves, and private match rooms admit only their players. Anyone with
+// the code may watch a tournament match, but only the match's sides are
+// seated and nobody else can connect under their names.
+func closedTo(room *Room, roomType string, userID uuid.UUID) bool {
+	if room == nil {
+		return roomType == services.DailyRoomType
+	}
+	if room.match == nil || !room.match.private() {
+		return false
+	}
+	_, plays := room.match.spec.Sides[userID]
+	return userID == uuid.Nil || !plays
+}
+
+// roomType is the type the match's game is recorded under.
+func (m *matchRoom) roomType() string {
+	if m.spec.RoomType != "" {
+		return m.spec.RoomType
+	}
+	return matchRoomType
+}
+
+// question returns the question of round when the match asks about fixed
+// countries. ok is false for matches asking random ones.
+func (m *matchRoom) question(mode string, round int) (q *game.Question, ok bool, err error) {
+	if len(m.spec.Countries) == 0 {
+		return nil, false, nil
+	}
+	if round < 1 || round > len(m.spec.Countries) {
+		return nil, true, errors.New("no country left for this round")
+	}
+	q, err = game.Data.QuestionForCountry(mode, m.spec.Countries[round-1])
+	return q, true, err
+}
+
+// complete hands each side's total score to the match, once.
+func (m *matchRoom) complete(owners map[string]services.ProgressOwner, scores map[string]int) {
+	var totals [2]int
+	for username, score := range scores {
+		owner, ok := owners[username]
+		if !ok {
+			continue
+		}
+		if side, ok := m.spec.Sides[owner.UserID]; ok {
+			totals[side] += score
+		}
+	}
+	m.once.Do(func() {
+		if m.spec.OnComplete != nil {
+			m.spec.OnComplete(totals)
+		}
+	})
+}
+
+// OpenMatchRoom opens a room for an organized match with the settings of
+// spec. Reopening a room that is still open keeps it as it is.
+func (h *Hub) OpenMatchRoom(spec services.MatchRoomSpec) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	code := spec.RoomCode
+	if code == "" {
+		for {
+			code = NewRoomCode()
+			if _, taken := h.rooms[code]; !taken {
+				break
+			}
+		}
+	} else if existing, ok := h.rooms[code]; ok {
+		if existing.match == nil {
+			return "", ErrRoomCodeTaken
+		}
+		return code, nil
+	}
+
+	room := NewRoom(code)
+	room.GameState.GameMode = spec.GameMode
+	room.GameState.RoomType = "PRIVATE"
+	if spec.RoomType != "" {
+		room.GameState.RoomType = spec.RoomType
+	}
+	room.GameState.TotalRounds = spec.Rounds
+	room.GameState.RoundTimeLimit = spec.RoundTimeLimit
+	room.GameState.TimeRemaining = spec.RoundTimeLimit
+	room.match = &matchRoom{spec: spec}
+	h.rooms[code] = room
+	go room.Run()
+
+	log.Printf("Opened match room %s (%s, %d rounds)", code, spec.GameMode, spec.Rounds)
+	return code, nil
+}
+
+// MatchRoomOpen reports whether the match room with code is still open.
+func (h *Hub) MatchRoomOpen(code string) bool {
+	room := h.GetRoom(code)
+	if room == nil || room.match == nil {
+		return false
+	}
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+	return !room.isCleanedUp && room.GameState.Status != domain.RoomClosed
+}
+
+// startMatchIfReady starts an organized match as soon as all its players
+// are in the room.
+func (r *Room) startMatchIfReady() {
+	if r.match == nil {
+		return
+	}
+
+	r.mu.Lock()
+	if r.GameState.Status != domain.RoomWaiting {
+		r.mu.Unlock()
+		return
+	}
+	present := make(map[uuid.UUID]bool, len(r.Clients))
+	for c := range r.Clients {
+		if !c.IsSpectator && r.match.plays(c) {
+			present[c.UserID] = true
+		}
+	}
+	if len(present) < len(r.match.spec.Sides) {
+		r.mu.Unlock()
+		return
+	}
+	r.GameState.Status = domain.RoomInProgress
+	r.GameState.CurrentRound = 0
+	r.mu.Unlock()
+
+	log.Printf("All players present, starting match in room %s", r.ID)
+	r.launchGame()
+}
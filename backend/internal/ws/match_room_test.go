@@ -0,0 +1,114 @@
+package ws
+
+import (
+	"briworld/internal/services"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDailyRoomAdmitsOnlyItsPlayer(t *testing.T) {
+	hub := NewHub()
+	player := uuid.New()
+
+	code, err := hub.OpenMatchRoom(services.MatchRoomSpec{
+		GameMode:       "FLAG",
+		Rounds:         2,
+		RoundTimeLimit: 10,
+		Sides:          map[uuid.UUID]int{player: 0},
+		RoomType:       services.DailyRoomType,
+		Countries:      []string{"FR", "JP"},
+	})
+	if err != nil {
+		t.Fatalf("OpenMatchRoom failed: %v", err)
+	}
+	room := hub.GetRoom(code)
+	defer room.cancel()
+
+	if room.GameState.RoomType != services.DailyRoomType {
+		t.Fatalf("room type = %s, want %s", room.GameState.RoomType, services.DailyRoomType)
+	}
+	if closedTo(room, services.DailyRoomType, player) {
+		t.Fatal("the player should be let into their daily room")
+	}
+	if !closedTo(room, services.DailyRoomType, uuid.New()) || !closedTo(room, services.DailyRoomType, uuid.Nil) {
+		t.Fatal("nobody else should be let into a daily room")
+	}
+	if !closedTo(nil, services.DailyRoomType, player) {
+		t.Fatal("clients should not open daily rooms themselves")
+	}
+	if closedTo(nil, "PRIVATE", player) {
+		t.Fatal("clients should open other rooms as before")
+	}
+	if !hub.MatchRoomOpen(code) || hub.MatchRoomOpen("NOPE42") {
+		t.Fatal("MatchRoomOpen should only report open match rooms")
+	}
+	if room.match.roomType() != services.DailyRoomType {
+		t.Fatalf("daily games should be recorded as %s", services.DailyRoomType)
+	}
+}
+
+func TestMatchRoomQuestions(t *testing.T) {
+	random := &matchRoom{}
+	if _, fixed, _ := random.question("FLAG", 1); fixed {
+		t.Fatal("a match without countries should ask random ones")
+	}
+	if random.roomType() != matchRoomType {
+		t.Fatalf("tournament games should be recorded as %s", matchRoomType)
+	}
+
+	daily := &matchRoom{spec: services.MatchRoomSpec{Countries: []string{"FR"}}}
+	if _, fixed, err := daily.question("FLAG", 2); !fixed || err == nil {
+		t.Fatal("a round past the fixed countries should fail")
+	}
+}
+
+func TestMatchRoomSeatsOnlyItsSides(t *testing.T) {
+	hub := NewHub()
+	alice, bob := uuid.New(), uuid.New()
+
+	code, err := hub.OpenMatchRoom(services.MatchRoomSpec{
+		GameMode:       "FLAG",
+		Rounds:         3,
+		RoundTimeLimit: 10,
+		Sides:          map[uuid.UUID]int{alice: 0, bob: 1},
+	})
+	if err != nil {
+		t.Fatalf("OpenMatchRoom failed: %v", err)
+	}
+	room := hub.GetRoom(code)
+	defer room.cancel()
+
+	player := &Client{Username: "alice", UserID: alice, Send: make(chan []byte, 10)}
+	room.AddClient(player)
+
+	// The opponent cannot take over alice's seat by using her name
+	opponent := &Client{Username: "alice", UserID: bob, Send: make(chan []byte, 10)}
+	room.AddClient(opponent)
+	if got := refusal(t, opponent); got != "username_taken" {
+		t.Fatalf("opponent using alice's name was sent %q, want username_taken", got)
+	}
+
+	outsider := &Client{Username: "carol", UserID: uuid.New(), Send: make(chan []byte, 10)}
+	guest := &Client{Username: "dave", SessionID: "guest", IsGuest: true, Send: make(chan []byte, 10)}
+	room.AddClient(outsider)
+	room.AddClient(guest)
+
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+	if _, ok := room.Clients[player]; !ok || player.IsSpectator {
+		t.Fatal("alice should play in her match")
+	}
+	if _, ok := room.Clients[opponent]; ok {
+		t.Fatal("the opponent should have been refused")
+	}
+	if !outsider.IsSpectator || !guest.IsSpectator {
+		t.Fatal("players outside the match may only watch")
+	}
+	if _, scored := room.GameState.Scores["carol"]; scored {
+		t.Fatal("a spectator should not get a score")
+	}
+	if owner := room.progressOwners["alice"]; owner.UserID != alice {
+		t.Fatalf("alice's seat belongs to %s", owner.UserID)
+	}
+}
@@ -0,0 +1,209 @@
+package ws
+
+import (
+	"briworld/internal/domain"
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// seatHoldTTL is how long a matched player's seat is held for them to
+// connect to the room.
+const seatHoldTTL = 30 * time.Second
+
+// NewRoomCode creates a 6-character room code
+func NewRoomCode() string {
+	const chars = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	b := make([]byte, 6)
+	rand.Read(b)
+	for i := range b {
+		b[i] = chars[int(b[i])%len(chars)]
+	}
+	return string(b)
+}
+
+// matchedRoom holds seats in a public room for players on their way to it.
+// The room may not exist yet when the matchmaker opened it.
+type matchedRoom struct {
+	gameMode string
+	seats    map[uuid.UUID]time.Time
+}
+
+// Matchmaker places players, alone or as a party, into public rooms with
+// enough free seats for all of them, so a party is never split.
+type Matchmaker struct {
+	mu    sync.Mutex
+	hub   *Hub
+	rooms map[string]*matchedRoom
+}
+
+func NewMatchmaker(hub *Hub) *Matchmaker {
+	return &Matchmaker{hub: hub, rooms: make(map[string]*matchedRoom)}
+}
+
+var Matchmaking = NewMatchmaker(GlobalHub)
+
+// prune drops expired seats. It must be called with m.mu held.
+func (m *Matchmaker) prune(now time.Time) {
+	for code, room := range m.rooms {
+		for id, expires := range room.seats {
+			if now.After(expires) {
+				delete(room.seats, id)
+			}
+		}
+		if len(room.seats) == 0 {
+			delete(m.rooms, code)
+		}
+	}
+}
+
+// release drops any seats held for userIDs. It must be called with m.mu held.
+func (m *Matchmaker) release(userIDs []uuid.UUID) {
+	for code, room := range m.rooms {
+		for _, id := range userIDs {
+			delete(room.seats, id)
+		}
+		if len(room.seats) == 0 {
+			delete(m.rooms, code)
+		}
+	}
+}
+
+// freeSeats returns how many more players the public waiting rooms of
+// gameMode can take, counting the seats already held. It must be called with
+// m.mu held.
+func (m *Matchmaker) freeSeats(gameMode string) map[string]int {
+	free := make(map[string]int)
+
+	m.hub.mu.RLock()
+	for code, room := range m.hub.rooms {
+		room.mu.RLock()
+		open := room.GameState.RoomType == "PUBLIC" &&
+			room.GameState.GameMode == gameMode &&
+			room.GameState.Status == domain.RoomWaiting &&
+			!room.isCleanedUp
+		players := 0
+		present := make(map[uuid.UUID]bool, len(room.Clients))
+		for c := range room.Clients {
+			if !c.IsSpectator {
+				players++
+			}
+			if !c.IsGuest {
+				present[c.UserID] = true
+			}
+		}
+		room.mu.RUnlock()
+
+		if !open {
+			continue
+		}
+		if held := m.rooms[code]; held != nil {
+			for id := range held.seats {
+				if !present[id] {
+					players++
+				}
+			}
+		}
+		free[code] = maxRoomPlayers - players
+	}
+	for code, held := range m.rooms {
+		if _, exists := m.hub.rooms[code]; !exists && held.gameMode == gameMode {
+			free[code] = maxRoomPlayers - len(held.seats)
+		}
+	}
+	m.hub.mu.RUnlock()
+
+	return free
+}
+
+// Place finds a public room of gameMode with a seat for each of userIDs,
+// or opens a new one, and holds the seats for seatHoldTTL. Among rooms that
+// fit, the fullest is picked so games fill up and start sooner.
+func (m *Matchmaker) Place(gameMode string, userIDs []uuid.UUID) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.prune(now)
+	m.release(userIDs)
+
+	code := ""
+	best := maxRoomPlayers + 1
+	for candidate, free := range m.freeSeats(gameMode) {
+		if free < len(userIDs) {
+			continue
+		}
+		// Ties go to the lowest code so placement does not depend on map order
+		if free < best || (free == best && candidate < code) {
+			code, best = candidate, free
+		}
+	}
+	if code == "" {
+		for {
+			code = NewRoomCode()
+			_, held := m.rooms[code]
+			if !held && m.hub.GetRoom(code) == nil {
+				break
+			}
+		}
+	}
+
+	room := m.rooms[code]
+	if room == nil {
+		room = &matchedRoom{gameMode: gameMode, seats: make(map[uuid.UUID]time.Time)}
+		m.rooms[code] = room
+	}
+	for _, id := range userIDs {
+		room.seats[id] = now.Add(seatHoldTTL)
+	}
+	return code
+}
+
+// holds reports whether a seat in roomCode is held for userID.
+func (m *Matchmaker) holds(roomCode string, userID uuid.UUID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	room := m.rooms[roomCode]
+	if room == nil {
+		return false
+	}
+	expires, ok := room.seats[userID]
+	return ok && time.Now().Before(expires)
+}
+
+// heldSeats returns the users with a seat held in roomCode.
+func (m *Matchmaker) heldSeats(roomCode string) []uuid.UUID {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	room := m.rooms[roomCode]
+	if room == nil {
+		return nil
+	}
+	now := time.Now()
+	held := make([]uuid.UUID, 0, len(room.seats))
+	for id, expires := range room.seats {
+		if now.Before(expires) {
+			held = append(held, id)
+		}
+	}
+	return held
+}
+
+// claim releases the seat held for userID once they joined roomCode.
+func (m *Matchmaker) claim(roomCode string, userID uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	room := m.rooms[roomCode]
+	if room == nil {
+		return
+	}
+	delete(room.seats, userID)
+	if len(room.seats) == 0 {
+		delete(m.rooms, roomCode)
+	}
+}
@@ -0,0 +1,291 @@
+package ws
+
+import (
+	"briworld/internal/models"
+	"briworld/internal/services"
+	"briworld/internal/utils"
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// notifyChannel is the Redis channel notifications travel between instances on.
+const notifyChannel = "notifications"
+
+type notifyConn struct {
+	userID uuid.UUID
+	conn   *websocket.Conn
+	send   chan []byte
+}
+
+// notifyEnvelope is a notification on its way to another instance.
+type notifyEnvelope struct {
+	UserID  uuid.UUID       `json:"user_id"`
+	Message json.RawMessage `json:"message"`
+}
+
+// NotifyHub tracks the notification sockets open on this server and
+// delivers notifications to them.
+type NotifyHub struct {
+	mu    sync.RWMutex
+	conns map[uuid.UUID]map[*notifyConn]struct{}
+	// relay publishes notifications to every instance once Listen runs.
+	relay *redis.Client
+}
+
+func NewNotifyHub() *NotifyHub {
+	return &NotifyHub{conns: make(map[uuid.UUID]map[*notifyConn]struct{})}
+}
+
+var Notifications = NewNotifyHub()
+
+func (h *NotifyHub) add(c *notifyConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.conns[c.userID] == nil {
+		h.conns[c.userID] = make(map[*notifyConn]struct{})
+	}
+	h.conns[c.userID][c] = struct{}{}
+}
+
+// remove drops c, closes its send channel and reports whether it was the
+// user's last socket on this server.
+func (h *NotifyHub) remove(c *notifyConn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	conns := h.conns[c.userID]
+	if _, ok := conns[c]; !ok {
+		return false
+	}
+	delete(conns, c)
+	close(c.send)
+	if len(conns) == 0 {
+		delete(h.conns, c.userID)
+		return true
+	}
+	return false
+}
+
+// deliver sends data to every socket of userID on this server and returns
+// how many received it.
+func (h *NotifyHub) deliver(userID uuid.UUID, data []byte) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	delivered := 0
+	for c := range h.conns[userID] {
+		select {
+		case c.send <- data:
+			delivered++
+		default:
+			log.Printf("[WS] Notification buffer full for %s", userID)
+		}
+	}
+	return delivered
+}
+
+func notificationMessage(notification *models.Notification) ([]byte, error) {
+	return json.Marshal(Message{Type: "notification", Payload: notification})
+}
+
+// PushToUser delivers a notification to userID. With Redis it goes through
+// every instance, so it reaches the user wherever they are connected.
+func (h *NotifyHub) PushToUser(userID uuid.UUID, notification *models.Notification) {
+	data, err := notificationMessage(notification)
+	if err != nil {
+		log.Printf("Error marshaling notification: %v", err)
+		return
+	}
+	h.publish(userID, data)
+}
+
+// Send delivers a live message that is not kept in the inbox, such as a
+// party update, to userID wherever they are connected.
+func (h *NotifyHub) Send(userID uuid.UUID, messageType string, payload interface{}) {
+	data, err := json.Marshal(Message{Type: messageType, Payload: payload})
+	if err != nil {
+		log.Printf("Error marshaling %s message: %v", messageType, err)
+		return
+	}
+	h.publish(userID, data)
+}
+
+// publish hands data to every instance through Redis once Listen runs, and
+// delivers it locally otherwise or when Redis fails.
+func (h *NotifyHub) publish(userID uuid.UUID, data []byte) {
+	if h.relay != nil {
+		envelope, _ := json.Marshal(notifyEnvelope{UserID: userID, Message: data})
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := h.relay.Publish(ctx, notifyChannel, envelope).Err()
+		cancel()
+		if err == nil {
+			return
+		}
+		log.Printf("Failed to publish notification, delivering locally: %v", err)
+	}
+	h.deliver(userID, data)
+}
+
+// PushToAll delivers a notification to every socket on this server.
+func (h *NotifyHub) PushToAll(notification *models.Notification) {
+	data, err := notificationMessage(notification)
+	if err != nil {
+		log.Printf("Error marshaling notification: %v", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, conns := range h.conns {
+		for c := range conns {
+			select {
+			case c.send <- data:
+			default:
+			}
+		}
+	}
+}
+
+// Listen relays notifications published by any instance to the sockets on
+// this one. From then on PushToUser publishes instead of delivering locally.
+func (h *NotifyHub) Listen(ctx context.Context, client *redis.Client) {
+	sub := client.Subscribe(ctx, notifyChannel)
+	if _, err := sub.Receive(ctx); err != nil {
+		log.Printf("⚠️  Notification relay unavailable: %v", err)
+		sub.Close()
+		return
+	}
+	h.relay = client
+
+	go func() {
+		defer sub.Close()
+		for msg := range sub.Channel() {
+			var envelope notifyEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+				continue
+			}
+			h.deliver(envelope.UserID, envelope.Message)
+		}
+	}()
+}
+
+// AuthenticateNotify checks the token query parameter before the
+// notification socket is upgraded.
+func AuthenticateNotify(c *fiber.Ctx) error {
+	claims, err := utils.ValidateJWT(c.Query("token"), currentSettings().jwtSecret)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired token"})
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user ID format"})
+	}
+	if active, err := services.SessionActive(c.Context(), userID, claims.SessionID); err != nil || !active {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session has been signed out"})
+	}
+	c.Locals("user_id", userID)
+	return c.Next()
+}
+
+// HandleNotifySocket serves /ws/notify, the per-user socket that carries
+// notifications outside of rooms. An open socket keeps the user online.
+func HandleNotifySocket(c *websocket.Conn) {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		c.Close()
+		return
+	}
+
+	nc := &notifyConn{userID: userID, conn: c, send: make(chan []byte, 64)}
+	Notifications.add(nc)
+	touchPresence(userID)
+
+	if notificationService != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		unread, err := notificationService.UnreadCount(ctx, userID)
+		cancel()
+		if err == nil {
+			if data, err := json.Marshal(Message{Type: "notify_ready", Payload: map[string]interface{}{"unread": unread}}); err == nil {
+				nc.send <- data
+			}
+		}
+	}
+
+	go nc.writePump()
+	nc.readPump()
+
+	if Notifications.remove(nc) && presenceService != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		if err := presenceService.Disconnect(ctx, userID); err != nil {
+			log.Printf("Failed to update presence on disconnect: %v", err)
+		}
+		cancel()
+	}
+}
+
+func touchPresence(userID uuid.UUID) {
+	if presenceService == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := presenceService.Touch(ctx, userID); err != nil {
+		log.Printf("Failed to update presence: %v", err)
+	}
+}
+
+// readPump discards what the client sends and returns when the socket
+// closes.
+func (nc *notifyConn) readPump() {
+	nc.conn.SetReadLimit(4096)
+	nc.conn.SetReadDeadline(time.Now().Add(pongWait))
+	nc.conn.SetPongHandler(func(string) error {
+		nc.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+	for {
+		if _, _, err := nc.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// writePump sends notifications and pings, and refreshes presence with
+// every ping.
+func (nc *notifyConn) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		nc.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-nc.send:
+			nc.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				nc.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := nc.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			nc.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := nc.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+			touchPresence(nc.userID)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package ws
+
+import (
+	"briworld/internal/models"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNotifyHubPushToUser(t *testing.T) {
+	hub := NewNotifyHub()
+	userID := uuid.New()
+
+	first := &notifyConn{userID: userID, send: make(chan []byte, 4)}
+	second := &notifyConn{userID: userID, send: make(chan []byte, 4)}
+	other := &notifyConn{userID: uuid.New(), send: make(chan []byte, 4)}
+	hub.add(first)
+	hub.add(second)
+	hub.add(other)
+
+	hub.PushToUser(userID, &models.Notification{Type: "friend_invite", Data: `{"room_code":"ABC123"}`})
+	if len(first.send) != 1 || len(second.send) != 1 {
+		t.Fatal("every socket of the user should receive the notification")
+	}
+	if len(other.send) != 0 {
+		t.Fatal("other users should not receive the notification")
+	}
+
+	var msg struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Type string `json:"type"`
+			Data struct {
+				RoomCode string `json:"room_code"`
+			} `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(<-first.send, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "notification" || msg.Payload.Type != "friend_invite" || msg.Payload.Data.RoomCode != "ABC123" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	hub.PushToAll(&models.Notification{Type: "daily_challenge_reset"})
+	if len(other.send) != 1 {
+		t.Fatal("PushToAll should reach every socket")
+	}
+
+	if hub.remove(first) {
+		t.Fatal("removing one of two sockets should not report the last one")
+	}
+	if !hub.remove(second) {
+		t.Fatal("removing the final socket should report the last one")
+	}
+	if hub.remove(second) {
+		t.Fatal("removing a socket twice should do nothing")
+	}
+}
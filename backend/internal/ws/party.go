@@ -0,0 +1,346 @@
+package ws
+
+import (
+	"briworld/internal/domain"
+	"briworld/internal/services"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxPartySize keeps a whole party inside one room's player seats.
+const maxPartySize = maxRoomPlayers
+
+// partyInviteTTL is how long a party invite can be accepted.
+const partyInviteTTL = 5 * time.Minute
+
+var (
+	ErrAlreadyInParty      = errors.New("already in a party")
+	ErrNotInParty          = errors.New("not in a party")
+	ErrPartyFull           = errors.New("party is full")
+	ErrNotPartyLeader      = errors.New("only the party leader can do that")
+	ErrPartyInviteNotFound = errors.New("party invite not found or expired")
+	ErrPartyMemberNotFound = errors.New("player is not in your party")
+)
+
+// PartyMember is a player in a party.
+type PartyMember struct {
+	services.PlayerCard
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// Party is a group of players that follows its leader between rooms and is
+// matched into public rooms together. Members are kept in join order, so the
+// longest-standing member takes over when the leader leaves.
+type Party struct {
+	ID        string        `json:"id"`
+	LeaderID  uuid.UUID     `json:"leader_id"`
+	Members   []PartyMember `json:"members"`
+	Invited   []uuid.UUID   `json:"invited"`
+	MaxSize   int           `json:"max_size"`
+	CreatedAt time.Time     `json:"created_at"`
+
+	invites map[uuid.UUID]time.Time
+}
+
+// MemberIDs returns the user IDs of every member, leader included.
+func (p *Party) MemberIDs() []uuid.UUID {
+	ids := make([]uuid.UUID, len(p.Members))
+	for i, m := range p.Members {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func (p *Party) memberIndex(userID uuid.UUID) int {
+	for i, m := range p.Members {
+		if m.ID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+// snapshot copies the party for use outside the manager's lock.
+func (p *Party) snapshot(now time.Time) Party {
+	copied := Party{
+		ID:        p.ID,
+		LeaderID:  p.LeaderID,
+		Members:   append([]PartyMember(nil), p.Members...),
+		Invited:   make([]uuid.UUID, 0, len(p.invites)),
+		MaxSize:   p.MaxSize,
+		CreatedAt: p.CreatedAt,
+	}
+	for id, expires := range p.invites {
+		if now.Before(expires) {
+			copied.Invited = append(copied.Invited, id)
+		}
+	}
+	return copied
+}
+
+// PartyPusher sends a live message to a user's notification socket.
+type PartyPusher func(userID uuid.UUID, messageType string, payload interface{})
+
+// PartyManager holds the parties of this server instance in memory.
+type PartyManager struct {
+	mu      sync.Mutex
+	parties map[string]*Party
+	byUser  map[uuid.UUID]*Party
+	maxSize int
+	push    PartyPusher
+}
+
+func NewPartyManager(maxSize int, push PartyPusher) *PartyManager {
+	return &PartyManager{
+		parties: make(map[string]*Party),
+		byUser:  make(map[uuid.UUID]*Party),
+		maxSize: maxSize,
+		push:    push,
+	}
+}
+
+var Parties = NewPartyManager(maxPartySize, Notifications.Send)
+
+// publish sends the party's new state to its members and to anyone else
+// affected, such as a player who just left.
+func (m *PartyManager) publish(party Party, others ...uuid.UUID) {
+	if m.push == nil {
+		return
+	}
+	for _, id := range party.MemberIDs() {
+		m.push(id, "party_update", party)
+	}
+	for _, id := range others {
+		m.push(id, "party_update", nil)
+	}
+}
+
+// Get returns the party userID is in.
+func (m *PartyManager) Get(userID uuid.UUID) (Party, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	party, ok := m.byUser[userID]
+	if !ok {
+		return Party{}, false
+	}
+	return party.snapshot(time.Now()), true
+}
+
+// Create starts a party led by leader.
+func (m *PartyManager) Create(leader services.PlayerCard) (Party, error) {
+	m.mu.Lock()
+	if _, ok := m.byUser[leader.ID]; ok {
+		m.mu.Unlock()
+		return Party{}, ErrAlreadyInParty
+	}
+
+	now := time.Now()
+	party := &Party{
+		ID:        uuid.NewString(),
+		LeaderID:  leader.ID,
+		Members:   []PartyMember{{PlayerCard: leader, JoinedAt: now}},
+		MaxSize:   m.maxSize,
+		CreatedAt: now,
+		invites:   make(map[uuid.UUID]time.Time),
+	}
+	m.parties[party.ID] = party
+	m.byUser[leader.ID] = party
+	snapshot := party.snapshot(now)
+	m.mu.Unlock()
+
+	m.publish(snapshot)
+	return snapshot, nil
+}
+
+// Invite lets targetID join the party of leaderID for partyInviteTTL.
+func (m *PartyManager) Invite(leaderID, targetID uuid.UUID) (Party, error) {
+	m.mu.Lock()
+	party, ok := m.byUser[leaderID]
+	if !ok {
+		m.mu.Unlock()
+		return Party{}, ErrNotInParty
+	}
+	if party.LeaderID != leaderID {
+		m.mu.Unlock()
+		return Party{}, ErrNotPartyLeader
+	}
+	if party.memberIndex(targetID) >= 0 {
+		m.mu.Unlock()
+		return Party{}, ErrAlreadyInParty
+	}
+	if len(party.Members) >= party.MaxSize {
+		m.mu.Unlock()
+		return Party{}, ErrPartyFull
+	}
+
+	now := time.Now()
+	party.invites[targetID] = now.Add(partyInviteTTL)
+	snapshot := party.snapshot(now)
+	m.mu.Unlock()
+
+	m.publish(snapshot)
+	return snapshot, nil
+}
+
+// Join adds member to partyID if they hold an invite to it.
+func (m *PartyManager) Join(partyID string, member services.PlayerCard) (Party, error) {
+	m.mu.Lock()
+	if _, ok := m.byUser[member.ID]; ok {
+		m.mu.Unlock()
+		return Party{}, ErrAlreadyInParty
+	}
+	party, ok := m.parties[partyID]
+	if !ok {
+		m.mu.Unlock()
+		return Party{}, ErrPartyInviteNotFound
+	}
+
+	now := time.Now()
+	expires, invited := party.invites[member.ID]
+	if !invited || now.After(expires) {
+		delete(party.invites, member.ID)
+		m.mu.Unlock()
+		return Party{}, ErrPartyInviteNotFound
+	}
+	if len(party.Members) >= party.MaxSize {
+		m.mu.Unlock()
+		return Party{}, ErrPartyFull
+	}
+
+	delete(party.invites, member.ID)
+	party.Members = append(party.Members, PartyMember{PlayerCard: member, JoinedAt: now})
+	m.byUser[member.ID] = party
+	snapshot := party.snapshot(now)
+	m.mu.Unlock()
+
+	m.publish(snapshot)
+	return snapshot, nil
+}
+
+// Leave takes userID out of their party. The longest-standing member takes
+// over from a leader who leaves, and the last one out disbands the party.
+func (m *PartyManager) Leave(userID uuid.UUID) error {
+	m.mu.Lock()
+	party, ok := m.byUser[userID]
+	if !ok {
+		m.mu.Unlock()
+		return ErrNotInParty
+	}
+
+	remaining, disbanded := m.removeMember(party, userID)
+	m.mu.Unlock()
+
+	if disbanded {
+		m.publish(Party{}, userID)
+	} else {
+		m.publish(remaining, userID)
+	}
+	return nil
+}
+
+// Kick removes targetID from the party of leaderID.
+func (m *PartyManager) Kick(leaderID, targetID uuid.UUID) (Party, error) {
+	m.mu.Lock()
+	party, ok := m.byUser[leaderID]
+	if !ok {
+		m.mu.Unlock()
+		return Party{}, ErrNotInParty
+	}
+	if party.LeaderID != leaderID {
+		m.mu.Unlock()
+		return Party{}, ErrNotPartyLeader
+	}
+	if targetID == leaderID || party.memberIndex(targetID) < 0 {
+		m.mu.Unlock()
+		return Party{}, ErrPartyMemberNotFound
+	}
+
+	remaining, _ := m.removeMember(party, targetID)
+	m.mu.Unlock()
+
+	m.publish(remaining, targetID)
+	return remaining, nil
+}
+
+// removeMember must be called with m.mu held.
+func (m *PartyManager) removeMember(party *Party, userID uuid.UUID) (Party, bool) {
+	if i := party.memberIndex(userID); i >= 0 {
+		party.Members = append(party.Members[:i], party.Members[i+1:]...)
+	}
+	delete(m.byUser, userID)
+
+	if len(party.Members) == 0 {
+		delete(m.parties, party.ID)
+		return Party{}, true
+	}
+	if party.LeaderID == userID {
+		party.LeaderID = party.Members[0].ID
+	}
+	return party.snapshot(time.Now()), false
+}
+
+// followers returns the members who follow userID between rooms, which is
+// everyone else in the party when userID leads it.
+func (m *PartyManager) followers(userID uuid.UUID) (string, []uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	party, ok := m.byUser[userID]
+	if !ok || party.LeaderID != userID {
+		return "", nil
+	}
+	ids := make([]uuid.UUID, 0, len(party.Members)-1)
+	for _, member := range party.Members {
+		if member.ID != userID {
+			ids = append(ids, member.ID)
+		}
+	}
+	return party.ID, ids
+}
+
+// followLeader tells the party of client, when client leads one, to follow
+// them into this room. Members already here or on their way through the
+// matchmaker are left alone.
+func (r *Room) followLeader(client *Client) {
+	if client.IsGuest {
+		return
+	}
+	partyID, followers := Parties.followers(client.UserID)
+	if len(followers) == 0 {
+		return
+	}
+
+	r.mu.RLock()
+	roomType := r.GameState.RoomType
+	gameMode := r.GameState.GameMode
+	closed := r.GameState.Status == domain.RoomClosed
+	present := make(map[uuid.UUID]bool, len(r.Clients))
+	for c := range r.Clients {
+		if !c.IsGuest {
+			present[c.UserID] = true
+		}
+	}
+	r.mu.RUnlock()
+
+	if roomType == "SINGLE" || closed || r.match != nil {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"party_id":  partyID,
+		"leader":    client.Username,
+		"room_code": r.ID,
+		"game_mode": gameMode,
+		"room_type": roomType,
+	}
+	for _, id := range followers {
+		if present[id] || Matchmaking.holds(r.ID, id) {
+			continue
+		}
+		Notifications.Send(id, "party_follow", payload)
+	}
+}
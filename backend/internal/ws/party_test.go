@@ -0,0 +1,156 @@
+package ws
+
+import (
+	"briworld/internal/domain"
+	"briworld/internal/services"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func testCard(name string) services.PlayerCard {
+	return services.PlayerCard{ID: uuid.New(), Username: name}
+}
+
+func TestPartyInviteJoinAndLeave(t *testing.T) {
+	pushed := map[uuid.UUID]int{}
+	parties := NewPartyManager(3, func(userID uuid.UUID, messageType string, payload interface{}) {
+		pushed[userID]++
+	})
+	leader, friend, stranger, extra := testCard("leader"), testCard("friend"), testCard("stranger"), testCard("extra")
+
+	party, err := parties.Create(leader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parties.Create(leader); err != ErrAlreadyInParty {
+		t.Fatalf("creating a second party should fail, got %v", err)
+	}
+
+	if _, err := parties.Join(party.ID, stranger); err != ErrPartyInviteNotFound {
+		t.Fatalf("joining without an invite should fail, got %v", err)
+	}
+	if _, err := parties.Invite(leader.ID, friend.ID); err != nil {
+		t.Fatal(err)
+	}
+	party, err = parties.Join(party.ID, friend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(party.Members) != 2 || len(party.Invited) != 0 {
+		t.Fatalf("unexpected party %+v", party)
+	}
+	if pushed[friend.ID] == 0 {
+		t.Fatal("members should get party updates")
+	}
+
+	if _, err := parties.Invite(friend.ID, stranger.ID); err != ErrNotPartyLeader {
+		t.Fatalf("only the leader should invite, got %v", err)
+	}
+	parties.Invite(leader.ID, stranger.ID)
+	parties.Invite(leader.ID, extra.ID)
+	parties.Join(party.ID, stranger)
+	if _, err := parties.Join(party.ID, extra); err != ErrPartyFull {
+		t.Fatalf("joining a full party should fail, got %v", err)
+	}
+
+	if err := parties.Leave(leader.ID); err != nil {
+		t.Fatal(err)
+	}
+	party, ok := parties.Get(friend.ID)
+	if !ok || party.LeaderID != friend.ID {
+		t.Fatalf("the longest-standing member should lead, got %+v", party)
+	}
+	if _, ok := parties.Get(leader.ID); ok {
+		t.Fatal("the leader should have left")
+	}
+
+	if _, err := parties.Kick(friend.ID, stranger.ID); err != nil {
+		t.Fatal(err)
+	}
+	parties.Leave(friend.ID)
+	if len(parties.parties) != 0 || len(parties.byUser) != 0 {
+		t.Fatal("the last member leaving should disband the party")
+	}
+}
+
+func publicRoom(code, mode string, players int) *Room {
+	room := NewRoom(code)
+	room.GameState.RoomType = "PUBLIC"
+	room.GameState.GameMode = mode
+	room.GameState.Status = domain.RoomWaiting
+	for i := 0; i < players; i++ {
+		room.Clients[&Client{UserID: uuid.New()}] = true
+	}
+	return room
+}
+
+func TestMatchmakerKeepsPartiesTogether(t *testing.T) {
+	hub := NewHub()
+	hub.rooms["ALMOST"] = publicRoom("ALMOST", "FLAG", 4)
+	hub.rooms["EMPTY1"] = publicRoom("EMPTY1", "FLAG", 1)
+	hub.rooms["OTHER1"] = publicRoom("OTHER1", "WORLD_MAP", 0)
+	matchmaker := NewMatchmaker(hub)
+
+	party := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	code := matchmaker.Place("FLAG", party)
+	if code != "EMPTY1" {
+		t.Fatalf("a party of 3 should not be split into a room with 2 seats, got %s", code)
+	}
+	for _, id := range party {
+		if !matchmaker.holds(code, id) {
+			t.Fatal("every member should hold a seat")
+		}
+	}
+
+	solo := uuid.New()
+	if code := matchmaker.Place("FLAG", []uuid.UUID{solo}); code != "ALMOST" {
+		t.Fatalf("a solo player should fill the fullest room, got %s", code)
+	}
+
+	second := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	code = matchmaker.Place("FLAG", second)
+	if code == "EMPTY1" || code == "ALMOST" || code == "OTHER1" {
+		t.Fatalf("held seats should count as taken, got %s", code)
+	}
+	if again := matchmaker.Place("FLAG", []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}); again != code {
+		t.Fatalf("a room the matchmaker opened should take more players, got %s and %s", code, again)
+	}
+
+	matchmaker.claim(code, second[0])
+	if matchmaker.holds(code, second[0]) {
+		t.Fatal("joining should release the seat")
+	}
+}
+
+func TestAddClientKeepsHeldSeatsForTheParty(t *testing.T) {
+	room := publicRoom("HELD01", "FLAG", maxRoomPlayers-2)
+	defer room.cancel()
+	go room.Run()
+
+	member := uuid.New()
+	Matchmaking.mu.Lock()
+	Matchmaking.rooms[room.ID] = &matchedRoom{gameMode: "FLAG", seats: map[uuid.UUID]time.Time{
+		member:     time.Now().Add(seatHoldTTL),
+		uuid.New(): time.Now().Add(seatHoldTTL),
+	}}
+	Matchmaking.mu.Unlock()
+	defer func() {
+		Matchmaking.mu.Lock()
+		delete(Matchmaking.rooms, room.ID)
+		Matchmaking.mu.Unlock()
+	}()
+
+	stranger := &Client{Username: "stranger", UserID: uuid.New(), Send: make(chan []byte, 10)}
+	room.AddClient(stranger)
+	if !stranger.IsSpectator {
+		t.Fatal("a player joining by code took a seat held for a party member")
+	}
+
+	arriving := &Client{Username: "member", UserID: member, Send: make(chan []byte, 10)}
+	room.AddClient(arriving)
+	if arriving.IsSpectator {
+		t.Fatal("the party member did not get their held seat")
+	}
+}
@@ -0,0 +1,27 @@
+package ws
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/game"
+	"briworld/internal/services"
+	"log"
+)
+
+// practiceQuestionLocked asks the room owner about the country their review
+// schedule picks next. ok is false when there is no schedule to pick from,
+// and the round gets a random flag instead. Caller must hold r.mu.
+func (r *Room) practiceQuestionLocked() (q *game.Question, ok bool, err error) {
+	owner, known := r.progressOwners[r.Owner]
+	db := database.GetDB()
+	if !known || owner.IsGuest() || db == nil {
+		return nil, false, nil
+	}
+
+	code, err := services.NewProgressService(db).NextPracticeCountry(owner, game.Data.CountryKeys, r.GameState.UsedCountries)
+	if err != nil || code == "" {
+		log.Printf("Error picking practice country in room %s: %v", r.ID, err)
+		return nil, false, nil
+	}
+	q, err = game.Data.QuestionForCountry(string(game.ModePractice), code)
+	return q, true, err
+}
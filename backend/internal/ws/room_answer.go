@@ -3,13 +3,45 @@ package ws
 import (
 	"briworld/internal/game"
 	redisClient "briworld/internal/redis"
+	"briworld/internal/services"
 	"briworld/internal/utils"
 	"context"
 	"encoding/json"
 	"log"
 	"strings"
+	"time"
 )
 
+const (
+	// maxRoundMisses is how many wrong answers put a player out of guesses
+	// for the round. A question with options gets one, so the options
+	// cannot simply be tried in turn.
+	maxRoundMisses        = 3
+	maxRoundMissesOptions = 1
+)
+
+// guessesLeftLocked returns how many more wrong answers username may give
+// this round. Caller must hold r.mu.
+func (r *Room) guessesLeftLocked(username string) int {
+	limit := maxRoundMisses
+	if q := r.GameState.Question; q != nil && len(q.Options) > 0 {
+		limit = maxRoundMissesOptions
+	}
+	return max(limit-r.roundMisses[username], 0)
+}
+
+func (r *Room) outOfGuessesLocked(username string) bool {
+	return r.guessesLeftLocked(username) == 0
+}
+
+// recordMissLocked counts a wrong answer given responseMs after the round
+// started. Caller must hold r.mu.
+func (r *Room) recordMissLocked(username string, responseMs int) {
+	r.roundMisses[username]++
+	stats := r.answerStatsLocked(username)
+	stats.misses = append(stats.misses, responseMs)
+}
+
 // HandleAnswer processes a player's answer submission.
 func (r *Room) HandleAnswer(client *Client, payload interface{}) {
 	// Spectators cannot answer
@@ -46,19 +78,31 @@ func (r *Room) HandleAnswer(client *Client, payload interface{}) {
 
 	r.mu.Lock()
 
-	// If wrong answer, just ignore it and let player keep trying
-	if !isCorrect {
+	// Only count first correct answer, and nothing once the guesses ran out
+	if r.GameState.Answered[client.Username] || r.outOfGuessesLocked(client.Username) {
 		r.mu.Unlock()
 		return
 	}
 
-	// Only process correct answers
-	// Only count first correct answer
-	if r.GameState.Answered[client.Username] {
+	// A wrong answer lets the player try again, a few times
+	if !isCorrect {
+		r.recordMissLocked(client.Username, int(time.Since(r.roundStartedAt).Milliseconds()))
+		left := r.guessesLeftLocked(client.Username)
 		r.mu.Unlock()
+		if left == 0 {
+			r.SendToClient(client, "answer_submitted", map[string]interface{}{
+				"is_correct": false,
+				"player":     client.Username,
+				"error":      "No guesses left this round",
+			})
+		}
 		return
 	}
 	r.GameState.Answered[client.Username] = true
+	responseMs := int(time.Since(r.roundStartedAt).Milliseconds())
+	r.recordAnswerLocked(client.Username, responseMs)
+	gameMode := r.GameState.GameMode
+	countryCode := r.GameState.Question.CountryCode
 
 	// Calculate points based on time remaining (100 to 25 points)
 	pointsEarned := 0
@@ -74,19 +118,48 @@ func (r *Room) HandleAnswer(client *Client, payload interface{}) {
 	r.GameState.Scores[client.Username] += pointsEarned
 	currentScore := r.GameState.Scores[client.Username]
 	scores := cloneStringIntMap(r.GameState.Scores)
+	roomType := r.GameState.RoomType
 
 	log.Printf("Player %s answered correctly in room %s (+%d points)",
 		client.Username, r.ID, pointsEarned)
 
 	r.mu.Unlock()
 
-	// Broadcast correct answer to all players
-	r.BroadcastMessage("answer_submitted", map[string]interface{}{
+	if owner := client.progressOwner(); !owner.IsGuest() {
+		services.PublishAchievementEvent(services.AchievementEvent{
+			Kind:     services.EventRoundAnswered,
+			UserID:   owner.UserID,
+			GameMode: gameMode,
+			Region:   game.GetRegionForCountry(countryCode),
+			Stats:    map[string]int{"correct": 1, "response_ms": responseMs},
+		})
+	}
+
+	// FLAG_QUIZ is an alias for FLAG mode
+	endsRound := gameMode == "FLAG" || gameMode == "FLAG_QUIZ" || gameMode == "PRACTICE" ||
+		(gameMode == "LAST_STANDING" && roomType == "SINGLE")
+
+	// Tell everyone who answered. While the round goes on, only the player
+	// who answered learns the country.
+	answered := map[string]interface{}{
 		"is_correct":    true,
 		"player":        client.Username,
-		"country_name":  correctAnswer,
 		"points_earned": pointsEarned,
-	})
+	}
+	if endsRound {
+		answered["country_name"] = correctAnswer
+		answered["country_code"] = countryCode
+		r.BroadcastMessage("answer_submitted", answered)
+	} else {
+		r.BroadcastMessageExcept(client, "answer_submitted", answered)
+		r.SendToClient(client, "answer_submitted", map[string]interface{}{
+			"is_correct":    true,
+			"player":        client.Username,
+			"country_name":  correctAnswer,
+			"country_code":  countryCode,
+			"points_earned": pointsEarned,
+		})
+	}
 
 	// Broadcast score update to all players
 	r.BroadcastMessage("score_update", map[string]interface{}{
@@ -96,23 +169,11 @@ func (r *Room) HandleAnswer(client *Client, payload interface{}) {
 	})
 	r.BroadcastStateSnapshot()
 
-	// For FLAG and LAST_STANDING, end round immediately after correct answer
-	r.mu.RLock()
-	gameMode := r.GameState.GameMode
-	roomType := r.GameState.RoomType
-	r.mu.RUnlock()
-
-	// FLAG_QUIZ is an alias for FLAG mode
-	if gameMode == "FLAG" || gameMode == "FLAG_QUIZ" {
+	// FLAG modes and single player Last Standing end the round on the first
+	// correct answer. Other modes wait for everyone or for the timer.
+	if endsRound {
 		log.Printf("Correct answer submitted in room %s by %s, ending round", r.ID, client.Username)
 		r.EndRound()
-	} else if gameMode == "LAST_STANDING" {
-		// In single player, end round immediately
-		if roomType == "SINGLE" {
-			log.Printf("Correct answer in SINGLE Last Standing, ending round")
-			r.EndRound()
-		}
-		// In multiplayer, wait for all players to answer or timer to expire
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"briworld/internal/game"
 	"briworld/internal/domain"
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -191,6 +192,54 @@ func TestSendToClient(t *testing.T) {
 	}
 }
 
+func TestStatePayloadHidesTheCountryDuringARound(t *testing.T) {
+	room := NewRoom("TEST123")
+	defer room.cancel()
+
+	room.GameState.Status = domain.RoomInProgress
+	room.GameState.RoundActive = true
+	room.GameState.Question = &game.Question{Type: "emoji", Emoji: "🥐🗼", CountryName: "France", CountryCode: "FR"}
+
+	data, err := json.Marshal(room.BuildStatePayload())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "France") || strings.Contains(string(data), `"country_code"`) {
+		t.Errorf("Round payload gives the answer away: %s", data)
+	}
+
+	room.GameState.RoundActive = false
+	data, _ = json.Marshal(room.BuildStatePayload())
+	if !strings.Contains(string(data), "France") {
+		t.Error("Payload after the round should name the country")
+	}
+}
+
+func TestHandleAnswerKeepsTheCountryFromOthers(t *testing.T) {
+	room := NewRoom("TEST123")
+	defer room.cancel()
+
+	room.GameState.Status = domain.RoomInProgress
+	room.GameState.GameMode = "EMOJI"
+	room.GameState.RoundActive = true
+	room.GameState.Question = &game.Question{Type: "emoji", CountryName: "France", CountryCode: "FR"}
+	room.GameState.TimeRemaining = 10
+
+	alice := &Client{Username: "alice", Send: make(chan []byte, 10)}
+	bob := &Client{Username: "bob", Send: make(chan []byte, 10)}
+	room.Clients[alice] = true
+	room.Clients[bob] = true
+
+	room.HandleAnswer(alice, map[string]interface{}{"answer": "France"})
+
+	if got := <-alice.Send; !strings.Contains(string(got), "France") {
+		t.Errorf("Player who answered should learn the country: %s", got)
+	}
+	if got := <-bob.Send; strings.Contains(string(got), "France") {
+		t.Errorf("Other players should not learn the country yet: %s", got)
+	}
+}
+
 func BenchmarkHandleAnswer(b *testing.B) {
 	room := NewRoom("TEST123")
 	defer room.cancel()
@@ -219,3 +268,53 @@ func BenchmarkHandleAnswer(b *testing.B) {
 		room.HandleAnswer(client, payload)
 	}
 }
+
+func TestHandleAnswerCapsWrongGuesses(t *testing.T) {
+	room := NewRoom("TEST123")
+	defer room.cancel()
+
+	room.GameState.Status = domain.RoomInProgress
+	room.GameState.RoundActive = true
+	room.GameState.Question = &game.Question{Type: "flag", CountryName: "France", CountryCode: "FR"}
+	room.GameState.Scores["alice"] = 0
+	room.GameState.TimeRemaining = 10
+	room.roundStartedAt = time.Now()
+
+	client := &Client{Username: "alice", Send: make(chan []byte, 10)}
+	for i := 0; i < maxRoundMisses; i++ {
+		room.HandleAnswer(client, map[string]interface{}{"answer": "Brazil"})
+	}
+	if misses := room.answerStats["alice"].misses; len(misses) != maxRoundMisses {
+		t.Fatalf("recorded %d misses, want %d", len(misses), maxRoundMisses)
+	}
+
+	room.HandleAnswer(client, map[string]interface{}{"answer": "France"})
+	if room.GameState.Answered["alice"] || room.GameState.Scores["alice"] != 0 {
+		t.Error("a player out of guesses still scored")
+	}
+	if len(room.answerStats["alice"].misses) != maxRoundMisses {
+		t.Error("answers after running out of guesses were counted")
+	}
+}
+
+func TestHandleAnswerGivesOneGuessOnOptions(t *testing.T) {
+	room := NewRoom("TEST123")
+	defer room.cancel()
+
+	room.GameState.Status = domain.RoomInProgress
+	room.GameState.RoundActive = true
+	room.GameState.Question = &game.Question{
+		Type:        "silhouette",
+		CountryName: "France",
+		CountryCode: "FR",
+		Options:     []string{"Spain", "France", "Italy", "Chile"},
+	}
+	room.GameState.Scores["alice"] = 0
+
+	client := &Client{Username: "alice", Send: make(chan []byte, 10)}
+	room.HandleAnswer(client, map[string]interface{}{"answer": "Spain"})
+	room.HandleAnswer(client, map[string]interface{}{"answer": "France"})
+	if room.GameState.Answered["alice"] {
+		t.Error("a second option was accepted after a wrong one")
+	}
+}
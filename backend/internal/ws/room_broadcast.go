@@ -1,6 +1,7 @@
 package ws
 
 import (
+	"briworld/internal/game"
 	"encoding/json"
 	"log"
 )
@@ -38,6 +39,16 @@ func cloneStringBoolMap(src map[string]bool) map[string]bool {
 	return dst
 }
 
+// questionLocked returns the question to send to players. While the round
+// is being played they do not get the country it asks about. Caller must
+// hold r.mu.
+func (r *Room) questionLocked() *game.Question {
+	if r.GameState.RoundActive {
+		return r.GameState.Question.Public()
+	}
+	return r.GameState.Question
+}
+
 func (r *Room) BuildStatePayload() map[string]interface{} {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -62,7 +73,7 @@ func (r *Room) BuildStatePayload() map[string]interface{} {
 		"status":            r.GameState.Status,
 		"current_round":     r.GameState.CurrentRound,
 		"total_rounds":      r.GameState.TotalRounds,
-		"question":          r.GameState.Question,
+		"question":          r.questionLocked(),
 		"scores":            cloneStringIntMap(r.GameState.Scores),
 		"time_remaining":    r.GameState.TimeRemaining,
 		"round_time_limit":  r.GameState.RoundTimeLimit,
@@ -109,6 +120,23 @@ func (r *Room) BroadcastMessage(messageType string, payload interface{}) {
 	}
 }
 
+// BroadcastMessageExcept sends a typed message to every client in the room
+// but skip.
+func (r *Room) BroadcastMessageExcept(skip *Client, messageType string, payload interface{}) {
+	r.mu.RLock()
+	clients := make([]*Client, 0, len(r.Clients))
+	for client := range r.Clients {
+		if client != skip {
+			clients = append(clients, client)
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, client := range clients {
+		r.SendToClient(client, messageType, payload)
+	}
+}
+
 // BroadcastRoomUpdate sends the current room state to all clients.
 func (r *Room) BroadcastRoomUpdate() {
 	r.mu.RLock()
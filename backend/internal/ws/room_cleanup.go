@@ -3,19 +3,23 @@ package ws
 import (
 	"briworld/internal/database"
 	"briworld/internal/domain"
+	"briworld/internal/game"
 	"briworld/internal/models"
 	redisClient "briworld/internal/redis"
+	"briworld/internal/services"
 	"context"
 	"log"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // RestartGame resets the game state and starts a new game.
 func (r *Room) RestartGame(username string) {
 	r.mu.Lock()
 
-	// Only owner can restart
-	if r.Owner != username {
+	// Only owner can restart, and an organized match is played once
+	if r.Owner != username || r.match != nil {
 		r.mu.Unlock()
 		return
 	}
@@ -32,18 +36,22 @@ func (r *Room) RestartGame(username string) {
 	r.GameState.PaintedCountries = make(map[string]string)
 	r.GameState.EliminatedPlayers = make(map[string]bool)
 	r.GameState.ActivePlayers = 0
+	r.answerStats = make(map[string]*answerStats)
+	r.roundMisses = make(map[string]int)
 
 	// For single player, start immediately
 	if r.GameState.RoomType == "SINGLE" {
 		r.GameState.Status = domain.RoomInProgress
 		r.GameState.CurrentRound = 1
 		r.mu.Unlock()
+		r.syncPresence()
 		r.StartRound()
 	} else {
 		// For multiplayer, go to waiting room
 		r.GameState.Status = domain.RoomWaiting
 		r.mu.Unlock()
-		r.BroadcastMessage("game_restarted", r.GameState)
+		r.syncPresence()
+		r.BroadcastMessage("game_restarted", r.BuildStatePayload())
 		r.BroadcastRoomUpdate()
 	}
 
@@ -72,9 +80,16 @@ func (r *Room) CloseRoom(username string) {
 		r.mu.Unlock()
 		return
 	}
+	r.closeLocked("Room has been closed by the owner", username)
+}
+
+// closeLocked closes the room and kicks everyone in it with message. It is
+// called with r.mu held and releases it. It returns false when the room was
+// already closed.
+func (r *Room) closeLocked(message, by string) bool {
 	if r.isCleanedUp {
 		r.mu.Unlock()
-		return
+		return false
 	}
 
 	r.GameState.Status = domain.RoomClosed
@@ -84,7 +99,7 @@ func (r *Room) CloseRoom(username string) {
 
 	// Notify all clients
 	r.BroadcastMessage("room_closed", map[string]interface{}{
-		"message": "Room has been closed by the owner",
+		"message": message,
 	})
 
 	// Wait for broadcast to complete
@@ -92,7 +107,11 @@ func (r *Room) CloseRoom(username string) {
 
 	// Close all client connections
 	r.mu.Lock()
+	userIDs := make([]uuid.UUID, 0, len(r.Clients))
 	for client := range r.Clients {
+		if !client.IsGuest {
+			userIDs = append(userIDs, client.UserID)
+		}
 		close(client.Send)
 		if client.Conn != nil {
 			client.Conn.Close()
@@ -102,12 +121,15 @@ func (r *Room) CloseRoom(username string) {
 	r.isCleanedUp = true
 	r.mu.Unlock()
 
+	leavePresence(roomID, userIDs...)
+
 	// Cancel context to stop all goroutines
 	r.cancel()
 
 	cleanupRoomResources(roomID)
 
-	log.Printf("Room %s closed and removed by %s", roomID, username)
+	log.Printf("Room %s closed and removed by %s", roomID, by)
+	return true
 }
 
 // AutoCleanup automatically cleans up inactive rooms.
@@ -173,9 +195,17 @@ func (r *Room) ScheduleCleanup() {
 }
 
 // UpdatePlayerStats updates database statistics for all players after game ends.
-func (r *Room) UpdatePlayerStats(scores map[string]int) {
+// Only players owners maps to an account are credited; guests and players
+// missing from owners are skipped. gameStats holds each player's numbers from the game
+// for the achievement engine. Practice games neither move ratings nor win
+// streaks.
+func (r *Room) UpdatePlayerStats(scores map[string]int, owners map[string]services.ProgressOwner, gameStats map[string]map[string]int) {
 	log.Printf("Updating player stats for room %s with scores: %v", r.ID, scores)
 
+	r.mu.Lock()
+	practice := r.GameState.GameMode == string(game.ModePractice)
+	r.mu.Unlock()
+
 	// Find winner (highest score)
 	maxScore := 0
 	for _, score := range scores {
@@ -184,6 +214,8 @@ func (r *Room) UpdatePlayerStats(scores map[string]int) {
 		}
 	}
 
+	requireVerified := currentSettings().requireVerifiedEmail
+
 	// Update stats for each player
 	for username, score := range scores {
 		isWinner := score == maxScore && maxScore > 0
@@ -192,58 +224,69 @@ func (r *Room) UpdatePlayerStats(scores map[string]int) {
 			winValue = 1
 		}
 
+		owner, known := owners[username]
+		if !known {
+			log.Printf("Skipping account stats for %s: no known owner", username)
+			continue
+		}
+		if owner.IsGuest() {
+			log.Printf("Skipping account stats for guest %s", username)
+			continue
+		}
+
 		log.Printf("Updating %s: score=%d, isWinner=%v, maxScore=%d",
 			username, score, isWinner, maxScore)
 
 		if db := database.GetDB(); db != nil {
-			// Calculate rating change based on performance
-			ratingService := &domain.RatingService{}
-			performanceScore := score
-			ratingChange := ratingService.CalculateRatingChange(performanceScore, isWinner)
+			// Organized matches and practice do not count towards the ladder.
+			ranked := r.match == nil && !practice
 			var user models.User
-			if err := db.DB.Where("username = ?", username).First(&user).Error; err == nil {
-				newRank, newTier := models.GetRankFromRating(max(0, user.Rating+ratingChange))
-				if err := db.DB.Exec(`
+			if err := db.DB.Where("id = ?", owner.UserID).First(&user).Error; err == nil {
+				if requireVerified && !user.EmailVerified {
+					// Unverified accounts still collect stats but are not rated.
+					ranked = false
+				}
+				var winStreak int
+				if err := db.DB.Raw(`
 					UPDATE users 
 					SET total_points = total_points + ?,
 						total_games = total_games + 1,
 						total_wins = total_wins + ?,
-						win_streak = CASE WHEN ? = 1 THEN win_streak + 1 ELSE 0 END,
+						win_streak = CASE WHEN ? THEN win_streak WHEN ? = 1 THEN win_streak + 1 ELSE 0 END,
 						longest_win_streak = CASE 
-							WHEN ? = 1 AND win_streak + 1 > longest_win_streak 
+							WHEN NOT ? AND ? = 1 AND win_streak + 1 > longest_win_streak 
 							THEN win_streak + 1 
 							ELSE longest_win_streak 
-						END,
-						rating = GREATEST(0, rating + ?),
-						rank = ?,
-						rank_tier = ?
-					WHERE username = ?
-				`, score, winValue, winValue, winValue, ratingChange, newRank, newTier, username).Error; err != nil {
+						END
+					WHERE id = ?
+					RETURNING win_streak
+				`, score, winValue, practice, winValue, practice, winValue, user.ID).Scan(&winStreak).Error; err != nil {
 					log.Printf("Error updating stats for %s: %v", username, err)
-				} else {
-					log.Printf("Successfully updated stats for %s (rating change: %+d)", username, ratingChange)
+					continue
+				}
+
+				newRank := user.Rank
+				if ranked {
+					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					change, err := services.NewRankingService(db.DB, requireVerified).RecordGame(ctx, services.RankedGame{
+						UserID: user.ID,
+						Score:  score,
+						Won:    isWinner,
+					})
+					cancel()
+					if err != nil {
+						log.Printf("Error updating rank for %s: %v", username, err)
+					} else {
+						newRank = change.NewRank
+						log.Printf("Updated rating for %s: %d -> %d (%s)", username, change.OldRating, change.NewRating, change.NewRank)
+					}
 				}
+				log.Printf("Successfully updated stats for %s", username)
+				publishGameAchievements(user, gameStats[username], isWinner && len(scores) >= 2, winStreak, newRank)
 				continue
 			}
 
-			if err := db.DB.Exec(`
-				UPDATE users 
-				SET total_points = total_points + ?,
-					total_games = total_games + 1,
-					total_wins = total_wins + ?,
-					win_streak = CASE WHEN ? = 1 THEN win_streak + 1 ELSE 0 END,
-					longest_win_streak = CASE 
-						WHEN ? = 1 AND win_streak + 1 > longest_win_streak 
-						THEN win_streak + 1 
-						ELSE longest_win_streak 
-					END,
-					rating = GREATEST(0, rating + ?)
-				WHERE username = ?
-			`, score, winValue, winValue, winValue, ratingChange, username).Error; err != nil {
-				log.Printf("Error updating stats for %s: %v", username, err)
-			} else {
-				log.Printf("Successfully updated stats for %s (rating change: %+d)", username, ratingChange)
-			}
+			log.Printf("Error updating stats for %s: user %s not found", username, owner.UserID)
 		}
 	}
 }
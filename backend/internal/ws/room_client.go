@@ -3,12 +3,22 @@ package ws
 import (
 	"briworld/internal/domain"
 	redisClient "briworld/internal/redis"
+	"briworld/internal/utils"
 	"context"
 	"log"
+
+	"github.com/google/uuid"
 )
 
-// AddClient adds a new client to the room or handles reconnection.
+// AddClient adds a new client to the room or handles reconnection. A client
+// only takes over a username when it has the same owner as the player who
+// holds it, the same account or the same guest session; anyone else is
+// refused.
 func (r *Room) AddClient(client *Client) {
+	// Read before locking the room: the matchmaker locks rooms while it
+	// holds its own lock
+	heldSeats := Matchmaking.heldSeats(r.ID)
+
 	r.mu.Lock()
 	isFirstClient := len(r.Clients) == 0
 
@@ -21,6 +31,15 @@ func (r *Room) AddClient(client *Client) {
 		}
 	}
 
+	owner := client.progressOwner()
+	held, named := r.progressOwners[client.Username]
+	if (existingClient != nil && existingClient.progressOwner() != owner) || (named && held != owner) {
+		r.mu.Unlock()
+		log.Printf("Refused %s in room %s: the name belongs to another player", client.Username, r.ID)
+		r.refuseClient(client, "username_taken", "Another player in this room is using this name")
+		return
+	}
+
 	if existingClient != nil {
 		// Reconnection: close old connection and replace
 		log.Printf("Player %s reconnecting to room %s", client.Username, r.ID)
@@ -39,17 +58,32 @@ func (r *Room) AddClient(client *Client) {
 
 	// Check if room is full (6 player limit) - count only active players, not spectators
 	playerCount := 0
+	present := make(map[uuid.UUID]bool, len(r.Clients))
 	for c := range r.Clients {
 		if !c.IsSpectator {
 			playerCount++
 		}
+		if !c.IsGuest {
+			present[c.UserID] = true
+		}
+	}
+	// Seats the matchmaker holds for others on their way count as taken, so
+	// nobody joining by code pushes a party member out
+	for _, id := range heldSeats {
+		if !present[id] && (client.IsGuest || id != client.UserID) {
+			playerCount++
+		}
 	}
 
-	if playerCount >= 6 {
+	if playerCount >= maxRoomPlayers {
 		// Room is full - add as spectator
 		client.IsSpectator = true
-		log.Printf("Player %s joined room %s as SPECTATOR (room full: %d/6 players)",
-			client.Username, r.ID, playerCount)
+		log.Printf("Player %s joined room %s as SPECTATOR (room full: %d/%d players)",
+			client.Username, r.ID, playerCount, maxRoomPlayers)
+	} else if r.match != nil && !r.match.plays(client) {
+		// Only the match's players play in a match room
+		client.IsSpectator = true
+		log.Printf("Player %s joined match room %s as SPECTATOR", client.Username, r.ID)
 	} else {
 		client.IsSpectator = false
 	}
@@ -69,10 +103,14 @@ func (r *Room) AddClient(client *Client) {
 		if _, exists := r.GameState.Scores[client.Username]; !exists {
 			r.GameState.Scores[client.Username] = 0
 		}
+		if !named {
+			r.progressOwners[client.Username] = owner
+		}
 	}
 
-	// Set game mode and room type from first client
-	if isFirstClient {
+	// Set game mode and room type from first client; match rooms keep the
+	// settings they were opened with
+	if isFirstClient && r.match == nil {
 		r.GameState.GameMode = client.GameMode
 		r.GameState.RoomType = client.RoomType
 		r.GameState.TotalRounds = client.RoundsCount
@@ -108,7 +146,7 @@ func (r *Room) AddClient(client *Client) {
 		"status":            string(r.GameState.Status),
 		"current_round":     r.GameState.CurrentRound,
 		"total_rounds":      r.GameState.TotalRounds,
-		"question":          r.GameState.Question,
+		"question":          r.questionLocked(),
 		"scores":            cloneStringIntMap(r.GameState.Scores),
 		"time_remaining":    r.GameState.TimeRemaining,
 		"round_time_limit":  r.GameState.RoundTimeLimit,
@@ -122,6 +160,10 @@ func (r *Room) AddClient(client *Client) {
 		"player_banners":    playerBanners,
 		"is_owner":          r.Owner == client.Username,
 	}
+	// Guests need the token to claim this session's progress after signing up
+	if client.IsGuest {
+		joinedPayload["guest_token"] = utils.SignGuestSession(client.SessionID, currentSettings().jwtSecret)
+	}
 
 	shouldAutoStart := r.GameState.RoomType == "SINGLE" && r.GameState.Status == domain.RoomWaiting && r.Owner == client.Username
 	roomID := r.ID
@@ -149,13 +191,29 @@ func (r *Room) AddClient(client *Client) {
 		r.GameState.Status = "in_progress"
 		r.GameState.CurrentRound = 0
 		r.mu.Unlock()
-		go r.BroadcastMessage("game_started", r.GameState)
+		go r.BroadcastMessage("game_started", r.BuildStatePayload())
 		go r.StartRound()
 	}
 
 	// Broadcast room update to all clients
 	r.BroadcastRoomUpdate()
 	r.BroadcastStateSnapshot()
+	r.syncPresence()
+	if !client.IsGuest {
+		Matchmaking.claim(roomID, client.UserID)
+	}
+	r.followLeader(client)
+	r.startMatchIfReady()
+}
+
+// refuseClient tells a client that was not let into the room why and closes
+// its connection.
+func (r *Room) refuseClient(client *Client, messageType, message string) {
+	r.SendToClient(client, messageType, map[string]interface{}{
+		"message":   message,
+		"room_code": r.ID,
+	})
+	close(client.Send)
 }
 
 // RemoveClient removes a client from the room.
@@ -186,6 +244,7 @@ func (r *Room) RemoveClient(client *Client) {
 		delete(r.GameState.PlayerColors, client.Username)
 		delete(r.GameState.EliminatedPlayers, client.Username)
 		delete(r.GameState.Teams, client.Username)
+		delete(r.progressOwners, client.Username)
 		for countryCode, paintedBy := range r.GameState.PaintedCountries {
 			if paintedBy == client.Username {
 				delete(r.GameState.PaintedCountries, countryCode)
@@ -252,6 +311,10 @@ func (r *Room) RemoveClient(client *Client) {
 	// Release lock before external calls
 	r.mu.Unlock()
 
+	if !client.IsGuest {
+		leavePresence(roomID, client.UserID)
+	}
+
 	if shouldCleanup {
 		if immediateCleanup {
 			r.cancel()
@@ -1,8 +1,13 @@
 package ws
 
 import (
+	"briworld/internal/services"
+	"briworld/internal/utils"
+	"encoding/json"
 	"testing"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestAddClient(t *testing.T) {
@@ -139,6 +144,83 @@ func TestAddClientReconnection(t *testing.T) {
 	}
 }
 
+// refusal returns the type of the message a refused client was sent.
+func refusal(t *testing.T, client *Client) string {
+	t.Helper()
+	var msg Message
+	for data := range client.Send {
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("invalid message: %v", err)
+		}
+	}
+	return msg.Type
+}
+
+func TestAddClientRefusesAnotherAccountsUsername(t *testing.T) {
+	room := NewRoom("TEST123")
+	defer room.cancel()
+
+	victim := uuid.New()
+	alice := &Client{Username: "alice", UserID: victim, Send: make(chan []byte, 10), RoomType: "PRIVATE"}
+	room.AddClient(alice)
+	room.mu.Lock()
+	room.GameState.Scores["alice"] = 120
+	room.mu.Unlock()
+
+	impostor := &Client{Username: "alice", UserID: uuid.New(), Send: make(chan []byte, 10), RoomType: "PRIVATE"}
+	room.AddClient(impostor)
+
+	room.mu.RLock()
+	_, victimPresent := room.Clients[alice]
+	_, impostorPresent := room.Clients[impostor]
+	owner := room.progressOwners["alice"]
+	score := room.GameState.Scores["alice"]
+	room.mu.RUnlock()
+
+	if !victimPresent || impostorPresent {
+		t.Fatalf("victim present = %v, impostor present = %v; want the victim kept", victimPresent, impostorPresent)
+	}
+	if owner.UserID != victim || score != 120 {
+		t.Fatalf("alice's owner = %s with %d points, want %s with 120", owner.UserID, score, victim)
+	}
+	if got := refusal(t, impostor); got != "username_taken" {
+		t.Fatalf("impostor was sent %q, want username_taken", got)
+	}
+
+	// The same account may still reconnect, also after it dropped
+	room.RemoveClient(alice)
+	again := &Client{Username: "alice", UserID: victim, Send: make(chan []byte, 10), RoomType: "PRIVATE"}
+	room.AddClient(again)
+	room.mu.RLock()
+	_, back := room.Clients[again]
+	room.mu.RUnlock()
+	if !back {
+		t.Fatal("the account holding the name could not reconnect")
+	}
+}
+
+func TestAddClientKeepsADroppedGuestsName(t *testing.T) {
+	room := NewRoom("TEST123")
+	defer room.cancel()
+
+	guest := &Client{Username: "guest", SessionID: "session-a", IsGuest: true, Send: make(chan []byte, 10)}
+	room.AddClient(guest)
+	room.RemoveClient(guest)
+
+	other := &Client{Username: "guest", SessionID: "session-b", IsGuest: true, Send: make(chan []byte, 10)}
+	room.AddClient(other)
+	if got := refusal(t, other); got != "username_taken" {
+		t.Fatalf("another guest session was sent %q, want username_taken", got)
+	}
+
+	room.mu.RLock()
+	owner := room.progressOwners["guest"]
+	room.mu.RUnlock()
+	if owner != (services.ProgressOwner{GuestSessionID: "session-a"}) {
+		t.Fatalf("guest's owner = %+v, want session-a", owner)
+	}
+}
+
 func TestRemoveClient(t *testing.T) {
 	room := NewRoom("TEST123")
 	defer room.cancel()
@@ -311,3 +393,14 @@ func BenchmarkRemoveClient(b *testing.B) {
 		room.RemoveClient(clients[i])
 	}
 }
+
+func TestAdmitGuestChecksTheGuestToken(t *testing.T) {
+	token := utils.SignGuestSession("guest-session", currentSettings().jwtSecret)
+
+	if !admitGuest("guest-session", "guest", token) {
+		t.Error("the session's own token was refused")
+	}
+	if admitGuest("other-session", "guest", token) {
+		t.Error("a token for another session was accepted")
+	}
+}
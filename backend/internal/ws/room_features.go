@@ -206,7 +206,7 @@ func (r *Room) AcceptPromotion(client *Client) {
 		}
 	}
 
-	if playerCount >= 6 {
+	if playerCount >= maxRoomPlayers {
 		r.SendToClient(client, "promotion_rejected", map[string]interface{}{
 			"error": "No player slots available",
 		})
@@ -4,16 +4,23 @@ import (
 	"briworld/internal/domain"
 	"briworld/internal/game"
 	redisClient "briworld/internal/redis"
+	"briworld/internal/services"
+	"briworld/internal/utils"
 	"context"
 	"log"
 	"time"
 )
 
+// flagTokenTTL keeps a round's flag URL working well past the round, for
+// the results screen and slow clients.
+const flagTokenTTL = time.Hour
+
 // StartGame initiates the game (only owner can start).
 func (r *Room) StartGame(username string) {
 	r.mu.Lock()
 
-	if r.Owner != username {
+	// Organized matches start on their own once every player is in
+	if r.Owner != username || r.match != nil {
 		r.mu.Unlock()
 		return
 	}
@@ -43,6 +50,13 @@ func (r *Room) StartGame(username string) {
 	r.GameState.CurrentRound = 0
 
 	r.mu.Unlock()
+	r.launchGame()
+}
+
+// launchGame announces a game that was just set in progress and starts its
+// first round.
+func (r *Room) launchGame() {
+	r.syncPresence()
 
 	// Send an authoritative state immediately before the first round starts.
 	go r.BroadcastMessage("game_started", r.BuildStatePayload())
@@ -63,6 +77,10 @@ func (r *Room) StartRound() {
 	r.GameState.CurrentRound++
 	r.GameState.RoundActive = true
 	r.GameState.Answered = make(map[string]bool)
+	r.roundMisses = make(map[string]int)
+	if r.GameState.CurrentRound == 1 {
+		r.answerStats = make(map[string]*answerStats)
+	}
 
 	// WORLD_MAP mode doesn't need questions
 	if r.GameState.GameMode == "WORLD_MAP" {
@@ -74,8 +92,19 @@ func (r *Room) StartRound() {
 		return
 	}
 
-	// Generate question for other modes
-	question, err := game.Data.GenerateQuestion(r.GameState.GameMode, r.GameState.UsedCountries)
+	// Generate question for other modes; matches on fixed countries ask
+	// them in order and practice games ask what the player is due to review
+	var question *game.Question
+	var err error
+	picked := false
+	if r.match != nil {
+		question, picked, err = r.match.question(r.GameState.GameMode, r.GameState.CurrentRound)
+	} else if r.GameState.GameMode == string(game.ModePractice) {
+		question, picked, err = r.practiceQuestionLocked()
+	}
+	if !picked {
+		question, err = game.Data.GenerateQuestion(r.GameState.GameMode, r.GameState.UsedCountries)
+	}
 	if err != nil {
 		log.Printf("Error generating question for room %s: %v", r.ID, err)
 		r.mu.Unlock()
@@ -83,6 +112,9 @@ func (r *Room) StartRound() {
 		return
 	}
 
+	if question.FlagCode != "" {
+		question.FlagURL = flagImageURL(question.FlagCode)
+	}
 	r.GameState.Question = question
 	r.GameState.UsedCountries[question.CountryCode] = true
 
@@ -93,6 +125,7 @@ func (r *Room) StartRound() {
 		r.GameState.RoundTimeLimit = timeLimit
 	}
 	r.GameState.TimeRemaining = timeLimit
+	r.roundStartedAt = time.Now()
 
 	r.mu.Unlock()
 
@@ -108,6 +141,17 @@ func (r *Room) StartRound() {
 	}
 }
 
+// flagImageURL returns the server URL of the flag of countryCode. The URL is
+// different every round and does not name the country.
+func flagImageURL(countryCode string) string {
+	token, err := utils.SealFlagToken(countryCode, currentSettings().jwtSecret, flagTokenTTL)
+	if err != nil {
+		log.Printf("Failed to seal flag token: %v", err)
+		return ""
+	}
+	return "/api/v2/flags/" + token
+}
+
 // startCountdownTimer runs the countdown for a round.
 func (r *Room) startCountdownTimer(duration int) {
 	ticker := time.NewTicker(1 * time.Second)
@@ -163,16 +207,23 @@ func (r *Room) EndRound() {
 
 	r.GameState.RoundActive = false
 	correctAnswer := ""
+	countryCode := ""
 	if r.GameState.Question != nil {
 		correctAnswer = r.GameState.Question.CountryName
+		countryCode = r.GameState.Question.CountryCode
 	}
 	currentRound := r.GameState.CurrentRound
 	totalRounds := r.GameState.TotalRounds
 	gameMode := r.GameState.GameMode
 	scores := cloneStringIntMap(r.GameState.Scores)
+	roundOwners := r.roundOwnersLocked()
+	answered := cloneStringBoolMap(r.GameState.Answered)
 
 	r.mu.Unlock()
 
+	go recordRoundMastery(roundOwners, countryCode, answered)
+	go recordRoundLeaderboards(gameMode, roundOwners, countryCode, answered)
+
 	log.Printf("Round %d ended in room %s. Correct answer: %s",
 		currentRound, r.ID, correctAnswer)
 
@@ -287,16 +338,34 @@ func (r *Room) EndGame() {
 
 	// Calculate final scores
 	scores := make(map[string]int)
+	owners := make(map[string]services.ProgressOwner)
 	for username, score := range r.GameState.Scores {
 		scores[username] = score
+		if owner, ok := r.progressOwners[username]; ok {
+			owners[username] = owner
+		}
+	}
+	gameMode := r.GameState.GameMode
+	roomType := r.GameState.RoomType
+	if r.match != nil {
+		roomType = r.match.roomType()
 	}
+	gameStats := r.gameStatsLocked()
+	timings := r.gameTimingsLocked()
 
 	r.mu.Unlock()
 
 	log.Printf("Game ended in room %s. Final scores: %v", r.ID, scores)
+	r.syncPresence()
+	if r.match != nil {
+		go r.match.complete(owners, scores)
+	}
 
 	// Update player stats in database
-	go r.UpdatePlayerStats(scores)
+	go r.UpdatePlayerStats(scores, owners, gameStats)
+	go recordMatchResults(r.ID, gameMode, roomType, owners, scores, gameStats)
+	go recordGameLeaderboards(gameMode, owners, scores)
+	go services.AnalyseGameTimings(timings)
 
 	// Broadcast game completion
 	r.BroadcastMessage("game_completed", r.BuildStatePayload())
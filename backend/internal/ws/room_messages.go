@@ -6,6 +6,14 @@ import (
 
 // HandleMessage routes incoming WebSocket messages to appropriate handlers.
 func (r *Room) HandleMessage(client *Client, msg *Message) {
+	// A connection the room refused or replaced speaks for nobody
+	r.mu.RLock()
+	_, member := r.Clients[client]
+	r.mu.RUnlock()
+	if !member {
+		return
+	}
+
 	switch msg.Type {
 	case "start_game":
 		r.StartGame(client.Username)
@@ -36,6 +44,9 @@ func (r *Room) HandleMessage(client *Client, msg *Message) {
 	case "close_room":
 		r.CloseRoom(client.Username)
 
+	case "invite_friend":
+		r.InviteFriend(client, msg.Payload)
+
 	default:
 		log.Printf("Unknown message type: %s from %s", msg.Type, client.Username)
 	}
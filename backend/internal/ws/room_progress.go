@@ -0,0 +1,106 @@
+package ws
+
+import (
+	"briworld/internal/database"
+	"briworld/internal/game"
+	"briworld/internal/models"
+	"briworld/internal/services"
+	"log"
+	"time"
+)
+
+// roundOwnersLocked returns the progress owners of players still in the game.
+// Caller must hold r.mu.
+func (r *Room) roundOwnersLocked() map[string]services.ProgressOwner {
+	owners := make(map[string]services.ProgressOwner, len(r.GameState.Scores))
+	for username := range r.GameState.Scores {
+		if r.GameState.EliminatedPlayers[username] {
+			continue
+		}
+		if owner, ok := r.progressOwners[username]; ok {
+			owners[username] = owner
+		}
+	}
+	return owners
+}
+
+// recordRoundMastery credits every player in owners with one answer about
+// the round's country: correct if they answered it, incorrect otherwise.
+func recordRoundMastery(owners map[string]services.ProgressOwner, countryCode string, answered map[string]bool) {
+	db := database.GetDB()
+	if db == nil || countryCode == "" {
+		return
+	}
+
+	progress := services.NewProgressService(db)
+	for username, owner := range owners {
+		mastered, err := progress.RecordMastery(owner, countryCode, answered[username])
+		if err != nil {
+			log.Printf("Error recording mastery for %s: %v", username, err)
+			continue
+		}
+		if !mastered || owner.IsGuest() {
+			continue
+		}
+		count, err := progress.MasteredCount(owner)
+		if err != nil {
+			log.Printf("Error counting mastered countries for %s: %v", username, err)
+			continue
+		}
+		services.PublishAchievementEvent(services.AchievementEvent{
+			Kind:   services.EventCountryMastered,
+			UserID: owner.UserID,
+			Region: game.GetRegionForCountry(countryCode),
+			Stats:  map[string]int{"mastered_countries": count},
+		})
+	}
+}
+
+// recordMatchResults stores each player's result of a finished game.
+func recordMatchResults(roomCode, gameMode, roomType string, owners map[string]services.ProgressOwner, scores map[string]int, gameStats map[string]map[string]int) {
+	db := database.GetDB()
+	if db == nil {
+		return
+	}
+
+	maxScore := 0
+	for _, score := range scores {
+		if score > maxScore {
+			maxScore = score
+		}
+	}
+
+	progress := services.NewProgressService(db)
+	playedAt := time.Now()
+	for username, score := range scores {
+		owner, ok := owners[username]
+		if !ok {
+			continue
+		}
+
+		placement := 1
+		for _, other := range scores {
+			if other > score {
+				placement++
+			}
+		}
+
+		result := models.MatchResult{
+			Username:    username,
+			RoomCode:    roomCode,
+			GameMode:    gameMode,
+			RoomType:    roomType,
+			Score:       score,
+			Placement:   placement,
+			PlayerCount: len(scores),
+			Won:         score == maxScore && maxScore > 0,
+			Rounds:      gameStats[username]["rounds"],
+			Correct:     gameStats[username]["correct_answers"],
+			Misses:      gameStats[username]["wrong_answers"],
+			PlayedAt:    playedAt,
+		}
+		if err := progress.RecordMatch(owner, result); err != nil {
+			log.Printf("Error recording match result for %s: %v", username, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package ws
+
+import (
+	"briworld/internal/services"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestResolvePlayerWithoutTokenIsGuest(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty token", ""},
+		{"garbage token", "not-a-jwt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := resolvePlayer(tt.token)
+			if account.UserID != uuid.Nil {
+				t.Errorf("resolvePlayer(%q) = %s, want guest", tt.token, account.UserID)
+			}
+		})
+	}
+}
+
+func TestAddClientRecordsProgressOwner(t *testing.T) {
+	room := NewRoom("TEST123")
+	defer room.cancel()
+	go room.Run()
+
+	userID := uuid.New()
+	member := &Client{
+		Username: "alice",
+		UserID:   userID,
+		Send:     make(chan []byte, 10),
+		RoomType: "PRIVATE",
+	}
+	guest := &Client{
+		Username:  "bob",
+		SessionID: "guest-session",
+		IsGuest:   true,
+		Send:      make(chan []byte, 10),
+		RoomType:  "PRIVATE",
+	}
+
+	room.AddClient(member)
+	room.AddClient(guest)
+	time.Sleep(50 * time.Millisecond)
+
+	room.mu.RLock()
+	memberOwner := room.progressOwners["alice"]
+	guestOwner := room.progressOwners["bob"]
+	room.mu.RUnlock()
+
+	if memberOwner.IsGuest() || memberOwner.UserID != userID {
+		t.Errorf("alice owner = %+v, want user %s", memberOwner, userID)
+	}
+	if !guestOwner.IsGuest() || guestOwner.GuestSessionID != "guest-session" {
+		t.Errorf("bob owner = %+v, want guest session", guestOwner)
+	}
+}
+
+func TestRoundOwnersSkipsEliminatedPlayers(t *testing.T) {
+	room := NewRoom("TEST123")
+	defer room.cancel()
+
+	room.mu.Lock()
+	room.GameState.Scores["alice"] = 100
+	room.GameState.Scores["bob"] = 50
+	room.GameState.EliminatedPlayers["bob"] = true
+	room.progressOwners["alice"] = services.ProgressOwner{UserID: uuid.New()}
+	room.progressOwners["bob"] = services.ProgressOwner{GuestSessionID: "guest-session"}
+	owners := room.roundOwnersLocked()
+	room.mu.Unlock()
+
+	if _, ok := owners["alice"]; !ok {
+		t.Error("Expected alice to be credited for the round")
+	}
+	if _, ok := owners["bob"]; ok {
+		t.Error("Expected eliminated bob to be skipped")
+	}
+}
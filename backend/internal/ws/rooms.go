@@ -2,9 +2,11 @@ package ws
 
 import (
 	"briworld/internal/game"
+	"briworld/internal/services"
 	"context"
 	"log"
 	"sync"
+	"time"
 )
 
 // Room represents a game room where players compete in real-time.
@@ -22,6 +24,17 @@ type Room struct {
 	cancel             context.CancelFunc
 	inactiveRoundCount int
 	isCleanedUp        bool
+	// progressOwners maps each player's username to the account or guest
+	// session their results are recorded against.
+	progressOwners map[string]services.ProgressOwner
+	// match is set on rooms opened for an organized match.
+	match *matchRoom
+	// roundStartedAt and answerStats time each player's answers through a
+	// game for their achievements and the cheat detection pipeline.
+	// roundMisses counts each player's wrong answers this round.
+	roundStartedAt time.Time
+	answerStats    map[string]*answerStats
+	roundMisses    map[string]int
 }
 
 // NewRoom creates a new game room with the given ID.
@@ -38,6 +51,10 @@ func NewRoom(id string) *Room {
 		ctx:         ctx,
 		cancel:      cancel,
 		isCleanedUp: false,
+
+		progressOwners: make(map[string]services.ProgressOwner),
+		answerStats:    make(map[string]*answerStats),
+		roundMisses:    make(map[string]int),
 	}
 }
 
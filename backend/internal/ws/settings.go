@@ -0,0 +1,41 @@
+package ws
+
+import (
+	"briworld/internal/config"
+	"sync"
+)
+
+// settings are the parts of the server config the sockets need on every
+// connection, round and game.
+type settings struct {
+	jwtSecret            string
+	requireVerifiedEmail bool
+}
+
+var (
+	socketSettings     settings
+	socketSettingsOnce sync.Once
+)
+
+// Configure hands the sockets the server config. Call it at startup; a
+// socket used before that reads the environment once itself.
+func Configure(cfg *config.Config) {
+	socketSettingsOnce.Do(func() {
+		socketSettings = settingsFrom(cfg)
+	})
+}
+
+func settingsFrom(cfg *config.Config) settings {
+	return settings{
+		jwtSecret:            cfg.JWT.Secret,
+		requireVerifiedEmail: cfg.Auth.RequireVerifiedEmail,
+	}
+}
+
+// currentSettings returns the config set by Configure.
+func currentSettings() settings {
+	socketSettingsOnce.Do(func() {
+		socketSettings = settingsFrom(config.Load())
+	})
+	return socketSettings
+}
@@ -74,7 +74,7 @@ func (sb *SnapshotBuilder) Build(r *Room, client *Client) map[string]interface{}
 		"current_round":        r.GameState.CurrentRound,
 		"total_rounds":         r.GameState.TotalRounds,
 		"round_time_limit":     r.GameState.RoundTimeLimit,
-		"question":             r.GameState.Question,
+		"question":             r.questionLocked(),
 		"scores":               scores,
 		"round_deadline":       deadline,
 		"game_mode":            r.GameState.GameMode,
@@ -0,0 +1,172 @@
+package ws
+
+import (
+	"briworld/internal/domain"
+	"briworld/internal/services"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// inviteCooldown is how often a player may invite the same friend.
+const inviteCooldown = 10 * time.Second
+
+var (
+	friendService       *services.FriendService
+	presenceService     *services.PresenceService
+	notificationService *services.NotificationService
+)
+
+// SetSocial wires the services behind friend presence, room invites and the
+// notification socket. Without them rooms skip presence updates and refuse
+// invites.
+func SetSocial(friends *services.FriendService, presence *services.PresenceService, notifications *services.NotificationService) {
+	friendService = friends
+	presenceService = presence
+	notificationService = notifications
+}
+
+// syncPresence records every signed-in player of the room as being in it,
+// in its lobby or playing depending on the game status.
+func (r *Room) syncPresence() {
+	if presenceService == nil {
+		return
+	}
+
+	r.mu.RLock()
+	userIDs := make([]uuid.UUID, 0, len(r.Clients))
+	for c := range r.Clients {
+		if !c.IsGuest {
+			userIDs = append(userIDs, c.UserID)
+		}
+	}
+	roomID := r.ID
+	gameMode := r.GameState.GameMode
+	inGame := r.GameState.Status == domain.RoomInProgress
+	r.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for _, id := range userIDs {
+		if err := presenceService.EnterRoom(ctx, id, roomID, gameMode, inGame); err != nil {
+			log.Printf("Failed to update presence in room %s: %v", roomID, err)
+			return
+		}
+	}
+}
+
+// leavePresence records that the given players left roomID.
+func leavePresence(roomID string, userIDs ...uuid.UUID) {
+	if presenceService == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for _, id := range userIDs {
+		if id == uuid.Nil {
+			continue
+		}
+		if err := presenceService.LeaveRoom(ctx, id, roomID); err != nil {
+			log.Printf("Failed to update presence after leaving room %s: %v", roomID, err)
+			return
+		}
+	}
+}
+
+// InviteFriend sends a friend an invite to this room through their
+// notification inbox, which pushes it to any notification socket they have
+// open.
+func (r *Room) InviteFriend(client *Client, payload interface{}) {
+	data, _ := json.Marshal(payload)
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	json.Unmarshal(data, &req)
+
+	fail := func(message string) {
+		r.SendToClient(client, "invite_failed", map[string]interface{}{
+			"user_id": req.UserID,
+			"error":   message,
+		})
+	}
+
+	if client.IsGuest {
+		fail("Sign in to invite friends")
+		return
+	}
+	if friendService == nil || notificationService == nil {
+		fail("Invites are unavailable")
+		return
+	}
+
+	friendID, err := uuid.Parse(req.UserID)
+	if err != nil || friendID == client.UserID {
+		fail("Invalid friend")
+		return
+	}
+
+	r.mu.RLock()
+	roomType := r.GameState.RoomType
+	gameMode := r.GameState.GameMode
+	status := r.GameState.Status
+	r.mu.RUnlock()
+
+	if roomType == "SINGLE" {
+		fail("Single player rooms cannot be joined")
+		return
+	}
+	if r.match != nil && r.match.private() {
+		fail("This room cannot be joined")
+		return
+	}
+	if status == domain.RoomClosed {
+		fail("This room is closed")
+		return
+	}
+
+	if last, ok := client.invitedAt[friendID]; ok && time.Since(last) < inviteCooldown {
+		fail("You already invited this friend")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	friends, err := friendService.AreFriends(ctx, client.UserID, friendID)
+	if err != nil {
+		log.Printf("Failed to check friendship for invite: %v", err)
+		fail("Failed to send invite")
+		return
+	}
+	if !friends {
+		fail("You can only invite friends")
+		return
+	}
+
+	if client.invitedAt == nil {
+		client.invitedAt = make(map[uuid.UUID]time.Time)
+	}
+	client.invitedAt[friendID] = time.Now()
+
+	if _, err := notificationService.Notify(ctx, friendID, services.NotificationFriendInvite, map[string]interface{}{
+		"from": map[string]interface{}{
+			"id":         client.UserID,
+			"username":   client.Username,
+			"avatar_url": client.AvatarURL,
+		},
+		"room_code": r.ID,
+		"game_mode": gameMode,
+		"room_type": roomType,
+	}); err != nil {
+		log.Printf("Failed to send invite: %v", err)
+		fail("Failed to send invite")
+		return
+	}
+
+	r.SendToClient(client, "invite_sent", map[string]interface{}{
+		"user_id": friendID,
+	})
+}
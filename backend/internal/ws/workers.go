@@ -63,9 +63,10 @@ func (p *GameStartWorkerPool) processGameStart(job *GameStartJob) {
 	r.GameState.CurrentRound = 0
 
 	r.mu.Unlock()
+	r.syncPresence()
 
 	// Send game_started immediately in background
-	go r.BroadcastMessage("game_started", r.GameState)
+	go r.BroadcastMessage("game_started", r.BuildStatePayload())
 	// Start first round in background
 	go r.StartRound()
 }
@@ -9,6 +9,8 @@ import Login from "./pages/Login";
 import Register from "./pages/Register";
 import ForgotPassword from "./pages/ForgotPassword";
 import ResetPassword from "./pages/ResetPassword";
+import VerifyEmail from "./pages/VerifyEmail";
+import OAuthCallback from "./pages/OAuthCallback";
 import About from "./pages/About";
 import Lobby from "./pages/Lobby";
 import Profile from "./pages/Profile";
@@ -32,6 +34,8 @@ const App = () => (
             <Route path="/register" element={<Register />} />
             <Route path="/forgot-password" element={<ForgotPassword />} />
             <Route path="/reset-password" element={<ResetPassword />} />
+            <Route path="/verify-email" element={<VerifyEmail />} />
+            <Route path="/oauth/callback" element={<OAuthCallback />} />
             <Route path="/about" element={<About />} />
             <Route path="/lobby" element={<Lobby />} />
             <Route path="/profile" element={<Profile />} />
@@ -33,6 +33,7 @@ export const CollisionDialog = () => {
     // Clear session and return to lobby
     sessionStorage.removeItem('currentRoomCode');
     sessionStorage.removeItem('sessionId');
+    sessionStorage.removeItem('guestToken');
     setOpen(false);
     navigate('/lobby');
   };
@@ -77,7 +77,7 @@ export function MetaStatsWidget() {
             <Star className="w-4 h-4 text-purple-500" />
             <span className="text-muted-foreground">Achievements</span>
           </div>
-          <span className="font-bold text-foreground">{stats.achievementCount}/13</span>
+          <span className="font-bold text-foreground">{stats.achievementCount}/15</span>
         </div>
       </div>
 
@@ -14,7 +14,7 @@ const EXPLORATION_MODES = [];
 // ROUND-BASED MODES - Have rounds, timers, round state
 // const ROUND_BASED_MODES = ['FLAG', 'WORLD_MAP', 'CAPITAL_RUSH', 'SILHOUETTE', 'EMOJI', 'TEAM_BATTLE', 'LAST_STANDING', 'BORDER_LOGIC']; // 'AUDIO' - TODO: Will be added later
 // Emoji mode is intentionally disabled for now, but kept in code for future reuse.
-const ROUND_BASED_MODES = ['FLAG', 'WORLD_MAP', 'SILHOUETTE', 'LAST_STANDING', 'BORDER_LOGIC']; // 'CAPITAL_RUSH', 'EMOJI', 'TEAM_BATTLE' - TODO: Will be added later
+const ROUND_BASED_MODES = ['FLAG', 'WORLD_MAP', 'SILHOUETTE', 'LAST_STANDING', 'BORDER_LOGIC', 'PRACTICE']; // 'CAPITAL_RUSH', 'EMOJI', 'TEAM_BATTLE' - TODO: Will be added later
 
 export const isExplorationMode = (mode: string): boolean => EXPLORATION_MODES.includes(mode);
 export const isRoundBasedMode = (mode: string): boolean => ROUND_BASED_MODES.includes(mode);
@@ -33,6 +33,9 @@ const GAME_MODE_CONFIG: GameModeConfig[] = [
   // Knowledge & Deduction Modes
   { id: "BORDER_LOGIC", title: "Border Logic", description: "Guess country by neighbors", icon: "🧠", roomTypes: ["single", "private", "public"] },
 
+  // Learning Modes (signed-in single player)
+  { id: "PRACTICE", title: "Practice", description: "Review the flags you keep missing", icon: "🎯", roomTypes: ["single"] },
+
   // Team Modes (Multiplayer only)
   // { id: "TEAM_BATTLE", title: "Team Battle", description: "Red vs Blue team competition", icon: "⚔️", roomTypes: ["private", "public"] }, // TODO: Will be added later
 
@@ -34,6 +34,7 @@ export const useGameAutoStart = ({
       "EMOJI",
       "LAST_STANDING",
       "BORDER_LOGIC",
+      "PRACTICE",
     ];
 
     if (
@@ -96,7 +96,7 @@ export const useGameState = ({ config, gameState, roomUpdate, ws }: UseGameState
   useEffect(() => {
     if (!gameState) return;
 
-    const timedModes = ['FLAG', 'CAPITAL_RUSH', 'SILHOUETTE', 'EMOJI', 'TEAM_BATTLE', 'LAST_STANDING', 'BORDER_LOGIC'];
+    const timedModes = ['FLAG', 'CAPITAL_RUSH', 'SILHOUETTE', 'EMOJI', 'TEAM_BATTLE', 'LAST_STANDING', 'BORDER_LOGIC', 'PRACTICE'];
     
     if (
       timedModes.includes(config.gameMode) &&
@@ -44,7 +44,7 @@ export const useRoomManagement = ({ config, ws }: UseRoomManagementProps) => {
       try {
         const message = JSON.parse(event.data);
         
-        if (message.type === 'room_closed' || message.type === 'room_expired') {
+        if (message.type === 'room_closed' || message.type === 'room_expired' || message.type === 'kicked') {
           if (ws) ws.close();
           sessionStorage.removeItem('currentRoomCode');
           sessionStorage.removeItem('gameMode');
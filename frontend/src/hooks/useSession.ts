@@ -43,6 +43,7 @@ export const useSession = () => {
 
   const clearSession = () => {
     sessionStorage.removeItem('sessionId');
+    sessionStorage.removeItem('guestToken');
     localStorage.removeItem('username');
     localStorage.removeItem('token');
     setSession(null);
@@ -8,6 +8,7 @@ import {
   GameConfig,
 } from "@/types/game";
 import type { WebSocketOutgoingMessage } from "@/types/ws";
+import { getFlagUrl } from "@/lib/api";
 
 function buildWebSocketUrl(params: {
   roomCode: string;
@@ -18,6 +19,7 @@ function buildWebSocketUrl(params: {
   rounds: number;
   timeout: number;
   token: string;
+  guestToken: string;
 }): string {
   const explicitWsUrl = import.meta.env.VITE_WS_URL;
   const apiBase = import.meta.env.VITE_API_URL;
@@ -47,6 +49,9 @@ function buildWebSocketUrl(params: {
   baseUrl.searchParams.set("rounds", String(params.rounds));
   baseUrl.searchParams.set("timeout", String(params.timeout));
   baseUrl.searchParams.set("token", params.token);
+  if (params.guestToken) {
+    baseUrl.searchParams.set("guest_token", params.guestToken);
+  }
 
   return baseUrl.toString();
 }
@@ -116,23 +121,14 @@ export const useWebSocket = (
   useEffect(() => {
     if (!roomCode || !username || !gameMode || !roomType) return;
 
-    let sessionId = sessionStorage.getItem("sessionId");
-    if (!sessionId) {
-      sessionId = generateSessionId();
-      sessionStorage.setItem("sessionId", sessionId);
+    let storedSessionId = sessionStorage.getItem("sessionId");
+    if (!storedSessionId) {
+      storedSessionId = generateSessionId();
+      sessionStorage.setItem("sessionId", storedSessionId);
     }
+    const sessionId = storedSessionId;
 
     const token = localStorage.getItem("token") || "";
-    const wsUrl = buildWebSocketUrl({
-      roomCode,
-      username,
-      sessionId,
-      gameMode,
-      roomType,
-      rounds,
-      timeout,
-      token,
-    });
 
     let websocket: WebSocket | null = null;
     let reconnectTimer: ReturnType<typeof setTimeout> | null = null;
@@ -142,7 +138,21 @@ export const useWebSocket = (
     const connect = () => {
       if (cancelled) return;
 
-      websocket = new WebSocket(wsUrl);
+      // Read the guest token on every attempt: the first connection of a
+      // guest session receives it, and reconnects must present it
+      websocket = new WebSocket(
+        buildWebSocketUrl({
+          roomCode,
+          username,
+          sessionId,
+          gameMode,
+          roomType,
+          rounds,
+          timeout,
+          token,
+          guestToken: sessionStorage.getItem("guestToken") || "",
+        }),
+      );
       wsRef.current = websocket;
 
       websocket.onopen = () => {
@@ -165,11 +175,11 @@ export const useWebSocket = (
           // 🖼️ Wait for images before starting timer (fixes flag/silhouette delay)
           case "round_started": {
             const roundState = message.payload as GameStateSnapshot;
-            const flagCode = roundState.question?.flag_code;
+            const flagUrl = roundState.question && getFlagUrl(roundState.question);
 
-            if (flagCode) {
+            if (flagUrl) {
               const img = new Image();
-              img.src = `/flags/${flagCode}.png`;
+              img.src = flagUrl;
               img.onload = () => {
                 applySnapshot(roundState);
               };
@@ -224,6 +234,9 @@ export const useWebSocket = (
 
           // Handle room_joined — flat payload with full room state
           case "room_joined": {
+            if (message.payload.guest_token) {
+              sessionStorage.setItem("guestToken", message.payload.guest_token);
+            }
             applySnapshot(message.payload as GameStateSnapshot);
             break;
           }
@@ -315,6 +328,9 @@ export const useWebSocket = (
           }
 
           case "session_collision":
+          case "username_taken":
+            // The server will not take this session or name again, so stop retrying
+            cancelled = true;
             window.dispatchEvent(
               new CustomEvent("session_collision", {
                 detail: message.payload,
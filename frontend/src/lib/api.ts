@@ -8,6 +8,18 @@ const getMusicUrl = (path: string) => {
   return path;
 };
 
+// Round flags are served by the backend under an opaque URL so the question
+// never names the country; flag_code only arrives once the round is over.
+const getFlagUrl = (question: { flag_url?: string; flag_code?: string }) => {
+  if (question.flag_url) {
+    return `${BACKEND_BASE}${question.flag_url}`;
+  }
+  if (question.flag_code) {
+    return `https://flagcdn.com/w640/${question.flag_code.toLowerCase()}.png`;
+  }
+  return undefined;
+};
+
 type JsonObject = Record<string, unknown>;
 
 interface ApiResponse<T> {
@@ -65,12 +77,38 @@ class ApiClient {
     });
   }
 
+  async loginTwoFactor(challengeToken: string, code: string) {
+    return this.request('/auth/login/2fa', {
+      method: 'POST',
+      body: JSON.stringify({ challenge_token: challengeToken, code }),
+    });
+  }
+
   async logout() {
-    return this.request('/auth/logout', { method: 'POST' });
+    return this.request('/auth/logout', {
+      method: 'POST',
+      body: JSON.stringify({ refresh_token: localStorage.getItem('refreshToken') }),
+    });
   }
 
   async refreshToken() {
-    return this.request('/auth/refresh', { method: 'POST' });
+    return this.request('/auth/refresh', {
+      method: 'POST',
+      body: JSON.stringify({ refresh_token: localStorage.getItem('refreshToken') }),
+    });
+  }
+
+  // Moves progress earned as a guest in this browser session onto the account.
+  async claimGuestProgress() {
+    const sessionId = sessionStorage.getItem('sessionId');
+    const guestToken = sessionStorage.getItem('guestToken');
+    if (!sessionId || !guestToken) return null;
+    const claimed = await this.request('/auth/claim-guest', {
+      method: 'POST',
+      body: JSON.stringify({ session_id: sessionId, guest_token: guestToken }),
+    });
+    sessionStorage.removeItem('guestToken');
+    return claimed;
   }
 
   // User endpoints
@@ -176,6 +214,17 @@ class ApiClient {
     return response.json();
   }
 
+  async renameProfileAsset(assetId: string, name: string) {
+    return this.request(`/user/profile-assets/${assetId}`, {
+      method: 'PATCH',
+      body: JSON.stringify({ name }),
+    });
+  }
+
+  async getStorageUsage() {
+    return this.request('/user/storage');
+  }
+
   async deleteProfileAsset(assetId: string) {
     return this.request(`/user/profile-assets/${assetId}`, { method: 'DELETE' });
   }
@@ -187,6 +236,185 @@ class ApiClient {
     });
   }
 
+  // Player endpoints
+  async getPlayer(username: string) {
+    return this.request(`/players/${encodeURIComponent(username)}`);
+  }
+
+  async searchPlayers(query: string, page = 1, pageSize = 20) {
+    const params = new URLSearchParams({ q: query, page: String(page), page_size: String(pageSize) });
+    return this.request(`/players?${params}`);
+  }
+
+  // Friend endpoints
+  async getFriends() {
+    return this.request('/friends');
+  }
+
+  async sendFriendRequest(username: string) {
+    return this.request('/friends/requests', {
+      method: 'POST',
+      body: JSON.stringify({ username }),
+    });
+  }
+
+  async acceptFriendRequest(requestId: string) {
+    return this.request(`/friends/requests/${requestId}/accept`, { method: 'POST' });
+  }
+
+  async declineFriendRequest(requestId: string) {
+    return this.request(`/friends/requests/${requestId}`, { method: 'DELETE' });
+  }
+
+  async removeFriend(userId: string) {
+    return this.request(`/friends/${userId}`, { method: 'DELETE' });
+  }
+
+  async getBlockedPlayers() {
+    return this.request('/friends/blocks');
+  }
+
+  async blockPlayer(username: string) {
+    return this.request('/friends/blocks', {
+      method: 'POST',
+      body: JSON.stringify({ username }),
+    });
+  }
+
+  async unblockPlayer(userId: string) {
+    return this.request(`/friends/blocks/${userId}`, { method: 'DELETE' });
+  }
+
+  // Notification endpoints
+  async getNotifications(options: { unread?: boolean; before?: string; limit?: number } = {}) {
+    const params = new URLSearchParams();
+    if (options.unread) params.set('unread', 'true');
+    if (options.before) params.set('before', options.before);
+    if (options.limit) params.set('limit', String(options.limit));
+    const query = params.toString();
+    return this.request(`/notifications${query ? `?${query}` : ''}`);
+  }
+
+  async markNotificationsRead(ids: string[] = []) {
+    return this.request('/notifications/read', {
+      method: 'POST',
+      body: JSON.stringify({ ids }),
+    });
+  }
+
+  // Party endpoints
+  async getParty() {
+    return this.request('/party');
+  }
+
+  async createParty() {
+    return this.request('/party', { method: 'POST' });
+  }
+
+  async leaveParty() {
+    return this.request('/party', { method: 'DELETE' });
+  }
+
+  async inviteToParty(userId: string) {
+    return this.request('/party/invites', {
+      method: 'POST',
+      body: JSON.stringify({ user_id: userId }),
+    });
+  }
+
+  async joinParty(partyId: string) {
+    return this.request(`/party/${partyId}/join`, { method: 'POST' });
+  }
+
+  async kickPartyMember(userId: string) {
+    return this.request(`/party/members/${userId}`, { method: 'DELETE' });
+  }
+
+  async findMatch(gameMode: string) {
+    return this.request('/matchmaking', {
+      method: 'POST',
+      body: JSON.stringify({ game_mode: gameMode }),
+    });
+  }
+
+  // Leaderboard endpoints
+  async getModeLeaderboard(mode: string, window = 'all_time', limit = 50) {
+    const params = new URLSearchParams({ window, limit: String(limit) });
+    return this.request(`/leaderboards/modes/${encodeURIComponent(mode)}?${params}`);
+  }
+
+  async getRegionLeaderboard(region: string, window = 'all_time', limit = 50) {
+    const params = new URLSearchParams({ window, limit: String(limit) });
+    return this.request(`/leaderboards/regions/${encodeURIComponent(region)}?${params}`);
+  }
+
+  // Season endpoints
+  async getSeasons() {
+    return this.request('/seasons');
+  }
+
+  async getSeasonStandings(seasonId: string, limit = 50, offset = 0) {
+    const params = new URLSearchParams({ limit: String(limit), offset: String(offset) });
+    return this.request(`/seasons/${seasonId}/standings?${params}`);
+  }
+
+  // Tournament endpoints
+  async getTournaments(status?: string) {
+    return this.request(`/tournaments${status ? `?status=${encodeURIComponent(status)}` : ''}`);
+  }
+
+  async getTournament(tournamentId: string) {
+    return this.request(`/tournaments/${tournamentId}`);
+  }
+
+  async createTournament(data: JsonObject) {
+    return this.request('/tournaments', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async registerForTournament(tournamentId: string, name?: string) {
+    return this.request(`/tournaments/${tournamentId}/register`, {
+      method: 'POST',
+      body: JSON.stringify({ name }),
+    });
+  }
+
+  async withdrawFromTournament(tournamentId: string) {
+    return this.request(`/tournaments/${tournamentId}/register`, { method: 'DELETE' });
+  }
+
+  async checkInToTournament(tournamentId: string) {
+    return this.request(`/tournaments/${tournamentId}/check-in`, { method: 'POST' });
+  }
+
+  async startTournament(tournamentId: string) {
+    return this.request(`/tournaments/${tournamentId}/start`, { method: 'POST' });
+  }
+
+  async cancelTournament(tournamentId: string) {
+    return this.request(`/tournaments/${tournamentId}/cancel`, { method: 'POST' });
+  }
+
+  async resolveTournamentMatch(tournamentId: string, matchId: string, winnerId: string) {
+    return this.request(`/tournaments/${tournamentId}/matches/${matchId}/resolve`, {
+      method: 'POST',
+      body: JSON.stringify({ winner_id: winnerId }),
+    });
+  }
+
+  // Daily challenge endpoints
+  async startDailyChallenge() {
+    return this.request('/daily-challenge/start', { method: 'POST' });
+  }
+
+  async getDailyLeaderboard(date?: string, limit = 50) {
+    const params = new URLSearchParams({ limit: String(limit) });
+    if (date) params.set('date', date);
+    return this.request(`/daily-challenge/leaderboard?${params}`);
+  }
+
   // Room endpoints
   async getRooms() {
     return this.request('/rooms');
@@ -206,4 +434,4 @@ class ApiClient {
 }
 
 export const api = new ApiClient();
-export { getMusicUrl };
+export { getMusicUrl, getFlagUrl };
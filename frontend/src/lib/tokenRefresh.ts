@@ -2,20 +2,21 @@ const API_BASE = import.meta.env.VITE_API_URL || '/api/v2';
 
 export const refreshToken = async (): Promise<string | null> => {
   try {
-    const token = localStorage.getItem("token");
-    if (!token) return null;
+    const refresh = localStorage.getItem("refreshToken");
+    if (!refresh) return null;
 
     const response = await fetch(`${API_BASE}/auth/refresh`, {
       method: "POST",
       headers: {
         "Content-Type": "application/json",
       },
-      body: JSON.stringify({ access_token: token }),
+      body: JSON.stringify({ refresh_token: refresh }),
     });
 
     if (response.ok) {
       const data = await response.json();
       localStorage.setItem("token", data.access_token);
+      localStorage.setItem("refreshToken", data.refresh_token);
       console.log("Token refreshed successfully");
       return data.access_token;
     }
@@ -56,6 +57,7 @@ export const fetchWithTokenRefresh = async (
     } else {
       console.log("Token refresh failed, redirecting to login");
       localStorage.removeItem("token");
+      localStorage.removeItem("refreshToken");
       window.location.href = "/login";
     }
   }
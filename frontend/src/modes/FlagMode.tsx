@@ -1,5 +1,6 @@
 import { useState } from 'react';
 import { GameState } from '@/types/game';
+import { getFlagUrl } from '@/lib/api';
 import { Input } from '@/components/ui/input';
 import { Button } from '@/components/ui/button';
 
@@ -39,11 +40,11 @@ export function FlagMode({ gameState, username, onSubmitAnswer }: FlagModeProps)
       <div className="w-full h-52 sm:h-60">
         <div className="relative bg-card/95 backdrop-blur-sm rounded-3xl shadow-2xl border border-border/50 overflow-hidden h-full flex flex-col">
           {/* Flag Image */}
-          {gameState.question.flag_code && (
+          {getFlagUrl(gameState.question) && (
             <div className="flex-1 p-3 sm:p-4 flex items-center justify-center min-h-0">
               <div className="relative w-full h-full flex items-center justify-center">
                 <img
-                  src={`https://flagcdn.com/w640/${gameState.question.flag_code.toLowerCase()}.png`}
+                  src={getFlagUrl(gameState.question)}
                   alt="Flag"
                   className="max-w-full max-h-full object-contain rounded-xl shadow-lg border-2 border-white/10"
                   onError={(e) => {
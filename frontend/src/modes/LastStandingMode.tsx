@@ -15,6 +15,7 @@
 import { useState, useEffect } from 'react';
 import { useNavigate } from 'react-router-dom';
 import { GameState } from '@/types/game';
+import { getFlagUrl } from '@/lib/api';
 import { Input } from '@/components/ui/input';
 import { Button } from '@/components/ui/button';
 import { Card } from '@/components/ui/card';
@@ -170,11 +171,11 @@ export function LastStandingMode({ gameState, username, onSubmitAnswer }: LastSt
                 <div className="w-full h-64 sm:h-80">
                     <div className="relative h-full flex flex-col">
                         {/* Flag Image */}
-                        {gameState.question.flag_code && (
+                        {getFlagUrl(gameState.question) && (
                             <div className="flex-1 p-4 sm:p-6 flex items-center justify-center min-h-0">
                                 <div className="relative w-full h-full flex items-center justify-center">
                                     <img
-                                        src={`https://flagcdn.com/w640/${gameState.question.flag_code.toLowerCase()}.png`}
+                                        src={getFlagUrl(gameState.question)}
                                         alt="Flag"
                                         className="max-w-full max-h-full object-contain rounded-xl shadow-lg border-2 border-white/10"
                                     />
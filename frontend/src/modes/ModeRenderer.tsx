@@ -103,6 +103,7 @@ export function ModeRenderer({
       //   return <AudioMode gameState={gameState} username={username} onSubmitAnswer={onSubmitAnswer} />;
 
       case 'FLAG':
+      case 'PRACTICE':
         /* FlagMode also receives roomCode and roomType for conditional UI */
         return <FlagMode gameState={gameState} username={username} onSubmitAnswer={onSubmitAnswer} roomCode={roomCode} roomType={roomType} />;
 
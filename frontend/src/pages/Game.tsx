@@ -225,14 +225,14 @@ const Game = () => {
     navigate("/lobby");
   };
 
-  // Handle room closed or expired
+  // Handle room closed or expired, or being kicked by a moderator
   useEffect(() => {
     if (!ws) return;
 
     const handleMessage = (event: MessageEvent) => {
       try {
         const message = JSON.parse(event.data);
-        if (message.type === "room_closed" || message.type === "room_expired") {
+        if (message.type === "room_closed" || message.type === "room_expired" || message.type === "kicked") {
           // Inline cleanup to avoid stale closure
           if (ws && ws.readyState === WebSocket.OPEN) {
             ws.send(JSON.stringify({ type: "leave_room" }));
@@ -375,7 +375,9 @@ const Game = () => {
           const answerData = message.payload;
           if (answerData.is_correct && answerData.player !== config.username) {
             toast({
-              title: `${answerData.player} guessed ${answerData.country_name}! 🎉`,
+              title: answerData.country_name
+                ? `${answerData.player} guessed ${answerData.country_name}! 🎉`
+                : `${answerData.player} guessed it! 🎉`,
               duration: 2000,
             });
           }
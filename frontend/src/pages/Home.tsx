@@ -5,6 +5,7 @@ import { useNavigate } from "react-router-dom";
 import { ThemeToggle } from "@/components/ThemeToggle";
 import { useEffect, useState } from "react";
 import { getOrCreateGuestUsername } from "@/lib/guestUsername";
+import { api } from "@/lib/api";
 import {
   DropdownMenu,
   DropdownMenuContent,
@@ -26,6 +27,7 @@ const Home = () => {
   }, []);
 
   const handleLogout = () => {
+    api.logout().catch(() => undefined);
     localStorage.removeItem('token');
     localStorage.removeItem('refreshToken');
     localStorage.removeItem('username');
@@ -8,7 +8,7 @@ import { GameConfig } from "@/types/game";
 import { GameLobby } from "@/components/lobby";
 import { getOrCreateGuestUsername } from "@/lib/guestUsername";
 
-type GameMode = "FLAG" | "WORLD_MAP" | "CAPITAL_RUSH" | "LAST_STANDING" | "BORDER_LOGIC" | "SILHOUETTE" | "TEAM_BATTLE" | "EMOJI"; // "AUDIO" - TODO: Will be added later
+type GameMode = "FLAG" | "WORLD_MAP" | "CAPITAL_RUSH" | "LAST_STANDING" | "BORDER_LOGIC" | "SILHOUETTE" | "TEAM_BATTLE" | "EMOJI" | "PRACTICE"; // "AUDIO" - TODO: Will be added later
 type RoomType = "SINGLE" | "PRIVATE" | "PUBLIC";
 
 interface PublicRoom {
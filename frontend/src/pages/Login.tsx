@@ -1,4 +1,4 @@
-import { useState } from "react";
+import { useEffect, useState } from "react";
 import { useNavigate, Link } from "react-router-dom";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
@@ -11,28 +11,61 @@ import { api } from "@/lib/api";
 
 interface LoginResponse {
   access_token: string;
+  refresh_token: string;
   user: {
     username: string;
   };
 }
 
+interface TwoFactorChallenge {
+  two_factor_required: true;
+  challenge_token: string;
+}
+
 const Login = () => {
   const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
+  const [challengeToken, setChallengeToken] = useState("");
+  const [code, setCode] = useState("");
   const [loading, setLoading] = useState(false);
   const navigate = useNavigate();
   const { toast } = useToast();
 
+  // Social logins on 2FA accounts come back here with a challenge in the fragment.
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    const token = params.get("challenge_token");
+    if (token) {
+      setChallengeToken(token);
+      window.history.replaceState(null, "", window.location.pathname + window.location.search);
+    }
+  }, []);
+
+  const completeLogin = (data: LoginResponse) => {
+    localStorage.setItem("token", data.access_token);
+    localStorage.setItem("refreshToken", data.refresh_token);
+    localStorage.setItem("username", data.user.username);
+    api.claimGuestProgress().catch(() => undefined);
+    toast({ title: "Login successful!", description: "Welcome back!" });
+    navigate("/lobby");
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setLoading(true);
 
     try {
-      const data = await api.login(email, password) as LoginResponse;
-      localStorage.setItem("token", data.access_token);
-      localStorage.setItem("username", data.user.username);
-      toast({ title: "Login successful!", description: "Welcome back!" });
-      navigate("/lobby");
+      if (challengeToken) {
+        completeLogin(await api.loginTwoFactor(challengeToken, code) as LoginResponse);
+        return;
+      }
+
+      const data = await api.login(email, password) as LoginResponse | TwoFactorChallenge;
+      if ("two_factor_required" in data) {
+        setChallengeToken(data.challenge_token);
+        return;
+      }
+      completeLogin(data);
     } catch (error: unknown) {
       const description = error instanceof Error ? error.message : "Invalid credentials";
       toast({ title: "Login failed", description, variant: "destructive" });
@@ -61,6 +94,28 @@ const Login = () => {
               <CardDescription className="text-sm sm:text-base">Sign in to your BriWorld account</CardDescription>
             </CardHeader>
             <CardContent className="p-4 sm:p-6">
+              {challengeToken ? (
+              <form onSubmit={handleSubmit} className="space-y-3 sm:space-y-4">
+                <div className="space-y-1 sm:space-y-2">
+                  <Label htmlFor="code" className="text-sm sm:text-base">Authentication code</Label>
+                  <Input
+                    id="code"
+                    inputMode="numeric"
+                    autoComplete="one-time-code"
+                    placeholder="123456 or recovery code"
+                    value={code}
+                    onChange={(e) => setCode(e.target.value)}
+                    required
+                    autoFocus
+                    className="h-10 sm:h-12 text-sm sm:text-base"
+                  />
+                </div>
+                <Button type="submit" className="w-full h-10 sm:h-12 text-sm sm:text-base" disabled={loading}>
+                  <LogIn className="w-3 h-3 sm:w-4 sm:h-4 mr-1 sm:mr-2" />
+                  {loading ? "Verifying..." : "Verify"}
+                </Button>
+              </form>
+              ) : (
               <form onSubmit={handleSubmit} className="space-y-3 sm:space-y-4">
                 <div className="space-y-1 sm:space-y-2">
                   <Label htmlFor="email" className="text-sm sm:text-base">Email</Label>
@@ -94,6 +149,7 @@ const Login = () => {
                   {loading ? "Signing in..." : "Sign In"}
                 </Button>
               </form>
+              )}
               <div className="mt-3 sm:mt-4 text-center text-xs sm:text-sm">
                 Don't have an account?{" "}
                 <Link to="/register" className="text-primary hover:underline">
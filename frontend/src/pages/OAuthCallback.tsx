@@ -0,0 +1,44 @@
+import { useEffect } from "react";
+import { useNavigate } from "react-router-dom";
+import { useToast } from "@/hooks/use-toast";
+import { BackgroundLayout } from "@/components/BackgroundLayout";
+import { api } from "@/lib/api";
+
+// Landing page for social login. The backend redirects here with the tokens
+// in the URL fragment.
+const OAuthCallback = () => {
+  const navigate = useNavigate();
+  const { toast } = useToast();
+
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    const accessToken = params.get("access_token");
+    const refreshToken = params.get("refresh_token");
+    const username = params.get("username");
+
+    window.history.replaceState(null, "", window.location.pathname);
+
+    if (!accessToken || !refreshToken || !username) {
+      toast({ title: "Login failed", description: "Missing login response", variant: "destructive" });
+      navigate("/login");
+      return;
+    }
+
+    localStorage.setItem("token", accessToken);
+    localStorage.setItem("refreshToken", refreshToken);
+    localStorage.setItem("username", username);
+    api.claimGuestProgress().catch(() => undefined);
+    toast({ title: "Login successful!", description: params.get("new_account") ? "Welcome to BriWorld!" : "Welcome back!" });
+    navigate("/lobby");
+  }, [navigate, toast]);
+
+  return (
+    <BackgroundLayout>
+      <div className="min-h-screen flex items-center justify-center p-4 text-muted-foreground">
+        Signing you in...
+      </div>
+    </BackgroundLayout>
+  );
+};
+
+export default OAuthCallback;
@@ -23,8 +23,11 @@ const Register = () => {
     setLoading(true);
 
     try {
-      await api.register(username, email, password);
-      toast({ title: "Registration successful!", description: "Please log in with your credentials." });
+      const data = await api.register(username, email, password) as { warning?: string };
+      const description = data.warning
+        ? `${data.warning} Please log in with your credentials.`
+        : "Please log in with your credentials.";
+      toast({ title: "Registration successful!", description });
       navigate("/login");
     } catch (error: unknown) {
       const description = error instanceof Error ? error.message : "Registration failed";
@@ -369,6 +369,7 @@ export default function Silhouette() {
                       "border-green-500 bg-green-500/20 text-green-400",
                     showResult &&
                       selectedAnswer === option &&
+                      wsGameState.question.country_name &&
                       option !== wsGameState.question.country_name &&
                       "border-red-500 bg-red-500/20 text-red-400",
                     !showResult &&
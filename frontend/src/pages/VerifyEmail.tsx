@@ -0,0 +1,85 @@
+import { useState, useEffect, useRef } from "react";
+import { useNavigate, useSearchParams } from "react-router-dom";
+import { Button } from "@/components/ui/button";
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "@/components/ui/card";
+import { MailCheck, ArrowLeft } from "lucide-react";
+import { BackgroundLayout } from "@/components/BackgroundLayout";
+
+type Status = "verifying" | "verified" | "failed";
+
+const VerifyEmail = () => {
+  const [status, setStatus] = useState<Status>("verifying");
+  const [message, setMessage] = useState("Confirming your email address...");
+  const [searchParams] = useSearchParams();
+  const navigate = useNavigate();
+  const token = searchParams.get("token");
+  // Verification tokens are single-use, so the request must not repeat when
+  // the effect runs twice in development.
+  const requested = useRef(false);
+
+  useEffect(() => {
+    if (requested.current) return;
+    requested.current = true;
+
+    if (!token) {
+      setStatus("failed");
+      setMessage("No verification token found");
+      return;
+    }
+
+    const verify = async () => {
+      try {
+        const response = await fetch(
+          `${import.meta.env.VITE_API_URL || ''}/api/v2/auth/verify?token=${encodeURIComponent(token)}`,
+        );
+        const data = await response.json();
+        if (!response.ok) {
+          throw new Error(data.error || 'Failed to verify email');
+        }
+        setStatus("verified");
+        setMessage("Your email address is verified");
+      } catch (error: unknown) {
+        setStatus("failed");
+        setMessage(error instanceof Error ? error.message : "Failed to verify email");
+      }
+    };
+    verify();
+  }, [token]);
+
+  return (
+    <BackgroundLayout>
+      <div className="min-h-screen flex items-center justify-center p-2 sm:p-4">
+        <div className="w-full max-w-md mx-2 sm:mx-4">
+          <Button
+            variant="outline"
+            className="mb-3 sm:mb-4 h-8 sm:h-10 text-xs sm:text-sm"
+            onClick={() => navigate("/login")}
+          >
+            <ArrowLeft className="w-3 h-3 sm:w-4 sm:h-4 mr-1 sm:mr-2" />
+            Back to Login
+          </Button>
+
+          <Card className="bg-card text-card-foreground">
+            <CardHeader className="text-center p-4 sm:p-6">
+              <CardTitle className="text-xl sm:text-2xl">Verify Email</CardTitle>
+              <CardDescription className="text-sm sm:text-base">{message}</CardDescription>
+            </CardHeader>
+            {status !== "verifying" && (
+              <CardContent className="p-4 sm:p-6">
+                <Button
+                  className="w-full h-10 sm:h-12 text-sm sm:text-base"
+                  onClick={() => navigate(status === "verified" ? "/lobby" : "/login")}
+                >
+                  <MailCheck className="w-3 h-3 sm:w-4 sm:h-4 mr-1 sm:mr-2" />
+                  {status === "verified" ? "Continue" : "Back to Login"}
+                </Button>
+              </CardContent>
+            )}
+          </Card>
+        </div>
+      </div>
+    </BackgroundLayout>
+  );
+};
+
+export default VerifyEmail;
@@ -12,7 +12,8 @@ export type GameMode =
   | "SILHOUETTE"
   | "TEAM_BATTLE"
   | "EMOJI"
-  | "BORDER_LOGIC"; // AUDIO reserved
+  | "BORDER_LOGIC"
+  | "PRACTICE"; // AUDIO reserved
 
 export type RoomType = "SINGLE" | "PRIVATE" | "PUBLIC";
 
@@ -96,8 +97,11 @@ export interface GameState {
 
 export interface Question {
   type: string;
+  /** Opaque URL of the flag to guess */
+  flag_url?: string;
+  /** Only sent once the round has ended */
   flag_code?: string;
-  country_name: string;
+  country_name?: string;
   country_code?: string;
   time_limit: number;
 
@@ -237,12 +241,17 @@ export interface GameStateSnapshot extends GameState {
   player_banners?: Record<string, string>;
 }
 
+export interface RoomJoinedPayload extends GameStateSnapshot {
+  // Only sent to guests; proves the session is theirs when claiming its progress
+  guest_token?: string;
+}
+
 /* -------------------------------------------------------------------------- */
 /*                       PRODUCTION-GRADE WS MESSAGE CONTRACT                 */
 /* -------------------------------------------------------------------------- */
 
 export type WebSocketMessage =
-  | { type: "room_joined"; payload: StateSnapshotPayload }
+  | { type: "room_joined"; payload: RoomJoinedPayload }
   | { type: "room_update"; payload: RoomUpdatePayload }
   | { type: "state_snapshot"; payload: StateSnapshotPayload }
   | { type: "game_started"; payload: StateSnapshotPayload }
@@ -4,7 +4,20 @@ export interface DailyChallenge {
   game_mode: string;
   difficulty: 'EASY' | 'MEDIUM' | 'HARD';
   country_code?: string;
+  rounds: number;
+  round_time_limit: number;
   reward: number;
+  played?: boolean;
+  streak?: DailyStreak;
+}
+
+export interface DailyStreak {
+  current: number;
+  longest: number;
+  freezes: number;
+  last_day?: string;
+  played_today: boolean;
+  timezone: string;
 }
 
 export interface Season {
@@ -33,6 +46,10 @@ export interface CountryMastery {
   xp: number;
   correct: number;
   incorrect: number;
+  ease: number;
+  interval_days: number;
+  repetitions: number;
+  due_at?: string;
   updated_at: string;
 }
 
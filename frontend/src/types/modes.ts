@@ -6,7 +6,8 @@ export type GameMode =
     | 'EMOJI'
     | 'TEAM_BATTLE'
     | 'LAST_STANDING'
-    | 'BORDER_LOGIC';
+    | 'BORDER_LOGIC'
+    | 'PRACTICE';
     // | 'AUDIO'; // TODO: Will be added later
 
 export interface GameModeConfig {
@@ -91,6 +92,15 @@ export const MODES: Record<GameMode, GameModeConfig> = {
         defaultTimeout: 20,
         minPlayers: 1,
         icon: '🧩'
+    },
+    PRACTICE: {
+        id: 'PRACTICE',
+        title: 'Practice',
+        description: 'Review the flags you keep missing',
+        isTimed: true,
+        defaultTimeout: 20,
+        minPlayers: 1,
+        icon: '🎯'
     }
     // AUDIO: { // TODO: Will be added later
     //     id: 'AUDIO',
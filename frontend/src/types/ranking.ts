@@ -5,6 +5,28 @@ export interface RankInfo {
   position: number;
   placement_matches: number;
   is_placement_complete: boolean;
+  season: SeasonRank | null;
+  decay?: RankDecay;
+}
+
+export interface RankDecay {
+  applies: boolean;
+  floor_rating: number;
+  points_per_day: number;
+  inactive_days: number;
+  inactive_since?: string;
+  starts_at?: string;
+  decaying: boolean;
+}
+
+export interface SeasonRank {
+  season_id: string;
+  rating: number;
+  rank: string;
+  rank_tier: number;
+  points: number;
+  wins: number;
+  losses: number;
 }
 
 export interface LeaderboardEntry {
@@ -20,12 +42,52 @@ export interface LeaderboardEntry {
   position?: number;
 }
 
+export type LeaderboardWindow = 'daily' | 'weekly' | 'season' | 'all_time';
+
+export interface BoardEntry {
+  position: number;
+  user_id: string;
+  username: string;
+  avatar_url: string;
+  rank: string;
+  points: number;
+  correct?: number;
+  answered?: number;
+  accuracy?: number;
+}
+
+export interface Board {
+  board: 'mode' | 'region';
+  key: string;
+  window: LeaderboardWindow;
+  from?: string;
+  entries: BoardEntry[];
+  me?: BoardEntry;
+  around_me?: BoardEntry[];
+}
+
 export interface Season {
   id: string;
   name: string;
   start_date: string;
   end_date: string;
   is_active: boolean;
+  ended_at?: string;
+  next_season_id?: string;
+}
+
+export interface SeasonStanding {
+  season_id: string;
+  user_id: string;
+  username: string;
+  avatar_url: string;
+  position: number;
+  rating: number;
+  rank: string;
+  rank_tier: number;
+  games: number;
+  wins: number;
+  reward: number;
 }
 
 export const RANK_COLORS: Record<string, string> = {
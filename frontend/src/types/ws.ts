@@ -92,7 +92,7 @@ export type ClientCommand =
 
 export interface RoomJoinedEvent {
   type: "room_joined";
-  payload: GameState;
+  payload: GameState & { guest_token?: string };
 }
 
 export interface RoomUpdateEvent {